@@ -1,17 +1,116 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/openswe/go-swe-agent/pkg/agents"
 	"github.com/openswe/go-swe-agent/pkg/graph"
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/metrics"
+	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/tools"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+	"github.com/openswe/go-swe-agent/pkg/webhook"
 )
 
 var (
-	workingDir string
-	request    string
+	workingDir    string
+	request       string
+	requestFile   string
+	criteria      []string
+	images        []string
+	artifactsDir  string
+	toolLog       string
+	toolLogMB     int64
+	traceOut      string
+	eventLog      string
+	memLimitMB    int64
+	cpuLimit      float64
+	unsafe        bool
+	allowCmds     []string
+	endpoint      string
+	region        string
+	provider      string
+	model         string
+	estimateOnly  bool
+	maxCost       float64
+	pullRequest   int
+	commitSHA     string
+	githubRepo    string
+	plannerIters  int
+	maxIters      int
+	maxToolCalls  int
+	maxTurns      int
+	nudge         string
+	maxNudges     int
+	maxIdleTurns  int
+	onlyTasks     []int
+	reproFirst    bool
+	skipTasks     []int
+	indexWorkers  int
+	readWorkers   int
+	readBudgetKB  int
+	digestLength  int
+	maxFiles      int
+	generated     string
+	detectEnc     bool
+	submodules    string
+	protectedList []string
+	cleanScratch  bool
+	updateIgnore  bool
+	scopePolicy   string
+	batchFile     string
+	batchOnFail   string
+	planTokens    int
+	execTokens    int
+	maxLineLength int
+	maxReadLines  int
+	bashTimeout   int
+	stopSeqs      []string
+	requestTag    string
+	blockedSignal string
+	explain       bool
+	stream        bool
+	dryRun        bool
+	diffOutput    string
+	checkpoint    string
+	resume        string
+	emitPlan      string
+	planIn        string
+	interactive   bool
+	approvePlan   bool
+	autoApprove   bool
+	explainErrs   bool
+	diffHook      string
+	diffHookFix   bool
+	verifyCmd     string
+	verifyCadence string
+	repairs       int
+	maxReplans    int
+	replanAt      float64
+	allowUnsafe   bool
+	allowOutside  bool
+	interleaved   bool
+	maxSteps      int
+	parallel      int
+	partialTasks  string
+	stallWindow   int
+	stallRepeat   float64
+	webhookURL    string
+	webhookKey    string
+	metricsOn     bool
+	prices        []string
+	metricsFile   string
+	explainIters  int
+	explainFormat string
+	explainOut    string
 )
 
 func main() {
@@ -27,13 +126,142 @@ This agent will:
 
 Example:
   go-swe-agent --dir ./my-project --request "Add a new REST API endpoint for user management"
-  go-swe-agent -d . -r "Fix the bug in the authentication system"`,
+  go-swe-agent -d . -r "Fix the bug in the authentication system"
+  go-swe-agent -r "Add a /health endpoint" --criteria "GET /health returns 200" --criteria "go test ./... passes"
+  go-swe-agent --request-file request.json
+  go-swe-agent --batch requests.yaml --batch-on-failure continue
+  go-swe-agent --pr 42 -r "Address the review comments on this pull request"
+  go-swe-agent --resume checkpoint.json --interactive
+  go-swe-agent -r "Split the config package" --emit-plan plan.json
+  go-swe-agent --plan-in plan.json`,
 		Run: runAgent,
 	}
 
 	rootCmd.Flags().StringVarP(&workingDir, "dir", "d", ".", "Working directory for the agent")
 	rootCmd.Flags().StringVarP(&request, "request", "r", "", "The task request for the agent")
-	rootCmd.MarkFlagRequired("request")
+	rootCmd.Flags().BoolVar(&allowUnsafe, "allow-unsafe-dir", false, "Allow a working directory such as / or $HOME that is too broad for autonomous changes")
+	rootCmd.Flags().BoolVar(&allowOutside, "allow-outside-workdir", false, "Let the file tools read and write paths outside the working directory")
+	rootCmd.Flags().StringVar(&batchFile, "batch", "", "Run the requests in this file one after another, one per line or as a YAML list, each building on the last")
+	rootCmd.Flags().StringVar(&batchOnFail, "batch-on-failure", graph.BatchStop, "What a --batch does when a request fails: stop (skip the rest) or continue")
+	rootCmd.Flags().StringVar(&requestFile, "request-file", "", "Path to a JSON structured request (request, acceptance_criteria)")
+	rootCmd.Flags().StringArrayVar(&criteria, "criteria", nil, "Acceptance criterion to verify at the end of the run (repeatable)")
+	rootCmd.Flags().StringArrayVar(&images, "image", nil, "Image file (PNG, JPEG, GIF, WebP) to show the planner, e.g. a screenshot (repeatable)")
+	rootCmd.Flags().StringVar(&artifactsDir, "artifacts-dir", "", "Collect all run outputs under <dir>/<run-id> with a manifest.json")
+	rootCmd.Flags().StringVar(&toolLog, "tool-log", "", "Stream every tool call and result to this JSONL file as they happen")
+	rootCmd.Flags().Int64Var(&toolLogMB, "tool-log-max-size", 10, "Rotate the tool log after it reaches this many megabytes")
+	rootCmd.Flags().StringVar(&traceOut, "trace-out", "", "Write the model's text and chosen tools for every turn to this JSONL file")
+	rootCmd.Flags().StringVar(&eventLog, "log-json", "", "Write the run as structured JSONL events (plan, task status, model calls, tool calls with results) to this file")
+	rootCmd.Flags().Int64Var(&memLimitMB, "mem-limit", 0, "Memory limit in megabytes for each command the agent runs, on Linux (0 for no limit)")
+	rootCmd.Flags().BoolVar(&unsafe, "unsafe", false, "Run every bash command, without refusing those that damage the machine (rm -rf /, sudo, curl | sh, mkfs, shutdown, ...)")
+	rootCmd.Flags().StringSliceVar(&allowCmds, "allow-commands", nil, "Only let bash run these programs, e.g. go,make,git,ls,cat,grep (shell builtins such as cd and echo are always allowed)")
+	rootCmd.Flags().Float64Var(&cpuLimit, "cpu-limit", 0, "CPU cores each command the agent runs may use, e.g. 2 or 0.5, on Linux (0 for no limit)")
+	rootCmd.Flags().StringVar(&endpoint, "endpoint", "", "Override the LLM provider base URL, e.g. an internal API gateway")
+	rootCmd.Flags().StringVar(&region, "region", "", "LLM provider region (defaults to $AWS_REGION or us-west-2 for Bedrock)")
+	rootCmd.Flags().StringVar(&provider, "provider", llm.ProviderBedrock, "LLM provider: bedrock, anthropic (needs ANTHROPIC_API_KEY) or openai (needs OPENAI_API_KEY)")
+	rootCmd.Flags().StringVar(&model, "model", "", fmt.Sprintf("Model ID for every phase (default %s on Bedrock, %s on Anthropic, %s on OpenAI)", llm.DefaultBedrockModel, llm.DefaultAnthropicModel, llm.DefaultOpenAIModel))
+	rootCmd.Flags().BoolVar(&estimateOnly, "estimate-only", false, "Generate the plan and print a cost/time estimate without executing it")
+	rootCmd.Flags().Float64Var(&maxCost, "max-cost", 0, "Abort before execution if the estimated cost in USD exceeds this amount")
+	rootCmd.Flags().IntVar(&pullRequest, "pr", 0, "GitHub pull request number whose diff and review comments are used as planning context")
+	rootCmd.Flags().StringVar(&commitSHA, "commit", "", "Git commit SHA whose changes are used as planning context")
+	rootCmd.Flags().StringVar(&githubRepo, "github-repo", "", "GitHub repository as owner/name (defaults to the origin remote)")
+	rootCmd.Flags().IntVar(&plannerIters, "planner-iterations", 0, "Exploration turns for the planner (default: chosen from repository size)")
+	rootCmd.Flags().IntVar(&maxIters, "max-iterations", 0, "Maximum LLM turns per task (default: chosen from repository size)")
+	rootCmd.Flags().IntVar(&planTokens, "planner-max-tokens", 0, fmt.Sprintf("Output tokens per planning response (default %d, lowered to the model's limit)", agents.DefaultPlannerMaxTokens))
+	rootCmd.Flags().IntVar(&execTokens, "executor-max-tokens", 0, fmt.Sprintf("Output tokens per execution response; raise it if large file writes get cut off (default %d, lowered to the model's limit)", agents.DefaultExecutorMaxTokens))
+	rootCmd.Flags().IntVar(&maxToolCalls, "max-tool-calls", 0, fmt.Sprintf("Maximum tool executions per task, independent of turns (default %d)", agents.DefaultMaxToolCalls))
+	rootCmd.Flags().BoolVar(&interleaved, "interleaved", false, "Plan one step at a time, choosing each after seeing how the previous one went, instead of a full plan upfront")
+	rootCmd.Flags().IntVar(&maxSteps, "max-steps", agents.DefaultMaxSteps, "Maximum steps in an --interleaved run")
+	rootCmd.Flags().IntVar(&parallel, "parallel", 1, "Run up to N tasks at a time; a task starts once the tasks the plan says it depends on are done")
+	rootCmd.Flags().BoolVar(&reproFirst, "repro-first", false, "For bug fixes: write a failing test that reproduces the bug first, and check it passes after the fix")
+	rootCmd.Flags().IntSliceVar(&onlyTasks, "only-tasks", nil, "Execute only these plan tasks, by number (e.g. 2,4,5)")
+	rootCmd.Flags().IntSliceVar(&skipTasks, "skip-tasks", nil, "Do not execute these plan tasks, by number (e.g. 3)")
+	rootCmd.Flags().StringVar(&nudge, "nudge", "", "Message sent when the model answers a task without a tool call (default: built-in)")
+	rootCmd.Flags().StringVar(&partialTasks, "partial-tasks", graph.PartialFlag, "What to do when a task is only partially done: flag (report the remaining work) or follow-up (add a task for it)")
+	rootCmd.Flags().IntVar(&stallWindow, "stall-window", agents.DefaultStallWindow, "Tool calls the run watchdog looks at; the run halts when they mostly repeat without progress (0 disables)")
+	rootCmd.Flags().Float64Var(&stallRepeat, "stall-repeat", agents.DefaultStallRepeat, "Fraction of the --stall-window calls that must repeat earlier ones to halt the run")
+	rootCmd.Flags().IntVar(&maxNudges, "max-nudges", agents.DefaultMaxNudges, "Consecutive turns without a tool call before a task fails as stalled")
+	rootCmd.Flags().IntVar(&maxIdleTurns, "max-idle-turns", agents.DefaultMaxIdleTurns, "Consecutive turns without a tool call or new text before a task fails as stalled")
+	rootCmd.Flags().IntVar(&maxTurns, "max-turns", 0, "Conversation turns kept per phase; older turns are summarized and dropped (0 keeps all)")
+	rootCmd.Flags().IntVar(&indexWorkers, "index-concurrency", 0, "Workers reading files while analyzing the repository (default: one per CPU)")
+	rootCmd.Flags().IntVar(&readWorkers, "read-concurrency", tools.DefaultReadConcurrency, "Files the read_files tool reads at once")
+	rootCmd.Flags().IntVar(&readBudgetKB, "read-budget", tools.DefaultReadBudget/1024, "Kilobytes of file content one read_files call returns; further files are skipped and named")
+	rootCmd.Flags().IntVar(&maxFiles, "max-files-changed", 0, "Maximum distinct files the agent may change; further new files are refused (0 for no limit)")
+	rootCmd.Flags().StringVar(&generated, "generated-files", tools.GeneratedWarn, "What to do when the agent edits generated or vendored files: warn, block or off")
+	rootCmd.Flags().BoolVar(&cleanScratch, "clean-scratch", false, "Remove files the agent created that look left over (logs, captured output, backups) at the end of the run")
+	rootCmd.Flags().StringVar(&scopePolicy, "scope-violations", graph.ScopeWarn, "What to do with changes outside the request file's target_files or forbidden_files: warn, fail or revert")
+	rootCmd.Flags().BoolVar(&updateIgnore, "update-gitignore", false, "Add .gitignore entries for build output and dependency directories created during the run, instead of only proposing them")
+	rootCmd.Flags().StringSliceVar(&protectedList, "protected-paths", tools.DefaultProtectedPaths, "Files the agent must not change, as .gitattributes-like patterns; pass --protected-paths= when changing them is the task")
+	rootCmd.Flags().StringVar(&submodules, "submodules", tools.SubmodulesRecurse, "Whether list_files and search go into git submodules: recurse or skip")
+	rootCmd.Flags().BoolVar(&detectEnc, "detect-encoding", false, "Detect and keep non-UTF-8 file encodings (Latin-1, Windows-1252, UTF-16) in read_file and write_file")
+	rootCmd.Flags().IntVar(&maxLineLength, "max-line-length", tools.DefaultMaxLineLength, "Longest line read_file and search return in full; longer lines are shortened (0 to disable)")
+	rootCmd.Flags().IntVar(&maxReadLines, "max-read-lines", tools.DefaultMaxReadLines, "Most lines read_file returns when no line range is given (0 to return whole files)")
+	rootCmd.Flags().IntVar(&bashTimeout, "bash-timeout", int(tools.DefaultBashTimeout.Seconds()), "Seconds a bash command may run before it is killed, unless the call sets timeout_seconds (0 to disable)")
+	rootCmd.Flags().IntVar(&digestLength, "digest-length", agents.DefaultDigestLength, "Characters of each completed task's output shown to later tasks (0 to omit)")
+	rootCmd.Flags().StringVar(&requestTag, "request-tag", "", "Tag sent with every LLM request, with the run ID, phase and task ID, for gateway attribution")
+	rootCmd.Flags().StringArrayVar(&stopSeqs, "stop-sequence", nil, "Provider stop sequence that ends a model response (repeatable)")
+	rootCmd.Flags().StringVar(&blockedSignal, "blocked-signal", "", "Sentinel the model emits when it needs human input; halts the run (e.g. NEEDS_HUMAN_INPUT)")
+	rootCmd.Flags().BoolVar(&explain, "explain-actions", false, "Require a stated intent before every bash/write_file/edit_file call and record it (non-blocking audit trail)")
+	rootCmd.Flags().BoolVar(&stream, "stream", false, "Print the model's text during execution as it is generated")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview file changes as diffs and bash commands without writing or running anything")
+	rootCmd.Flags().StringVar(&diffOutput, "diff-output", "", "Write the unified diff of all changes to this file at the end of the run instead of printing it")
+	rootCmd.Flags().StringVar(&checkpoint, "checkpoint", "", "Save resumable state to this file after planning and each task")
+	rootCmd.Flags().StringVar(&resume, "resume", "", "Continue the run saved in this checkpoint instead of starting a new one")
+	rootCmd.Flags().StringVar(&emitPlan, "emit-plan", "", "Write the plan and its request to this file and stop before execution")
+	rootCmd.Flags().StringVar(&planIn, "plan-in", "", "Execute the plan in this file, written by --emit-plan and possibly edited, instead of planning")
+	rootCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Answer the agent's questions from the terminal instead of halting the run")
+	rootCmd.Flags().BoolVar(&explainErrs, "explain-errors", false, "Explain each task failure and suggest a fix, with one extra model call per failed task")
+	rootCmd.Flags().BoolVarP(&autoApprove, "yes", "y", false, "Run the plan without asking for confirmation (the default when stdin is not a terminal)")
+	rootCmd.Flags().BoolVar(&autoApprove, "auto-approve", false, "Same as --yes")
+	rootCmd.Flags().BoolVar(&approvePlan, "approve-plan", false, "Choose which tasks to run before each round of execution; the rest is refreshed and offered again (requires --interactive)")
+	rootCmd.Flags().StringVar(&diffHook, "diff-hook", "", "Shell command that receives the full diff on stdin after execution; a non-zero exit blocks success")
+	rootCmd.Flags().BoolVar(&diffHookFix, "diff-hook-feedback", false, "Feed a failing diff hook's output back to the model for one fix attempt")
+	rootCmd.Flags().StringVar(&verifyCmd, "verify-command", "", "Build/test command run after tasks and at the end, e.g. \"go build ./... && go test ./...\"")
+	rootCmd.Flags().IntVar(&repairs, "repair-attempts", 0, "Repair passes when the final --verify-command fails with every task complete: plan a fix from the output, run it and check again (0 disables)")
+	rootCmd.Flags().IntVar(&maxReplans, "max-replans", 0, "Times the plan may be revised when too many tasks fail: the failures and their errors go back to the planner, whose new tasks then run (0 disables)")
+	rootCmd.Flags().Float64Var(&replanAt, "replan-threshold", graph.DefaultReplanThreshold, "Share of the tasks run, from 0 to 1, that must fail before --max-replans revises the plan (0 re-plans after any failure)")
+	rootCmd.Flags().StringVar(&verifyCadence, "verify-cadence", graph.VerifyAuto, "When --verify-command runs: each (after every task), end (once), or auto (chosen by how long the first run takes)")
+	rootCmd.Flags().StringVar(&webhookURL, "webhook", "", "POST run lifecycle events as JSON to this URL")
+	rootCmd.Flags().StringVar(&webhookKey, "webhook-secret", os.Getenv("GO_SWE_AGENT_WEBHOOK_SECRET"), "Secret used to HMAC-sign webhook payloads")
+	rootCmd.Flags().BoolVar(&metricsOn, "metrics", os.Getenv("GO_SWE_AGENT_METRICS") != "", "Append this run's tokens, cost, duration and outcome to a local metrics file, never sent anywhere (default on when GO_SWE_AGENT_METRICS is set)")
+	rootCmd.Flags().StringVar(&metricsFile, "metrics-file", metrics.DefaultPath(), "Local metrics file written with --metrics and read by the stats command")
+	rootCmd.Flags().StringArrayVar(&prices, "price", nil, "Price of a model as MODEL=INPUT,OUTPUT in USD per million tokens, for the cost estimate and summary (repeatable)")
+	rootCmd.MarkFlagsMutuallyExclusive("request", "request-file", "resume", "batch", "plan-in")
+	rootCmd.MarkFlagsMutuallyExclusive("batch", "checkpoint")
+	rootCmd.MarkFlagsMutuallyExclusive("emit-plan", "plan-in")
+	rootCmd.MarkFlagsOneRequired("request", "request-file", "resume", "batch", "plan-in")
+
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Summarize the local metrics of past runs",
+		Long: `Summarize the metrics file written by runs with --metrics: totals,
+averages per run, a line per model and the runs of recent weeks.`,
+		Args: cobra.NoArgs,
+		Run:  runStats,
+	}
+	statsCmd.Flags().StringVar(&metricsFile, "metrics-file", metrics.DefaultPath(), "Local metrics file to summarize")
+	rootCmd.AddCommand(statsCmd)
+
+	explainCmd := &cobra.Command{
+		Use:   "explain <question>",
+		Short: "Answer a question about the codebase without changing it",
+		Long: `Explore the codebase with read-only tools and answer a question about
+it, with file and line citations. Nothing is planned or changed.
+
+Example:
+  go-swe-agent explain --dir . "how does auth work?"
+  go-swe-agent explain "where are retries configured?" --format json -o answer.json`,
+		Args: cobra.ExactArgs(1),
+		Run:  runExplain,
+	}
+	explainCmd.Flags().StringVarP(&workingDir, "dir", "d", ".", "Working directory to explain")
+	explainCmd.Flags().IntVar(&explainIters, "max-iterations", agents.DefaultExplainIterations, "Exploration turns before the answer is required")
+	explainCmd.Flags().StringVar(&explainFormat, "format", "markdown", "Report format: markdown or json")
+	explainCmd.Flags().StringVarP(&explainOut, "output", "o", "", "Write the report to this file instead of standard output")
+	explainCmd.Flags().StringVar(&endpoint, "endpoint", "", "Override the LLM provider base URL, e.g. an internal API gateway")
+	explainCmd.Flags().StringVar(&region, "region", "", "LLM provider region (defaults to $AWS_REGION or us-west-2 for Bedrock)")
+	explainCmd.Flags().StringVar(&provider, "provider", llm.ProviderBedrock, "LLM provider: bedrock, anthropic (needs ANTHROPIC_API_KEY) or openai (needs OPENAI_API_KEY)")
+	explainCmd.Flags().StringVar(&model, "model", "", fmt.Sprintf("Model ID (default %s on Bedrock, %s on Anthropic, %s on OpenAI)", llm.DefaultBedrockModel, llm.DefaultAnthropicModel, llm.DefaultOpenAIModel))
+	rootCmd.AddCommand(explainCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		color.Red("Error: %v\n", err)
@@ -41,8 +269,111 @@ Example:
 	}
 }
 
-func runAgent(cmd *cobra.Command, args []string) {
-	// Check for AWS credentials
+func runStats(cmd *cobra.Command, args []string) {
+	records, skipped, err := metrics.Load(metricsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("No metrics at %s yet. Run the agent with --metrics to record them.\n", metricsFile)
+		return
+	}
+	if err != nil {
+		color.Red("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if skipped > 0 {
+		color.Yellow("Skipped %d unreadable line(s) in %s\n", skipped, metricsFile)
+	}
+	metrics.Summarize(records).Write(os.Stdout)
+}
+
+func runExplain(cmd *cobra.Command, args []string) {
+	checkCredentials()
+	if explainFormat != "markdown" && explainFormat != "json" {
+		color.Red("Error: --format must be markdown or json\n")
+		os.Exit(1)
+	}
+	if explainIters < 1 {
+		color.Red("Error: --max-iterations must be at least 1\n")
+		os.Exit(1)
+	}
+	endpointConfig := checkEndpoint()
+
+	opts := graph.Options{
+		Endpoint:          endpointConfig,
+		Provider:          provider,
+		Model:             model,
+		ExplainIterations: explainIters,
+	}
+	if explainOut == "" {
+		// Keep standard output for the report
+		opts.Output = os.Stderr
+	}
+	orchestrator, err := graph.NewOrchestrator(workingDir, &state.Request{Description: args[0]}, opts)
+	if err != nil {
+		color.Red("\n❌ Explain failed: %v\n", err)
+		os.Exit(1)
+	}
+	explanation, err := orchestrator.Explain()
+	if err != nil {
+		color.Red("\n❌ Explain failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if explainOut != "" {
+		f, err := os.Create(explainOut)
+		if err != nil {
+			color.Red("Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	if explainFormat == "json" {
+		data, err := json.MarshalIndent(explanation, "", "  ")
+		if err != nil {
+			color.Red("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(out, string(data))
+	} else {
+		graph.WriteExplanation(out, explanation)
+	}
+	if explainOut != "" {
+		color.Green("\n📄 Explanation written to %s\n", explainOut)
+	}
+}
+
+// stdinIsTerminal reports whether a person can answer prompts on stdin.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// checkCredentials exits when --provider or --model is invalid, and with
+// setup instructions when the provider's credentials are not configured.
+func checkCredentials() {
+	if err := llm.ValidateModel(provider, model); err != nil {
+		color.Red("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if provider == llm.ProviderAnthropic {
+		if os.Getenv("ANTHROPIC_API_KEY") == "" {
+			color.Red("Error: an Anthropic API key is required\n")
+			fmt.Println("\nPlease set your API key:")
+			fmt.Println("  export ANTHROPIC_API_KEY=your-api-key")
+			os.Exit(1)
+		}
+		return
+	}
+	if provider == llm.ProviderOpenAI {
+		if os.Getenv("OPENAI_API_KEY") == "" {
+			color.Red("Error: an OpenAI API key is required\n")
+			fmt.Println("\nPlease set your API key:")
+			fmt.Println("  export OPENAI_API_KEY=your-api-key")
+			os.Exit(1)
+		}
+		return
+	}
 	if os.Getenv("AWS_ACCESS_KEY_ID") == "" || os.Getenv("AWS_SECRET_ACCESS_KEY") == "" {
 		color.Red("Error: AWS credentials are required\n")
 		fmt.Println("\nPlease configure your AWS credentials:")
@@ -51,15 +382,363 @@ func runAgent(cmd *cobra.Command, args []string) {
 		fmt.Println("  export AWS_REGION=us-west-2  # Optional, defaults to us-west-2")
 		fmt.Println("\nOr configure using AWS CLI:")
 		fmt.Println("  aws configure")
-		fmt.Println("\nMake sure your AWS account has access to Amazon Bedrock with Claude 3 Opus model.")
+		fmt.Printf("\nMake sure your AWS account has access to the %s model on Amazon Bedrock.\n", bedrockModel())
 		os.Exit(1)
 	}
+}
+
+// bedrockModel is the model a Bedrock run uses.
+func bedrockModel() string {
+	if model != "" {
+		return model
+	}
+	return llm.DefaultBedrockModel
+}
+
+// checkEndpoint exits when --endpoint or --region is malformed for the
+// provider, and returns the endpoint config of the run.
+func checkEndpoint() llm.EndpointConfig {
+	endpointConfig := llm.EndpointConfig{Endpoint: endpoint, Region: region}
+	resolved := endpointConfig.ForBedrock()
+	if provider == llm.ProviderAnthropic {
+		resolved = endpointConfig.ForAnthropic()
+	}
+	if provider == llm.ProviderOpenAI {
+		resolved = endpointConfig.ForOpenAI()
+	}
+	if err := resolved.Validate(); err != nil {
+		color.Red("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return endpointConfig
+}
+
+func runAgent(cmd *cobra.Command, args []string) {
+	checkCredentials()
+
+	switch generated {
+	case tools.GeneratedWarn, tools.GeneratedBlock, tools.GeneratedOff:
+	default:
+		color.Red("Error: --generated-files must be warn, block or off\n")
+		os.Exit(1)
+	}
+	switch scopePolicy {
+	case graph.ScopeWarn, graph.ScopeFail, graph.ScopeRevert:
+	default:
+		color.Red("Error: --scope-violations must be warn, fail or revert\n")
+		os.Exit(1)
+	}
+	switch submodules {
+	case tools.SubmodulesRecurse, tools.SubmodulesSkip:
+	default:
+		color.Red("Error: --submodules must be recurse or skip\n")
+		os.Exit(1)
+	}
+	if repairs < 0 {
+		color.Red("Error: --repair-attempts must not be negative\n")
+		os.Exit(1)
+	}
+	if maxReplans < 0 {
+		color.Red("Error: --max-replans must not be negative\n")
+		os.Exit(1)
+	}
+	if replanAt < 0 || replanAt >= 1 {
+		color.Red("Error: --replan-threshold must be at least 0 and below 1\n")
+		os.Exit(1)
+	}
+	if dryRun && verifyCmd != "" {
+		color.Red("Error: --dry-run changes nothing, so there is nothing for --verify-command to check\n")
+		os.Exit(1)
+	}
+	if dryRun && diffOutput != "" {
+		color.Red("Error: --dry-run changes nothing, so there is no diff for --diff-output\n")
+		os.Exit(1)
+	}
+	if repairs > 0 && verifyCmd == "" {
+		color.Red("Error: --repair-attempts needs a --verify-command to check the build\n")
+		os.Exit(1)
+	}
+	switch verifyCadence {
+	case graph.VerifyAuto, graph.VerifyEach, graph.VerifyEnd:
+	default:
+		color.Red("Error: --verify-cadence must be auto, each or end\n")
+		os.Exit(1)
+	}
+	switch batchOnFail {
+	case graph.BatchStop, graph.BatchContinue:
+	default:
+		color.Red("Error: --batch-on-failure must be stop or continue\n")
+		os.Exit(1)
+	}
+	switch partialTasks {
+	case graph.PartialFlag, graph.PartialFollowUp:
+	default:
+		color.Red("Error: --partial-tasks must be flag or follow-up\n")
+		os.Exit(1)
+	}
+	if bashTimeout < 0 {
+		color.Red("Error: --bash-timeout must not be negative\n")
+		os.Exit(1)
+	}
+	if readWorkers < 1 || readBudgetKB < 1 {
+		color.Red("Error: --read-concurrency and --read-budget must be at least 1\n")
+		os.Exit(1)
+	}
+	if unsafe && len(allowCmds) > 0 {
+		color.Red("Error: --unsafe turns off the checks that --allow-commands configures\n")
+		os.Exit(1)
+	}
+	if memLimitMB < 0 || cpuLimit < 0 {
+		color.Red("Error: --mem-limit and --cpu-limit must be zero or positive\n")
+		os.Exit(1)
+	}
+	if planTokens < 0 || execTokens < 0 {
+		color.Red("Error: --planner-max-tokens and --executor-max-tokens must be zero or positive\n")
+		os.Exit(1)
+	}
+	if stallWindow < 0 {
+		color.Red("Error: --stall-window must be zero or positive\n")
+		os.Exit(1)
+	}
+	if stallRepeat <= 0 || stallRepeat > 1 {
+		color.Red("Error: --stall-repeat must be greater than 0 and at most 1\n")
+		os.Exit(1)
+	}
+	if stallWindow == 0 {
+		stallWindow = -1
+	}
+	if interleaved && (reproFirst || estimateOnly || maxCost > 0 || len(onlyTasks) > 0 || len(skipTasks) > 0) {
+		color.Red("Error: --interleaved has no upfront plan, so it cannot be combined with --repro-first, --estimate-only, --max-cost, --only-tasks or --skip-tasks\n")
+		os.Exit(1)
+	}
+	if approvePlan && !interactive {
+		color.Red("Error: --approve-plan asks for approval from the terminal, so it requires --interactive\n")
+		os.Exit(1)
+	}
+	if parallel < 1 {
+		color.Red("Error: --parallel must be at least 1\n")
+		os.Exit(1)
+	}
+	if parallel > 1 && (interactive || interleaved || dryRun || stream || reproFirst || verifyCadence == graph.VerifyEach) {
+		color.Red("Error: --parallel runs several tasks at once, so it cannot be combined with --interactive, --interleaved, --dry-run, --stream, --repro-first or --verify-cadence each\n")
+		os.Exit(1)
+	}
+	if approvePlan && interleaved {
+		color.Red("Error: --interleaved has no upfront plan to approve, so it cannot be combined with --approve-plan\n")
+		os.Exit(1)
+	}
+	if batchFile != "" && (len(criteria) > 0 || len(images) > 0 || len(onlyTasks) > 0 || len(skipTasks) > 0) {
+		color.Red("Error: --criteria, --image, --only-tasks and --skip-tasks apply to a single request, so they cannot be combined with --batch\n")
+		os.Exit(1)
+	}
+	if emitPlan != "" && (resume != "" || batchFile != "" || interleaved) {
+		color.Red("Error: --emit-plan writes a new upfront plan, so it cannot be combined with --resume, --batch or --interleaved\n")
+		os.Exit(1)
+	}
+	if planIn != "" && interleaved {
+		color.Red("Error: --interleaved has no upfront plan, so it cannot be combined with --plan-in\n")
+		os.Exit(1)
+	}
+	if planIn != "" && (reproFirst || estimateOnly || maxCost > 0 || len(images) > 0) {
+		color.Red("Error: --plan-in skips planning, so it cannot be combined with --repro-first, --estimate-only, --max-cost or --image\n")
+		os.Exit(1)
+	}
+	if maxNudges < 1 || maxIdleTurns < 1 {
+		color.Red("Error: --max-nudges and --max-idle-turns must be at least 1\n")
+		os.Exit(1)
+	}
+
+	endpointConfig := checkEndpoint()
+
+	req := &state.Request{Description: request}
+	var planFile *state.PlanFile
+	if planIn != "" {
+		loaded, err := state.LoadPlanFile(planIn)
+		if err != nil {
+			color.Red("Error: %v\n", err)
+			os.Exit(1)
+		}
+		planFile = loaded
+		req = loaded.Request
+	}
+	if requestFile != "" {
+		loaded, err := state.LoadRequest(requestFile)
+		if err != nil {
+			color.Red("Error: %v\n", err)
+			os.Exit(1)
+		}
+		req = loaded
+	}
+	req.AcceptanceCriteria = append(req.AcceptanceCriteria, criteria...)
+	req.Images = append(req.Images, images...)
+
+	var batch []string
+	if batchFile != "" {
+		loaded, err := state.LoadBatch(batchFile)
+		if err != nil {
+			color.Red("Error: %v\n", err)
+			os.Exit(1)
+		}
+		batch = loaded
+	}
+
+	var resumed *state.AgentState
+	if resume != "" {
+		loaded, err := state.LoadState(resume)
+		if err != nil {
+			color.Red("Error: %v\n", err)
+			os.Exit(1)
+		}
+		resumed = loaded
+	}
 
 	// Create and run orchestrator
-	orchestrator := graph.NewOrchestrator(workingDir, request)
+	opts := graph.Options{
+		ArtifactsDir:      artifactsDir,
+		AllowUnsafeDir:    allowUnsafe,
+		ToolLogPath:       toolLog,
+		ToolLogMaxBytes:   toolLogMB * 1024 * 1024,
+		TracePath:         traceOut,
+		EventLogPath:      eventLog,
+		MemoryLimitBytes:  memLimitMB * 1024 * 1024,
+		CPULimit:          cpuLimit,
+		Unsafe:            unsafe,
+		Endpoint:          endpointConfig,
+		Provider:          provider,
+		Model:             model,
+		EstimateOnly:      estimateOnly,
+		MaxEstimatedCost:  maxCost,
+		PullRequest:       pullRequest,
+		Commit:            commitSHA,
+		GitHubRepo:        githubRepo,
+		PlannerIterations: plannerIters,
+		Interleaved:       interleaved,
+		MaxSteps:          maxSteps,
+		Parallel:          parallel,
+		MaxIterations:     maxIters,
+		MaxToolCalls:      maxToolCalls,
+		MaxHistoryTurns:   maxTurns,
+		PlannerMaxTokens:  planTokens,
+		ExecutorMaxTokens: execTokens,
+		Nudge:             nudge,
+		MaxNudges:         maxNudges,
+		MaxIdleTurns:      maxIdleTurns,
+		ReproFirst:        reproFirst,
+		OnlyTasks:         onlyTasks,
+		SkipTasks:         skipTasks,
+		IndexConcurrency:  indexWorkers,
+		ReadConcurrency:   readWorkers,
+		ReadBudgetBytes:   readBudgetKB * 1024,
+		MaxFilesChanged:   maxFiles,
+		GeneratedFiles:    generated,
+		DetectEncoding:    detectEnc,
+		Submodules:        submodules,
+		ProtectedPaths:    protectedList,
+		CleanScratch:      cleanScratch,
+		UpdateGitignore:   updateIgnore,
+		ScopeViolations:   scopePolicy,
+		MaxLineLength:     maxLineLength,
+		MaxReadLines:      maxReadLines,
+		BashTimeout:       time.Duration(bashTimeout) * time.Second,
+		DigestLength:      digestLength,
+		DiffHook:          diffHook,
+		DiffHookFeedback:  diffHookFix,
+		PartialTasks:      partialTasks,
+		StallWindow:       stallWindow,
+		StallRepeat:       stallRepeat,
+		VerifyCommand:     verifyCmd,
+		VerifyCadence:     verifyCadence,
+		RepairAttempts:    repairs,
+		MaxReplans:        maxReplans,
+		ReplanThreshold:   replanAt,
+		StopSequences:     stopSeqs,
+		RequestTag:        requestTag,
+		BlockedSignal:     blockedSignal,
+		ExplainActions:    explain,
+		Stream:            stream,
+		DryRun:            dryRun,
+		DiffOutputPath:    diffOutput,
+		CheckpointPath:    checkpoint,
+		Resume:            resumed,
+		ResumePath:        resume,
+		EmitPlanPath:      emitPlan,
+		PlanIn:            planFile,
+		PlanInPath:        planIn,
+		Interactive:       interactive,
+		ApprovePlan:       approvePlan,
+		ConfirmPlan:       !autoApprove && !approvePlan && stdinIsTerminal(),
+		ExplainErrors:     explainErrs,
+	}
+	if digestLength == 0 {
+		// Zero means "default" in Options; negative omits task outputs
+		opts.DigestLength = -1
+	}
+	if replanAt == 0 {
+		opts.ReplanThreshold = -1
+	}
+	if maxLineLength == 0 {
+		opts.MaxLineLength = -1
+	}
+	if maxReadLines == 0 {
+		opts.MaxReadLines = -1
+	}
+	opts.AllowOutsideWorkDir = allowOutside
+	if len(allowCmds) > 0 {
+		opts.SafetyPolicy = &tools.SafetyPolicy{Deny: tools.DefaultDenyRules, Allow: allowCmds}
+	}
+	if bashTimeout == 0 {
+		opts.BashTimeout = -1
+	}
+	if metricsOn {
+		opts.MetricsPath = metricsFile
+	}
+	for _, spec := range prices {
+		model, pricing, err := llm.ParsePrice(spec)
+		if err != nil {
+			color.Red("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if opts.Pricing == nil {
+			opts.Pricing = make(map[string]llm.Pricing)
+		}
+		opts.Pricing[model] = pricing
+	}
+	var notifier *webhook.Notifier
+	if webhookURL != "" {
+		if _, err := url.ParseRequestURI(webhookURL); err != nil {
+			color.Red("Error: invalid webhook URL: %v\n", err)
+			os.Exit(1)
+		}
+		notifier = webhook.New(webhookURL, webhookKey, ui.NewPrinter(opts.Output))
+		opts.Hooks = append(opts.Hooks, notifier)
+	}
+	if batch != nil {
+		result := graph.RunBatch(workingDir, batch, opts, batchOnFail)
+		if notifier != nil {
+			notifier.Close(10 * time.Second)
+		}
+		if !result.Success() {
+			os.Exit(1)
+		}
+		return
+	}
+	orchestrator, err := graph.NewOrchestrator(workingDir, req, opts)
+	if err != nil {
+		color.Red("\n❌ Agent failed: %v\n", err)
+		os.Exit(1)
+	}
 	
-	if err := orchestrator.Run(); err != nil {
+	result, err := orchestrator.Run()
+	if notifier != nil {
+		// Give pending deliveries a chance before exiting
+		notifier.Close(10 * time.Second)
+	}
+	if err != nil {
 		color.Red("\n❌ Agent failed: %v\n", err)
 		os.Exit(1)
 	}
+	
+	if !result.Success() {
+		os.Exit(1)
+	}
 }
\ No newline at end of file
@@ -0,0 +1,86 @@
+// Package llmtest provides a scripted llm.Client for tests that drive the
+// agents without calling a model.
+package llmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+)
+
+// Client answers each model call with the next of its Turns, each a list
+// of content blocks in the Messages API format, and repeats the last one
+// when they run out. Without Turns, every call fails. Each answer takes
+// Delay. It is safe for concurrent use.
+type Client struct {
+	llm.ClientSettings
+	Turns [][]string
+	Delay time.Duration
+
+	mu    sync.Mutex
+	calls int
+	last  []llm.AnthropicMessage
+}
+
+func (c *Client) Model() string { return "claude-sonnet-4-20250514" }
+
+func (c *Client) CreateMessage(messages []llm.AnthropicMessage, system string, tools []llm.Tool) (*llm.AnthropicResponse, error) {
+	return c.CreateMessageWith(messages, system, tools, llm.CallOptions{})
+}
+
+func (c *Client) CreateMessageWith(messages []llm.AnthropicMessage, system string, tools []llm.Tool, opts llm.CallOptions) (*llm.AnthropicResponse, error) {
+	time.Sleep(c.Delay)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.Turns) == 0 {
+		return nil, io.EOF
+	}
+	turn := c.Turns[len(c.Turns)-1]
+	if c.calls < len(c.Turns) {
+		turn = c.Turns[c.calls]
+	}
+	c.calls++
+	c.last = append([]llm.AnthropicMessage(nil), messages...)
+	response := &llm.AnthropicResponse{Role: "assistant", StopReason: "tool_use"}
+	for _, block := range turn {
+		response.Content = append(response.Content, json.RawMessage(block))
+	}
+	return response, nil
+}
+
+func (c *Client) CreateMessageStream(messages []llm.AnthropicMessage, system string, tools []llm.Tool, opts llm.CallOptions, handler llm.StreamHandler) (*llm.AnthropicResponse, error) {
+	return c.CreateMessageWith(messages, system, tools, opts)
+}
+
+func (c *Client) ParseContent(content []json.RawMessage) (string, []llm.ToolUseContent, error) {
+	return (&llm.AnthropicClient{}).ParseContent(content)
+}
+
+// Calls returns the number of model calls answered.
+func (c *Client) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+// LastMessages returns the messages of the latest model call.
+func (c *Client) LastMessages() []llm.AnthropicMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// ToolUse returns a tool_use block calling the named tool with input, a
+// JSON object.
+func ToolUse(id, name, input string) string {
+	return fmt.Sprintf(`{"type":"tool_use","id":%q,"name":%q,"input":%s}`, id, name, input)
+}
+
+// TextBlock returns a text block.
+func TextBlock(s string) string {
+	return fmt.Sprintf(`{"type":"text","text":%q}`, s)
+}
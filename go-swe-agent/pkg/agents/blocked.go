@@ -0,0 +1,35 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+)
+
+// BlockedError is returned by the executor when the model emits the
+// blocked signal because it cannot continue without human input.
+type BlockedError struct {
+	TaskID   string
+	Question string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("task %s is blocked and needs human input: %s", e.TaskID, e.Question)
+}
+
+// blockedQuestion reports whether a response carries the blocked signal and
+// returns what the model said before it. The signal is also registered as a
+// stop sequence, so it usually ends the response rather than appearing in it.
+func blockedQuestion(response *llm.AnthropicResponse, text, signal string) (string, bool) {
+	if signal == "" {
+		return "", false
+	}
+	if response.StopReason == "stop_sequence" && response.StopSequence == signal {
+		return strings.TrimSpace(text), true
+	}
+	if i := strings.Index(text, signal); i >= 0 {
+		return strings.TrimSpace(text[:i]), true
+	}
+	return "", false
+}
@@ -0,0 +1,398 @@
+package agents
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/tools"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+const submitExplanationToolName = "submit_explanation"
+
+// DefaultExplainIterations is the number of exploration turns the
+// codebase explainer gets before it must answer. Answering a question
+// usually takes more reading than planning a change.
+const DefaultExplainIterations = 15
+
+// readOnlyTools are the exploration tools offered when answering a
+// question. bash is left out because it can change files.
+var readOnlyTools = map[string]bool{
+	"read_file":  true,
+	"read_files": true,
+	"list_files": true,
+	"glob":       true,
+	"search":     true,
+	"deps":       true,
+}
+
+// CodebaseExplainer answers a question about the codebase with the
+// planner's exploration loop, restricted to read-only tools, and no plan
+// or edits.
+type CodebaseExplainer struct {
+	client       llm.Client
+	toolExecutor *tools.ToolExecutor
+
+	// Out receives progress output; nil writes to standard output
+	Out *ui.Printer
+
+	// MaxIterations caps the exploration turns before the final answer
+	MaxIterations int
+
+	// MaxTokens caps the output of each model call. Zero uses the
+	// client's default.
+	MaxTokens int
+
+	// MaxHistoryTurns caps the assistant turns sent back to the model;
+	// older turns are replaced by a short note. Zero keeps them all.
+	MaxHistoryTurns int
+}
+
+func NewCodebaseExplainer(client llm.Client, toolExecutor *tools.ToolExecutor) *CodebaseExplainer {
+	return &CodebaseExplainer{
+		client:        client,
+		toolExecutor:  toolExecutor,
+		MaxIterations: DefaultExplainIterations,
+		MaxTokens:     DefaultPlannerMaxTokens,
+	}
+}
+
+// Explain answers agentState's request, taken as a question about the code
+// in its working directory. Citations of missing files or lines are sent
+// back to the model to correct while it can still explore, and dropped
+// from the final answer.
+func (c *CodebaseExplainer) Explain(agentState *state.AgentState) (*state.CodebaseExplanation, error) {
+	c.Out.Println("\n🔍 Exploring the codebase...")
+
+	messages := c.buildMessages(agentState)
+	systemPrompt := codebaseExplainerPrompt
+	availableTools := c.getTools()
+
+	for i := 0; i < c.MaxIterations; i++ {
+		start := time.Now()
+		response, err := c.client.CreateMessageWith(trimHistory(messages, c.MaxHistoryTurns), systemPrompt, availableTools, llm.CallOptions{MaxTokens: c.MaxTokens})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get LLM response: %w", err)
+		}
+		agentState.RecordUsage(state.PhasePlanning, response.Usage.InputTokens, response.Usage.OutputTokens, time.Since(start))
+
+		_, toolCalls, _ := c.client.ParseContent(response.Content)
+		messages = append(messages, llm.AnthropicMessage{
+			Role:    "assistant",
+			Content: response.Content,
+		})
+
+		if len(toolCalls) == 0 {
+			messages = append(messages, llm.AnthropicMessage{
+				Role: "user",
+				Content: []interface{}{
+					llm.TextContent{
+						Type: "text",
+						Text: "Continue exploring with the available tools, or call submit_explanation when you can answer the question.",
+					},
+				},
+			})
+			continue
+		}
+
+		var toolResults []interface{}
+		for _, toolCall := range toolCalls {
+			if result, ok := incompleteToolCallResult(c.Out, availableTools, toolCall, response.StopReason); !ok {
+				toolResults = append(toolResults, result)
+				continue
+			}
+
+			if toolCall.Name == submitExplanationToolName {
+				explanation, err := parseExplanation(toolCall.Input)
+				if err == nil {
+					if bad := c.checkCitations(agentState.WorkingDir, explanation); len(bad) > 0 {
+						err = fmt.Errorf("these citations do not match the files; check the paths and line numbers (search shows line numbers) and submit again:\n- %s", strings.Join(bad, "\n- "))
+					}
+				}
+				if err != nil {
+					toolResults = append(toolResults, llm.ToolResultContent{
+						Type:      "tool_result",
+						ToolUseID: toolCall.ID,
+						Content:   fmt.Sprintf("Error: %v", err),
+						IsError:   true,
+					})
+					continue
+				}
+				explanation.Question = agentState.OriginalRequest
+				return explanation, nil
+			}
+
+			var output string
+			if !readOnlyTools[toolCall.Name] {
+				output = fmt.Sprintf("Error: %s is not available; only read-only tools can be used to answer a question", toolCall.Name)
+			} else {
+				c.Out.Printf("  📂 Exploring: %s\n", toolCall.Name)
+				output, err = c.toolExecutor.Execute(toolCall.Name, toolCall.Input)
+				if err != nil {
+					output = fmt.Sprintf("Error: %v", err)
+				}
+			}
+
+			if len(output) > 5000 && toolCall.Name != "read_files" {
+				output = output[:5000] + "\n... (truncated)"
+			}
+
+			toolResults = append(toolResults, llm.ToolResultContent{
+				Type:      "tool_result",
+				ToolUseID: toolCall.ID,
+				Content:   output,
+			})
+		}
+
+		messages = append(messages, llm.AnthropicMessage{
+			Role:    "user",
+			Content: toolResults,
+		})
+	}
+
+	// Final attempt: only the submit_explanation tool is offered
+	messages = append(messages, llm.AnthropicMessage{
+		Role: "user",
+		Content: []interface{}{
+			llm.TextContent{
+				Type: "text",
+				Text: "Based on your exploration, answer the question now by calling submit_explanation.",
+			},
+		},
+	})
+
+	start := time.Now()
+	structured := llm.CallOptions{ToolChoice: llm.ForceTool(submitExplanationToolName), Strict: true, MaxTokens: c.MaxTokens}
+	response, err := c.client.CreateMessageWith(trimHistory(messages, c.MaxHistoryTurns), systemPrompt, []llm.Tool{submitExplanationTool()}, structured)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the explanation: %w", err)
+	}
+	agentState.RecordUsage(state.PhasePlanning, response.Usage.InputTokens, response.Usage.OutputTokens, time.Since(start))
+
+	text, toolCalls, _ := c.client.ParseContent(response.Content)
+	var explanation *state.CodebaseExplanation
+	for _, toolCall := range toolCalls {
+		if toolCall.Name == submitExplanationToolName {
+			explanation, _ = parseExplanation(toolCall.Input)
+			break
+		}
+	}
+	if explanation == nil && strings.TrimSpace(text) != "" {
+		// Providers without forced tool use may answer in prose
+		explanation = &state.CodebaseExplanation{Summary: strings.TrimSpace(text)}
+	}
+	if explanation == nil {
+		return nil, fmt.Errorf("failed to explain the codebase: the model gave no answer")
+	}
+	if bad := c.checkCitations(agentState.WorkingDir, explanation); len(bad) > 0 {
+		c.Out.Yellow("⚠️  Dropped %d citation(s) that do not match the files\n", len(bad))
+	}
+	explanation.Question = agentState.OriginalRequest
+	return explanation, nil
+}
+
+func (c *CodebaseExplainer) buildMessages(agentState *state.AgentState) []llm.AnthropicMessage {
+	var notes string
+	if note := c.toolExecutor.SubmoduleNote(); note != "" {
+		notes = "\n" + note + "\n"
+	}
+	return []llm.AnthropicMessage{
+		{
+			Role: "user",
+			Content: []interface{}{
+				llm.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf(`Answer the following question about this codebase:
+
+QUESTION: %s
+%s
+Explore the code until you understand it, then call submit_explanation. Do not propose changes.`, agentState.OriginalRequest, notes),
+				},
+			},
+		},
+	}
+}
+
+const codebaseExplainerPrompt = `You are an expert software engineer explaining a codebase to a developer who is new to it.
+
+Use the available tools to explore the codebase:
+- Use list_files to understand the project structure
+- Use glob to find files by name, e.g. **/*_test.go
+- Use search to find where things are defined and used; it shows line numbers
+- Use read_file and read_files to read the relevant code
+
+The tools are read-only. You are answering a question, not making changes, so do not plan or suggest edits unless the question asks for them.
+
+When you understand the answer, call submit_explanation with a short summary and sections that walk through the relevant code: components, the steps of a flow, or whatever fits the question. Back each section with citations of the files and lines it describes, using paths relative to the working directory. Cite only code you have read.`
+
+func (c *CodebaseExplainer) getTools() []llm.Tool {
+	llmTools := []llm.Tool{submitExplanationTool()}
+	for _, toolDef := range tools.GetAvailableTools() {
+		name := toolDef["name"].(string)
+		if !readOnlyTools[name] {
+			continue
+		}
+		llmTools = append(llmTools, llm.Tool{
+			Name:        name,
+			Description: toolDef["description"].(string),
+			InputSchema: toolDef["input_schema"].(map[string]interface{}),
+		})
+	}
+	return llmTools
+}
+
+// checkCitations removes the citations of explanation that name a file
+// that cannot be read or lines past its end, and describes each one.
+func (c *CodebaseExplainer) checkCitations(workingDir string, explanation *state.CodebaseExplanation) []string {
+	lineCounts := make(map[string]int) // path -> lines, -1 if unreadable
+	var bad []string
+	for i := range explanation.Sections {
+		section := &explanation.Sections[i]
+		kept := section.Citations[:0]
+		for _, cite := range section.Citations {
+			lines, ok := lineCounts[cite.Path]
+			if !ok {
+				lines = -1
+				path := cite.Path
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(workingDir, path)
+				}
+				if content, err := c.toolExecutor.Backend.ReadFile(path); err == nil {
+					lines = strings.Count(string(content), "\n")
+					if len(content) > 0 && content[len(content)-1] != '\n' {
+						lines++
+					}
+				}
+				lineCounts[cite.Path] = lines
+			}
+
+			end := cite.EndLine
+			if end == 0 {
+				end = cite.StartLine
+			}
+			switch {
+			case lines < 0:
+				bad = append(bad, fmt.Sprintf("%s: the file cannot be read", cite.Path))
+			case cite.StartLine < 1 || end < cite.StartLine || end > lines:
+				bad = append(bad, fmt.Sprintf("%s lines %d-%d: the file has %d lines", cite.Path, cite.StartLine, end, lines))
+			default:
+				kept = append(kept, cite)
+			}
+		}
+		section.Citations = kept
+	}
+	return bad
+}
+
+func submitExplanationTool() llm.Tool {
+	return llm.Tool{
+		Name:        submitExplanationToolName,
+		Description: "Submit the answer to the question once exploration is done, as a summary and sections backed by file and line citations.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"summary": map[string]interface{}{
+					"type":        "string",
+					"description": "A direct answer to the question in a few sentences",
+				},
+				"sections": map[string]interface{}{
+					"type":        "array",
+					"description": "The parts of the explanation in reading order, such as components or the steps of a flow",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"title": map[string]interface{}{
+								"type":        "string",
+								"description": "A short heading",
+							},
+							"body": map[string]interface{}{
+								"type":        "string",
+								"description": "What this part does and how, in plain prose",
+							},
+							"citations": map[string]interface{}{
+								"type":        "array",
+								"description": "The code this section describes",
+								"items": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"path": map[string]interface{}{
+											"type":        "string",
+											"description": "File path relative to the working directory",
+										},
+										"start_line": map[string]interface{}{
+											"type":        "integer",
+											"description": "First line cited, starting at 1",
+										},
+										"end_line": map[string]interface{}{
+											"type":        "integer",
+											"description": "Last line cited (optional; defaults to start_line)",
+										},
+										"note": map[string]interface{}{
+											"type":        "string",
+											"description": "What these lines show (optional)",
+										},
+									},
+									"required": []string{"path", "start_line"},
+								},
+							},
+						},
+						"required": []string{"title", "body"},
+					},
+				},
+			},
+			"required": []string{"summary", "sections"},
+		},
+	}
+}
+
+// parseExplanation converts a submit_explanation call into an explanation.
+func parseExplanation(input map[string]interface{}) (*state.CodebaseExplanation, error) {
+	summary, _ := input["summary"].(string)
+	if strings.TrimSpace(summary) == "" {
+		return nil, fmt.Errorf("submit_explanation requires a 'summary'")
+	}
+	explanation := &state.CodebaseExplanation{Summary: strings.TrimSpace(summary)}
+
+	rawSections, _ := input["sections"].([]interface{})
+	for _, raw := range rawSections {
+		s, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var section state.ExplanationSection
+		section.Title, _ = s["title"].(string)
+		section.Body, _ = s["body"].(string)
+		if strings.TrimSpace(section.Body) == "" {
+			continue
+		}
+		rawCitations, _ := s["citations"].([]interface{})
+		for _, rawCite := range rawCitations {
+			c, ok := rawCite.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var cite state.Citation
+			cite.Path, _ = c["path"].(string)
+			cite.Path = strings.TrimPrefix(strings.TrimSpace(cite.Path), "./")
+			if start, ok := c["start_line"].(float64); ok {
+				cite.StartLine = int(start)
+			}
+			if end, ok := c["end_line"].(float64); ok {
+				cite.EndLine = int(end)
+			}
+			if cite.EndLine == cite.StartLine {
+				cite.EndLine = 0
+			}
+			cite.Note, _ = c["note"].(string)
+			if cite.Path != "" {
+				section.Citations = append(section.Citations, cite)
+			}
+		}
+		explanation.Sections = append(explanation.Sections, section)
+	}
+	return explanation, nil
+}
@@ -0,0 +1,69 @@
+package agents
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// dependsAnnotation matches the prefix a planner writing its plan as text
+// puts on a task that needs others done first, such as
+// "[depends: task-1, task-2] Wire up the handler" or "[depends on: 1]".
+var dependsAnnotation = regexp.MustCompile(`(?i)^\[depends(?:\s+on)?:\s*([^\]]*)\]\s*`)
+
+// cutDependencies removes a dependency annotation from the start of a
+// task description and returns the 1-based plan positions it lists.
+func cutDependencies(description string) (string, []int) {
+	m := dependsAnnotation.FindStringSubmatch(description)
+	if m == nil {
+		return description, nil
+	}
+	var positions []int
+	for _, field := range strings.FieldsFunc(m[1], func(r rune) bool { return r == ',' || r == ' ' }) {
+		field = strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(field), "task-"), "#")
+		if n, err := strconv.Atoi(field); err == nil {
+			positions = append(positions, n)
+		}
+	}
+	return strings.TrimSpace(description[len(m[0]):]), positions
+}
+
+// linkDependencies sets the DependsOn of each task from deps, the 1-based
+// plan positions of the tasks it depends on, once the tasks have IDs. Only
+// earlier tasks are kept, so the plan's order stays one that satisfies
+// every dependency and the dependencies can never form a cycle.
+func linkDependencies(tasks []state.Task, deps [][]int) {
+	for i := range tasks {
+		if i >= len(deps) {
+			break
+		}
+		seen := make(map[int]bool)
+		for _, n := range deps[i] {
+			if n < 1 || n > i || seen[n] {
+				continue
+			}
+			seen[n] = true
+			tasks[i].DependsOn = append(tasks[i].DependsOn, tasks[n-1].ID)
+		}
+	}
+}
+
+// plannedDependencies reads a submitted task's depends_on list of plan
+// positions.
+func plannedDependencies(raw interface{}) []int {
+	items, _ := raw.([]interface{})
+	var positions []int
+	for _, item := range items {
+		switch v := item.(type) {
+		case float64:
+			positions = append(positions, int(v))
+		case string:
+			if _, n := cutDependencies("[depends: " + v + "]"); len(n) > 0 {
+				positions = append(positions, n...)
+			}
+		}
+	}
+	return positions
+}
@@ -0,0 +1,55 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxFindingsLength bounds the planner's findings carried into every task.
+const MaxFindingsLength = 2000
+
+// DefaultDigestLength is the number of characters of a completed task's
+// output carried into the context of later tasks.
+const DefaultDigestLength = 300
+
+// digest condenses a task output to at most maxLen characters for use as
+// downstream context: whitespace is collapsed to single spaces and the text
+// is cut at a word boundary. The full output stays on the task.
+func digest(output string, maxLen int) string {
+	text := strings.Join(strings.Fields(output), " ")
+	if maxLen <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) <= maxLen {
+		return text
+	}
+
+	cut := string(runes[:maxLen])
+	if i := strings.LastIndex(cut, " "); i > maxLen/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " .,;:") + "…"
+}
+
+// truncateLines cuts text to at most maxLen characters at a line boundary,
+// keeping its line structure, and notes how many lines were dropped.
+func truncateLines(text string, maxLen int) string {
+	if len([]rune(text)) <= maxLen {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	var kept []string
+	length := 0
+	for _, line := range lines {
+		length += len([]rune(line)) + 1
+		if length > maxLen {
+			break
+		}
+		kept = append(kept, line)
+	}
+	if len(kept) == 0 {
+		return digest(text, maxLen)
+	}
+	return strings.Join(kept, "\n") + fmt.Sprintf("\n… (%d more lines)", len(lines)-len(kept))
+}
@@ -3,45 +3,206 @@ package agents
 import (
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/fatih/color"
 	"github.com/openswe/go-swe-agent/pkg/llm"
 	"github.com/openswe/go-swe-agent/pkg/state"
 	"github.com/openswe/go-swe-agent/pkg/tools"
+	"github.com/openswe/go-swe-agent/pkg/ui"
 )
 
+// DefaultMaxIterations is the number of LLM turns a task may take; complex
+// tasks need several rounds of reading, editing and testing.
+const DefaultMaxIterations = 15
+
+// DefaultMaxToolCalls bounds the tool executions of a single task,
+// independently of how many turns they are spread over.
+const DefaultMaxToolCalls = 40
+
+// DefaultMaxNudges is the number of consecutive turns without a tool call
+// that are answered with a nudge before the task fails as stalled.
+const DefaultMaxNudges = 3
+
+// DefaultMaxIdleTurns is the number of consecutive prose-only turns that
+// add nothing, an empty reply or a repeat of the previous one, after which
+// a task fails as stalled without using up its nudges.
+const DefaultMaxIdleTurns = 2
+
+// DefaultExecutorMaxTokens caps each execution response. It is high so
+// that large file writes are not cut off; clients lower it to what the
+// model supports.
+const DefaultExecutorMaxTokens = 16384
+
 type Executor struct {
-	client       *llm.BedrockClient
+	client       llm.Client
 	toolExecutor *tools.ToolExecutor
+
+	// Out receives progress output; nil writes to standard output
+	Out *ui.Printer
+
+	// MaxIterations caps the LLM turns spent on a single task
+	MaxIterations int
+
+	// MaxToolCalls caps the tool executions of a single task
+	MaxToolCalls int
+
+	// MaxHistoryTurns caps the assistant turns sent back to the model;
+	// older turns are replaced by a short note. Zero keeps them all.
+	MaxHistoryTurns int
+
+	// MaxTokens caps the output of each model call. Zero uses the
+	// client's default.
+	MaxTokens int
+
+	// Nudge replaces the default message sent when the model answers with
+	// prose only, without a tool call or the completion signal
+	Nudge string
+
+	// MaxNudges caps consecutive prose-only turns; one more fails the task
+	MaxNudges int
+
+	// MaxIdleTurns caps consecutive prose-only turns with no new text:
+	// empty, or the same as the previous reply
+	MaxIdleTurns int
+
+	// DigestLength caps how much of each completed task's output is shown
+	// to later tasks; zero lists only the task descriptions
+	DigestLength int
+
+	// VerifyFailure is the output of the build/test check that failed after
+	// the previous task; it is shown to the next task
+	VerifyFailure string
+
+	// BlockedSignal, when set, is the sentinel the model emits to stop the
+	// run because it needs human input
+	BlockedSignal string
+
+	// AskUser answers ask_user questions interactively. When nil, a
+	// question blocks the task and halts the run until it is answered.
+	AskUser func(question string) (string, error)
+
+	// ExtendBudget, when set, is asked whether a task that reached its turn
+	// or tool call limit may continue. Granting it allows another
+	// MaxIterations turns and MaxToolCalls tool calls in the same
+	// conversation; otherwise the task is recorded as incomplete.
+	ExtendBudget func(task *state.Task, reason string) bool
+
+	// Watchdog, when set, halts the run once tool calls keep repeating
+	// without progress across tasks
+	Watchdog *Watchdog
+
+	// Monitor, when set, is told before every model and tool call what the
+	// task is doing, so the run can be inspected while it goes on
+	Monitor *state.Monitor
+
+	// Trace, when set, records the model's text and chosen tools for
+	// every turn
+	Trace *Trace
+
+	// ExplainActions requires a one-line intent before every high-risk tool
+	// call and records it with the call. Calls are never blocked.
+	ExplainActions bool
+
+	// Results keeps truncated tool results so the model can read the rest
+	// with read_more
+	Results *ResultStore
+
+	// Stream prints the model's text as it is generated instead of
+	// waiting for each response to complete
+	Stream bool
 }
 
-func NewExecutor(workingDir string) *Executor {
+func NewExecutor(client llm.Client, toolExecutor *tools.ToolExecutor) *Executor {
 	return &Executor{
-		client:       llm.NewBedrockClient(),
-		toolExecutor: tools.NewToolExecutor(workingDir),
+		client:        client,
+		toolExecutor:  toolExecutor,
+		MaxIterations: DefaultMaxIterations,
+		MaxToolCalls:  DefaultMaxToolCalls,
+		MaxNudges:     DefaultMaxNudges,
+		MaxIdleTurns:  DefaultMaxIdleTurns,
+		DigestLength:  DefaultDigestLength,
+		Results:       NewResultStore(maxStoredResults),
+		MaxTokens:     DefaultExecutorMaxTokens,
 	}
 }
 
+// Fork returns an Executor with the same settings that runs tasks with
+// client and toolExecutor, for a task running alongside others. It has its
+// own read_more results and stall watchdog, and no monitor or interactive
+// callbacks.
+func (e *Executor) Fork(client llm.Client, toolExecutor *tools.ToolExecutor) *Executor {
+	fork := *e
+	fork.client = client
+	fork.toolExecutor = toolExecutor
+	fork.Results = NewResultStore(maxStoredResults)
+	if e.Watchdog != nil {
+		fork.Watchdog = NewWatchdog(e.Watchdog.Window, e.Watchdog.Repeat)
+	}
+	fork.Monitor = nil
+	fork.AskUser = nil
+	fork.ExtendBudget = nil
+	return &fork
+}
+
 func (e *Executor) ExecuteTask(agentState *state.AgentState, task *state.Task) error {
-	color.Yellow("\n🔧 Executing: %s\n", task.Description)
+	e.Out.Yellow("\n🔧 Executing: %s\n", task.Description)
 	
 	agentState.StartTask(task.ID)
+	e.toolExecutor.TaskID = task.ID
+	agentState.SetCreateConflicts(task, e.toolExecutor.PlanCreates(task.Creates))
+	if len(task.CreateConflicts) > 0 {
+		e.Out.Yellow("  ⚠️  Planned as new but already present: %s\n", strings.Join(task.CreateConflicts, ", "))
+	}
 	
 	// Build conversation with task context
 	messages := e.buildTaskMessages(agentState, task)
 	systemPrompt := e.buildExecutorSystemPrompt()
 	availableTools := e.getExecutorTools()
 	
-	// Allow up to 15 iterations for complex tasks
-	maxIterations := 15
-	for i := 0; i < maxIterations; i++ {
-		response, err := e.client.CreateMessage(messages, systemPrompt, availableTools)
+	stopReason := "max iterations reached"
+	nudges := 0
+	idle := 0
+	lastReply := ""
+	
+	maxIterations := e.MaxIterations
+	maxToolCalls := e.MaxToolCalls
+	for i := 0; ; i++ {
+		if i >= maxIterations || task.ToolCalls >= maxToolCalls {
+			if task.ToolCalls >= maxToolCalls {
+				stopReason = "tool call limit reached"
+			}
+			e.Out.Yellow("  ⚠️  Stopped after %d turns and %d tool calls (%s)\n", task.Turns, task.ToolCalls, stopReason)
+			if e.ExtendBudget == nil || !e.ExtendBudget(task, stopReason) {
+				break
+			}
+			maxIterations = i + e.MaxIterations
+			maxToolCalls = task.ToolCalls + e.MaxToolCalls
+			stopReason = "max iterations reached"
+			e.Out.Cyan("  ➕ Continuing with %d more turns and %d more tool calls\n", e.MaxIterations, e.MaxToolCalls)
+		}
+
+		agentState.CountTurn(task)
+		e.Monitor.Update(agentState, state.PhaseExecution, "waiting for the model")
+		start := time.Now()
+		response, err := e.createMessage(trimHistory(messages, e.MaxHistoryTurns), systemPrompt, availableTools)
 		if err != nil {
 			agentState.MarkTaskFailed(task.ID, err.Error())
 			return fmt.Errorf("LLM error: %w", err)
 		}
+		agentState.RecordUsage(state.PhaseExecution, response.Usage.InputTokens, response.Usage.OutputTokens, time.Since(start))
+		agentState.AddTaskTokens(task, response.Usage.InputTokens, response.Usage.OutputTokens)
 		
 		text, toolCalls, _ := e.client.ParseContent(response.Content)
+		e.Trace.Record(state.PhaseExecution, task.ID, task.Turns, text, toolCalls, response.StopReason)
+		
+		if question, blocked := blockedQuestion(response, text, e.BlockedSignal); blocked {
+			if question == "" {
+				question = "no details given"
+			}
+			agentState.MarkTaskBlocked(task.ID, "", question)
+			e.Out.Red("  ✋ Blocked: %s\n", question)
+			return &BlockedError{TaskID: task.ID, Question: question}
+		}
 		
 		// Add assistant message
 		messages = append(messages, llm.AnthropicMessage{
@@ -50,11 +211,103 @@ func (e *Executor) ExecuteTask(agentState *state.AgentState, task *state.Task) e
 		})
 		
 		if len(toolCalls) > 0 {
+			nudges = 0
+			idle = 0
 			// Execute tool calls
 			var toolResults []interface{}
+			var completion *llm.ToolUseContent
+			missingIntent := false
+			limited := false
 			
-			for _, toolCall := range toolCalls {
-				color.Cyan("  🔨 %s: %s\n", toolCall.Name, e.getToolDescription(toolCall))
+			for idx, toolCall := range toolCalls {
+				if result, ok := incompleteToolCallResult(e.Out, availableTools, toolCall, response.StopReason); !ok {
+					toolResults = append(toolResults, result)
+					continue
+				}
+				
+				// Only this call ends a task. Completion-like words are never
+				// looked for: not in the model's text, the task description
+				// ("mark the feature as done") or tool results (a grep hit
+				// for "task completed")
+				if toolCall.Name == completeTaskToolName {
+					completion = &toolCalls[idx]
+					continue
+				}
+				
+				if toolCall.Name == askUserToolName {
+					question, _ := toolCall.Input["question"].(string)
+					e.Out.Magenta("  ❓ %s\n", question)
+					if e.AskUser == nil {
+						agentState.MarkTaskBlocked(task.ID, "", question)
+						return &BlockedError{TaskID: task.ID, Question: question}
+					}
+					answer, err := e.AskUser(question)
+					if err != nil {
+						return fmt.Errorf("failed to read answer: %w", err)
+					}
+					agentState.AnswerClarification(agentState.AskClarification(task.ID, question), answer)
+					toolResults = append(toolResults, llm.ToolResultContent{
+						Type:      "tool_result",
+						ToolUseID: toolCall.ID,
+						Content:   "The user answered: " + answer,
+					})
+					continue
+				}
+				
+				if toolCall.Name == blockTaskToolName {
+					question, _ := toolCall.Input["question"].(string)
+					reason, _ := toolCall.Input["reason"].(string)
+					if strings.TrimSpace(question) == "" {
+						toolResults = append(toolResults, llm.ToolResultContent{
+							Type:      "tool_result",
+							ToolUseID: toolCall.ID,
+							Content:   "Error: block_task requires a specific question for the human",
+							IsError:   true,
+						})
+						continue
+					}
+					// The task waits for an answer; the rest of the plan goes on
+					agentState.MarkTaskBlocked(task.ID, strings.TrimSpace(reason), strings.TrimSpace(question))
+					e.Out.Yellow("  ✋ Blocked: %s\n", question)
+					return nil
+				}
+				
+				if toolCall.Name == readMoreToolName {
+					id, _ := toolCall.Input["tool_call_id"].(string)
+					e.Out.Cyan("  🔨 read_more: %s\n", id)
+					toolResults = append(toolResults, e.Results.readMoreResult(toolCall))
+					continue
+				}
+				
+				if task.ToolCalls >= maxToolCalls {
+					limited = true
+					toolResults = append(toolResults, llm.ToolResultContent{
+						Type:      "tool_result",
+						ToolUseID: toolCall.ID,
+						Content:   "Error: tool call limit reached for this task; call not executed",
+						IsError:   true,
+					})
+					continue
+				}
+				var action *state.Action
+				if e.ExplainActions && highRiskTools[toolCall.Name] {
+					intent := statedIntent(text)
+					if intent == "" {
+						missingIntent = true
+						e.Out.Yellow("  ⚠️  No intent stated\n")
+					} else {
+						e.Out.Magenta("  💭 Intent: %s\n", intent)
+					}
+					action = &state.Action{
+						Time:   time.Now(),
+						Tool:   toolCall.Name,
+						Target: e.getToolDescription(toolCall),
+						Intent: intent,
+					}
+				}
+				agentState.CountToolCall(task, action)
+				e.Out.Cyan("  🔨 %s: %s\n", toolCall.Name, e.getToolDescription(toolCall))
+				e.Monitor.ToolCall(agentState, state.PhaseExecution, toolCall.Name+": "+e.getToolDescription(toolCall))
 				
 				output, err := e.toolExecutor.Execute(toolCall.Name, toolCall.Input)
 				isError := err != nil
@@ -63,9 +316,10 @@ func (e *Executor) ExecuteTask(agentState *state.AgentState, task *state.Task) e
 					output = fmt.Sprintf("Error: %v", err)
 				}
 				
-				// Truncate very long outputs
-				if len(output) > 10000 {
-					output = output[:10000] + "\n... (output truncated)"
+				// Truncate very long outputs, keeping them for read_more;
+				// read_files keeps to its own budget
+				if toolCall.Name != "read_files" {
+					output = e.Results.Truncate(toolCall.ID, output)
 				}
 				
 				toolResults = append(toolResults, llm.ToolResultContent{
@@ -74,47 +328,190 @@ func (e *Executor) ExecuteTask(agentState *state.AgentState, task *state.Task) e
 					Content:   output,
 					IsError:   isError,
 				})
+				
+				if e.Watchdog != nil {
+					if report := e.Watchdog.Observe(agentState, task.ID, toolCall.Name, toolCall.Input); report != nil {
+						agentState.SetStall(report)
+						stalled := &StalledError{Report: report}
+						e.Out.Red("  🛑 %v\n", stalled)
+						agentState.MarkTaskFailed(task.ID, stalled.Error())
+						return stalled
+					}
+				}
+			}
+			
+			// A completion sent along with calls that were not executed
+			// claims work that never happened
+			if completion != nil && limited {
+				toolResults = append(toolResults, llm.ToolResultContent{
+					Type:      "tool_result",
+					ToolUseID: completion.ID,
+					Content:   "Error: not completed: the tool call limit was reached before every call in this turn ran",
+					IsError:   true,
+				})
+				completion = nil
 			}
 			
+			if completion != nil {
+				// Task completed successfully
+				summary, _ := completion.Input["summary"].(string)
+				if summary == "" {
+					summary = "Task completed"
+				}
+				if partial, _ := completion.Input["partial"].(bool); partial {
+					remaining, _ := completion.Input["remaining"].(string)
+					if strings.TrimSpace(remaining) == "" {
+						remaining = "not described"
+					}
+					agentState.MarkTaskPartial(task.ID, summary, strings.TrimSpace(remaining))
+					e.Out.Yellow("  🟡 Task partially completed; remaining: %s\n", strings.TrimSpace(remaining))
+					return nil
+				}
+				agentState.MarkTaskComplete(task.ID, summary)
+				e.Out.Green("  ✅ Task completed\n")
+				return nil
+			}
+			
+			if missingIntent {
+				toolResults = append(toolResults, llm.TextContent{Type: "text", Text: intentReminder})
+			}
 			messages = append(messages, llm.AnthropicMessage{
 				Role:    "user",
 				Content: toolResults,
 			})
 			
-		} else if strings.Contains(strings.ToLower(text), "task completed") || 
-				  strings.Contains(strings.ToLower(text), "task complete") ||
-				  strings.Contains(strings.ToLower(text), "successfully completed") ||
-				  strings.Contains(strings.ToLower(text), "done") && i > 0 {
-			// Task completed successfully
-			agentState.MarkTaskComplete(task.ID, text)
-			color.Green("  ✅ Task completed\n")
-			return nil
-		} else if i == 0 && text != "" {
-			// First response with no tools, ask to proceed
+		} else {
+			// Text alone never completes a task; nudge the model to act or
+			// signal completion, and give up on a model that keeps stalling
+			nudges++
+			// A reply that is empty or repeats the previous one adds
+			// nothing, so the remaining nudges are not spent on it
+			reply := strings.TrimSpace(text)
+			if reply == "" || reply == lastReply {
+				idle++
+			} else {
+				idle = 0
+			}
+			lastReply = reply
+			if e.MaxIdleTurns > 0 && idle >= e.MaxIdleTurns {
+				reason := fmt.Sprintf("stalled: %d consecutive turns without a tool call or new text", idle)
+				e.Out.Red("  ❌ Task %s\n", reason)
+				agentState.MarkTaskFailed(task.ID, reason)
+				return fmt.Errorf("task %s", reason)
+			}
+			if nudges > e.MaxNudges {
+				reason := fmt.Sprintf("stalled: %d consecutive turns without a tool call", nudges)
+				e.Out.Red("  ❌ Task %s\n", reason)
+				agentState.MarkTaskFailed(task.ID, reason)
+				return fmt.Errorf("task %s", reason)
+			}
+			nudge := e.nudge(i)
+			e.Out.Yellow("  💬 No tool call; nudging (%d/%d)\n", nudges, e.MaxNudges)
 			messages = append(messages, llm.AnthropicMessage{
 				Role: "user",
 				Content: []interface{}{
 					llm.TextContent{
 						Type: "text",
-						Text: "Please proceed with implementing this task using the available tools.",
+						Text: nudge,
 					},
 				},
 			})
 		}
 	}
 	
-	// Max iterations reached
-	agentState.MarkTaskComplete(task.ID, "Task completed (max iterations reached)")
+	// Turn or tool call limit reached without complete_task: the work may
+	// be unfinished, so the task is not reported as completed
+	agentState.MarkTaskIncomplete(task.ID, fmt.Sprintf("stopped before complete_task: %s after %d turns and %d tool calls", stopReason, task.Turns, task.ToolCalls))
+	e.Out.Yellow("  ⏹️  Task not completed\n")
 	return nil
 }
 
+// createMessage asks the model for the next turn. With Stream set, its
+// text is printed, indented, while it arrives.
+func (e *Executor) createMessage(messages []llm.AnthropicMessage, systemPrompt string, availableTools []llm.Tool) (*llm.AnthropicResponse, error) {
+	opts := llm.CallOptions{MaxTokens: e.MaxTokens}
+	if !e.Stream {
+		return e.client.CreateMessageWith(messages, systemPrompt, availableTools, opts)
+	}
+
+	lineStart := true
+	response, err := e.client.CreateMessageStream(messages, systemPrompt, availableTools, opts, llm.StreamHandler{
+		Text: func(delta string) {
+			for _, line := range strings.SplitAfter(delta, "\n") {
+				if line == "" {
+					continue
+				}
+				if lineStart && line != "\n" {
+					e.Out.Print("  💬 ")
+				}
+				e.Out.Print(line)
+				lineStart = strings.HasSuffix(line, "\n")
+			}
+		},
+	})
+	if !lineStart {
+		e.Out.Println()
+	}
+	return response, err
+}
+
+// nudge is the message sent after a prose-only turn.
+func (e *Executor) nudge(turn int) string {
+	if e.Nudge != "" {
+		return e.Nudge
+	}
+	if turn == 0 {
+		return "Please proceed with implementing this task using the available tools."
+	}
+	return "If the task is fully implemented, call complete_task. Otherwise, continue using the available tools."
+}
+
 func (e *Executor) buildTaskMessages(agentState *state.AgentState, task *state.Task) []llm.AnthropicMessage {
 	// Build context from completed tasks
 	var context strings.Builder
-	if len(agentState.CompletedTasks) > 0 {
+	if agentState.Plan != nil && agentState.Plan.Findings != "" {
+		context.WriteString("What planning found out about the codebase (rely on it instead of re-exploring):\n")
+		context.WriteString(agentState.Plan.Findings)
+		context.WriteString("\n\n")
+	}
+	if note := e.toolExecutor.SubmoduleNote(); note != "" {
+		context.WriteString(note)
+		context.WriteString("\n\n")
+	}
+	if note := agentState.Scope.Note(); note != "" {
+		context.WriteString(note)
+		context.WriteString("\n\n")
+	}
+	if len(task.Creates) > 0 {
+		context.WriteString(fmt.Sprintf("The plan expects this task to create: %s\n\n", strings.Join(task.Creates, ", ")))
+	}
+	if agentState.TestConventions != "" && writesTests(task.Description) {
+		context.WriteString(agentState.TestConventions)
+		context.WriteString("\n\n")
+	}
+	if completed := agentState.FinishedTasks(); len(completed) > 0 {
 		context.WriteString("Previously completed tasks:\n")
-		for _, t := range agentState.CompletedTasks {
-			context.WriteString(fmt.Sprintf("- %s\n", t.Description))
+		for _, t := range completed {
+			if summary := digest(t.Output, e.DigestLength); summary != "" {
+				context.WriteString(fmt.Sprintf("- %s\n  Result: %s\n", t.Description, summary))
+			} else {
+				context.WriteString(fmt.Sprintf("- %s\n", t.Description))
+			}
+			if t.Status == "partial" {
+				context.WriteString(fmt.Sprintf("  Only partly done. Remaining: %s\n", t.Remaining))
+			}
+		}
+		context.WriteString("\n")
+	}
+	if e.VerifyFailure != "" {
+		context.WriteString("The project's build/test check failed after the previous task. If the failure is caused by earlier changes or concerns this task, fix it as part of this task:\n")
+		context.WriteString(e.VerifyFailure)
+		context.WriteString("\n\n")
+	}
+	if answers := agentState.AnsweredClarifications(task.ID); len(answers) > 0 {
+		context.WriteString("Earlier attempts at this task asked the user questions. Their answers:\n")
+		for _, c := range answers {
+			context.WriteString(fmt.Sprintf("- Q: %s\n  A: %s\n", c.Question, c.Answer))
 		}
 		context.WriteString("\n")
 	}
@@ -136,7 +533,7 @@ Please implement this task step by step. Use the available tools to:
 3. Test your changes if applicable
 4. Verify the implementation
 
-When the task is complete, say "Task completed" with a brief summary.`, 
+When the task is complete, call the complete_task tool with a brief summary.`, 
 						context.String(), task.Description, agentState.OriginalRequest),
 				},
 			},
@@ -145,7 +542,7 @@ When the task is complete, say "Task completed" with a brief summary.`,
 }
 
 func (e *Executor) buildExecutorSystemPrompt() string {
-	return `You are an expert software engineer implementing specific tasks.
+	prompt := `You are an expert software engineer implementing specific tasks.
 
 Your approach should be:
 1. First understand the existing code by reading relevant files
@@ -159,15 +556,29 @@ Important guidelines:
 - Follow the existing code style and patterns
 - Test your changes when possible using bash commands
 - Create directories before writing files to them
+- Put temporary files in the scratch directory (scratch_dir tool or $SCRATCH_DIR), never in the project
 - Handle errors gracefully
-- When task is complete, explicitly state "Task completed" with a summary
+- If the task truly cannot be done without a human (missing credentials, an ambiguous requirement, a design decision), call block_task with a specific question instead of guessing
+- When the task is complete, call the complete_task tool with a summary
 
 Be thorough but efficient. Focus on correctness over speed.`
+	
+	if e.ExplainActions {
+		prompt += `
+
+This run is audited. Before every bash, write_file or edit_file call, write one line starting with "Intent:" that says what the call will do and why, e.g. "Intent: add the retry option to config.go so callers can tune backoff".`
+	}
+	if e.BlockedSignal != "" {
+		prompt += fmt.Sprintf(`
+
+If you cannot make progress without a decision or information from a human (missing credentials, ambiguous requirements, an unsafe operation), explain exactly what you need and then write %s. Do not use it for problems you can solve yourself.`, e.BlockedSignal)
+	}
+	return prompt
 }
 
 func (e *Executor) getExecutorTools() []llm.Tool {
 	toolDefs := tools.GetAvailableTools()
-	var llmTools []llm.Tool
+	llmTools := []llm.Tool{completeTaskTool(), askUserTool(), blockTaskTool(), readMoreTool()}
 	
 	for _, toolDef := range toolDefs {
 		llmTools = append(llmTools, llm.Tool{
@@ -192,21 +603,57 @@ func (e *Executor) getToolDescription(toolCall llm.ToolUseContent) string {
 		}
 	case "read_file":
 		if path, ok := toolCall.Input["path"].(string); ok {
+			start, hasStart := toolCall.Input["start_line"].(float64)
+			end, hasEnd := toolCall.Input["end_line"].(float64)
+			switch {
+			case hasStart && hasEnd:
+				return fmt.Sprintf("%s:%d-%d", path, int(start), int(end))
+			case hasStart:
+				return fmt.Sprintf("%s:%d-", path, int(start))
+			case hasEnd:
+				return fmt.Sprintf("%s:1-%d", path, int(end))
+			}
 			return path
 		}
-	case "write_file":
+	case "read_files":
+		paths, _ := toolCall.Input["paths"].([]interface{})
+		names := make([]string, 0, len(paths))
+		for _, p := range paths {
+			names = append(names, fmt.Sprint(p))
+		}
+		return strings.Join(names, ", ")
+	case "write_file", "edit_file":
 		if path, ok := toolCall.Input["path"].(string); ok {
 			return path
 		}
 	case "search":
+		var quoted []string
 		if pattern, ok := toolCall.Input["pattern"].(string); ok {
-			return fmt.Sprintf("'%s'", pattern)
+			quoted = append(quoted, fmt.Sprintf("'%s'", pattern))
 		}
+		patterns, _ := toolCall.Input["patterns"].([]interface{})
+		for _, p := range patterns {
+			quoted = append(quoted, fmt.Sprintf("'%v'", p))
+		}
+		return strings.Join(quoted, ", ")
 	case "list_files":
 		if path, ok := toolCall.Input["path"].(string); ok {
 			return path
 		}
 		return "current directory"
+	case "glob":
+		pattern, _ := toolCall.Input["pattern"].(string)
+		if path, ok := toolCall.Input["path"].(string); ok && path != "" {
+			return pattern + " in " + path
+		}
+		return pattern
+	case "scratch_dir":
+		return "scratch directory"
+	case "deps":
+		if filter, ok := toolCall.Input["filter"].(string); ok && filter != "" {
+			return filter
+		}
+		return "project dependencies"
 	}
 	return ""
 }
\ No newline at end of file
@@ -0,0 +1,253 @@
+package agents
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openswe/go-swe-agent/internal/llmtest"
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/tools"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+func listFiles(id string) string {
+	return llmtest.ToolUse(id, "list_files", "{}")
+}
+
+func completeTask(id string) string {
+	return llmtest.ToolUse(id, completeTaskToolName, `{"summary":"done"}`)
+}
+
+// newTestExecutor returns an executor driven by client in a temporary
+// working directory, and a state with one pending task.
+func newTestExecutor(t *testing.T, client llm.Client) (*Executor, *state.AgentState) {
+	dir := t.TempDir()
+	executor := NewExecutor(client, tools.NewToolExecutor(dir))
+	executor.Out = ui.NewPrinter(io.Discard)
+	agentState := state.NewAgentState(dir, "request")
+	agentState.Plan = &state.Plan{Tasks: []state.Task{{ID: "task-1", Description: "list the files", Status: "pending"}}}
+	return executor, agentState
+}
+
+func TestExecuteTaskToolCallLimitIsNotCompleted(t *testing.T) {
+	client := &llmtest.Client{Turns: [][]string{
+		{listFiles("a"), listFiles("b"), listFiles("c"), completeTask("d")},
+	}}
+	executor, agentState := newTestExecutor(t, client)
+	executor.MaxToolCalls = 2
+	task := &agentState.Plan.Tasks[0]
+
+	if err := executor.ExecuteTask(agentState, task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	if task.Status != "incomplete" {
+		t.Errorf("status = %q, want incomplete", task.Status)
+	}
+	if task.ToolCalls != 2 {
+		t.Errorf("tool calls = %d, want 2", task.ToolCalls)
+	}
+}
+
+func TestExecuteTaskExtendedBudgetRunsMoreToolCalls(t *testing.T) {
+	client := &llmtest.Client{Turns: [][]string{
+		{listFiles("a"), listFiles("b")},
+		{listFiles("c")},
+		{completeTask("d")},
+	}}
+	executor, agentState := newTestExecutor(t, client)
+	executor.MaxToolCalls = 2
+	extensions := 0
+	executor.ExtendBudget = func(task *state.Task, reason string) bool {
+		extensions++
+		return extensions == 1
+	}
+	task := &agentState.Plan.Tasks[0]
+
+	if err := executor.ExecuteTask(agentState, task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	if extensions != 1 {
+		t.Errorf("budget extended %d times, want 1", extensions)
+	}
+	if task.ToolCalls != 3 {
+		t.Errorf("tool calls = %d, want 3", task.ToolCalls)
+	}
+	if task.Status != "completed" {
+		t.Errorf("status = %q, want completed", task.Status)
+	}
+}
+
+// TestParallelTasksWithSnapshots runs two tasks of one plan at the same
+// time while their state is saved and snapshotted, as a run with
+// --parallel does. Run with -race.
+func TestParallelTasksWithSnapshots(t *testing.T) {
+	script := [][]string{
+		{listFiles("a"), listFiles("b")},
+		{listFiles("c")},
+		{completeTask("d")},
+	}
+	executor, agentState := newTestExecutor(t, &llmtest.Client{Turns: script, Delay: 5 * time.Millisecond})
+	executor.ExplainActions = true
+	agentState.Plan.Tasks = append(agentState.Plan.Tasks, state.Task{ID: "task-2", Description: "list them again", Status: "pending"})
+	fork := executor.Fork(&llmtest.Client{Turns: script, Delay: 5 * time.Millisecond}, tools.NewToolExecutor(agentState.WorkingDir))
+	checkpoint := t.TempDir() + "/checkpoint.json"
+	monitor := state.NewMonitor()
+
+	done := make(chan struct{})
+	snapshots := make(chan int)
+	go func() {
+		n := 0
+		for {
+			select {
+			case <-done:
+				snapshots <- n
+				return
+			default:
+			}
+			monitor.Update(agentState, state.PhaseExecution, "snapshot")
+			if err := agentState.SaveState(checkpoint); err != nil {
+				t.Errorf("SaveState: %v", err)
+			}
+			n++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i, e := range []*Executor{executor, fork} {
+		wg.Add(1)
+		go func(e *Executor, task *state.Task) {
+			defer wg.Done()
+			if err := e.ExecuteTask(agentState, task); err != nil {
+				t.Errorf("ExecuteTask: %v", err)
+			}
+		}(e, &agentState.Plan.Tasks[i])
+	}
+	wg.Wait()
+	close(done)
+	<-snapshots
+
+	for _, task := range agentState.Plan.Tasks {
+		if task.Status != "completed" || task.ToolCalls != 3 || task.Turns != 3 {
+			t.Errorf("%s: status %s, %d tool calls, %d turns; want completed, 3, 3", task.ID, task.Status, task.ToolCalls, task.Turns)
+		}
+	}
+	if _, err := state.LoadState(checkpoint); err != nil {
+		t.Errorf("LoadState: %v", err)
+	}
+}
+
+// Completion words in the task description, the model's text or tool
+// results never end a task; only complete_task does.
+func TestExecuteTaskCompletesOnlyOnCompleteTask(t *testing.T) {
+	client := &llmtest.Client{Turns: [][]string{
+		{llmtest.TextBlock("Task completed. The feature is done."), llmtest.ToolUse("a", "bash", `{"command":"echo task completed"}`)},
+		{llmtest.TextBlock("Done."), listFiles("b")},
+		{completeTask("c")},
+	}}
+	executor, agentState := newTestExecutor(t, client)
+	task := &agentState.Plan.Tasks[0]
+	task.Description = "mark the feature as done"
+
+	if err := executor.ExecuteTask(agentState, task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	if client.Calls() != 3 {
+		t.Errorf("%d model calls, want 3: the task ended before complete_task", client.Calls())
+	}
+	if task.Status != "completed" {
+		t.Errorf("status = %q, want completed", task.Status)
+	}
+}
+
+func TestExecuteTaskTextSayingDoneIsNotCompletion(t *testing.T) {
+	client := &llmtest.Client{Turns: [][]string{
+		{llmtest.TextBlock("The task is done.")},
+		{llmtest.TextBlock("Task completed.")},
+		{llmtest.TextBlock("Everything is finished, done.")},
+	}}
+	executor, agentState := newTestExecutor(t, client)
+	task := &agentState.Plan.Tasks[0]
+	task.Description = "mark the feature as done"
+
+	executor.ExecuteTask(agentState, task)
+	if task.Status == "completed" {
+		t.Error("the task was completed without complete_task")
+	}
+}
+
+// lastText returns the text of the last message sent to the model.
+func lastText(messages []llm.AnthropicMessage) string {
+	content, _ := messages[len(messages)-1].Content.([]interface{})
+	for _, block := range content {
+		if text, ok := block.(llm.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
+
+// The stall count starts at the first turn without a tool call, and a
+// stalled task fails as stalled before it reaches the iteration limit.
+func TestExecuteTaskFailsAfterMaxNudges(t *testing.T) {
+	client := &llmtest.Client{Turns: [][]string{
+		{listFiles("a")},
+		{listFiles("b")},
+		{llmtest.TextBlock("I will look at the files.")},
+		{llmtest.TextBlock("First I need to think about it.")},
+		{llmtest.TextBlock("The change belongs in main.go.")},
+		{llmtest.TextBlock("Let me plan the edit.")},
+	}}
+	executor, agentState := newTestExecutor(t, client)
+	var out bytes.Buffer
+	executor.Out = ui.NewPrinter(&out)
+	executor.MaxIterations = 6
+	executor.MaxNudges = 2
+	executor.Nudge = "Use a tool now."
+	task := &agentState.Plan.Tasks[0]
+
+	err := executor.ExecuteTask(agentState, task)
+	if err == nil || !strings.Contains(err.Error(), "stalled: 3 consecutive turns without a tool call") {
+		t.Errorf("ExecuteTask error = %v, want the task stalled", err)
+	}
+	if strings.Contains(out.String(), "max iterations reached") {
+		t.Errorf("the task reached the iteration limit:\n%s", out.String())
+	}
+	if task.Status != "failed" {
+		t.Errorf("status = %q, want failed", task.Status)
+	}
+	if client.Calls() != 5 || task.ToolCalls != 2 {
+		t.Errorf("%d model calls and %d tool calls, want 5 and 2: the stall count starts at turn 3", client.Calls(), task.ToolCalls)
+	}
+	if nudges := strings.Count(out.String(), "nudging ("); nudges != 2 || !strings.Contains(out.String(), "nudging (1/2)") {
+		t.Errorf("%d nudges, want 2 counted from 1:\n%s", nudges, out.String())
+	}
+	if got := lastText(client.LastMessages()); got != "Use a tool now." {
+		t.Errorf("nudge = %q, want the configured one", got)
+	}
+}
+
+func TestExecuteTaskToolCallResetsNudges(t *testing.T) {
+	client := &llmtest.Client{Turns: [][]string{
+		{llmtest.TextBlock("I will look at the files.")},
+		{llmtest.TextBlock("First I need to think about it.")},
+		{listFiles("a")},
+		{llmtest.TextBlock("The change belongs in main.go.")},
+		{llmtest.TextBlock("Let me plan the edit.")},
+		{completeTask("b")},
+	}}
+	executor, agentState := newTestExecutor(t, client)
+	executor.MaxNudges = 2
+	task := &agentState.Plan.Tasks[0]
+
+	if err := executor.ExecuteTask(agentState, task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	if task.Status != "completed" {
+		t.Errorf("status = %q, want completed", task.Status)
+	}
+}
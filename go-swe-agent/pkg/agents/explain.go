@@ -0,0 +1,99 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+const explainErrorToolName = "explain_error"
+
+// maxExplainedError bounds the error output sent to be explained; build
+// and test tools report the failure at the end.
+const maxExplainedError = 6000
+
+// Explainer turns the error of a failed task into an explanation and a
+// suggested fix for a human, with a single model call and no tools.
+type Explainer struct {
+	client llm.Client
+
+	// MaxTokens caps the output of each model call. Zero uses the
+	// client's default.
+	MaxTokens int
+}
+
+func NewExplainer(client llm.Client) *Explainer {
+	return &Explainer{client: client, MaxTokens: DefaultPlannerMaxTokens}
+}
+
+// Explain explains failure, the error of task or the build/test output
+// that followed it.
+func (e *Explainer) Explain(agentState *state.AgentState, task *state.Task, failure string) (*state.ErrorExplanation, error) {
+	if len(failure) > maxExplainedError {
+		failure = "... (earlier output omitted)\n" + failure[len(failure)-maxExplainedError:]
+	}
+	prompt := fmt.Sprintf(`An automated coding agent working on this request failed at one of its tasks.
+
+REQUEST: %s
+
+TASK: %s
+
+ERROR:
+%s
+
+Explain to the developer taking over what went wrong, in a few plain sentences, and suggest a concrete fix: which file or command to look at and what to change. Call explain_error with your answer.`, agentState.OriginalRequest, task.Description, failure)
+
+	messages := []llm.AnthropicMessage{{
+		Role:    "user",
+		Content: []interface{}{llm.TextContent{Type: "text", Text: prompt}},
+	}}
+	start := time.Now()
+	structured := llm.CallOptions{ToolChoice: llm.ForceTool(explainErrorToolName), Strict: true, MaxTokens: e.MaxTokens}
+	response, err := e.client.CreateMessageWith(messages, "You explain build, test and tool errors clearly and suggest fixes.", []llm.Tool{explainErrorTool()}, structured)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain the error: %w", err)
+	}
+	agentState.RecordUsage(state.PhaseExecution, response.Usage.InputTokens, response.Usage.OutputTokens, time.Since(start))
+
+	text, toolCalls, _ := e.client.ParseContent(response.Content)
+	explanation := &state.ErrorExplanation{TaskID: task.ID, Task: task.Description, Error: failure}
+	for _, toolCall := range toolCalls {
+		if toolCall.Name == explainErrorToolName {
+			explanation.Explanation, _ = toolCall.Input["explanation"].(string)
+			explanation.SuggestedFix, _ = toolCall.Input["suggested_fix"].(string)
+			break
+		}
+	}
+	if explanation.Explanation == "" {
+		// Providers without forced tool use may answer in prose
+		explanation.Explanation = strings.TrimSpace(text)
+	}
+	if explanation.Explanation == "" {
+		return nil, fmt.Errorf("failed to explain the error: the model gave no explanation")
+	}
+	return explanation, nil
+}
+
+func explainErrorTool() llm.Tool {
+	return llm.Tool{
+		Name:        explainErrorToolName,
+		Description: "Report what went wrong and how to fix it.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"explanation": map[string]interface{}{
+					"type":        "string",
+					"description": "What went wrong and why, in a few plain sentences",
+				},
+				"suggested_fix": map[string]interface{}{
+					"type":        "string",
+					"description": "A concrete fix: what to change, in which file or command",
+				},
+			},
+			"required": []string{"explanation", "suggested_fix"},
+		},
+	}
+}
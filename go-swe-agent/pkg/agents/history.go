@@ -0,0 +1,118 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+)
+
+// maxEvictedNoteLines bounds the carried-forward note about evicted turns.
+const maxEvictedNoteLines = 30
+
+// trimHistory keeps the first message (the phase's task) and the most
+// recent maxTurns assistant turns, each with the tool results that answer
+// it. Older turns are replaced by a note listing the tool calls they made.
+// The messages slice itself is left untouched. maxTurns <= 0 keeps all.
+func trimHistory(messages []llm.AnthropicMessage, maxTurns int) []llm.AnthropicMessage {
+	if maxTurns <= 0 || len(messages) == 0 {
+		return messages
+	}
+
+	// Each turn starts at an assistant message
+	var turnStarts []int
+	for i, msg := range messages[1:] {
+		if msg.Role == "assistant" {
+			turnStarts = append(turnStarts, i+1)
+		}
+	}
+	if len(turnStarts) <= maxTurns {
+		return messages
+	}
+
+	keepFrom := turnStarts[len(turnStarts)-maxTurns]
+	evicted := messages[1:keepFrom]
+	evictedTurns := len(turnStarts) - maxTurns
+
+	first := messages[0]
+	first.Content = append(contentBlocks(first.Content), llm.TextContent{
+		Type: "text",
+		Text: evictedNote(evicted, evictedTurns),
+	})
+
+	trimmed := make([]llm.AnthropicMessage, 0, 1+len(messages)-keepFrom)
+	trimmed = append(trimmed, first)
+	return append(trimmed, messages[keepFrom:]...)
+}
+
+// contentBlocks returns message content as a list of blocks, wrapping plain
+// string content in a text block.
+func contentBlocks(content interface{}) []interface{} {
+	switch c := content.(type) {
+	case string:
+		return []interface{}{llm.TextContent{Type: "text", Text: c}}
+	case []interface{}:
+		return append([]interface{}{}, c...)
+	}
+
+	data, _ := json.Marshal(content)
+	var raws []json.RawMessage
+	json.Unmarshal(data, &raws)
+	blocks := make([]interface{}, len(raws))
+	for i, raw := range raws {
+		blocks[i] = raw
+	}
+	return blocks
+}
+
+// evictedNote summarizes the tool calls of evicted turns.
+func evictedNote(evicted []llm.AnthropicMessage, turns int) string {
+	var lines []string
+	for _, msg := range evicted {
+		if msg.Role != "assistant" {
+			continue
+		}
+		data, _ := json.Marshal(msg.Content)
+		var blocks []struct {
+			Type  string                 `json:"type"`
+			Name  string                 `json:"name"`
+			Input map[string]interface{} `json:"input"`
+		}
+		json.Unmarshal(data, &blocks)
+		for _, b := range blocks {
+			if b.Type == "tool_use" {
+				lines = append(lines, "- "+describeCall(b.Name, b.Input))
+			}
+		}
+	}
+
+	var note strings.Builder
+	note.WriteString(fmt.Sprintf("[%d earlier turns were removed to keep the conversation short.", turns))
+	if len(lines) == 0 {
+		note.WriteString("]")
+		return note.String()
+	}
+	note.WriteString(" Tool calls made in them:\n")
+	if len(lines) > maxEvictedNoteLines {
+		omitted := len(lines) - maxEvictedNoteLines
+		lines = append(lines[len(lines)-maxEvictedNoteLines:], fmt.Sprintf("(and %d earlier calls)", omitted))
+	}
+	note.WriteString(strings.Join(lines, "\n"))
+	note.WriteString("\nRe-read files if you need their current contents.]")
+	return note.String()
+}
+
+// describeCall renders a tool call on one line, e.g. `read_file path=main.go`.
+func describeCall(name string, input map[string]interface{}) string {
+	var args []string
+	for _, key := range []string{"path", "command", "pattern"} {
+		if v, ok := input[key].(string); ok {
+			if len(v) > 80 {
+				v = v[:80] + "..."
+			}
+			args = append(args, fmt.Sprintf("%s=%q", key, v))
+		}
+	}
+	return strings.TrimSpace(name + " " + strings.Join(args, " "))
+}
@@ -0,0 +1,33 @@
+package agents
+
+import "strings"
+
+// highRiskTools change the working tree or run arbitrary commands. With
+// ExplainActions the model must state its intent before calling them.
+var highRiskTools = map[string]bool{
+	"bash":       true,
+	"write_file": true,
+	"edit_file":  true,
+}
+
+const intentPrefix = "Intent:"
+
+const intentReminder = "Reminder: before every bash, write_file or edit_file call, write one line starting with \"Intent:\" that says what you are about to do and why."
+
+// statedIntent returns the intent line from the text preceding a tool call:
+// the last line starting with "Intent:", or failing that the last line of
+// text. It returns "" when the model said nothing.
+func statedIntent(text string) string {
+	var last string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if len(line) >= len(intentPrefix) && strings.EqualFold(line[:len(intentPrefix)], intentPrefix) {
+			return strings.TrimSpace(line[len(intentPrefix):])
+		}
+		last = line
+	}
+	return last
+}
@@ -2,28 +2,66 @@ package agents
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/openswe/go-swe-agent/pkg/llm"
 	"github.com/openswe/go-swe-agent/pkg/state"
 	"github.com/openswe/go-swe-agent/pkg/tools"
+	"github.com/openswe/go-swe-agent/pkg/ui"
 )
 
+// DefaultPlannerIterations is the number of exploration turns the planner
+// gets before it must submit a plan.
+const DefaultPlannerIterations = 5
+
+// DefaultPlannerMaxTokens caps each planning response. Exploring and
+// submitting a plan take far less output than writing code.
+const DefaultPlannerMaxTokens = 4096
+
 type Planner struct {
-	client       *llm.BedrockClient
+	client       llm.Client
 	toolExecutor *tools.ToolExecutor
+
+	// Out receives progress output; nil writes to standard output
+	Out *ui.Printer
+
+	// MaxIterations caps the exploration turns before the final plan request
+	MaxIterations int
+
+	// MaxTokens caps the output of each model call. Zero uses the
+	// client's default.
+	MaxTokens int
+
+	// MaxHistoryTurns caps the assistant turns sent back to the model;
+	// older turns are replaced by a short note. Zero keeps them all.
+	MaxHistoryTurns int
+
+	// Images are attached to the initial request, e.g. screenshots of a UI bug
+	Images []llm.ImageContent
+
+	// ReproFirst requires a repro_command with the plan. The task writing
+	// the reproducing test is added by the orchestrator.
+	ReproFirst bool
+
+	// Trace, when set, records the model's text and chosen tools for
+	// every turn
+	Trace *Trace
 }
 
-func NewPlanner(workingDir string) *Planner {
+func NewPlanner(client llm.Client, toolExecutor *tools.ToolExecutor) *Planner {
 	return &Planner{
-		client:       llm.NewBedrockClient(),
-		toolExecutor: tools.NewToolExecutor(workingDir),
+		client:        client,
+		toolExecutor:  toolExecutor,
+		MaxIterations: DefaultPlannerIterations,
+		MaxTokens:     DefaultPlannerMaxTokens,
 	}
 }
 
 func (p *Planner) GeneratePlan(agentState *state.AgentState) error {
-	fmt.Println("\n🔍 Analyzing codebase and generating plan...")
+	p.Out.Println("\n🔍 Analyzing codebase and generating plan...")
 	
 	// First, gather context about the codebase
 	messages := p.buildContextMessages(agentState)
@@ -35,111 +73,184 @@ func (p *Planner) GeneratePlan(agentState *state.AgentState) error {
 	availableTools := p.getPlannerTools()
 	
 	// Initial exploration
-	for i := 0; i < 5; i++ { // Allow up to 5 tool calls for exploration
-		response, err := p.client.CreateMessage(messages, systemPrompt, availableTools)
+	for i := 0; i < p.MaxIterations; i++ {
+		start := time.Now()
+		response, err := p.client.CreateMessageWith(trimHistory(messages, p.MaxHistoryTurns), systemPrompt, availableTools, llm.CallOptions{MaxTokens: p.MaxTokens})
 		if err != nil {
 			return fmt.Errorf("failed to get LLM response: %w", err)
 		}
+		agentState.RecordUsage(state.PhasePlanning, response.Usage.InputTokens, response.Usage.OutputTokens, time.Since(start))
 		
 		text, toolCalls, _ := p.client.ParseContent(response.Content)
+		p.Trace.Record(state.PhasePlanning, "", i+1, text, toolCalls, response.StopReason)
+		
+		messages = append(messages, llm.AnthropicMessage{
+			Role:    "assistant",
+			Content: response.Content,
+		})
 		
-		if len(toolCalls) > 0 {
-			// Execute tool calls
+		if len(toolCalls) == 0 {
+			// Prose without a tool call never ends planning; ask for the plan explicitly
 			messages = append(messages, llm.AnthropicMessage{
-				Role:    "assistant",
-				Content: response.Content,
+				Role: "user",
+				Content: []interface{}{
+					llm.TextContent{
+						Type: "text",
+						Text: "Continue exploring with the available tools, or call submit_plan when you are ready to submit the plan.",
+					},
+				},
 			})
+			continue
+		}
+		
+		var toolResults []interface{}
+		for _, toolCall := range toolCalls {
+			if result, ok := incompleteToolCallResult(p.Out, availableTools, toolCall, response.StopReason); !ok {
+				toolResults = append(toolResults, result)
+				continue
+			}
 			
-			var toolResults []interface{}
-			for _, toolCall := range toolCalls {
-				fmt.Printf("  📂 Exploring: %s\n", toolCall.Name)
-				output, err := p.toolExecutor.Execute(toolCall.Name, toolCall.Input)
-				if err != nil {
-					output = fmt.Sprintf("Error: %v", err)
+			if toolCall.Name == submitPlanToolName {
+				plan, err := parsePlanSubmission(toolCall.Input)
+				if err == nil && p.ReproFirst && plan.Reproduction == nil && !plan.NoChangesNeeded {
+					err = fmt.Errorf("submit_plan requires repro_command in reproduce-first mode")
 				}
-				
-				// Truncate very long outputs
-				if len(output) > 5000 {
-					output = output[:5000] + "\n... (truncated)"
+				if err != nil {
+					toolResults = append(toolResults, llm.ToolResultContent{
+						Type:      "tool_result",
+						ToolUseID: toolCall.ID,
+						Content:   fmt.Sprintf("Error: %v", err),
+						IsError:   true,
+					})
+					continue
 				}
-				
-				toolResults = append(toolResults, llm.ToolResultContent{
-					Type:      "tool_result",
-					ToolUseID: toolCall.ID,
-					Content:   output,
-				})
+				agentState.ReplacePlan(plan)
+				p.reportPlan(plan)
+				return nil
 			}
 			
-			messages = append(messages, llm.AnthropicMessage{
-				Role:    "user",
-				Content: toolResults,
-			})
-		} else {
-			// No more tool calls, we should have a plan now
-			if strings.Contains(text, "PLAN:") {
-				plan := p.parsePlanFromText(text)
-				if plan != nil {
-					agentState.Plan = plan
-					fmt.Printf("\n✅ Generated plan with %d tasks\n", len(plan.Tasks))
-					return nil
-				}
+			p.Out.Printf("  📂 Exploring: %s\n", toolCall.Name)
+			output, err := p.toolExecutor.Execute(toolCall.Name, toolCall.Input)
+			if err != nil {
+				output = fmt.Sprintf("Error: %v", err)
+			}
+			
+			// Truncate very long outputs
+			if len(output) > 5000 {
+				output = output[:5000] + "\n... (truncated)"
 			}
+			
+			toolResults = append(toolResults, llm.ToolResultContent{
+				Type:      "tool_result",
+				ToolUseID: toolCall.ID,
+				Content:   output,
+			})
 		}
+		
+		messages = append(messages, llm.AnthropicMessage{
+			Role:    "user",
+			Content: toolResults,
+		})
 	}
 	
-	// Final attempt to get a plan without tools
+	// Final attempt: only the submit_plan tool is offered
 	messages = append(messages, llm.AnthropicMessage{
 		Role: "user",
 		Content: []interface{}{
 			llm.TextContent{
 				Type: "text",
-				Text: "Based on your exploration, please provide a concrete plan in the format:\nPLAN:\n1. [Task description]\n2. [Task description]\n...",
+				Text: "Based on your exploration, please submit a concrete plan now by calling submit_plan.",
 			},
 		},
 	})
 	
-	response, err := p.client.CreateMessage(messages, systemPrompt, nil)
+	start := time.Now()
+	// Force the call where the provider supports it, so the plan arrives
+	// as schema-shaped input instead of prose
+	structured := llm.CallOptions{ToolChoice: llm.ForceTool(submitPlanToolName), Strict: true, MaxTokens: p.MaxTokens}
+	response, err := p.client.CreateMessageWith(trimHistory(messages, p.MaxHistoryTurns), systemPrompt, []llm.Tool{submitPlanTool()}, structured)
 	if err != nil {
 		return fmt.Errorf("failed to get final plan: %w", err)
 	}
+	agentState.RecordUsage(state.PhasePlanning, response.Usage.InputTokens, response.Usage.OutputTokens, time.Since(start))
 	
-	text, _, _ := p.client.ParseContent(response.Content)
-	plan := p.parsePlanFromText(text)
+	text, toolCalls, _ := p.client.ParseContent(response.Content)
+	var plan *state.Plan
+	for _, toolCall := range toolCalls {
+		if toolCall.Name == submitPlanToolName {
+			plan, _ = parsePlanSubmission(toolCall.Input)
+			break
+		}
+	}
+	if plan == nil {
+		// Last resort: extract a plan written out as prose
+		plan = p.parsePlanFromText(text)
+	}
 	if plan == nil {
 		return fmt.Errorf("failed to generate a valid plan")
 	}
 	
-	agentState.Plan = plan
-	fmt.Printf("\n✅ Generated plan with %d tasks\n", len(plan.Tasks))
+	agentState.ReplacePlan(plan)
+	p.reportPlan(plan)
 	return nil
 }
 
+func (p *Planner) reportPlan(plan *state.Plan) {
+	if plan.NoChangesNeeded {
+		p.Out.Println("\n✅ No changes needed")
+		return
+	}
+	p.Out.Printf("\n✅ Generated plan with %d tasks\n", len(plan.Tasks))
+}
+
 func (p *Planner) buildContextMessages(agentState *state.AgentState) []llm.AnthropicMessage {
+	var reference string
+	if agentState.ReferenceContext != "" {
+		reference = fmt.Sprintf("\nThe request refers to the following existing change. Use it as context:\n\n%s\n", agentState.ReferenceContext)
+	}
+	
+	var content []interface{}
+	for _, image := range p.Images {
+		content = append(content, image)
+	}
+	
+	var images string
+	if len(p.Images) > 0 {
+		images = fmt.Sprintf("\nThe %d attached image(s) show what the request refers to.\n", len(p.Images))
+	}
+	var notes string
+	if note := p.toolExecutor.SubmoduleNote(); note != "" {
+		notes = "\n" + note + "\n"
+	}
+	if note := agentState.Scope.Note(); note != "" {
+		notes += "\n" + note + "\n"
+	}
+	
 	return []llm.AnthropicMessage{
 		{
 			Role: "user",
-			Content: []interface{}{
+			Content: append(content,
 				llm.TextContent{
 					Type: "text",
 					Text: fmt.Sprintf(`Please analyze this codebase and create a detailed plan to complete the following request:
 
 REQUEST: %s
-
+%s%s%s
 First, explore the codebase structure to understand:
 1. The project layout and key files
 2. The technology stack and dependencies
 3. Existing patterns and conventions
 4. Relevant code sections for this task
 
-Then provide a concrete, step-by-step plan to complete the request.`, agentState.OriginalRequest),
+Then provide a concrete, step-by-step plan to complete the request.`, agentState.OriginalRequest, images, reference, notes),
 				},
-			},
+			),
 		},
 	}
 }
 
 func (p *Planner) buildPlannerSystemPrompt() string {
-	return `You are an expert software engineer tasked with planning code changes.
+	prompt := `You are an expert software engineer tasked with planning code changes.
 
 Your job is to:
 1. Thoroughly analyze the codebase structure
@@ -149,25 +260,32 @@ Your job is to:
 Use the available tools to explore the codebase:
 - Use list_files to understand the project structure
 - Use read_file to examine key files (README, package.json, go.mod, etc.)
+- Use glob to find files by name, e.g. **/*_test.go, instead of bash find
 - Use search to find relevant code patterns
-- Use bash for commands like 'find', 'ls -la', etc.
+- Use bash for other commands like 'ls -la', etc.
 
-After exploration, provide your plan in this format:
-PLAN:
-1. [Specific task description]
-2. [Specific task description]
-...
+After exploration, call the submit_plan tool with an ordered list of tasks.
+Give each task the positions of the earlier tasks it needs finished first in depends_on, e.g. [1] for a task that calls a function task 1 adds, so independent tasks can run at the same time. Tasks that change the same file must depend on each other. If you write the plan as text instead, mark such a task like "3. [depends: task-1] Register the new handler".
+Include your findings: the key files, conventions and commands the tasks will need. Tasks are executed without your exploration history, so the findings are all they know about the codebase besides what they read themselves.
+If the codebase already does what the request asks, do not invent changes: call submit_plan with no_changes_needed set to true, no tasks, and a summary of the evidence.
 
 Each task should be concrete and actionable. Focus on:
 - Understanding before changing
 - Following existing patterns
 - Making incremental, testable changes
 - Ensuring the code remains functional`
+	
+	if p.ReproFirst {
+		prompt += `
+
+This is a bug fix in reproduce-first mode. A task that writes a test reproducing the bug is added automatically before your tasks, so do not plan one. Pass repro_command to submit_plan: a shell command that runs only that test (e.g. "go test ./pkg/parser -run TestEmptyInput"). It must fail while the bug exists; your tasks must fix the bug so that it passes.`
+	}
+	return prompt
 }
 
 func (p *Planner) getPlannerTools() []llm.Tool {
 	toolDefs := tools.GetAvailableTools()
-	var llmTools []llm.Tool
+	llmTools := []llm.Tool{submitPlanTool()}
 	
 	for _, toolDef := range toolDefs {
 		llmTools = append(llmTools, llm.Tool{
@@ -181,60 +299,27 @@ func (p *Planner) getPlannerTools() []llm.Tool {
 }
 
 func (p *Planner) parsePlanFromText(text string) *state.Plan {
-	if !strings.Contains(text, "PLAN:") {
-		return nil
-	}
-	
-	parts := strings.Split(text, "PLAN:")
-	if len(parts) < 2 {
-		return nil
-	}
-	
-	planText := parts[1]
-	lines := strings.Split(planText, "\n")
-	
 	var tasks []state.Task
-	taskID := 1
-	
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		
-		// Look for numbered items
-		for _, prefix := range []string{"1.", "2.", "3.", "4.", "5.", "6.", "7.", "8.", "9.", "10.", "11.", "12.", "13.", "14.", "15."} {
-			if strings.HasPrefix(line, prefix) {
-				taskDesc := strings.TrimSpace(strings.TrimPrefix(line, prefix))
-				if taskDesc != "" {
-					tasks = append(tasks, state.Task{
-						ID:          fmt.Sprintf("task-%d", taskID),
-						Description: taskDesc,
-						Status:      "pending",
-					})
-					taskID++
-					break
-				}
-			}
-		}
-		
-		// Also handle bullet points
-		if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
-			taskDesc := strings.TrimSpace(line[2:])
-			if taskDesc != "" {
-				tasks = append(tasks, state.Task{
-					ID:          fmt.Sprintf("task-%d", taskID),
-					Description: taskDesc,
-					Status:      "pending",
-				})
-				taskID++
-			}
+	if _, planText, found := strings.Cut(text, "PLAN:"); found {
+		tasks = listedTasks(planText)
+	} else {
+		// Without the header, only a numbered list of at least two steps
+		// is taken for a plan; bullets alone are too often just notes
+		tasks = numberedTasks(text)
+		if len(tasks) < 2 {
+			tasks = nil
 		}
 	}
 	
 	if len(tasks) == 0 {
 		return nil
 	}
+	deps := make([][]int, len(tasks))
+	for i := range tasks {
+		tasks[i].Description, deps[i] = cutDependencies(tasks[i].Description)
+	}
+	state.AssignTaskIDs(tasks)
+	linkDependencies(tasks, deps)
 	
 	return &state.Plan{
 		Tasks:      tasks,
@@ -242,4 +327,51 @@ func (p *Planner) parsePlanFromText(text string) *state.Plan {
 		CreatedAt:  time.Now(),
 		IsApproved: true, // Auto-approve for simplicity
 	}
-}
\ No newline at end of file
+}
+
+// numberedItem matches a numbered list item such as "1. Add the flag" or
+// "2) Update the docs".
+var numberedItem = regexp.MustCompile(`^(\d{1,2})[.)]\s+(.+)$`)
+
+// listedTasks turns every numbered item and bullet point of text into a task.
+func listedTasks(text string) []state.Task {
+	var tasks []state.Task
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		var description string
+		if m := numberedItem.FindStringSubmatch(line); m != nil {
+			description = m[2]
+		} else if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
+			description = line[2:]
+		}
+		if description = strings.TrimSpace(description); description != "" {
+			tasks = append(tasks, state.Task{Description: description, Status: "pending"})
+		}
+	}
+	return tasks
+}
+
+// numberedTasks returns the longest run of list items numbered 1, 2, 3...
+// in text. Other lines may come between the items, e.g. details of a step.
+func numberedTasks(text string) []state.Task {
+	var best, run []state.Task
+	for _, line := range strings.Split(text, "\n") {
+		m := numberedItem.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(m[1])
+		switch n {
+		case 1:
+			run = nil
+		case len(run) + 1:
+		default:
+			continue
+		}
+		run = append(run, state.Task{Description: strings.TrimSpace(m[2]), Status: "pending"})
+		if len(run) > len(best) {
+			best = run
+		}
+	}
+	return best
+}
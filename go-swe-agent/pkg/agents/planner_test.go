@@ -0,0 +1,115 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePlanFromText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string // task descriptions, or nil for no plan
+	}{
+		{
+			name: "empty",
+			text: "",
+		},
+		{
+			name: "whitespace only",
+			text: "  \n\t\n",
+		},
+		{
+			name: "header",
+			text: "I looked around.\n\nPLAN:\n1. Add the flag\n2. Update the docs\n",
+			want: []string{"Add the flag", "Update the docs"},
+		},
+		{
+			name: "header with bullets",
+			text: "PLAN:\n- Add the flag\n* Update the docs",
+			want: []string{"Add the flag", "Update the docs"},
+		},
+		{
+			name: "header with a single step",
+			text: "PLAN:\n1. Fix the typo",
+			want: []string{"Fix the typo"},
+		},
+		{
+			name: "header with nothing listed",
+			text: "PLAN:\nNothing to do here.",
+		},
+		{
+			name: "no header",
+			text: "Here is what I will do:\n1. Add the flag\n2) Parse it in main\n3. Update the docs",
+			want: []string{"Add the flag", "Parse it in main", "Update the docs"},
+		},
+		{
+			name: "no header with details between steps",
+			text: "1. Add the flag\n   It goes in cmd/main.go.\n2. Update the docs\n   - mention the default",
+			want: []string{"Add the flag", "Update the docs"},
+		},
+		{
+			name: "no header with a single step",
+			text: "1. Fix the typo",
+		},
+		{
+			name: "no header with bullets only",
+			text: "Notes:\n- the flag is unused\n- the docs are stale",
+		},
+		{
+			name: "no header takes the longest numbered run",
+			text: "Findings:\n1. The flag is unused\n\nSteps:\n1. Remove the flag\n2. Drop its docs\n3. Run the tests",
+			want: []string{"Remove the flag", "Drop its docs", "Run the tests"},
+		},
+		{
+			name: "no header skips out of order numbers",
+			text: "1. Add the flag\n3. Not a step\n2. Update the docs",
+			want: []string{"Add the flag", "Update the docs"},
+		},
+		{
+			name: "mixed headers only read the plan section",
+			text: "ANALYSIS:\n1. The flag is unused\n2. The docs are stale\n\nPLAN:\n1. Remove the flag\n- Drop its docs",
+			want: []string{"Remove the flag", "Drop its docs"},
+		},
+		{
+			name: "markdown header is not the plan header",
+			text: "## Plan\n1. Remove the flag\n2. Drop its docs",
+			want: []string{"Remove the flag", "Drop its docs"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := (&Planner{}).parsePlanFromText(tt.text)
+			if tt.want == nil {
+				if plan != nil {
+					t.Errorf("got a plan with %d tasks, want none", len(plan.Tasks))
+				}
+				return
+			}
+			if plan == nil {
+				t.Fatalf("got no plan, want %q", tt.want)
+			}
+			var got []string
+			for _, task := range plan.Tasks {
+				got = append(got, task.Description)
+				if task.ID == "" || task.Status != "pending" {
+					t.Errorf("task %q: ID %q, status %q; want an ID and pending", task.Description, task.ID, task.Status)
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("tasks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePlanFromTextDependencies(t *testing.T) {
+	plan := (&Planner{}).parsePlanFromText("1. Add the flag\n2. [depends: 1] Parse it in main")
+	if plan == nil || len(plan.Tasks) != 2 {
+		t.Fatalf("plan = %+v, want 2 tasks", plan)
+	}
+	second := plan.Tasks[1]
+	if second.Description != "Parse it in main" || len(second.DependsOn) != 1 || second.DependsOn[0] != plan.Tasks[0].ID {
+		t.Errorf("second task = %+v, want it to depend on %s", second, plan.Tasks[0].ID)
+	}
+}
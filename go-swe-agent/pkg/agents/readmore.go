@@ -0,0 +1,140 @@
+package agents
+
+import (
+	"fmt"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+)
+
+const (
+	readMoreToolName = "read_more"
+
+	// maxResultLength is the longest tool result sent to the model at
+	// once; read_more returns chunks of the same size
+	maxResultLength = 10000
+
+	// maxStoredResults caps the bytes of truncated results kept for
+	// read_more; the oldest are evicted first
+	maxStoredResults = 2 * 1024 * 1024
+)
+
+func readMoreTool() llm.Tool {
+	return llm.Tool{
+		Name:        readMoreToolName,
+		Description: "Read more of a tool result that was truncated, without running the tool again. Each call returns the next part; pass offset to jump to a character position.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"tool_call_id": map[string]interface{}{
+					"type":        "string",
+					"description": "The ID given in the truncation note of the result",
+				},
+				"offset": map[string]interface{}{
+					"type":        "integer",
+					"description": "Character position to continue from (optional; defaults to where the last part ended)",
+				},
+			},
+			"required": []string{"tool_call_id"},
+		},
+	}
+}
+
+// storedResult is a truncated tool result and where the next chunk starts.
+type storedResult struct {
+	text string
+	next int
+}
+
+// ResultStore keeps the full text of truncated tool results, keyed by tool
+// call ID, so the model can page through the rest with read_more. It holds
+// at most limit bytes and evicts the oldest results first.
+type ResultStore struct {
+	limit   int
+	size    int
+	order   []string
+	results map[string]*storedResult
+}
+
+// NewResultStore returns a store holding up to limit bytes of results.
+func NewResultStore(limit int) *ResultStore {
+	return &ResultStore{limit: limit, results: make(map[string]*storedResult)}
+}
+
+// Truncate cuts output to maxResultLength. The full output is kept under
+// id when it fits in the store, and the note tells the model how to read
+// the rest.
+func (s *ResultStore) Truncate(id, output string) string {
+	if len(output) <= maxResultLength {
+		return output
+	}
+	if !s.put(id, output) {
+		return output[:maxResultLength] + "\n... (output truncated)"
+	}
+	return output[:maxResultLength] + fmt.Sprintf("\n... (output truncated at %d of %d characters; call read_more with tool_call_id %q for the rest)", maxResultLength, len(output), id)
+}
+
+// put stores text under id, evicting the oldest results to make room. It
+// reports false for a nil store or a result larger than the whole store.
+func (s *ResultStore) put(id, text string) bool {
+	if s == nil || len(text) > s.limit {
+		return false
+	}
+	for s.size+len(text) > s.limit && len(s.order) > 0 {
+		oldest := s.order[0]
+		s.order = s.order[1:]
+		s.size -= len(s.results[oldest].text)
+		delete(s.results, oldest)
+	}
+	s.results[id] = &storedResult{text: text, next: maxResultLength}
+	s.order = append(s.order, id)
+	s.size += len(text)
+	return true
+}
+
+// ReadMore returns the next chunk of the result stored under id, starting
+// at offset when it is not negative, or where the last chunk ended.
+func (s *ResultStore) ReadMore(id string, offset int) (string, error) {
+	var r *storedResult
+	if s != nil {
+		r = s.results[id]
+	}
+	if r == nil {
+		return "", fmt.Errorf("no stored result for tool_call_id %q; it was not truncated or has been evicted. Run the tool again, narrowing its output", id)
+	}
+	if offset >= 0 {
+		r.next = offset
+	}
+	if r.next >= len(r.text) {
+		return "", fmt.Errorf("offset %d is past the end of the result (%d characters)", r.next, len(r.text))
+	}
+
+	start := r.next
+	end := start + maxResultLength
+	if end > len(r.text) {
+		end = len(r.text)
+	}
+	r.next = end
+	chunk := fmt.Sprintf("[Characters %d-%d of %d]\n%s", start, end, len(r.text), r.text[start:end])
+	if end < len(r.text) {
+		return chunk + fmt.Sprintf("\n... (%d characters remain; call read_more again for the next part)", len(r.text)-end), nil
+	}
+	return chunk + "\n[End of result]", nil
+}
+
+// readMoreResult answers a read_more call.
+func (s *ResultStore) readMoreResult(toolCall llm.ToolUseContent) llm.ToolResultContent {
+	id, _ := toolCall.Input["tool_call_id"].(string)
+	offset := -1
+	if n, ok := toolCall.Input["offset"].(float64); ok && n >= 0 {
+		offset = int(n)
+	}
+	result := llm.ToolResultContent{Type: "tool_result", ToolUseID: toolCall.ID}
+	chunk, err := s.ReadMore(id, offset)
+	if err != nil {
+		result.Content = fmt.Sprintf("Error: %v", err)
+		result.IsError = true
+		return result
+	}
+	result.Content = chunk
+	return result
+}
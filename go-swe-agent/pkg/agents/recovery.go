@@ -0,0 +1,69 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+// missingRequiredFields returns the required schema fields absent from a
+// tool call's input. A non-empty result usually means the call was cut off
+// by max_tokens and its input is incomplete.
+func missingRequiredFields(availableTools []llm.Tool, toolCall llm.ToolUseContent) []string {
+	var schema map[string]interface{}
+	for _, tool := range availableTools {
+		if tool.Name == toolCall.Name {
+			schema = tool.InputSchema
+			break
+		}
+	}
+	if schema == nil {
+		return nil
+	}
+
+	var required []string
+	switch r := schema["required"].(type) {
+	case []string:
+		required = r
+	case []interface{}:
+		for _, field := range r {
+			if name, ok := field.(string); ok {
+				required = append(required, name)
+			}
+		}
+	}
+
+	var missing []string
+	for _, field := range required {
+		if _, ok := toolCall.Input[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// incompleteToolCallResult checks a tool call for truncated input and, if
+// it is incomplete, returns a tool_result asking the model to re-issue it.
+// The call must not be executed when ok is false.
+func incompleteToolCallResult(out *ui.Printer, availableTools []llm.Tool, toolCall llm.ToolUseContent, stopReason string) (result llm.ToolResultContent, ok bool) {
+	missing := missingRequiredFields(availableTools, toolCall)
+	if len(missing) == 0 {
+		return llm.ToolResultContent{}, true
+	}
+
+	reason := "is missing required input"
+	if stopReason == "max_tokens" {
+		reason = "was cut off because the response hit the output token limit"
+	}
+	out.Yellow("  ⚠️  Incomplete %s call (missing %s); asking the model to re-issue it\n", toolCall.Name, strings.Join(missing, ", "))
+
+	return llm.ToolResultContent{
+		Type:      "tool_result",
+		ToolUseID: toolCall.ID,
+		Content: fmt.Sprintf("Error: this %s call %s and was not executed (missing: %s). Re-issue the call with complete arguments; if the content is large, split it into several smaller calls.",
+			toolCall.Name, reason, strings.Join(missing, ", ")),
+		IsError: true,
+	}, false
+}
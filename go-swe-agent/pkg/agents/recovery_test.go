@@ -0,0 +1,66 @@
+package agents
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+func TestIncompleteToolCallResult(t *testing.T) {
+	// Schemas built in Go list required fields as []string; those decoded
+	// from JSON, e.g. of MCP tools, as []interface{}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"type":"object","required":["path","content"]}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	available := []llm.Tool{
+		{Name: "write_file", InputSchema: map[string]interface{}{"type": "object", "required": []string{"path", "content"}}},
+		{Name: "mcp_write", InputSchema: decoded},
+		{Name: "list_files", InputSchema: map[string]interface{}{"type": "object"}},
+	}
+
+	tests := []struct {
+		name       string
+		tool       string
+		input      map[string]interface{}
+		stopReason string
+		want       string // text the result must contain, or "" when the call is complete
+	}{
+		{"complete", "write_file", map[string]interface{}{"path": "a.go", "content": "package a"}, "tool_use", ""},
+		{"empty content is given", "write_file", map[string]interface{}{"path": "a.go", "content": ""}, "tool_use", ""},
+		{"no required fields", "list_files", map[string]interface{}{}, "max_tokens", ""},
+		{"unknown tool", "no_such_tool", map[string]interface{}{}, "max_tokens", ""},
+		{"cut off", "write_file", map[string]interface{}{"path": "a.go"}, "max_tokens", "write_file call was cut off because the response hit the output token limit and was not executed (missing: content)"},
+		{"missing without truncation", "write_file", map[string]interface{}{}, "tool_use", "write_file call is missing required input and was not executed (missing: path, content)"},
+		{"decoded schema", "mcp_write", map[string]interface{}{"content": "x"}, "max_tokens", "(missing: path)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			call := llm.ToolUseContent{Type: "tool_use", ID: "call-1", Name: tt.tool, Input: tt.input}
+			result, ok := incompleteToolCallResult(ui.NewPrinter(&out), available, call, tt.stopReason)
+			if tt.want == "" {
+				if !ok || out.Len() != 0 {
+					t.Errorf("ok = %v, output %q; want the call run without a warning", ok, out.String())
+				}
+				return
+			}
+			if ok {
+				t.Fatal("ok = true, want the incomplete call refused")
+			}
+			if result.Type != "tool_result" || result.ToolUseID != "call-1" || !result.IsError {
+				t.Errorf("result = %+v, want an error tool_result for call-1", result)
+			}
+			if !strings.Contains(result.Content, tt.want) {
+				t.Errorf("content = %q, want it to contain %q", result.Content, tt.want)
+			}
+			if !strings.Contains(out.String(), "Incomplete "+tt.tool+" call") {
+				t.Errorf("output %q does not warn about the incomplete call", out.String())
+			}
+		})
+	}
+}
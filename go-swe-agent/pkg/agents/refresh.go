@@ -0,0 +1,76 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// RefreshTasks revises the pending tasks of the plan in light of the tasks
+// that have run since it was made: tasks already taken care of are
+// dropped, and tasks that no longer fit are rewritten. Finished tasks are
+// kept as they are, and pending tasks whose description does not change
+// keep their ID and approval.
+func (p *Planner) RefreshTasks(agentState *state.AgentState) error {
+	plan := agentState.Plan
+	var finished, pending []state.Task
+	for _, task := range plan.Tasks {
+		if task.Status == "pending" {
+			pending = append(pending, task)
+		} else {
+			finished = append(finished, task)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "REQUEST: %s\n\nThese tasks of the plan have run:\n", agentState.OriginalRequest)
+	for _, task := range finished {
+		fmt.Fprintf(&b, "- [%s] %s\n", task.Status, task.Description)
+		if summary := digest(task.Output, DefaultDigestLength); summary != "" {
+			fmt.Fprintf(&b, "  Result: %s\n", summary)
+		}
+	}
+	b.WriteString("\nThese tasks have not run yet:\n")
+	for _, task := range pending {
+		fmt.Fprintf(&b, "- %s\n", task.Description)
+	}
+	b.WriteString("\nRevise the tasks that have not run yet in light of what the others did. Keep the description of a task that still applies word for word, drop tasks that are already taken care of, and rewrite or add tasks only where the results call for it. Call submit_plan with the remaining tasks only, or with no_changes_needed and a summary if nothing is left to do.")
+
+	messages := []llm.AnthropicMessage{{
+		Role:    "user",
+		Content: []interface{}{llm.TextContent{Type: "text", Text: b.String()}},
+	}}
+	start := time.Now()
+	structured := llm.CallOptions{ToolChoice: llm.ForceTool(submitPlanToolName), Strict: true, MaxTokens: p.MaxTokens}
+	response, err := p.client.CreateMessageWith(messages, p.buildPlannerSystemPrompt(), []llm.Tool{submitPlanTool()}, structured)
+	if err != nil {
+		return fmt.Errorf("failed to refresh the plan: %w", err)
+	}
+	agentState.RecordUsage(state.PhasePlanning, response.Usage.InputTokens, response.Usage.OutputTokens, time.Since(start))
+
+	_, toolCalls, _ := p.client.ParseContent(response.Content)
+	for _, toolCall := range toolCalls {
+		if toolCall.Name != submitPlanToolName {
+			continue
+		}
+		submitted, err := parsePlanSubmission(toolCall.Input)
+		if err != nil {
+			return fmt.Errorf("failed to refresh the plan: %w", err)
+		}
+		// IDs are assigned again next to the finished tasks
+		for i := range submitted.Tasks {
+			submitted.Tasks[i].ID = ""
+		}
+		revised := *plan
+		revised.Tasks = append(finished, submitted.Tasks...)
+		agentState.ReplacePlan(&revised)
+		return nil
+	}
+	return fmt.Errorf("failed to refresh the plan: no plan was submitted")
+}
@@ -0,0 +1,314 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// Phase transitions are driven by these control tools rather than by
+// matching phrases in the model's text. They are handled by the agents
+// themselves and never reach the ToolExecutor.
+const (
+	submitPlanToolName     = "submit_plan"
+	completeTaskToolName   = "complete_task"
+	reportCriteriaToolName = "report_criteria"
+	askUserToolName        = "ask_user"
+	blockTaskToolName      = "block_task"
+	nextStepToolName       = "next_step"
+)
+
+func submitPlanTool() llm.Tool {
+	return llm.Tool{
+		Name:        submitPlanToolName,
+		Description: "Submit the final step-by-step plan once exploration is done. Call this exactly once. If the codebase already satisfies the request, set no_changes_needed with an empty task list and explain why in the summary.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"summary": map[string]interface{}{
+					"type":        "string",
+					"description": "A short summary of the overall approach, or why no changes are needed",
+				},
+				"findings": map[string]interface{}{
+					"type":        "string",
+					"description": "What exploration revealed that the tasks will need, as short bullet points: key files and what they contain, conventions, where things live, build and test commands. Every task receives this, so it saves re-reading files.",
+				},
+				"repro_command": map[string]interface{}{
+					"type":        "string",
+					"description": "Reproduce-first runs only: a shell command that runs just the test reproducing the bug. It must fail before the fix and pass after it.",
+				},
+				"no_changes_needed": map[string]interface{}{
+					"type":        "boolean",
+					"description": "True when the codebase already satisfies the request and nothing should be changed",
+				},
+				"tasks": map[string]interface{}{
+					"type":        "array",
+					"description": "The ordered list of concrete, actionable tasks",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"description": map[string]interface{}{
+								"type":        "string",
+								"description": "What this task should accomplish",
+							},
+							"creates": map[string]interface{}{
+								"type":        "array",
+								"items":       map[string]interface{}{"type": "string"},
+								"description": "New files this task creates, as paths relative to the repository root. Leave out files that already exist.",
+							},
+							"depends_on": map[string]interface{}{
+								"type":        "array",
+								"items":       map[string]interface{}{"type": "integer"},
+								"description": "Positions in this list (1 for the first task) of earlier tasks that must be finished before this one can start. Leave it empty for tasks that only need the codebase as it is.",
+							},
+						},
+						"required": []string{"description"},
+					},
+				},
+			},
+			"required": []string{"tasks"},
+		},
+	}
+}
+
+func completeTaskTool() llm.Tool {
+	return llm.Tool{
+		Name:        completeTaskToolName,
+		Description: "Signal that the current task is fully implemented and verified. This is the only way to finish a task. If only part of it could be done, set partial and describe what is left in remaining rather than claiming it is complete.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"summary": map[string]interface{}{
+					"type":        "string",
+					"description": "A brief summary of what was changed",
+				},
+				"partial": map[string]interface{}{
+					"type":        "boolean",
+					"description": "True when only part of the task was done",
+				},
+				"remaining": map[string]interface{}{
+					"type":        "string",
+					"description": "With partial: the work still left, specific enough to be picked up by someone else (e.g. \"update handlers/user.go the same way as handlers/order.go\")",
+				},
+			},
+		},
+	}
+}
+
+func askUserTool() llm.Tool {
+	return llm.Tool{
+		Name:        askUserToolName,
+		Description: "Ask the human a question when you cannot proceed without their decision or information (an ambiguous requirement, a missing credential, a risky choice). In unattended runs this pauses the run until someone answers, so only use it when guessing would be wrong.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"question": map[string]interface{}{
+					"type":        "string",
+					"description": "A specific question the human can answer directly",
+				},
+			},
+			"required": []string{"question"},
+		},
+	}
+}
+
+func blockTaskTool() llm.Tool {
+	return llm.Tool{
+		Name:        blockTaskToolName,
+		Description: "Set the current task aside as blocked when it genuinely cannot be done without human input (missing credentials, an ambiguous requirement, a design decision). The run continues with the other tasks and the question is shown to the human at the end. Do not use it for problems you can solve yourself, and do not guess instead of using it.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"question": map[string]interface{}{
+					"type":        "string",
+					"description": "A specific question whose answer would unblock the task",
+				},
+				"reason": map[string]interface{}{
+					"type":        "string",
+					"description": "What is missing, e.g. \"missing credentials\" or \"design decision\"",
+				},
+			},
+			"required": []string{"question"},
+		},
+	}
+}
+
+func nextStepTool() llm.Tool {
+	return llm.Tool{
+		Name:        nextStepToolName,
+		Description: "Choose the next step to execute, or declare the request done. Call this once per turn; you will see the step's outcome before choosing again.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"description": map[string]interface{}{
+					"type":        "string",
+					"description": "What the step should accomplish, complete enough to be carried out on its own",
+				},
+				"done": map[string]interface{}{
+					"type":        "boolean",
+					"description": "True when the request is complete and no further step is needed",
+				},
+				"summary": map[string]interface{}{
+					"type":        "string",
+					"description": "When done: a short summary of what was accomplished",
+				},
+			},
+		},
+	}
+}
+
+func reportCriteriaTool() llm.Tool {
+	return llm.Tool{
+		Name:        reportCriteriaToolName,
+		Description: "Report a verdict for every acceptance criterion once you have checked them all.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"results": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"number": map[string]interface{}{
+								"type":        "integer",
+								"description": "The criterion number as listed in the request",
+							},
+							"met": map[string]interface{}{
+								"type":        "boolean",
+								"description": "Whether the criterion is met",
+							},
+							"evidence": map[string]interface{}{
+								"type":        "string",
+								"description": "The concrete evidence behind the verdict",
+							},
+						},
+						"required": []string{"number", "met", "evidence"},
+					},
+				},
+			},
+			"required": []string{"results"},
+		},
+	}
+}
+
+// parsePlanSubmission builds a plan from the input of a submit_plan call.
+func parsePlanSubmission(input map[string]interface{}) (*state.Plan, error) {
+	rawTasks, ok := input["tasks"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("submit_plan requires a 'tasks' array")
+	}
+
+	var tasks []state.Task
+	var deps [][]int
+	// Positions of the submitted tasks among those kept, as tasks without
+	// a description are dropped
+	kept := make(map[int]int)
+	for n, raw := range rawTasks {
+		var desc string
+		var creates []string
+		var after []int
+		switch t := raw.(type) {
+		case map[string]interface{}:
+			desc, _ = t["description"].(string)
+			creates = plannedFiles(t["creates"])
+			after = plannedDependencies(t["depends_on"])
+		case string:
+			desc = t
+		}
+		desc, annotated := cutDependencies(strings.TrimSpace(desc))
+		if desc == "" {
+			continue
+		}
+		tasks = append(tasks, state.Task{
+			Description: desc,
+			Status:      "pending",
+			Creates:     creates,
+		})
+		deps = append(deps, append(after, annotated...))
+		kept[n+1] = len(tasks)
+	}
+	for i := range deps {
+		for j, n := range deps[i] {
+			deps[i][j] = kept[n]
+		}
+	}
+	state.AssignTaskIDs(tasks)
+	linkDependencies(tasks, deps)
+	summary, _ := input["summary"].(string)
+	findings, _ := input["findings"].(string)
+	findings = truncateLines(strings.TrimSpace(findings), MaxFindingsLength)
+
+	if noChanges, _ := input["no_changes_needed"].(bool); noChanges {
+		if len(tasks) > 0 {
+			return nil, fmt.Errorf("submit_plan received tasks together with no_changes_needed; send an empty task list or drop no_changes_needed")
+		}
+		if strings.TrimSpace(summary) == "" {
+			return nil, fmt.Errorf("submit_plan requires a summary explaining why no changes are needed")
+		}
+		return &state.Plan{
+			Tasks:           []state.Task{},
+			Summary:         summary,
+			CreatedAt:       time.Now(),
+			IsApproved:      true,
+			NoChangesNeeded: true,
+		}, nil
+	}
+
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("submit_plan received no tasks with a description; set no_changes_needed if the request is already satisfied")
+	}
+
+	if summary == "" {
+		summary = fmt.Sprintf("Plan with %d tasks", len(tasks))
+	}
+
+	plan := &state.Plan{
+		Tasks:      tasks,
+		Summary:    summary,
+		Findings:   findings,
+		CreatedAt:  time.Now(),
+		IsApproved: true, // Auto-approve for simplicity
+	}
+	if command, _ := input["repro_command"].(string); strings.TrimSpace(command) != "" {
+		plan.Reproduction = &state.Reproduction{Command: strings.TrimSpace(command)}
+	}
+	return plan, nil
+}
+
+// plannedFiles reads a task's list of files to create, dropping blanks.
+func plannedFiles(raw interface{}) []string {
+	items, _ := raw.([]interface{})
+	var files []string
+	for _, item := range items {
+		if file, _ := item.(string); strings.TrimSpace(file) != "" {
+			files = append(files, strings.TrimSpace(file))
+		}
+	}
+	return files
+}
+
+// parseCriteriaReport converts a report_criteria call into verdicts keyed by
+// criterion number.
+func parseCriteriaReport(input map[string]interface{}) map[int]state.CriterionResult {
+	verdicts := make(map[int]state.CriterionResult)
+
+	rawResults, _ := input["results"].([]interface{})
+	for _, raw := range rawResults {
+		r, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		num, ok := r["number"].(float64)
+		if !ok {
+			continue
+		}
+		met, _ := r["met"].(bool)
+		evidence, _ := r["evidence"].(string)
+		verdicts[int(num)] = state.CriterionResult{Met: met, Evidence: evidence}
+	}
+
+	return verdicts
+}
@@ -0,0 +1,47 @@
+package agents
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParsePlanSubmissionNoChangesNeeded(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string // part of the error, or "" for a plan
+	}{
+		{"no changes", `{"no_changes_needed":true,"summary":"--dry-run already exists in cmd/main.go","tasks":[]}`, ""},
+		{"no changes with tasks", `{"no_changes_needed":true,"summary":"done","tasks":["Add the flag"]}`, "together with no_changes_needed"},
+		{"no changes without summary", `{"no_changes_needed":true,"summary":"  ","tasks":[]}`, "requires a summary"},
+		{"no tasks", `{"summary":"nothing","tasks":[]}`, "set no_changes_needed"},
+		{"blank tasks", `{"summary":"nothing","tasks":[" ",{"description":""}]}`, "set no_changes_needed"},
+		{"tasks", `{"no_changes_needed":false,"tasks":["Add the flag"]}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.input), &input); err != nil {
+				t.Fatal(err)
+			}
+			plan, err := parsePlanSubmission(input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePlanSubmission: %v", err)
+			}
+			noChanges := input["no_changes_needed"] == true
+			if plan.NoChangesNeeded != noChanges || (len(plan.Tasks) == 0) != noChanges {
+				t.Errorf("plan = %+v, want no_changes_needed %v", plan, noChanges)
+			}
+			if noChanges && plan.Summary != input["summary"] {
+				t.Errorf("summary = %q, want the planner's evidence", plan.Summary)
+			}
+		})
+	}
+}
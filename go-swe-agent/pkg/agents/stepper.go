@@ -0,0 +1,310 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/tools"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+// DefaultMaxSteps caps the number of steps in an interleaved run.
+const DefaultMaxSteps = 15
+
+// stepOutcomeLength caps how much of a step's output the stepper sees.
+const stepOutcomeLength = 1500
+
+// Stepper drives an interleaved run. Instead of committing to a full plan
+// upfront, it chooses one step, lets the executor carry it out, looks at the
+// outcome and chooses the next, until the request is done. The conversation
+// carries over from step to step.
+type Stepper struct {
+	client       llm.Client
+	toolExecutor *tools.ToolExecutor
+
+	// Out receives progress output; nil writes to standard output
+	Out *ui.Printer
+
+	// MaxIterations caps the exploration turns before each step is chosen
+	MaxIterations int
+
+	// MaxTokens caps the output of each model call. Zero uses the
+	// client's default.
+	MaxTokens int
+
+	// MaxHistoryTurns caps the assistant turns sent back to the model;
+	// older turns are replaced by a short note. Zero keeps them all.
+	MaxHistoryTurns int
+
+	// Images are attached to the initial request, e.g. screenshots of a UI bug
+	Images []llm.ImageContent
+
+	// Trace, when set, records the model's text and chosen tools for
+	// every turn
+	Trace *Trace
+
+	messages []llm.AnthropicMessage
+	carry    []interface{}     // results owed to the model's last response
+	stepCall string            // ID of the next_step call awaiting its outcome
+	reported map[string]string // task ID -> status the model last saw
+}
+
+func NewStepper(client llm.Client, toolExecutor *tools.ToolExecutor) *Stepper {
+	return &Stepper{
+		client:        client,
+		toolExecutor:  toolExecutor,
+		MaxIterations: DefaultPlannerIterations,
+		MaxTokens:     DefaultPlannerMaxTokens,
+		reported:      make(map[string]string),
+	}
+}
+
+// NextStep shows the model how the steps run since the last call went and
+// asks for the next one. It returns nil once the model declares the request
+// done, after setting the plan summary from its answer.
+func (s *Stepper) NextStep(agentState *state.AgentState) (*state.Task, error) {
+	if s.messages == nil {
+		s.messages = s.buildInitialMessages(agentState)
+	} else {
+		s.messages = append(s.messages, llm.AnthropicMessage{
+			Role:    "user",
+			Content: s.observe(agentState),
+		})
+	}
+
+	systemPrompt := s.buildStepperSystemPrompt()
+	availableTools := s.getStepperTools()
+
+	for i := 0; i <= s.MaxIterations; i++ {
+		// Offer only next_step on the final turn so the model has to choose
+		turnTools := availableTools
+		if i == s.MaxIterations {
+			turnTools = []llm.Tool{nextStepTool()}
+		}
+
+		start := time.Now()
+		response, err := s.client.CreateMessageWith(trimHistory(s.messages, s.MaxHistoryTurns), systemPrompt, turnTools, llm.CallOptions{MaxTokens: s.MaxTokens})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get LLM response: %w", err)
+		}
+		agentState.RecordUsage(state.PhasePlanning, response.Usage.InputTokens, response.Usage.OutputTokens, time.Since(start))
+
+		text, toolCalls, _ := s.client.ParseContent(response.Content)
+		s.Trace.Record(state.PhasePlanning, "", i+1, text, toolCalls, response.StopReason)
+		s.messages = append(s.messages, llm.AnthropicMessage{
+			Role:    "assistant",
+			Content: response.Content,
+		})
+
+		if len(toolCalls) == 0 {
+			s.messages = append(s.messages, llm.AnthropicMessage{
+				Role: "user",
+				Content: []interface{}{
+					llm.TextContent{
+						Type: "text",
+						Text: "Call next_step with the next step, or with done set to true if the request is complete.",
+					},
+				},
+			})
+			continue
+		}
+
+		var results []interface{}
+		var chosen *llm.ToolUseContent
+		for idx, toolCall := range toolCalls {
+			if result, ok := incompleteToolCallResult(s.Out, turnTools, toolCall, response.StopReason); !ok {
+				results = append(results, result)
+				continue
+			}
+			if toolCall.Name == nextStepToolName && chosen == nil {
+				if err := validateStep(toolCall.Input); err != nil {
+					results = append(results, llm.ToolResultContent{
+						Type:      "tool_result",
+						ToolUseID: toolCall.ID,
+						Content:   fmt.Sprintf("Error: %v", err),
+						IsError:   true,
+					})
+					continue
+				}
+				chosen = &toolCalls[idx]
+				continue
+			}
+			if toolCall.Name == nextStepToolName {
+				results = append(results, llm.ToolResultContent{
+					Type:      "tool_result",
+					ToolUseID: toolCall.ID,
+					Content:   "Error: only one step can be chosen at a time; this call was ignored",
+					IsError:   true,
+				})
+				continue
+			}
+
+			s.Out.Printf("  📂 Exploring: %s\n", toolCall.Name)
+			output, err := s.toolExecutor.Execute(toolCall.Name, toolCall.Input)
+			if err != nil {
+				output = fmt.Sprintf("Error: %v", err)
+			}
+			if len(output) > 5000 {
+				output = output[:5000] + "\n... (truncated)"
+			}
+			results = append(results, llm.ToolResultContent{
+				Type:      "tool_result",
+				ToolUseID: toolCall.ID,
+				Content:   output,
+			})
+		}
+
+		if chosen != nil {
+			// The outcome of the step answers the call on the next turn
+			s.carry = results
+			s.stepCall = chosen.ID
+			return s.chooseStep(agentState, chosen.Input), nil
+		}
+		s.messages = append(s.messages, llm.AnthropicMessage{
+			Role:    "user",
+			Content: results,
+		})
+	}
+
+	return nil, fmt.Errorf("no next step chosen after %d turns", s.MaxIterations+1)
+}
+
+// chooseStep turns a next_step call into a task, or records the summary
+// and returns nil when the model says the request is done.
+func (s *Stepper) chooseStep(agentState *state.AgentState, input map[string]interface{}) *state.Task {
+	if done, _ := input["done"].(bool); done {
+		if summary, _ := input["summary"].(string); strings.TrimSpace(summary) != "" {
+			agentState.Plan.Summary = strings.TrimSpace(summary)
+		}
+		return nil
+	}
+	description, _ := input["description"].(string)
+	return &state.Task{
+		Description: strings.TrimSpace(description),
+		Status:      "pending",
+	}
+}
+
+func validateStep(input map[string]interface{}) error {
+	if done, _ := input["done"].(bool); done {
+		return nil
+	}
+	if description, _ := input["description"].(string); strings.TrimSpace(description) == "" {
+		return fmt.Errorf("next_step requires a description, or done set to true")
+	}
+	return nil
+}
+
+// observe reports the outcome of every step that finished since the model
+// last looked, answering its pending next_step call.
+func (s *Stepper) observe(agentState *state.AgentState) []interface{} {
+	text := s.outcomes(agentState)
+	if text == "" {
+		text = "No step has run since your last choice."
+	}
+	text += "\n\nChoose the next step with next_step. If the outcome shows the approach was wrong, correct course. Set done to true once the request is complete."
+
+	content := s.carry
+	s.carry = nil
+	if s.stepCall == "" {
+		return append(content, llm.TextContent{Type: "text", Text: text})
+	}
+	content = append(content, llm.ToolResultContent{
+		Type:      "tool_result",
+		ToolUseID: s.stepCall,
+		Content:   text,
+	})
+	s.stepCall = ""
+	return content
+}
+
+// outcomes describes the finished steps the model has not seen yet, or has
+// seen with a different status, e.g. blocked before a human answered.
+func (s *Stepper) outcomes(agentState *state.AgentState) string {
+	var b strings.Builder
+	for i, task := range agentState.Plan.Tasks {
+		if s.reported[task.ID] == task.Status || task.Status == "pending" || task.Status == "in_progress" {
+			continue
+		}
+		s.reported[task.ID] = task.Status
+		fmt.Fprintf(&b, "Step %d (%s): %s\n", i+1, task.Status, task.Description)
+		switch {
+		case task.Status == "partial":
+			fmt.Fprintf(&b, "  Result: %s\n  Remaining: %s\n", digest(task.Output, stepOutcomeLength), task.Remaining)
+		case task.Status == "completed" && task.Output != "":
+			fmt.Fprintf(&b, "  Result: %s\n", digest(task.Output, stepOutcomeLength))
+		case task.Error != "":
+			fmt.Fprintf(&b, "  Error: %s\n", digest(task.Error, stepOutcomeLength))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (s *Stepper) buildInitialMessages(agentState *state.AgentState) []llm.AnthropicMessage {
+	var reference string
+	if agentState.ReferenceContext != "" {
+		reference = fmt.Sprintf("\nThe request refers to the following existing change. Use it as context:\n\n%s\n", agentState.ReferenceContext)
+	}
+
+	var content []interface{}
+	for _, image := range s.Images {
+		content = append(content, image)
+	}
+
+	var images string
+	if len(s.Images) > 0 {
+		images = fmt.Sprintf("\nThe %d attached image(s) show what the request refers to.\n", len(s.Images))
+	}
+	var submodules string
+	if note := s.toolExecutor.SubmoduleNote(); note != "" {
+		submodules = "\n" + note + "\n"
+	}
+
+	// Steps already taken, e.g. before a resume
+	var progress string
+	if done := s.outcomes(agentState); done != "" {
+		progress = fmt.Sprintf("\nSteps taken so far:\n%s\n", done)
+	}
+
+	return []llm.AnthropicMessage{
+		{
+			Role: "user",
+			Content: append(content,
+				llm.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf(`Work on the following request one step at a time:
+
+REQUEST: %s
+%s%s%s%s
+Explore as much as you need to choose a good first step, then call next_step.`, agentState.OriginalRequest, images, reference, submodules, progress),
+				},
+			),
+		},
+	}
+}
+
+func (s *Stepper) buildStepperSystemPrompt() string {
+	return `You are an expert software engineer completing a request one step at a time.
+
+Instead of planning everything upfront, you choose a single step, another agent with full tool access carries it out, and you see the outcome before choosing the next step. Use this to adapt: when a step reveals that the approach is wrong, choose a step that corrects course.
+
+Use the available tools to explore the codebase before choosing a step, but leave the changes to the steps.
+
+Call next_step with one concrete, actionable step. Each step is executed without your conversation, so describe it completely: which files, what to change and how to check it.
+When the request is fully done, call next_step with done set to true and a summary of what was accomplished.`
+}
+
+func (s *Stepper) getStepperTools() []llm.Tool {
+	llmTools := []llm.Tool{nextStepTool()}
+	for _, toolDef := range tools.GetAvailableTools() {
+		llmTools = append(llmTools, llm.Tool{
+			Name:        toolDef["name"].(string),
+			Description: toolDef["description"].(string),
+			InputSchema: toolDef["input_schema"].(map[string]interface{}),
+		})
+	}
+	return llmTools
+}
@@ -0,0 +1,12 @@
+package agents
+
+import "regexp"
+
+// testTaskWords mark a task that may add or change tests.
+var testTaskWords = regexp.MustCompile(`(?i)\b(tests?|testing|specs?|test cases?|coverage|fixtures?)\b`)
+
+// writesTests reports whether a task description is about writing tests,
+// so the project's test conventions are worth showing to it.
+func writesTests(description string) bool {
+	return testTaskWords.MatchString(description)
+}
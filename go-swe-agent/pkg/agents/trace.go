@@ -0,0 +1,126 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/tools"
+)
+
+const (
+	// traceTextLimit caps the model's text kept per turn
+	traceTextLimit = 2000
+
+	// traceArgLimit caps each string argument, so file contents written by
+	// a tool do not drown out the decisions
+	traceArgLimit = 300
+)
+
+// Trace records why the agent did what it did: for every model turn, the
+// text the model wrote and the tools it chose with their arguments, as one
+// JSONL line keyed by phase, task and iteration. It is much smaller than
+// the tool log, as tool results are left out and long values are cut.
+// Secrets are redacted before anything is written. A nil Trace records
+// nothing.
+type Trace struct {
+	mu    sync.Mutex
+	runID string
+	file  *os.File
+}
+
+// TraceEntry is one model turn in the trace.
+type TraceEntry struct {
+	Time       time.Time   `json:"time"`
+	RunID      string      `json:"run_id"`
+	Phase      string      `json:"phase"`
+	TaskID     string      `json:"task_id,omitempty"`
+	Iteration  int         `json:"iteration"` // 1-based turn within the task or phase
+	Text       string      `json:"text,omitempty"`
+	Tools      []TraceCall `json:"tools,omitempty"`
+	StopReason string      `json:"stop_reason,omitempty"`
+}
+
+// TraceCall is a tool the model chose in a turn.
+type TraceCall struct {
+	Name  string                 `json:"name"`
+	Input map[string]interface{} `json:"input,omitempty"`
+}
+
+func NewTrace(path, runID string) (*Trace, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace: %w", err)
+	}
+	return &Trace{runID: runID, file: file}, nil
+}
+
+// Record adds a model turn to the trace.
+func (t *Trace) Record(phase, taskID string, iteration int, text string, calls []llm.ToolUseContent, stopReason string) {
+	if t == nil {
+		return
+	}
+	entry := TraceEntry{
+		Time:       time.Now(),
+		RunID:      t.runID,
+		Phase:      phase,
+		TaskID:     taskID,
+		Iteration:  iteration,
+		Text:       truncateTrace(tools.RedactSecrets(text), traceTextLimit),
+		StopReason: stopReason,
+	}
+	for _, call := range calls {
+		entry.Tools = append(entry.Tools, TraceCall{Name: call.Name, Input: traceInput(call.Input)})
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return
+	}
+	t.file.Write(append(data, '\n'))
+}
+
+func (t *Trace) Close() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file = nil
+	return err
+}
+
+// traceInput redacts and shortens the string arguments of a tool call.
+func traceInput(input map[string]interface{}) map[string]interface{} {
+	if len(input) == 0 {
+		return nil
+	}
+	compact := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		if str, ok := v.(string); ok {
+			v = truncateTrace(tools.RedactSecrets(str), traceArgLimit)
+		}
+		compact[k] = v
+	}
+	return compact
+}
+
+func truncateTrace(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return fmt.Sprintf("%s… (%d more characters)", string(runes[:limit]), len(runes)-limit)
+}
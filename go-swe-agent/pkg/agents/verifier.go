@@ -0,0 +1,200 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/tools"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+// Verifier checks the acceptance criteria of a request against the final
+// state of the working directory.
+type Verifier struct {
+	client       llm.Client
+	toolExecutor *tools.ToolExecutor
+
+	// Out receives progress output; nil writes to standard output
+	Out *ui.Printer
+
+	// MaxHistoryTurns caps the assistant turns sent back to the model;
+	// older turns are replaced by a short note. Zero keeps them all.
+	MaxHistoryTurns int
+
+	// MaxTokens caps the output of each model call. Zero uses the
+	// client's default.
+	MaxTokens int
+}
+
+func NewVerifier(client llm.Client, toolExecutor *tools.ToolExecutor) *Verifier {
+	return &Verifier{
+		client:       client,
+		toolExecutor: toolExecutor,
+		MaxTokens:    DefaultPlannerMaxTokens,
+	}
+}
+
+// VerifyCriteria asks the model to check each acceptance criterion and
+// stores a met/unmet verdict with evidence in agentState.CriteriaResults.
+// Criteria the model doesn't report on are recorded as unmet.
+func (v *Verifier) VerifyCriteria(agentState *state.AgentState) error {
+	if len(agentState.AcceptanceCriteria) == 0 {
+		return nil
+	}
+
+	v.Out.Println("\n🔎 Verifying acceptance criteria...")
+
+	messages := v.buildVerifierMessages(agentState)
+	systemPrompt := v.buildVerifierSystemPrompt()
+	availableTools := v.getVerifierTools()
+
+	var verdicts map[int]state.CriterionResult
+	for i := 0; i < 10 && verdicts == nil; i++ {
+		// Offer only the report tool on the final turn so the model has to answer
+		turnTools := availableTools
+		if i == 9 {
+			turnTools = []llm.Tool{reportCriteriaTool()}
+		}
+
+		start := time.Now()
+		response, err := v.client.CreateMessageWith(trimHistory(messages, v.MaxHistoryTurns), systemPrompt, turnTools, llm.CallOptions{MaxTokens: v.MaxTokens})
+		if err != nil {
+			return fmt.Errorf("failed to get LLM response: %w", err)
+		}
+		agentState.RecordUsage(state.PhaseVerification, response.Usage.InputTokens, response.Usage.OutputTokens, time.Since(start))
+
+		_, toolCalls, _ := v.client.ParseContent(response.Content)
+
+		messages = append(messages, llm.AnthropicMessage{
+			Role:    "assistant",
+			Content: response.Content,
+		})
+
+		if len(toolCalls) == 0 {
+			messages = append(messages, llm.AnthropicMessage{
+				Role: "user",
+				Content: []interface{}{
+					llm.TextContent{
+						Type: "text",
+						Text: "Continue checking with the available tools, or call report_criteria with a verdict for every criterion.",
+					},
+				},
+			})
+			continue
+		}
+
+		var toolResults []interface{}
+		for _, toolCall := range toolCalls {
+			if result, ok := incompleteToolCallResult(v.Out, turnTools, toolCall, response.StopReason); !ok {
+				toolResults = append(toolResults, result)
+				continue
+			}
+
+			if toolCall.Name == reportCriteriaToolName {
+				verdicts = parseCriteriaReport(toolCall.Input)
+				break
+			}
+
+			v.Out.Printf("  🔍 Checking: %s\n", toolCall.Name)
+			output, err := v.toolExecutor.Execute(toolCall.Name, toolCall.Input)
+			isError := err != nil
+			if err != nil {
+				output = fmt.Sprintf("Error: %v", err)
+			}
+
+			if len(output) > 10000 && toolCall.Name != "read_files" {
+				output = output[:10000] + "\n... (output truncated)"
+			}
+
+			toolResults = append(toolResults, llm.ToolResultContent{
+				Type:      "tool_result",
+				ToolUseID: toolCall.ID,
+				Content:   output,
+				IsError:   isError,
+			})
+		}
+		if verdicts != nil {
+			break
+		}
+
+		messages = append(messages, llm.AnthropicMessage{
+			Role:    "user",
+			Content: toolResults,
+		})
+	}
+
+	results := make([]state.CriterionResult, len(agentState.AcceptanceCriteria))
+	for i, criterion := range agentState.AcceptanceCriteria {
+		result, ok := verdicts[i+1]
+		if !ok {
+			result = state.CriterionResult{Evidence: "No verdict reported by the verifier"}
+		}
+		result.Criterion = criterion
+		results[i] = result
+
+		if result.Met {
+			v.Out.Green("  ✅ %s\n", criterion)
+		} else {
+			v.Out.Red("  ❌ %s\n", criterion)
+		}
+	}
+
+	agentState.CriteriaResults = results
+	return nil
+}
+
+func (v *Verifier) buildVerifierMessages(agentState *state.AgentState) []llm.AnthropicMessage {
+	var criteria strings.Builder
+	for i, criterion := range agentState.AcceptanceCriteria {
+		criteria.WriteString(fmt.Sprintf("%d. %s\n", i+1, criterion))
+	}
+
+	return []llm.AnthropicMessage{
+		{
+			Role: "user",
+			Content: []interface{}{
+				llm.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf(`The following request has just been implemented:
+
+REQUEST: %s
+
+Check whether each of these acceptance criteria is met by the current state of the codebase:
+%s
+Inspect the changes (for example with 'git diff' and 'git status') and run any commands needed to confirm each criterion.`, agentState.OriginalRequest, criteria.String()),
+				},
+			},
+		},
+	}
+}
+
+func (v *Verifier) buildVerifierSystemPrompt() string {
+	return `You are an expert software engineer verifying that a change meets its acceptance criteria.
+
+Do not modify any files. Use the available tools only to inspect the code and run checks.
+
+When you have checked every criterion, call the report_criteria tool with a verdict for each one,
+using the same numbering as the criteria. Only report a criterion as met when you have concrete evidence.`
+}
+
+func (v *Verifier) getVerifierTools() []llm.Tool {
+	toolDefs := tools.GetAvailableTools()
+	llmTools := []llm.Tool{reportCriteriaTool()}
+
+	for _, toolDef := range toolDefs {
+		// The verifier is read-only
+		if toolDef["name"] == "write_file" || toolDef["name"] == "edit_file" {
+			continue
+		}
+		llmTools = append(llmTools, llm.Tool{
+			Name:        toolDef["name"].(string),
+			Description: toolDef["description"].(string),
+			InputSchema: toolDef["input_schema"].(map[string]interface{}),
+		})
+	}
+
+	return llmTools
+}
@@ -0,0 +1,115 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// Defaults for the run-level stall watchdog.
+const (
+	DefaultStallWindow = 40
+	DefaultStallRepeat = 0.5
+)
+
+// maxReportedCalls caps the calls listed in a stall report.
+const maxReportedCalls = 5
+
+// Watchdog notices a run that stopped making progress across tasks, which
+// per-task limits miss when a loop spans tasks or stays under them. The run
+// has stalled when, over the last Window tool calls, no task finished, no
+// file was written by a call that was not a repeat, and at least Repeat of
+// the calls exactly repeated another one.
+type Watchdog struct {
+	Window int
+	Repeat float64
+
+	calls    []string // signatures of the calls since the last progress
+	writes   int
+	finished int
+}
+
+func NewWatchdog(window int, repeat float64) *Watchdog {
+	return &Watchdog{Window: window, Repeat: repeat}
+}
+
+// StalledError is returned by the executor when the watchdog halts the run.
+type StalledError struct {
+	Report *state.StallReport
+}
+
+func (e *StalledError) Error() string {
+	return fmt.Sprintf("run stalled: %d of the last %d tool calls repeated earlier ones without progress", e.Report.Window-e.Report.Distinct, e.Report.Window)
+}
+
+// Observe records a tool call that was just executed and returns a report
+// when the run has stalled.
+func (w *Watchdog) Observe(agentState *state.AgentState, taskID, name string, input map[string]interface{}) *state.StallReport {
+	signature := callSignature(name, input)
+	writes := 0
+	if agentState.Journal != nil {
+		writes = agentState.Journal.Writes()
+	}
+	finished := len(agentState.FinishedTasks())
+
+	repeated := false
+	for _, call := range w.calls {
+		if call == signature {
+			repeated = true
+			break
+		}
+	}
+	if finished > w.finished || (writes > w.writes && !repeated) {
+		w.calls = w.calls[:0]
+	}
+	w.writes, w.finished = writes, finished
+
+	w.calls = append(w.calls, signature)
+	if len(w.calls) > w.Window {
+		w.calls = w.calls[len(w.calls)-w.Window:]
+	}
+	if len(w.calls) < w.Window {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, call := range w.calls {
+		counts[call]++
+	}
+	repeats := len(w.calls) - len(counts)
+	if float64(repeats) < w.Repeat*float64(len(w.calls)) {
+		return nil
+	}
+
+	report := &state.StallReport{
+		Time:     time.Now(),
+		TaskID:   taskID,
+		Window:   len(w.calls),
+		Distinct: len(counts),
+	}
+	for call, count := range counts {
+		if len(call) > 300 {
+			call = call[:300] + "..."
+		}
+		report.Calls = append(report.Calls, state.CallCount{Call: call, Count: count})
+	}
+	sort.Slice(report.Calls, func(i, j int) bool {
+		if report.Calls[i].Count != report.Calls[j].Count {
+			return report.Calls[i].Count > report.Calls[j].Count
+		}
+		return report.Calls[i].Call < report.Calls[j].Call
+	})
+	if len(report.Calls) > maxReportedCalls {
+		report.Calls = report.Calls[:maxReportedCalls]
+	}
+	return report
+}
+
+// callSignature identifies a tool call by its name and exact input.
+func callSignature(name string, input map[string]interface{}) string {
+	encoded, _ := json.Marshal(input)
+	return name + " " + string(encoded)
+}
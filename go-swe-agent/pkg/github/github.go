@@ -0,0 +1,241 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	apiBaseURL = "https://api.github.com"
+
+	// maxDiffChars bounds how much of a diff is injected into the prompt
+	maxDiffChars = 40000
+)
+
+var remotePattern = regexp.MustCompile(`github\.com[:/]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+type Client struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a GitHub API client authenticated with GITHUB_TOKEN
+// when it is set. Public repositories work without a token.
+func NewClient() *Client {
+	return NewClientWithToken(os.Getenv("GITHUB_TOKEN"))
+}
+
+// NewClientWithToken creates a GitHub API client authenticated with token,
+// or unauthenticated when it is empty.
+func NewClientWithToken(token string) *Client {
+	return &Client{
+		token:      token,
+		baseURL:    apiBaseURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type PullRequest struct {
+	Number   int
+	Title    string
+	Body     string
+	Diff     string
+	Comments []Comment
+}
+
+type Commit struct {
+	SHA     string
+	Message string
+	Diff    string
+}
+
+type Comment struct {
+	Author string
+	Path   string
+	Line   int
+	Body   string
+}
+
+// DetectRepo returns "owner/name" for the origin remote of the git
+// repository in dir.
+func DetectRepo(dir string) (string, error) {
+	cmd := exec.Command("git", "remote", "get-url", "origin")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read git remote 'origin': %w", err)
+	}
+
+	m := remotePattern.FindStringSubmatch(strings.TrimSpace(string(out)))
+	if m == nil {
+		return "", fmt.Errorf("origin remote %q is not a GitHub repository", strings.TrimSpace(string(out)))
+	}
+	return m[1] + "/" + m[2], nil
+}
+
+func (c *Client) get(path, accept string) ([]byte, error) {
+	req, err := http.NewRequest("GET", c.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API error (status %d) for %s: %s", resp.StatusCode, path, string(body))
+	}
+	return body, nil
+}
+
+func (c *Client) getJSON(path string, v interface{}) error {
+	body, err := c.get(path, "application/vnd.github+json")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// FetchPullRequest loads a pull request's description, diff, review
+// comments and conversation comments.
+func (c *Client) FetchPullRequest(repo string, number int) (*PullRequest, error) {
+	var pr struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+	if err := c.getJSON(fmt.Sprintf("/repos/%s/pulls/%d", repo, number), &pr); err != nil {
+		return nil, err
+	}
+
+	diff, err := c.get(fmt.Sprintf("/repos/%s/pulls/%d", repo, number), "application/vnd.github.v3.diff")
+	if err != nil {
+		return nil, err
+	}
+
+	var reviewComments []struct {
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Path string `json:"path"`
+		Line int    `json:"line"`
+		Body string `json:"body"`
+	}
+	if err := c.getJSON(fmt.Sprintf("/repos/%s/pulls/%d/comments?per_page=100", repo, number), &reviewComments); err != nil {
+		return nil, err
+	}
+
+	var issueComments []struct {
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Body string `json:"body"`
+	}
+	if err := c.getJSON(fmt.Sprintf("/repos/%s/issues/%d/comments?per_page=100", repo, number), &issueComments); err != nil {
+		return nil, err
+	}
+
+	result := &PullRequest{
+		Number: number,
+		Title:  pr.Title,
+		Body:   pr.Body,
+		Diff:   string(diff),
+	}
+	for _, rc := range reviewComments {
+		result.Comments = append(result.Comments, Comment{Author: rc.User.Login, Path: rc.Path, Line: rc.Line, Body: rc.Body})
+	}
+	for _, ic := range issueComments {
+		result.Comments = append(result.Comments, Comment{Author: ic.User.Login, Body: ic.Body})
+	}
+	return result, nil
+}
+
+// FetchCommit loads a commit's message and diff, preferring the local git
+// history in dir and falling back to the GitHub API.
+func (c *Client) FetchCommit(dir, repo, sha string) (*Commit, error) {
+	cmd := exec.Command("git", "show", "--format=%H%n%B", sha)
+	cmd.Dir = dir
+	if out, err := cmd.Output(); err == nil {
+		header, diff, _ := strings.Cut(string(out), "\ndiff --git")
+		fullSHA, message, _ := strings.Cut(header, "\n")
+		if diff != "" {
+			diff = "diff --git" + diff
+		}
+		return &Commit{SHA: fullSHA, Message: strings.TrimSpace(message), Diff: diff}, nil
+	}
+
+	if repo == "" {
+		return nil, fmt.Errorf("commit %s not found locally and no GitHub repository is known", sha)
+	}
+
+	var commit struct {
+		SHA    string `json:"sha"`
+		Commit struct {
+			Message string `json:"message"`
+		} `json:"commit"`
+	}
+	if err := c.getJSON(fmt.Sprintf("/repos/%s/commits/%s", repo, sha), &commit); err != nil {
+		return nil, err
+	}
+	diff, err := c.get(fmt.Sprintf("/repos/%s/commits/%s", repo, sha), "application/vnd.github.v3.diff")
+	if err != nil {
+		return nil, err
+	}
+	return &Commit{SHA: commit.SHA, Message: commit.Commit.Message, Diff: string(diff)}, nil
+}
+
+// Context renders the pull request as prompt context.
+func (pr *PullRequest) Context() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("PULL REQUEST #%d: %s\n", pr.Number, pr.Title))
+	if pr.Body != "" {
+		b.WriteString("\nDescription:\n" + pr.Body + "\n")
+	}
+	if len(pr.Comments) > 0 {
+		b.WriteString("\nReview comments:\n")
+		for _, c := range pr.Comments {
+			location := ""
+			if c.Path != "" {
+				location = fmt.Sprintf(" on %s:%d", c.Path, c.Line)
+			}
+			b.WriteString(fmt.Sprintf("- @%s%s: %s\n", c.Author, location, c.Body))
+		}
+	}
+	b.WriteString("\nDiff:\n" + truncateDiff(pr.Diff))
+	return b.String()
+}
+
+// Context renders the commit as prompt context.
+func (c *Commit) Context() string {
+	return fmt.Sprintf("COMMIT %s\n\nMessage:\n%s\n\nDiff:\n%s", c.SHA, c.Message, truncateDiff(c.Diff))
+}
+
+func truncateDiff(diff string) string {
+	if len(diff) > maxDiffChars {
+		return diff[:maxDiffChars] + "\n... (diff truncated)"
+	}
+	return diff
+}
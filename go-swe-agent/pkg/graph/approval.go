@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// approveTasks asks, with --approve-plan, which pending tasks to run in
+// the next round of execution. From the second round on, the remaining
+// tasks are first refreshed against what the earlier rounds did. It
+// reports whether any task is approved to run; without --approve-plan
+// every task runs in a single round.
+func (o *Orchestrator) approveTasks(round int) bool {
+	if !o.opts.ApprovePlan || o.input == nil {
+		return round == 1
+	}
+	// Tasks approved before a checkpoint run without asking again
+	if len(o.awaitingApproval(true)) > 0 {
+		return true
+	}
+	if round > 1 && len(o.awaitingApproval(false)) > 0 {
+		o.out.Cyan("\n🔄 Refreshing the remaining tasks against the changes so far\n")
+		o.client.Settings().Tags.Phase = state.PhasePlanning
+		o.client.Settings().Tags.TaskID = ""
+		o.monitor.Update(o.state, state.PhasePlanning, "refreshing the remaining tasks")
+		if err := o.planner.RefreshTasks(o.state); err != nil {
+			o.out.Yellow("⚠️  %v; the remaining tasks are unchanged\n", err)
+		}
+		o.saveCheckpoint()
+	}
+	candidates := o.awaitingApproval(false)
+	if len(candidates) == 0 {
+		return false
+	}
+
+	o.out.Green("\n📋 Plan for approval:\n")
+	o.out.Green("─────────────────\n")
+	for i, task := range o.state.Plan.Tasks {
+		if task.Status == "pending" {
+			o.out.Printf("%d. %s\n", i+1, task.Description)
+		} else {
+			o.out.Printf("%d. [%s] %s\n", i+1, task.Status, task.Description)
+		}
+	}
+	for {
+		answer, err := o.ask(fmt.Sprintf("Run which tasks now? Task numbers such as %d-%d, \"all\", or \"none\" to stop", candidates[0], candidates[len(candidates)-1]))
+		if err != nil {
+			return false
+		}
+		numbers, err := parseApproval(answer, candidates)
+		if err != nil {
+			o.out.Yellow("⚠️  %v\n", err)
+			continue
+		}
+		if len(numbers) == 0 {
+			o.out.Yellow("⏹️  No tasks approved; %d task(s) are left pending\n", len(candidates))
+			return false
+		}
+		for _, n := range numbers {
+			o.state.Plan.Tasks[n-1].Approved = true
+		}
+		o.saveCheckpoint()
+		o.out.Cyan("\n▶️  Running task(s) %s\n", joinNumbers(numbers))
+		return true
+	}
+}
+
+// awaitingApproval returns the numbers of the pending tasks, among those
+// selected to run, that are approved or not.
+func (o *Orchestrator) awaitingApproval(approved bool) []int {
+	var numbers []int
+	for i, task := range o.state.Plan.Tasks {
+		// Tasks interrupted before a resume count as pending
+		if (task.Status != "pending" && task.Status != "in_progress") || task.Approved != approved {
+			continue
+		}
+		if task.FollowUpOf == "" && !o.selection.selected(i+1) {
+			continue
+		}
+		numbers = append(numbers, i+1)
+	}
+	return numbers
+}
+
+// parseApproval reads an answer to the approval question: "all", "none",
+// or task numbers and ranges such as "1-3, 5", which must be among
+// candidates.
+func parseApproval(answer string, candidates []int) ([]int, error) {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return nil, fmt.Errorf("answer with task numbers, \"all\" or \"none\"")
+	case "all", "y", "yes":
+		return candidates, nil
+	case "none", "n", "no", "stop":
+		return nil, nil
+	}
+	waiting := make(map[int]bool, len(candidates))
+	for _, n := range candidates {
+		waiting[n] = true
+	}
+	approved := make(map[int]bool)
+	for _, part := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a task number", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil || to < from {
+				return nil, fmt.Errorf("%q is not a range of task numbers", part)
+			}
+		}
+		for n := from; n <= to; n++ {
+			if !waiting[n] {
+				return nil, fmt.Errorf("task %d is not waiting for approval", n)
+			}
+			approved[n] = true
+		}
+	}
+	var numbers []int
+	for _, n := range candidates {
+		if approved[n] {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers, nil
+}
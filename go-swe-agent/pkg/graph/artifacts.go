@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const manifestFile = "manifest.json"
+
+// Artifact describes one file in a run's artifact directory.
+type Artifact struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"` // relative to the manifest
+	Description string `json:"description"`
+	Bytes       int64  `json:"bytes"`
+}
+
+// Manifest is written as manifest.json next to a run's artifacts.
+type Manifest struct {
+	RunID      string     `json:"run_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt time.Time  `json:"finished_at"`
+	Artifacts  []Artifact `json:"artifacts"`
+}
+
+// artifactDir collects everything a run produces under <base>/<run ID>.
+type artifactDir struct {
+	dir      string
+	manifest Manifest
+}
+
+func newArtifactDir(base, runID string) (*artifactDir, error) {
+	dir := filepath.Join(base, runID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	return &artifactDir{
+		dir:      dir,
+		manifest: Manifest{RunID: runID, CreatedAt: time.Now()},
+	}, nil
+}
+
+// Path returns where the artifact called name lives.
+func (a *artifactDir) Path(name string) string {
+	return filepath.Join(a.dir, name)
+}
+
+// Add records an artifact written to Path(name) by someone else.
+func (a *artifactDir) Add(name, description string) {
+	for _, existing := range a.manifest.Artifacts {
+		if existing.Name == name {
+			return
+		}
+	}
+	a.manifest.Artifacts = append(a.manifest.Artifacts, Artifact{
+		Name:        name,
+		Path:        name,
+		Description: description,
+	})
+}
+
+// WriteFile stores data as the artifact called name.
+func (a *artifactDir) WriteFile(name, description string, data []byte) error {
+	if err := os.WriteFile(a.Path(name), data, 0644); err != nil {
+		return fmt.Errorf("failed to write artifact %s: %w", name, err)
+	}
+	a.Add(name, description)
+	return nil
+}
+
+// WriteJSON stores v, indented, as the artifact called name.
+func (a *artifactDir) WriteJSON(name, description string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal artifact %s: %w", name, err)
+	}
+	return a.WriteFile(name, description, data)
+}
+
+// Close fills in artifact sizes and writes the manifest.
+func (a *artifactDir) Close() error {
+	a.manifest.FinishedAt = time.Now()
+	for i := range a.manifest.Artifacts {
+		if info, err := os.Stat(a.Path(a.manifest.Artifacts[i].Name)); err == nil {
+			a.manifest.Artifacts[i].Bytes = info.Size()
+		}
+	}
+
+	data, err := json.MarshalIndent(a.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(a.Path(manifestFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
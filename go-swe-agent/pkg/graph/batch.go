@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+// What a batch does when one of its requests fails.
+const (
+	BatchStop     = "stop"     // skip the remaining requests (default)
+	BatchContinue = "continue" // run the remaining requests anyway
+)
+
+// BatchRun is the outcome of one request of a batch.
+type BatchRun struct {
+	Request string     `json:"request"`
+	Result  *RunResult `json:"result,omitempty"`
+	Error   string     `json:"error,omitempty"`
+	Skipped bool       `json:"skipped,omitempty"` // not run after an earlier failure
+}
+
+func (r BatchRun) succeeded() bool {
+	return r.Error == "" && r.Result != nil && r.Result.Success()
+}
+
+// BatchResult summarizes a batch run.
+type BatchResult struct {
+	Runs []BatchRun `json:"runs"`
+}
+
+// Success reports whether every request of the batch ran and succeeded.
+func (b *BatchResult) Success() bool {
+	for _, run := range b.Runs {
+		if !run.succeeded() {
+			return false
+		}
+	}
+	return true
+}
+
+// RunBatch runs requests one after another in workingDir, each with opts.
+// Changes accumulate in the working directory, so every request builds on
+// the ones before it, and its planner is told what they were. onFailure
+// is BatchStop or BatchContinue.
+func RunBatch(workingDir string, requests []string, opts Options, onFailure string) *BatchResult {
+	out := ui.NewPrinter(opts.Output)
+	batch := &BatchResult{}
+	failed := false
+	for i, request := range requests {
+		run := BatchRun{Request: request}
+		if failed && onFailure != BatchContinue {
+			run.Skipped = true
+			batch.Runs = append(batch.Runs, run)
+			continue
+		}
+
+		out.Magenta("\n📚 Batch request %d/%d: %s\n", i+1, len(requests), request)
+		runOpts := opts
+		runOpts.BatchContext = batchContext(batch.Runs)
+		var result *RunResult
+		orchestrator, err := NewOrchestrator(workingDir, &state.Request{Description: request}, runOpts)
+		if err == nil {
+			result, err = orchestrator.Run()
+		}
+		run.Result = result
+		if err != nil {
+			run.Error = err.Error()
+			out.Red("\n❌ Agent failed: %v\n", err)
+		}
+		failed = failed || !run.succeeded()
+		batch.Runs = append(batch.Runs, run)
+	}
+	displayBatch(out, batch)
+	return batch
+}
+
+// batchContext tells a batch request which earlier requests changed the
+// working directory and how they ended.
+func batchContext(runs []BatchRun) string {
+	if len(runs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("This request is part of a batch. The working directory already contains the changes of the earlier requests:\n")
+	for i, run := range runs {
+		fmt.Fprintf(&b, "%d. %s (%s)\n", i+1, run.Request, run.outcome())
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// outcome describes how a batch request ended, e.g. "completed, 3 tasks".
+func (r BatchRun) outcome() string {
+	switch {
+	case r.Skipped:
+		return "skipped"
+	case r.Error != "":
+		return "failed: " + r.Error
+	case r.Result == nil:
+		return "no result"
+	}
+	outcome := r.Result.Termination
+	if r.Result.Completed > 0 || r.Result.Failed > 0 {
+		outcome += fmt.Sprintf(", %d task(s) completed, %d failed", r.Result.Completed, r.Result.Failed)
+	}
+	if !r.Result.Success() {
+		outcome += ", not successful"
+	}
+	return outcome
+}
+
+func displayBatch(out *ui.Printer, batch *BatchResult) {
+	out.Blue("\n═══════════════════════════════════════════")
+	out.Blue("       📚 Batch Summary")
+	out.Blue("═══════════════════════════════════════════\n")
+
+	succeeded := 0
+	for i, run := range batch.Runs {
+		switch {
+		case run.Skipped:
+			out.Printf("  ⏭️  %d. %s (skipped)\n", i+1, run.Request)
+		case run.succeeded():
+			succeeded++
+			out.Green("  ✅ %d. %s (%s)\n", i+1, run.Request, run.outcome())
+		default:
+			out.Red("  ❌ %d. %s (%s)\n", i+1, run.Request, run.outcome())
+		}
+	}
+	out.Printf("\n  %d of %d request(s) succeeded\n", succeeded, len(batch.Runs))
+}
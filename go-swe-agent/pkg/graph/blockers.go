@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// unblockTasks asks the human, in interactive runs, the questions of tasks
+// that were set aside as blocked. It reports whether any task got an
+// answer and should run again. An empty answer leaves the task blocked.
+func (o *Orchestrator) unblockTasks() bool {
+	pending := o.state.PendingClarifications()
+	if o.input == nil || len(pending) == 0 {
+		return false
+	}
+
+	o.out.Blue("\n✋ %d blocked task(s) need your input. Answer to unblock, or press Enter to leave blocked:\n", len(pending))
+	unblocked := false
+	for _, i := range pending {
+		c := o.state.Clarifications[i]
+		answer, err := o.ask(o.blockerLine(c))
+		if err != nil || answer == "" {
+			continue
+		}
+		o.state.AnswerClarification(i, answer)
+		unblocked = true
+	}
+	o.saveCheckpoint()
+	return unblocked
+}
+
+// humanInput returns the questions still waiting for an answer.
+func (o *Orchestrator) humanInput() []state.Clarification {
+	var questions []state.Clarification
+	for _, i := range o.state.PendingClarifications() {
+		questions = append(questions, o.state.Clarifications[i])
+	}
+	return questions
+}
+
+// displayHumanInput lists the questions blocked tasks are waiting on.
+func (o *Orchestrator) displayHumanInput(questions []state.Clarification) {
+	if len(questions) == 0 {
+		return
+	}
+	o.out.Yellow("\n🙋 Human input needed:\n")
+	for i, c := range questions {
+		o.out.Printf("  %d. %s\n", i+1, o.blockerLine(c))
+	}
+}
+
+// blockerLine describes a question with the task it blocks and its reason.
+func (o *Orchestrator) blockerLine(c state.Clarification) string {
+	line := c.Question
+	if c.Reason != "" {
+		line = fmt.Sprintf("(%s) %s", c.Reason, line)
+	}
+	for i, task := range o.state.Plan.Tasks {
+		if task.ID == c.TaskID {
+			return fmt.Sprintf("[task %d] %s", i+1, line)
+		}
+	}
+	return line
+}
@@ -0,0 +1,67 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/openswe/go-swe-agent/pkg/agents"
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// Explain answers the request as a question about the codebase, exploring
+// it with read-only tools. Nothing is planned or changed, and no run
+// artifacts, checkpoints or metrics are written.
+func (o *Orchestrator) Explain() (*state.CodebaseExplanation, error) {
+	o.out.Printf("📁 Working directory: %s\n", o.state.WorkingDir)
+	o.out.Printf("❓ Question: %s\n", o.state.OriginalRequest)
+
+	if _, err := o.loadPathRules(); err != nil {
+		return nil, err
+	}
+	if err := llm.ValidateMaxTokens(o.client.Model(), o.opts.PlannerMaxTokens); err != nil {
+		return nil, fmt.Errorf("invalid max tokens: %w", err)
+	}
+
+	explainer := agents.NewCodebaseExplainer(o.client, o.toolExecutor)
+	explainer.Out = o.out
+	explainer.MaxHistoryTurns = o.opts.MaxHistoryTurns
+	if o.opts.ExplainIterations > 0 {
+		explainer.MaxIterations = o.opts.ExplainIterations
+	}
+	if o.opts.PlannerMaxTokens > 0 {
+		explainer.MaxTokens = o.opts.PlannerMaxTokens
+	}
+	o.client.Settings().Tags = llm.RequestTags{Base: o.opts.RequestTag, RunID: o.state.RunID, Phase: state.PhasePlanning}
+
+	explanation, err := explainer.Explain(o.state)
+	if err != nil {
+		return nil, err
+	}
+	usage := o.state.Usage[state.PhasePlanning]
+	o.out.Printf("\n📊 %d model calls, %d input and %d output tokens\n", usage.Calls, usage.InputTokens, usage.OutputTokens)
+	return explanation, nil
+}
+
+// WriteExplanation writes explanation as Markdown, with each section's
+// citations listed as path:line references below it.
+func WriteExplanation(w io.Writer, explanation *state.CodebaseExplanation) {
+	fmt.Fprintf(w, "# %s\n\n%s\n", explanation.Question, explanation.Summary)
+	for _, section := range explanation.Sections {
+		fmt.Fprintf(w, "\n## %s\n\n%s\n", section.Title, section.Body)
+		if len(section.Citations) > 0 {
+			fmt.Fprintln(w)
+		}
+		for _, cite := range section.Citations {
+			ref := fmt.Sprintf("%s:%d", cite.Path, cite.StartLine)
+			if cite.EndLine > cite.StartLine {
+				ref += fmt.Sprintf("-%d", cite.EndLine)
+			}
+			if cite.Note != "" {
+				fmt.Fprintf(w, "- `%s`: %s\n", ref, cite.Note)
+			} else {
+				fmt.Fprintf(w, "- `%s`\n", ref)
+			}
+		}
+	}
+}
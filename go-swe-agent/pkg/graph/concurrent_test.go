@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/openswe/go-swe-agent/internal/llmtest"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// Two runs in one process, each with its own Options, keep their files,
+// output and checkpoints apart. Run with -race.
+func TestConcurrentRuns(t *testing.T) {
+	type run struct {
+		dir, checkpoint, request string
+		out                      bytes.Buffer
+		result                   *RunResult
+	}
+	runs := make([]*run, 2)
+	for i := range runs {
+		base := t.TempDir()
+		runs[i] = &run{
+			dir:        filepath.Join(base, "repo"),
+			checkpoint: filepath.Join(base, "checkpoint.json"),
+			request:    fmt.Sprintf("write run-%d.txt", i),
+		}
+		if err := os.Mkdir(runs[i].dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, r := range runs {
+		wg.Add(1)
+		go func(i int, r *run) {
+			defer wg.Done()
+			name := fmt.Sprintf("run-%d.txt", i)
+			plan := &state.Plan{Tasks: []state.Task{
+				{Description: "Write " + name, Status: "pending"},
+				{Description: "List the files", Status: "pending"},
+			}}
+			state.AssignTaskIDs(plan.Tasks)
+			client := &llmtest.Client{Turns: [][]string{
+				{llmtest.ToolUse("a", "write_file", fmt.Sprintf(`{"path":%q,"content":"run %d"}`, name, i))},
+				{llmtest.ToolUse("b", "complete_task", `{"summary":"written"}`)},
+				{llmtest.ToolUse("c", "list_files", "{}")},
+				{llmtest.ToolUse("d", "complete_task", `{"summary":"listed"}`)},
+			}}
+			request := &state.Request{Description: r.request}
+			o, err := NewOrchestrator(r.dir, request, Options{
+				Client:         client,
+				PlanIn:         &state.PlanFile{Request: request, Plan: plan},
+				AllowUnsafeDir: true,
+				Output:         &r.out,
+				TempDir:        t.TempDir(),
+				CheckpointPath: r.checkpoint,
+				StallWindow:    -1,
+			})
+			if err != nil {
+				t.Errorf("NewOrchestrator: %v", err)
+				return
+			}
+			if r.result, err = o.Run(); err != nil {
+				t.Errorf("run %d: %v\n%s", i, err, r.out.String())
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	for i, r := range runs {
+		if r.result == nil {
+			continue
+		}
+		if r.result.Completed != 2 {
+			t.Errorf("run %d: %d of 2 tasks completed\n%s", i, r.result.Completed, r.out.String())
+		}
+		for j := range runs {
+			_, err := os.Stat(filepath.Join(r.dir, fmt.Sprintf("run-%d.txt", j)))
+			if exists := err == nil; exists != (i == j) {
+				t.Errorf("run %d: run-%d.txt exists = %v", i, j, exists)
+			}
+		}
+
+		saved, err := state.LoadState(r.checkpoint)
+		if err != nil {
+			t.Errorf("run %d: LoadState: %v", i, err)
+			continue
+		}
+		if saved.WorkingDir != r.dir || saved.OriginalRequest != r.request {
+			t.Errorf("run %d: checkpoint of %q in %s, want %q in %s", i, saved.OriginalRequest, saved.WorkingDir, r.request, r.dir)
+		}
+		for _, task := range saved.Plan.Tasks {
+			if task.Status != "completed" {
+				t.Errorf("run %d: checkpointed %s is %s, want completed", i, task.ID, task.Status)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// editHelp lists the commands of the plan editor.
+const editHelp = `Edit the plan:
+  d N        delete task N
+  m N M      move task N to position M
+  a TEXT     add a task at the end
+  a N TEXT   add a task at position N
+  done       finish editing`
+
+// confirmPlan, with ConfirmPlan, asks on the terminal whether to run the
+// displayed plan, edit it first, or reject it. It reports false when the
+// plan was rejected and the run should stop before execution.
+func (o *Orchestrator) confirmPlan() bool {
+	if !o.opts.ConfirmPlan {
+		return true
+	}
+	if o.input == nil {
+		// Only answers read here come from the terminal; the model's
+		// questions still halt the run without --interactive
+		o.input = newInput(o.opts.Input)
+		defer func() { o.input = nil }()
+	}
+
+	for {
+		answer, err := o.ask("Run this plan? [y]es, [e]dit or [n]o")
+		if err != nil {
+			o.out.Yellow("⚠️  No answer; the plan is rejected\n")
+			return o.rejectPlan()
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			if len(o.state.Plan.Tasks) == 0 {
+				o.out.Yellow("⚠️  The plan has no tasks; edit it or reject it\n")
+				continue
+			}
+			o.saveCheckpoint()
+			return true
+		case "n", "no":
+			return o.rejectPlan()
+		case "e", "edit":
+			if len(o.opts.OnlyTasks) > 0 || len(o.opts.SkipTasks) > 0 {
+				o.out.Yellow("⚠️  The plan cannot be edited with --only-tasks or --skip-tasks, which refer to its task numbers\n")
+				continue
+			}
+			o.editPlan()
+		default:
+			o.out.Yellow("⚠️  Answer y, e or n\n")
+		}
+	}
+}
+
+// newInput reads answers from r, or from standard input when r is nil.
+func newInput(r io.Reader) *bufio.Reader {
+	if r == nil {
+		r = os.Stdin
+	}
+	return bufio.NewReader(r)
+}
+
+func (o *Orchestrator) rejectPlan() bool {
+	o.out.Yellow("\n⏹️  Plan rejected: skipping execution\n")
+	o.result.Termination = TerminationRejected
+	return false
+}
+
+// editPlan applies editor commands to the plan until the user is done,
+// then shows the edited plan.
+func (o *Orchestrator) editPlan() {
+	o.out.Println(editHelp)
+	for {
+		command, err := o.ask("Edit command (done to finish)")
+		if err != nil || strings.EqualFold(command, "done") {
+			break
+		}
+		tasks, err := applyPlanEdit(o.state.Plan.Tasks, command, o.reproTaskID())
+		if err != nil {
+			o.out.Yellow("⚠️  %v\n", err)
+			continue
+		}
+		o.state.Plan.Tasks = tasks
+		state.AssignTaskIDs(o.state.Plan.Tasks)
+		for i, task := range o.state.Plan.Tasks {
+			o.out.Printf("%d. %s\n", i+1, task.Description)
+		}
+	}
+	if len(o.state.Plan.Tasks) == 0 {
+		o.out.Yellow("⚠️  The plan has no tasks left; add one or reject it\n")
+	}
+	o.displayPlan()
+}
+
+// reproTaskID is the ID of the task that writes the reproducing test in
+// reproduce-first mode, or "".
+func (o *Orchestrator) reproTaskID() string {
+	if repro := o.state.Plan.Reproduction; repro != nil {
+		return repro.TaskID
+	}
+	return ""
+}
+
+// applyPlanEdit returns tasks with one editor command applied. Task
+// numbers start at 1. The task with the ID protected cannot be deleted.
+func applyPlanEdit(tasks []state.Task, command, protected string) ([]state.Task, error) {
+	op, rest, _ := strings.Cut(strings.TrimSpace(command), " ")
+	rest = strings.TrimSpace(rest)
+	edited := append([]state.Task(nil), tasks...)
+
+	switch strings.ToLower(op) {
+	case "d", "delete":
+		n, err := taskNumber(rest, len(edited))
+		if err != nil {
+			return nil, err
+		}
+		if protected != "" && edited[n-1].ID == protected {
+			return nil, fmt.Errorf("task %d writes the test reproducing the bug and cannot be deleted", n)
+		}
+		return append(edited[:n-1], edited[n:]...), nil
+
+	case "m", "move":
+		from, to, _ := strings.Cut(rest, " ")
+		n, err := taskNumber(from, len(edited))
+		if err != nil {
+			return nil, err
+		}
+		m, err := taskNumber(strings.TrimSpace(to), len(edited))
+		if err != nil {
+			return nil, err
+		}
+		task := edited[n-1]
+		edited = append(edited[:n-1], edited[n:]...)
+		return append(edited[:m-1], append([]state.Task{task}, edited[m-1:]...)...), nil
+
+	case "a", "add":
+		at := len(edited) + 1
+		if first, text, ok := strings.Cut(rest, " "); ok {
+			if n, err := strconv.Atoi(first); err == nil {
+				if n < 1 || n > len(edited)+1 {
+					return nil, fmt.Errorf("position %d is not between 1 and %d", n, len(edited)+1)
+				}
+				at, rest = n, strings.TrimSpace(text)
+			}
+		}
+		if rest == "" {
+			return nil, fmt.Errorf("add needs the task's description")
+		}
+		task := state.Task{Description: rest, Status: "pending"}
+		return append(edited[:at-1], append([]state.Task{task}, edited[at-1:]...)...), nil
+	}
+	return nil, fmt.Errorf("unknown command %q\n%s", command, editHelp)
+}
+
+// taskNumber parses a task number between 1 and count.
+func taskNumber(s string, count int) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a task number", s)
+	}
+	if n < 1 || n > count {
+		return 0, fmt.Errorf("task %d does not exist; the plan has %d task(s)", n, count)
+	}
+	return n, nil
+}
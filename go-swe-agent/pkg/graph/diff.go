@@ -0,0 +1,187 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/tools"
+)
+
+const (
+	diffHookTimeout = 5 * time.Minute
+
+	// maxSummaryDiffLines caps the diff printed in the summary; the full
+	// diff is written with --diff-output
+	maxSummaryDiffLines = 400
+)
+
+// DiffHookResult records the outcome of the --diff-hook policy check.
+type DiffHookResult struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output,omitempty"`
+	Passed   bool   `json:"passed"`
+	Attempts int    `json:"attempts"`
+}
+
+// collectDiff returns a unified diff of all changes in the git repository
+// at dir, including untracked files. A submodule whose commit changed
+// shows as a "Subproject commit" hunk; changes not yet committed inside a
+// checked-out submodule follow, with paths relative to dir.
+func collectDiff(dir string) (string, error) {
+	diff, err := repoDiff(dir, "")
+	if err != nil {
+		return "", err
+	}
+
+	submodules, err := tools.DetectSubmodules(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range submodules {
+		if !s.Initialized {
+			continue
+		}
+		changes, err := repoDiff(dir, s.Path)
+		if err != nil {
+			return "", fmt.Errorf("submodule %s: %w", s.Path, err)
+		}
+		diff += changes
+	}
+	return diff, nil
+}
+
+// changes returns the run's changes to the working directory as a unified
+// diff: against HEAD in a git repository, or against the files recorded
+// at the start of the run otherwise.
+func (o *Orchestrator) changes() (string, error) {
+	if o.snapshot != nil {
+		return o.snapshot.diff()
+	}
+	return collectDiff(o.state.WorkingDir)
+}
+
+// displayChanges writes the diff of the run to Options.DiffOutputPath, or
+// prints it, shortened, when no path is set.
+func (o *Orchestrator) displayChanges() {
+	diff, err := o.changes()
+	if err != nil {
+		o.out.Yellow("  ⚠️  No diff of the changes: %v\n", err)
+		return
+	}
+	if path := o.opts.DiffOutputPath; path != "" {
+		if err := os.WriteFile(path, []byte(diff), 0644); err != nil {
+			o.out.Yellow("  ⚠️  Failed to write diff: %v\n", err)
+			return
+		}
+		o.result.DiffPath = path
+		o.out.Printf("  📄 Diff written to %s\n", path)
+		return
+	}
+	if diff == "" {
+		return
+	}
+	o.out.Blue("\n📄 Changes:\n")
+	lines := strings.SplitAfter(strings.TrimSuffix(diff, "\n"), "\n")
+	if len(lines) > maxSummaryDiffLines {
+		diff = strings.Join(lines[:maxSummaryDiffLines], "")
+	}
+	o.printDiff("  ", diff)
+	if len(lines) > maxSummaryDiffLines {
+		o.out.Printf("  ... %d more lines; save the full diff with --diff-output\n", len(lines)-maxSummaryDiffLines)
+	}
+}
+
+// repoDiff diffs the repository at dir/sub, or dir itself when sub is
+// empty, with paths relative to dir.
+func repoDiff(dir, sub string) (string, error) {
+	var diff strings.Builder
+	repo := filepath.Join(dir, sub)
+
+	// Work in progress inside submodules is diffed separately, so only
+	// their commit changes are shown here
+	args := []string{"diff", "--ignore-submodules=dirty"}
+	if sub != "" {
+		args = append(args, "--src-prefix=a/"+sub+"/", "--dst-prefix=b/"+sub+"/")
+	}
+	tracked, err := gitOutput(repo, append(args, "HEAD")...)
+	if err != nil {
+		// Repositories without commits have no HEAD to diff against
+		tracked, err = gitOutput(repo, args...)
+		if err != nil {
+			return "", fmt.Errorf("failed to diff working directory (is it a git repository?): %w", err)
+		}
+	}
+	diff.WriteString(tracked)
+
+	untracked, err := gitOutput(repo, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return "", fmt.Errorf("failed to list untracked files: %w", err)
+	}
+	for _, file := range strings.Split(strings.TrimSpace(untracked), "\n") {
+		if file == "" {
+			continue
+		}
+		// --no-index exits 1 when the files differ, which is always the case here
+		cmd := exec.Command("git", "diff", "--no-index", "--", "/dev/null", filepath.ToSlash(filepath.Join(sub, file)))
+		cmd.Dir = dir
+		out, _ := cmd.Output()
+		diff.Write(out)
+	}
+
+	return diff.String(), nil
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// runDiffHook pipes diff into command and reports its exit code and
+// combined output. A non-zero exit means the diff was rejected.
+func runDiffHook(dir, command, diff string) (exitCode int, output string, err error) {
+	exitCode, output, err = runShell(dir, command, strings.NewReader(diff), diffHookTimeout)
+	if err != nil {
+		return exitCode, output, fmt.Errorf("diff hook: %w", err)
+	}
+	return exitCode, output, nil
+}
+
+// runShell runs command with bash in dir and reports its exit code and
+// combined output. Only failures to run it at all are errors.
+func runShell(dir, command string, stdin io.Reader, timeout time.Duration) (exitCode int, output string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	out, err := cmd.CombinedOutput()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return -1, string(out), fmt.Errorf("timed out after %s", timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), string(out), nil
+	}
+	if err != nil {
+		return -1, string(out), fmt.Errorf("failed to run %q: %w", command, err)
+	}
+	return 0, string(out), nil
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxDiffStatGroups is the number of directories listed in the summary;
+// the rest are folded into one line.
+const maxDiffStatGroups = 10
+
+// DiffStat summarizes the change in the working directory per directory.
+type DiffStat struct {
+	Groups  []DiffStatGroup `json:"groups"` // largest first
+	Files   int             `json:"files"`
+	Added   int             `json:"added"`
+	Removed int             `json:"removed"`
+}
+
+// DiffStatGroup is the part of the change in one directory, not counting
+// its subdirectories.
+type DiffStatGroup struct {
+	Dir     string `json:"dir"` // "." for the root
+	Files   int    `json:"files"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+}
+
+func (g DiffStatGroup) lines() int { return g.Added + g.Removed }
+
+// diffStat groups the changes against HEAD, including untracked files, by
+// directory. It returns nil outside a git repository or when nothing
+// changed.
+func diffStat(dir string) *DiffStat {
+	out, err := gitOutput(dir, "diff", "--numstat", "--ignore-submodules=dirty", "HEAD")
+	if err != nil {
+		// Repositories without commits have no HEAD to diff against
+		if out, err = gitOutput(dir, "diff", "--numstat", "--ignore-submodules=dirty"); err != nil {
+			return nil
+		}
+	}
+
+	groups := make(map[string]*DiffStatGroup)
+	add := func(file string, added, removed int) {
+		d := path.Dir(file)
+		g := groups[d]
+		if g == nil {
+			g = &DiffStatGroup{Dir: d}
+			groups[d] = g
+		}
+		g.Files++
+		g.Added += added
+		g.Removed += removed
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		// Binary files show "-" instead of line counts
+		added, _ := strconv.Atoi(fields[0])
+		removed, _ := strconv.Atoi(fields[1])
+		add(fields[2], added, removed)
+	}
+	for file := range untrackedFiles(dir) {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			add(file, 0, 0)
+			continue
+		}
+		lines := bytes.Count(data, []byte("\n"))
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			lines++
+		}
+		add(file, lines, 0)
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	stat := &DiffStat{}
+	for _, g := range groups {
+		stat.Groups = append(stat.Groups, *g)
+		stat.Files += g.Files
+		stat.Added += g.Added
+		stat.Removed += g.Removed
+	}
+	sort.Slice(stat.Groups, func(i, j int) bool {
+		a, b := stat.Groups[i], stat.Groups[j]
+		if a.lines() != b.lines() {
+			return a.lines() > b.lines()
+		}
+		return a.Dir < b.Dir
+	})
+	return stat
+}
+
+// displayDiffStat prints where the change is concentrated.
+func (o *Orchestrator) displayDiffStat(stat *DiffStat) {
+	if stat == nil {
+		return
+	}
+	total := stat.Added + stat.Removed
+	o.out.Printf("  📊 Diff: %d file(s), +%d -%d\n", stat.Files, stat.Added, stat.Removed)
+	for i, g := range stat.Groups {
+		if i == maxDiffStatGroups {
+			rest := DiffStatGroup{}
+			for _, g := range stat.Groups[i:] {
+				rest.Files += g.Files
+				rest.Added += g.Added
+				rest.Removed += g.Removed
+			}
+			o.out.Printf("     %d more directories: %d file(s), +%d -%d\n", len(stat.Groups)-i, rest.Files, rest.Added, rest.Removed)
+			break
+		}
+		share := ""
+		if total > 0 {
+			share = strconv.Itoa(g.lines()*100/total) + "%, "
+		}
+		o.out.Printf("     %-30s %s%d file(s), +%d -%d\n", g.Dir+"/", share, g.Files, g.Added, g.Removed)
+	}
+}
@@ -0,0 +1,33 @@
+package graph
+
+import "strings"
+
+// previewDryRun prints an action a dry run skipped: the diff of a file
+// write or the bash command.
+func (o *Orchestrator) previewDryRun(tool, preview string) {
+	if tool == "bash" {
+		o.out.Yellow("  🧪 Not run (dry run): %s\n", strings.TrimPrefix(preview, "$ "))
+		return
+	}
+	o.out.Yellow("  🧪 Not written (dry run):\n")
+	o.printDiff("     ", preview)
+}
+
+// printDiff prints a unified diff with each line indented, additions in
+// green and removals in red.
+func (o *Orchestrator) printDiff(indent, diff string) {
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			o.out.Printf("%s%s\n", indent, line)
+		case strings.HasPrefix(line, "+"):
+			o.out.Green("%s%s\n", indent, line)
+		case strings.HasPrefix(line, "-"):
+			o.out.Red("%s%s\n", indent, line)
+		case strings.HasPrefix(line, "@@"):
+			o.out.Cyan("%s%s\n", indent, line)
+		default:
+			o.out.Printf("%s%s\n", indent, line)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// Heuristics used until the planning phase provides real numbers.
+const (
+	defaultInputTokensPerTurn  = 6000
+	defaultOutputTokensPerTurn = 800
+	defaultTurnDuration        = 15 * time.Second
+
+	// Most tasks finish well before the iteration cap
+	expectedTurnFraction = 0.4
+)
+
+// CostEstimate is a rough projection of what executing a plan will cost.
+type CostEstimate struct {
+	Tasks               int           `json:"tasks"`
+	ExpectedTurns       int           `json:"expected_turns"`
+	MaxTurns            int           `json:"max_turns"`
+	InputTokensPerTurn  int           `json:"input_tokens_per_turn"`
+	OutputTokensPerTurn int           `json:"output_tokens_per_turn"`
+	ExpectedCost        float64       `json:"expected_cost_usd"`
+	MaxCost             float64       `json:"max_cost_usd"`
+	PricingKnown        bool          `json:"pricing_known"`
+	ExpectedDuration    time.Duration `json:"expected_duration_ns"`
+	FromPlanningUsage   bool          `json:"from_planning_usage"`
+}
+
+// estimateExecution projects the cost and duration of executing the plan,
+// at pricing when it is known. Per-turn token counts and latency come from
+// the planning phase when it made any calls, otherwise from fixed
+// heuristics.
+func estimateExecution(agentState *state.AgentState, pricing llm.Pricing, pricingKnown bool, maxIterations int) CostEstimate {
+	est := CostEstimate{
+		InputTokensPerTurn:  defaultInputTokensPerTurn,
+		OutputTokensPerTurn: defaultOutputTokensPerTurn,
+	}
+	turnDuration := defaultTurnDuration
+
+	if planning, ok := agentState.Usage[state.PhasePlanning]; ok && planning.Calls > 0 {
+		est.InputTokensPerTurn = planning.InputTokens / planning.Calls
+		est.OutputTokensPerTurn = planning.OutputTokens / planning.Calls
+		turnDuration = planning.Duration / time.Duration(planning.Calls)
+		est.FromPlanningUsage = true
+	}
+
+	if agentState.Plan != nil {
+		est.Tasks = len(agentState.Plan.Tasks)
+	}
+	est.MaxTurns = est.Tasks * maxIterations
+	est.ExpectedTurns = int(float64(est.MaxTurns)*expectedTurnFraction + 0.5)
+	est.ExpectedDuration = time.Duration(est.ExpectedTurns) * turnDuration
+
+	if pricingKnown {
+		est.PricingKnown = true
+		est.ExpectedCost = pricing.Cost(est.ExpectedTurns*est.InputTokensPerTurn, est.ExpectedTurns*est.OutputTokensPerTurn)
+		est.MaxCost = pricing.Cost(est.MaxTurns*est.InputTokensPerTurn, est.MaxTurns*est.OutputTokensPerTurn)
+	}
+
+	return est
+}
+
+func (o *Orchestrator) displayEstimate(est CostEstimate, model string) {
+	o.out.Cyan("\n💰 Execution Estimate (%s):\n", model)
+
+	source := "heuristic"
+	if est.FromPlanningUsage {
+		source = "planning phase usage"
+	}
+	o.out.Printf("  Turns: ~%d expected, %d max (%d tasks)\n", est.ExpectedTurns, est.MaxTurns, est.Tasks)
+	o.out.Printf("  Tokens per turn: ~%d in / ~%d out (%s)\n", est.InputTokensPerTurn, est.OutputTokensPerTurn, source)
+	if est.PricingKnown {
+		o.out.Printf("  Cost: ~$%.2f expected, up to $%.2f\n", est.ExpectedCost, est.MaxCost)
+	} else {
+		o.out.Printf("  Cost: unknown (no pricing for model %s)\n", model)
+	}
+	o.out.Printf("  Time: ~%s\n", est.ExpectedDuration.Round(time.Second))
+}
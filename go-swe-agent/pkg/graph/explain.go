@@ -0,0 +1,62 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// explainFailure, with --explain-errors, asks the model why a task failed,
+// or why the build/test check failed right after it, and what would fix
+// it. Each task is explained at most once.
+func (o *Orchestrator) explainFailure(task *state.Task) {
+	if !o.opts.ExplainErrors {
+		return
+	}
+	for _, e := range o.result.Explanations {
+		if e.TaskID == task.ID {
+			return
+		}
+	}
+
+	var failure string
+	if task.Status == "failed" && task.Error != "" {
+		failure = task.Error
+	}
+	if v := o.result.Verify; v != nil && v.Runs > 0 && !v.Passed && !o.verifyStale {
+		if failure != "" {
+			failure += "\n\n"
+		}
+		failure += fmt.Sprintf("The build/test check failed after the task: `%s` (exit code %d):\n%s", v.Command, v.ExitCode, tailOutput(v.Output, maxVerifyFeedback))
+	}
+	if failure == "" {
+		return
+	}
+
+	o.out.Cyan("  💡 Explaining the failure...\n")
+	explanation, err := o.explainer.Explain(o.state, task, failure)
+	if err != nil {
+		o.out.Yellow("  ⚠️  %v\n", err)
+		return
+	}
+	o.result.Explanations = append(o.result.Explanations, *explanation)
+	o.out.Printf("     %s\n", explanation.Explanation)
+	if explanation.SuggestedFix != "" {
+		o.out.Printf("     Suggested fix: %s\n", explanation.SuggestedFix)
+	}
+}
+
+// displayExplanations lists the failure explanations in the summary.
+func (o *Orchestrator) displayExplanations() {
+	if len(o.result.Explanations) == 0 {
+		return
+	}
+	o.out.Blue("\n💡 Why tasks failed:\n")
+	for _, e := range o.result.Explanations {
+		o.out.Printf("  - %s\n", e.Task)
+		o.out.Printf("    %s\n", e.Explanation)
+		if e.SuggestedFix != "" {
+			o.out.Green("    Suggested fix: %s\n", e.SuggestedFix)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package graph
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// artifactNames are directories that hold dependencies or build output
+// wherever they appear.
+var artifactNames = map[string]bool{
+	"node_modules":  true,
+	"__pycache__":   true,
+	".pytest_cache": true,
+	".mypy_cache":   true,
+	".ruff_cache":   true,
+	".tox":          true,
+	".venv":         true,
+	"venv":          true,
+	".gradle":       true,
+	".next":         true,
+	".nuxt":         true,
+	".parcel-cache": true,
+	"coverage":      true,
+}
+
+// buildOutputNames are build output directories whose names are too
+// common to ignore everywhere, so they are ignored where they appeared.
+var buildOutputNames = map[string]bool{
+	"dist":   true,
+	"build":  true,
+	"target": true,
+	"out":    true,
+	"bin":    true,
+	"obj":    true,
+}
+
+// artifactEntry returns the .gitignore entry for the dependency or build
+// output directory that the slash-separated path rel lies in.
+func artifactEntry(rel string) (string, bool) {
+	parts := strings.Split(path.Clean(rel), "/")
+	for i, part := range parts[:len(parts)-1] {
+		switch {
+		case artifactNames[part]:
+			return part + "/", true
+		case strings.HasSuffix(part, ".egg-info"):
+			return "*.egg-info/", true
+		case buildOutputNames[part]:
+			return "/" + strings.Join(parts[:i+1], "/") + "/", true
+		}
+	}
+	return "", false
+}
+
+// GitignoreSuggestion lists the .gitignore entries proposed for build
+// output and dependencies created during the run.
+type GitignoreSuggestion struct {
+	Entries []string `json:"entries"`
+	Applied bool     `json:"applied"` // appended to .gitignore
+}
+
+// newArtifactDirs returns the dependency and build output directories
+// created during the run that git does not ignore, with the number of
+// files in each, keyed by their .gitignore entry.
+func (o *Orchestrator) newArtifactDirs() map[string]int {
+	if o.untrackedAtStart == nil {
+		return nil
+	}
+	entries := make(map[string]int)
+	for file := range untrackedFiles(o.state.WorkingDir) {
+		if o.untrackedAtStart[file] {
+			continue
+		}
+		if entry, ok := artifactEntry(file); ok {
+			entries[entry]++
+		}
+	}
+	return entries
+}
+
+// suggestGitignore proposes .gitignore entries for the build output and
+// dependencies created during the run, and adds them with
+// --update-gitignore.
+func (o *Orchestrator) suggestGitignore() {
+	if o.opts.ToolBackend != nil {
+		return
+	}
+	dirs := o.newArtifactDirs()
+	if len(dirs) == 0 {
+		return
+	}
+	entries := make([]string, 0, len(dirs))
+	for entry := range dirs {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+
+	o.out.Yellow("\n🙈 Build output or dependencies created during the run are not ignored by git:\n")
+	for _, entry := range entries {
+		o.out.Printf("   %s (%d files)\n", entry, dirs[entry])
+	}
+	o.out.Println("   Proposed change to .gitignore:")
+	for _, entry := range entries {
+		o.out.Green("   + %s\n", entry)
+	}
+	o.result.Gitignore = &GitignoreSuggestion{Entries: entries}
+
+	if !o.opts.UpdateGitignore {
+		o.out.Println("   Use --update-gitignore to add these entries")
+		return
+	}
+	created, err := appendGitignore(o.state.WorkingDir, entries)
+	if err != nil {
+		o.out.Yellow("   ⚠️  %v\n", err)
+		return
+	}
+	o.state.Journal.Record(".gitignore", created, "")
+	o.result.Gitignore.Applied = true
+	o.out.Green("   Added to .gitignore\n")
+}
+
+// appendGitignore adds entries to the .gitignore at the root of dir. It
+// reports whether the file had to be created.
+func appendGitignore(dir string, entries []string) (bool, error) {
+	gitignore := filepath.Join(dir, ".gitignore")
+	existing, err := os.ReadFile(gitignore)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+	content := string(existing)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += strings.Join(entries, "\n") + "\n"
+	if err := os.WriteFile(gitignore, []byte(content), 0644); err != nil {
+		return false, fmt.Errorf("failed to update .gitignore: %w", err)
+	}
+	return existing == nil, nil
+}
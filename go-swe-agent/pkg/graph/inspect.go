@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"encoding/json"
+	"os"
+	"os/signal"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+// statusFile is where a run's status is written in the artifact
+// directory when it is inspected.
+const statusFile = "status.json"
+
+// watchInspect reports the run's status each time the process receives
+// inspectSignal, without interrupting the run. Call the returned function
+// to stop watching.
+func (o *Orchestrator) watchInspect() func() {
+	signals := make(chan os.Signal, 1)
+	if !notifyInspect(signals) {
+		return func() {}
+	}
+	statusPath := ""
+	if o.artifacts != nil {
+		statusPath = o.artifacts.Path(statusFile)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+				reportStatus(o.out, o.monitor.Snapshot(), statusPath)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// reportStatus writes snapshot as JSON to the run's output and, when
+// statusPath is set, to that file.
+func reportStatus(out *ui.Printer, snapshot state.Snapshot, statusPath string) {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		out.Yellow("  ⚠️  Failed to marshal run status: %v\n", err)
+		return
+	}
+	out.Printf("\n%s\n", data)
+	if statusPath == "" {
+		return
+	}
+	if err := os.WriteFile(statusPath, data, 0644); err != nil {
+		out.Yellow("  ⚠️  Failed to write run status: %v\n", err)
+	}
+}
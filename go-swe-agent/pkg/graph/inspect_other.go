@@ -0,0 +1,12 @@
+//go:build !unix
+
+package graph
+
+import "os"
+
+// inspectSignal is not available here, so runs cannot be inspected.
+const inspectSignal = "SIGUSR1"
+
+func notifyInspect(c chan<- os.Signal) bool {
+	return false
+}
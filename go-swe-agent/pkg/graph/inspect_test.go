@@ -0,0 +1,32 @@
+package graph
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+func TestReportStatusWritesToRunOutput(t *testing.T) {
+	var out bytes.Buffer
+	path := filepath.Join(t.TempDir(), statusFile)
+	reportStatus(ui.NewPrinter(&out), state.Snapshot{RunID: "run-1", Phase: state.PhaseExecution}, path)
+
+	if !strings.Contains(out.String(), `"run_id": "run-1"`) {
+		t.Errorf("output %q does not hold the status", out.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), `"run_id": "run-1"`) {
+		t.Errorf("status file = %q, %v", data, err)
+	}
+
+	out.Reset()
+	reportStatus(ui.NewPrinter(&out), state.Snapshot{RunID: "run-1"}, filepath.Join(path, "missing", statusFile))
+	if !strings.Contains(out.String(), "Failed to write run status") {
+		t.Errorf("output %q does not report the failed write", out.String())
+	}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package graph
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// inspectSignal asks a running agent to report its status.
+const inspectSignal = "SIGUSR1"
+
+func notifyInspect(c chan<- os.Signal) bool {
+	signal.Notify(c, syscall.SIGUSR1)
+	return true
+}
@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/agents"
+	"github.com/openswe/go-swe-agent/pkg/hooks"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// startInterleaved prepares an interleaved run: the plan starts empty and
+// grows one step at a time during execution.
+func (o *Orchestrator) startInterleaved() error {
+	if err := o.loadImages(); err != nil {
+		return err
+	}
+	if err := o.loadReferenceContext(); err != nil {
+		return err
+	}
+	o.stepper.Images = o.planner.Images
+
+	o.out.Yellow("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	o.out.Yellow("  Interleaved mode: planning step by step")
+	o.out.Yellow("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+
+	o.state.Plan = &state.Plan{
+		Tasks:       []state.Task{},
+		Summary:     "Interleaved run",
+		CreatedAt:   time.Now(),
+		IsApproved:  true,
+		Interleaved: true,
+	}
+	o.emit(hooks.PlanReady, o.state.Plan)
+	o.saveCheckpoint()
+	return nil
+}
+
+// interleave lets the stepper choose a step, runs it and repeats with the
+// outcome in view, until the model declares the request done or the step
+// limit is reached. It reports true when the run halted for human input.
+func (o *Orchestrator) interleave() (bool, error) {
+	maxSteps := o.opts.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = agents.DefaultMaxSteps
+	}
+
+	for len(o.state.Plan.Tasks) < maxSteps {
+		o.client.Settings().Tags.Phase = state.PhasePlanning
+		o.client.Settings().Tags.TaskID = ""
+		o.out.Blue("\n🧭 Choosing step %d...\n", len(o.state.Plan.Tasks)+1)
+		task, err := o.stepper.NextStep(o.state)
+		if err != nil {
+			return false, err
+		}
+		if task == nil {
+			o.out.Green("  ✅ Request done: %s\n", o.state.Plan.Summary)
+			return false, nil
+		}
+
+		o.state.Plan.Tasks = append(o.state.Plan.Tasks, *task)
+		state.AssignTaskIDs(o.state.Plan.Tasks)
+		i := len(o.state.Plan.Tasks) - 1
+		o.out.Cyan("  ➡️  %s\n", o.state.Plan.Tasks[i].Description)
+
+		halted, err := o.runTask(i)
+		if halted || err != nil {
+			return halted, err
+		}
+	}
+
+	o.out.Yellow("\n⚠️  Stopped after %d steps (the step limit) before the model declared the request done\n", maxSteps)
+	return false, nil
+}
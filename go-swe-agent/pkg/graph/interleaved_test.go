@@ -0,0 +1,170 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openswe/go-swe-agent/internal/llmtest"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+func nextStep(id, description string) string {
+	return llmtest.ToolUse(id, "next_step", fmt.Sprintf(`{"description":%q}`, description))
+}
+
+func runInterleaved(t *testing.T, client *llmtest.Client, maxSteps int) (*Orchestrator, *RunResult, string) {
+	t.Helper()
+	var out bytes.Buffer
+	o, err := NewOrchestrator(t.TempDir(), &state.Request{Description: "tidy the repository"}, Options{
+		Client:         client,
+		Interleaved:    true,
+		MaxSteps:       maxSteps,
+		AllowUnsafeDir: true,
+		Output:         &out,
+		StallWindow:    -1,
+	})
+	if err != nil {
+		t.Fatalf("NewOrchestrator: %v", err)
+	}
+	result, err := o.Run()
+	if err != nil {
+		t.Fatalf("Run: %v\n%s", err, out.String())
+	}
+	return o, result, out.String()
+}
+
+func TestInterleavedRunsStepsUntilDone(t *testing.T) {
+	client := &llmtest.Client{Turns: [][]string{
+		{nextStep("a", "List the files")},
+		{llmtest.ToolUse("b", "list_files", "{}")},
+		{llmtest.ToolUse("c", "complete_task", `{"summary":"listed"}`)},
+		{nextStep("d", "Write a README")},
+		{llmtest.ToolUse("e", "write_file", `{"path":"README.md","content":"# Project\n"}`)},
+		{llmtest.ToolUse("f", "complete_task", `{"summary":"written"}`)},
+		{llmtest.ToolUse("g", "next_step", `{"done":true,"summary":"the repository has a README"}`)},
+	}}
+	o, result, out := runInterleaved(t, client, 0)
+
+	if client.Calls() != len(client.Turns) {
+		t.Errorf("%d model calls, want %d\n%s", client.Calls(), len(client.Turns), out)
+	}
+	plan := o.state.Plan
+	if !plan.Interleaved || len(plan.Tasks) != 2 || result.Completed != 2 {
+		t.Fatalf("%d of %d steps completed, want 2 of 2\n%s", result.Completed, len(plan.Tasks), out)
+	}
+	if plan.Tasks[0].Description != "List the files" || plan.Tasks[1].Description != "Write a README" {
+		t.Errorf("steps = %q, %q", plan.Tasks[0].Description, plan.Tasks[1].Description)
+	}
+	if plan.Summary != "the repository has a README" {
+		t.Errorf("summary = %q, want the one given with done", plan.Summary)
+	}
+}
+
+func TestInterleavedStopsAtStepLimit(t *testing.T) {
+	client := &llmtest.Client{}
+	for i := 0; i < 3; i++ {
+		client.Turns = append(client.Turns,
+			[]string{nextStep("a", fmt.Sprintf("Step %d", i+1))},
+			[]string{llmtest.ToolUse("b", "complete_task", `{"summary":"done"}`)})
+	}
+	o, _, out := runInterleaved(t, client, 2)
+
+	if len(o.state.Plan.Tasks) != 2 {
+		t.Errorf("%d steps ran, want the limit of 2", len(o.state.Plan.Tasks))
+	}
+	if client.Calls() != 4 {
+		t.Errorf("%d model calls, want 4", client.Calls())
+	}
+	if !strings.Contains(out, "Stopped after 2 steps") {
+		t.Errorf("output does not report the step limit:\n%s", out)
+	}
+}
+
+// The same steps, planned up front or chosen one at a time, leave the
+// same task statuses and the same files.
+func TestInterleavedMatchesTwoPhase(t *testing.T) {
+	steps := []struct {
+		description string
+		turns       [][]string
+	}{
+		{"Write a README", [][]string{
+			{llmtest.ToolUse("a", "write_file", `{"path":"README.md","content":"# Project\n"}`)},
+			{llmtest.ToolUse("b", "complete_task", `{"summary":"written"}`)},
+		}},
+		{"Add a license", [][]string{
+			{llmtest.ToolUse("c", "write_file", `{"path":"LICENSE","content":"MIT\n"}`)},
+			{llmtest.ToolUse("d", "complete_task", `{"summary":"added"}`)},
+		}},
+	}
+
+	plan := &state.Plan{}
+	twoPhase := &llmtest.Client{}
+	interleaved := &llmtest.Client{}
+	for i, step := range steps {
+		plan.Tasks = append(plan.Tasks, state.Task{Description: step.description, Status: "pending"})
+		twoPhase.Turns = append(twoPhase.Turns, step.turns...)
+		interleaved.Turns = append(interleaved.Turns, []string{nextStep(fmt.Sprintf("step-%d", i), step.description)})
+		interleaved.Turns = append(interleaved.Turns, step.turns...)
+	}
+	interleaved.Turns = append(interleaved.Turns, []string{llmtest.ToolUse("done", "next_step", `{"done":true,"summary":"done"}`)})
+	state.AssignTaskIDs(plan.Tasks)
+
+	var out bytes.Buffer
+	request := &state.Request{Description: "tidy the repository"}
+	planned, err := NewOrchestrator(t.TempDir(), request, Options{
+		Client:         twoPhase,
+		PlanIn:         &state.PlanFile{Request: request, Plan: plan},
+		AllowUnsafeDir: true,
+		Output:         &out,
+		StallWindow:    -1,
+	})
+	if err != nil {
+		t.Fatalf("NewOrchestrator: %v", err)
+	}
+	if _, err := planned.Run(); err != nil {
+		t.Fatalf("Run: %v\n%s", err, out.String())
+	}
+	stepped, _, _ := runInterleaved(t, interleaved, 0)
+
+	want, got := planned.state.Plan.Tasks, stepped.state.Plan.Tasks
+	if len(got) != len(want) {
+		t.Fatalf("%d interleaved steps, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Description != want[i].Description || got[i].Status != want[i].Status {
+			t.Errorf("step %d = %q %s, want %q %s", i, got[i].Description, got[i].Status, want[i].Description, want[i].Status)
+		}
+	}
+	wantFiles, gotFiles := readTree(t, planned.state.WorkingDir), readTree(t, stepped.state.WorkingDir)
+	if len(wantFiles) != len(steps) || fmt.Sprint(gotFiles) != fmt.Sprint(wantFiles) {
+		t.Errorf("interleaved files = %v, want %v", gotFiles, wantFiles)
+	}
+}
+
+// readTree returns the contents of the files under dir by relative path,
+// leaving out the agent's own state.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if strings.HasPrefix(rel, ".") {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		files[rel] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("reading %s: %v", dir, err)
+	}
+	return files
+}
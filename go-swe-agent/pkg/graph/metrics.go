@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/metrics"
+)
+
+// recordMetrics appends the run's aggregates to the local metrics file
+// when --metrics is on. Nothing is sent anywhere.
+func (o *Orchestrator) recordMetrics(result *RunResult, runErr error, elapsed time.Duration) {
+	if o.opts.MetricsPath == "" {
+		return
+	}
+	record := metrics.Record{
+		Time:        time.Now(),
+		Model:       o.client.Model(),
+		Termination: "error",
+		Duration:    elapsed,
+	}
+	if runErr == nil && result != nil {
+		record.Termination = result.Termination
+		record.Success = result.Success()
+		record.Completed = result.Completed
+		record.Failed = result.Failed
+	}
+	if o.state.Plan != nil {
+		record.Tasks = len(o.state.Plan.Tasks)
+	}
+	for _, usage := range o.state.Usage {
+		record.Calls += usage.Calls
+		record.InputTokens += usage.InputTokens
+		record.OutputTokens += usage.OutputTokens
+	}
+	if pricing, ok := o.pricing(); ok {
+		record.Cost = pricing.Cost(record.InputTokens, record.OutputTokens)
+	}
+
+	if err := metrics.Append(o.opts.MetricsPath, record); err != nil {
+		o.out.Yellow("⚠️  %v\n", err)
+	}
+}
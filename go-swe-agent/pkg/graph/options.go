@@ -0,0 +1,376 @@
+package graph
+
+import (
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/openswe/go-swe-agent/pkg/hooks"
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/tools"
+)
+
+// Options configures optional orchestrator behavior. The zero value runs
+// the agent with default settings. Everything scoped to a run, such as
+// its output, input, paths and credentials, is set here rather than read
+// from globals, so several runs can execute concurrently in one process
+// when their Options do not share paths.
+type Options struct {
+	// ArtifactsDir, when set, collects the run's outputs (tool log, state,
+	// result, diff) under <ArtifactsDir>/<run ID> with a manifest.json.
+	// Individual output paths such as ToolLogPath take precedence.
+	ArtifactsDir string
+
+	// AllowUnsafeDir permits working directories that are too broad for
+	// autonomous changes, such as the filesystem root or home directory.
+	AllowUnsafeDir bool
+
+	// AllowOutsideWorkDir lets the file tools read, write and list paths
+	// outside the working directory. By default they are refused.
+	AllowOutsideWorkDir bool
+
+	// ToolLogPath, when set, streams every tool call and result to a
+	// rotating JSONL log at this path.
+	ToolLogPath string
+
+	// ToolLogMaxBytes is the size at which the tool log is rotated.
+	ToolLogMaxBytes int64
+
+	// TracePath, when set, writes the model's text and chosen tools for
+	// every turn to a compact JSONL file at this path.
+	TracePath string
+
+	// EventLogPath, when set, writes the run as structured events to a
+	// JSONL file at this path: the run's start and end, the plan, every
+	// task status change, model call and tool call with its input and
+	// output, secrets redacted.
+	EventLogPath string
+
+	// MetricsPath, when set, appends the run's aggregates (tokens, cost,
+	// duration, outcome and model, but no request or code) to a local
+	// JSONL file at this path.
+	MetricsPath string
+
+	// Pricing sets the price of models, by model ID, for the cost estimate,
+	// the usage summary and the metrics. It overrides the built-in list
+	// prices and covers models without one.
+	Pricing map[string]llm.Pricing
+
+	// MemoryLimitBytes and CPULimit cap each bash command the agent runs,
+	// on Linux only. Zero means no limit; limits that cannot be enforced
+	// are reported as warnings.
+	MemoryLimitBytes int64
+	CPULimit         float64
+
+	// SafetyPolicy replaces the default checks of bash commands, which
+	// refuse commands that damage the machine or pipe a download into a
+	// shell. Unsafe turns the checks off.
+	SafetyPolicy *tools.SafetyPolicy
+	Unsafe       bool
+
+	// Endpoint overrides the LLM provider's base URL and region.
+	Endpoint llm.EndpointConfig
+
+	// Provider is the LLM provider, llm.ProviderBedrock,
+	// llm.ProviderAnthropic or llm.ProviderOpenAI. Empty uses Bedrock.
+	Provider string
+
+	// Model is the model ID used for every phase. Empty uses the
+	// provider's default model.
+	Model string
+
+	// Client replaces the client created from Provider, Model, Endpoint,
+	// Credentials and APIKey, e.g. to share one across the runs of a batch
+	// or to use a fake in tests. Its settings are changed by the run, so
+	// it must not serve two runs at the same time. When it is nil and
+	// Provider or Model is invalid, NewOrchestrator returns an error.
+	Client llm.Client
+
+	// EstimateOnly stops after planning and printing the cost estimate.
+	EstimateOnly bool
+
+	// MaxEstimatedCost aborts before execution when the expected cost in
+	// USD exceeds it. Zero disables the check.
+	MaxEstimatedCost float64
+
+	// PullRequest and Commit inject an existing change (diff, description
+	// and review comments) into the planning context.
+	PullRequest int
+	Commit      string
+
+	// GitHubRepo is the "owner/name" used for GitHub API calls. It is
+	// detected from the origin remote when empty.
+	GitHubRepo string
+
+	// Interleaved replaces the upfront plan with one built step by step:
+	// each step is chosen after seeing the outcome of the previous one.
+	Interleaved bool
+
+	// MaxSteps caps the steps of an interleaved run. Zero uses
+	// agents.DefaultMaxSteps.
+	MaxSteps int
+
+	// Parallel runs up to that many tasks at a time, each starting once
+	// the tasks it depends on are done. Zero or one runs them one after
+	// another. Interactive, Interleaved, DryRun, Stream and ReproFirst runs,
+	// runs checking the build after every task and runs with an injected
+	// Client run one task at a time.
+	Parallel int
+
+	// PlannerIterations and MaxIterations override the exploration turns and
+	// per-task iterations otherwise chosen from the repository size.
+	PlannerIterations int
+	MaxIterations     int
+
+	// ExplainIterations overrides the exploration turns of Explain. Zero
+	// uses agents.DefaultExplainIterations.
+	ExplainIterations int
+
+	// ReproFirst starts the plan with a task that writes a test reproducing
+	// the bug. It must fail before the remaining tasks run and pass after.
+	ReproFirst bool
+
+	// OnlyTasks and SkipTasks restrict execution to part of the plan, by
+	// 1-based task number. Tasks left out stay pending.
+	OnlyTasks []int
+	SkipTasks []int
+
+	// MaxToolCalls caps tool executions per task, independently of turns.
+	MaxToolCalls int
+
+	// IndexConcurrency bounds the workers reading files during the
+	// repository analysis. Zero uses one per CPU.
+	IndexConcurrency int
+
+	// ReadConcurrency bounds the files the read_files tool reads at once,
+	// and ReadBudgetBytes the content it returns per call. Zero uses the
+	// tools package defaults.
+	ReadConcurrency int
+	ReadBudgetBytes int
+
+	// MaxFilesChanged caps the distinct files the agent may change through
+	// its file tools. Zero means no limit.
+	MaxFilesChanged int
+
+	// GeneratedFiles is the policy for writes to generated or vendored
+	// files: "warn" (default), "block" or "off".
+	GeneratedFiles string
+
+	// MaxLineLength is the longest line read_file and search return in
+	// full. Zero keeps the default; negative disables shortening.
+	MaxLineLength int
+
+	// MaxReadLines is the most lines read_file returns when no line range
+	// is given. Zero keeps the default; negative returns whole files.
+	MaxReadLines int
+
+	// BashTimeout is how long a bash command may run before it is killed.
+	// Zero keeps the default; negative disables the timeout.
+	BashTimeout time.Duration
+
+	// DetectEncoding makes the file tools detect and keep non-UTF-8 text
+	// encodings such as Latin-1 or UTF-16.
+	DetectEncoding bool
+
+	// BatchContext describes the earlier requests of a batch, whose
+	// changes are in the working directory. It is shown to the planner.
+	BatchContext string
+
+	// CleanScratch removes files the agent created that look left over,
+	// such as logs and backups, at the end of the run without asking.
+	CleanScratch bool
+
+	// ScopeViolations decides what happens to changes outside the
+	// request's target or forbidden files: "warn" (default), "fail" or
+	// "revert".
+	ScopeViolations string
+
+	// UpdateGitignore adds .gitignore entries for build output and
+	// dependency directories created during the run, instead of only
+	// proposing them.
+	UpdateGitignore bool
+
+	// ProtectedPaths are files the agent must not change, in
+	// .gitattributes-like patterns. Nil protects
+	// tools.DefaultProtectedPaths; an empty list protects nothing.
+	ProtectedPaths []string
+
+	// Submodules decides whether list_files and search go into git
+	// submodules: "recurse" (default) or "skip".
+	Submodules string
+
+	// ToolBackend runs the tools somewhere other than the local machine,
+	// e.g. tools.RemoteBackend in a container. The working directory must
+	// also exist locally, since analysis, diffs and the scratch directory
+	// stay local. Nil runs the tools locally.
+	ToolBackend tools.ToolBackend
+
+	// Nudge replaces the message sent when the model answers a task with
+	// prose only. MaxNudges caps such consecutive turns before the task
+	// fails, and MaxIdleTurns those among them that are empty or repeat
+	// the previous reply; zero keeps the defaults.
+	Nudge        string
+	MaxNudges    int
+	MaxIdleTurns int
+
+	// MaxHistoryTurns caps the conversation turns each phase sends to the
+	// model, evicting the oldest. Zero means no cap.
+	MaxHistoryTurns int
+
+	// PlannerMaxTokens and ExecutorMaxTokens cap the output of each model
+	// response while planning and while executing tasks. Zero keeps the
+	// defaults; a value above the model's limit fails the run.
+	PlannerMaxTokens  int
+	ExecutorMaxTokens int
+
+	// DigestLength caps the characters of each completed task's output
+	// passed to later tasks. Zero keeps the default; negative omits outputs.
+	DigestLength int
+
+	// DiffHook is a shell command that receives the full diff on stdin after
+	// execution. A non-zero exit blocks a successful result.
+	DiffHook string
+
+	// DiffHookFeedback gives the model one attempt to fix a failing diff hook.
+	DiffHookFeedback bool
+
+	// StallWindow is the number of tool calls the run-level watchdog looks
+	// at, and StallRepeat the fraction of them that must repeat an earlier
+	// call, with no task finished and no new file write, to halt the run.
+	// Zero uses the defaults; a negative StallWindow disables the watchdog.
+	StallWindow int
+	StallRepeat float64
+
+	// PartialTasks is what happens when a task is only partially done:
+	// PartialFlag (default) reports the remaining work and fails the run,
+	// PartialFollowUp adds a task that finishes it.
+	PartialTasks string
+
+	// VerifyCommand is the project's build/test command. It runs after
+	// tasks and at the end; a failure is shown to the next task and a final
+	// failure fails the run.
+	VerifyCommand string
+
+	// VerifyCadence is when VerifyCommand runs: VerifyEach after every
+	// task, VerifyEnd once at the end, or VerifyAuto (default), which times
+	// the first run and falls back to the end for slow suites.
+	VerifyCadence string
+
+	// RepairAttempts is how many times a final VerifyCommand failure, with
+	// every task complete, gets a planned repair pass and another check.
+	// Zero disables repairs.
+	RepairAttempts int
+
+	// MaxReplans is how many times the plan may be revised when more tasks
+	// than ReplanThreshold allows fail: the failures and their errors go
+	// back to the planner, which plans the remaining work. Zero disables
+	// re-planning.
+	MaxReplans int
+
+	// ReplanThreshold is the share of the tasks run, from 0 to 1, that
+	// must fail for a re-plan. Zero means DefaultReplanThreshold; a
+	// negative value re-plans after any failure.
+	ReplanThreshold float64
+
+	// RequestTag is added to the tags of every LLM request, next to the
+	// run ID, phase and task ID, for attribution in LLM gateways.
+	RequestTag string
+
+	// StopSequences are passed to the model provider to cut generation
+	// short when any of them is emitted.
+	StopSequences []string
+
+	// BlockedSignal is a sentinel the model is told to emit when it cannot
+	// continue without human input. Emitting it halts the run. Empty
+	// disables the watch.
+	BlockedSignal string
+
+	// ExplainActions makes the model state a one-line intent before every
+	// bash, write_file or edit_file call; intents are recorded with the
+	// calls.
+	ExplainActions bool
+
+	// DryRun runs the agent without writing files or running bash
+	// commands. The tools report success, file writes are previewed as
+	// diffs, and later reads see the previewed content.
+	DryRun bool
+
+	// DiffOutputPath is where the unified diff of the run's changes is
+	// written at the end. Empty prints the diff, shortened, in the summary
+	// instead. Outside a git repository the diff is taken against the
+	// files as they were when the run started.
+	DiffOutputPath string
+
+	// Stream prints the model's text during execution as it is generated,
+	// instead of showing nothing until each response is complete.
+	Stream bool
+
+	// CheckpointPath is where the agent state is saved after planning and
+	// after each task, so an interrupted or blocked run can be resumed.
+	CheckpointPath string
+
+	// Resume continues the run saved in a checkpoint instead of planning a
+	// new one; ResumePath is the file it was loaded from.
+	Resume     *state.AgentState
+	ResumePath string
+
+	// EmitPlanPath, when set, writes the plan with its request to this
+	// file and stops before execution.
+	EmitPlanPath string
+
+	// PlanIn executes a plan written with EmitPlanPath, possibly edited
+	// since, instead of planning; PlanInPath is the file it was loaded
+	// from.
+	PlanIn     *state.PlanFile
+	PlanInPath string
+
+	// Interactive answers the model's ask_user questions, and those left
+	// pending in a resumed checkpoint, from stdin. Without it a question
+	// halts the run.
+	Interactive bool
+
+	// ConfirmPlan asks on the terminal to run, edit or reject the plan
+	// before execution. Without it the plan runs as generated.
+	ConfirmPlan bool
+
+	// ExplainErrors makes one extra model call per failed task, or task
+	// after which the build/test check failed, to explain the failure and
+	// suggest a fix for whoever takes over the run.
+	ExplainErrors bool
+
+	// ApprovePlan asks, in interactive runs, which tasks to run before
+	// each round of execution. After a round the remaining tasks are
+	// refreshed against its results and offered again.
+	ApprovePlan bool
+
+	// Hooks receive run lifecycle events (run started, plan ready, each
+	// task finished, run finished).
+	Hooks []hooks.Hook
+
+	// Output receives the progress output of the run, uncolored. Nil
+	// writes to standard output, colored when it is a terminal.
+	Output io.Writer
+
+	// Input is where answers are read from with Interactive or
+	// ConfirmPlan. Nil reads standard input.
+	Input io.Reader
+
+	// TempDir is the directory the run's scratch directory is created in.
+	// Empty uses the system temporary directory.
+	TempDir string
+
+	// Credentials sign the run's Bedrock requests. Nil uses the
+	// credentials in the environment and shared AWS config, which every
+	// run in the process then shares.
+	Credentials aws.CredentialsProvider
+
+	// APIKey authenticates the run's Anthropic or OpenAI requests. Empty
+	// reads ANTHROPIC_API_KEY or OPENAI_API_KEY, which every run in the
+	// process then shares.
+	APIKey string
+
+	// GitHubToken authenticates the requests for PullRequest and Commit.
+	// Empty reads GITHUB_TOKEN.
+	GitHubToken string
+}
@@ -1,135 +1,1119 @@
 package graph
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/fatih/color"
 	"github.com/openswe/go-swe-agent/pkg/agents"
+	"github.com/openswe/go-swe-agent/pkg/github"
+	"github.com/openswe/go-swe-agent/pkg/hooks"
+	"github.com/openswe/go-swe-agent/pkg/llm"
 	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/tools"
+	"github.com/openswe/go-swe-agent/pkg/ui"
 )
 
 type Orchestrator struct {
-	state    *state.AgentState
-	planner  *agents.Planner
-	executor *agents.Executor
+	state        *state.AgentState
+	opts         Options
+	client       llm.Client
+	toolExecutor *tools.ToolExecutor
+	planner      *agents.Planner
+	executor     *agents.Executor
+	verifier     *agents.Verifier
+	explainer    *agents.Explainer
+	stepper      *agents.Stepper
+	out          *ui.Printer
+	result       *RunResult
+	artifacts    *artifactDir
+	monitor      *state.Monitor
+	events       *ui.EventLog
+	
+	// resumed is set when the state came from a checkpoint with a plan
+	resumed        bool
+	checkpointPath string
+	input          *bufio.Reader
+	selection      taskSelection
+	
+	// verifyStale is set when a task ran after the last build/test check
+	verifyStale bool
+	
+	// untrackedAtStart are the files git did not track when the run
+	// started, to tell which ones the agent created
+	untrackedAtStart map[string]bool
+	
+	// snapshot records the files at the start of the run when the working
+	// directory is not a git repository, to diff the changes against
+	snapshot *fileSnapshot
 }
 
-func NewOrchestrator(workingDir, request string) *Orchestrator {
+// NewOrchestrator sets up a run of request in workingDir. It fails when
+// no model client can be created, e.g. without an API key or with an
+// unknown model.
+func NewOrchestrator(workingDir string, request *state.Request, opts Options) (*Orchestrator, error) {
 	// Resolve to absolute path
 	absPath, err := filepath.Abs(workingDir)
 	if err != nil {
 		absPath = workingDir
 	}
 	
-	return &Orchestrator{
-		state:    state.NewAgentState(absPath, request),
-		planner:  agents.NewPlanner(absPath),
-		executor: agents.NewExecutor(absPath),
+	var agentState *state.AgentState
+	if opts.Resume != nil {
+		// A checkpoint carries its own request and working directory
+		agentState = opts.Resume
+		absPath = agentState.WorkingDir
+	} else {
+		agentState = state.NewAgentState(absPath, request.Description)
+		agentState.AcceptanceCriteria = request.AcceptanceCriteria
+		agentState.Images = request.Images
+		if len(request.TargetFiles) > 0 || len(request.ForbiddenFiles) > 0 {
+			agentState.Scope = &state.FileScope{Targets: request.TargetFiles, Forbidden: request.ForbiddenFiles}
+		}
+	}
+	
+	if agentState.Journal == nil {
+		agentState.Journal = &state.FileJournal{}
+	}
+	if agentState.ToolUsage == nil {
+		agentState.ToolUsage = state.ToolUsage{}
+	}
+	
+	toolExecutor := tools.NewToolExecutor(absPath)
+	toolExecutor.Journal = agentState.Journal
+	toolExecutor.Usage = agentState.ToolUsage
+	toolExecutor.MaxFilesChanged = opts.MaxFilesChanged
+	toolExecutor.GeneratedPolicy = opts.GeneratedFiles
+	toolExecutor.DetectEncoding = opts.DetectEncoding
+	toolExecutor.SubmodulePolicy = opts.Submodules
+	if opts.ProtectedPaths != nil {
+		toolExecutor.ProtectedPaths = opts.ProtectedPaths
+	}
+	if opts.MaxLineLength != 0 {
+		toolExecutor.MaxLineLength = opts.MaxLineLength
+	}
+	if opts.MaxReadLines != 0 {
+		toolExecutor.MaxReadLines = opts.MaxReadLines
+	}
+	toolExecutor.ReadConcurrency = opts.ReadConcurrency
+	if opts.BashTimeout != 0 {
+		toolExecutor.BashTimeout = opts.BashTimeout
+	}
+	toolExecutor.ReadBudget = opts.ReadBudgetBytes
+	if opts.ToolBackend != nil {
+		toolExecutor.Backend = opts.ToolBackend
+	}
+	toolExecutor.AllowOutsideWorkDir = opts.AllowOutsideWorkDir
+	if opts.Unsafe {
+		toolExecutor.Safety = nil
+	} else if opts.SafetyPolicy != nil {
+		toolExecutor.Safety = opts.SafetyPolicy
+	}
+	client := opts.Client
+	if client == nil {
+		client, err = llm.NewClient(opts.Provider, opts.Model, opts.Endpoint, opts.Credentials, opts.APIKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	out := ui.NewPrinter(opts.Output)
+	client.Settings().Out = out
+	if _, known := llm.MaxOutputTokens(client.Model()); opts.Client == nil && !known {
+		out.Yellow("  ⚠️  Unknown model %s: its output is capped at %d tokens per call\n", client.Model(), llm.DefaultMaxTokens)
+	}
+	
+	o := &Orchestrator{
+		state:        agentState,
+		opts:         opts,
+		client:       client,
+		toolExecutor: toolExecutor,
+		planner:      agents.NewPlanner(client, toolExecutor),
+		executor:     agents.NewExecutor(client, toolExecutor),
+		verifier:     agents.NewVerifier(client, toolExecutor),
+		explainer:    agents.NewExplainer(client),
+		stepper:      agents.NewStepper(client, toolExecutor),
+		result:       &RunResult{},
+		resumed:      opts.Resume != nil,
+		out:          out,
+		monitor:      state.NewMonitor(),
+	}
+	o.executor.Monitor = o.monitor
+	o.planner.Out = out
+	o.executor.Out = out
+	o.verifier.Out = out
+	o.stepper.Out = out
+	if opts.DryRun {
+		toolExecutor.DryRun = true
+		toolExecutor.Preview = o.previewDryRun
+	}
+	if opts.Interactive {
+		o.input = newInput(opts.Input)
+		o.executor.AskUser = o.ask
+		o.executor.ExtendBudget = o.extendBudget
+	}
+	return o, nil
+}
+
+func (o *Orchestrator) Run() (*RunResult, error) {
+	if o.opts.EventLogPath != "" {
+		if err := o.openEventLog(); err != nil {
+			return nil, err
+		}
+		defer o.events.Close()
+	}
+	o.emit(hooks.RunStarted, map[string]string{
+		"working_dir": o.state.WorkingDir,
+		"request":     o.state.OriginalRequest,
+	})
+	
+	start := time.Now()
+	result, err := o.run()
+	o.saveArtifacts(result, err)
+	o.recordMetrics(result, err, time.Since(start))
+	
+	finished := map[string]interface{}{"result": result}
+	if err != nil {
+		finished["error"] = err.Error()
+	}
+	o.emit(hooks.RunFinished, finished)
+	
+	return result, err
+}
+
+// openEventLog opens the --log-json event log for the run, the state and
+// the tools. Secrets are redacted from everything written to it.
+func (o *Orchestrator) openEventLog() error {
+	events, err := ui.NewEventLog(o.opts.EventLogPath, o.state.RunID)
+	if err != nil {
+		return err
 	}
+	events.Redact = tools.RedactSecrets
+	o.events = events
+	o.state.Events = events
+	o.toolExecutor.Events = events
+	return nil
 }
 
-func (o *Orchestrator) Run() error {
-	color.Blue("\n═══════════════════════════════════════════")
-	color.Blue("       🤖 Go SWE Agent Starting")
-	color.Blue("═══════════════════════════════════════════\n")
+func (o *Orchestrator) run() (*RunResult, error) {
+	o.out.Blue("\n═══════════════════════════════════════════")
+	o.out.Blue("       🤖 Go SWE Agent Starting")
+	o.out.Blue("═══════════════════════════════════════════\n")
 	
-	fmt.Printf("📁 Working Directory: %s\n", o.state.WorkingDir)
-	fmt.Printf("📝 Request: %s\n", o.state.OriginalRequest)
+	o.out.Printf("🆔 Run ID: %s\n", o.state.RunID)
+	o.out.Printf("📁 Working Directory: %s\n", o.state.WorkingDir)
+	o.out.Printf("📝 Request: %s\n", o.state.OriginalRequest)
+	o.out.Printf("🧠 Model: %s\n", o.client.Model())
 	
 	// Verify working directory exists
 	if _, err := os.Stat(o.state.WorkingDir); os.IsNotExist(err) {
-		return fmt.Errorf("working directory does not exist: %s", o.state.WorkingDir)
+		return nil, fmt.Errorf("working directory does not exist: %s", o.state.WorkingDir)
+	}
+	if !o.opts.AllowUnsafeDir {
+		if err := checkWorkingDir(o.state.WorkingDir); err != nil {
+			return nil, err
+		}
+	}
+	
+	if o.opts.ArtifactsDir != "" {
+		artifacts, err := newArtifactDir(o.opts.ArtifactsDir, o.state.RunID)
+		if err != nil {
+			return nil, err
+		}
+		o.artifacts = artifacts
+		o.out.Printf("🗂️  Artifacts: %s\n", artifacts.dir)
+	}
+	
+	// An explicit --tool-log wins over the artifact directory
+	toolLogPath := o.opts.ToolLogPath
+	if toolLogPath == "" && o.artifacts != nil {
+		toolLogPath = o.artifacts.Path("tools.jsonl")
+		o.artifacts.Add("tools.jsonl", "Every tool call and result as JSONL, secrets redacted")
+	}
+	// Checkpoints are written to --checkpoint, the artifact directory, or
+	// back to the file the run was resumed from
+	o.checkpointPath = o.opts.CheckpointPath
+	if o.checkpointPath == "" && o.artifacts != nil {
+		o.checkpointPath = o.artifacts.Path("checkpoint.json")
+		o.artifacts.Add("checkpoint.json", "Resumable agent state, updated after planning and each task")
+	}
+	if o.checkpointPath == "" && o.resumed {
+		o.checkpointPath = o.opts.ResumePath
+	}
+	
+	// An explicit --trace-out wins over the artifact directory too
+	tracePath := o.opts.TracePath
+	if tracePath == "" && o.artifacts != nil {
+		tracePath = o.artifacts.Path("trace.jsonl")
+		o.artifacts.Add("trace.jsonl", "The model's text and chosen tools for every turn, secrets redacted")
+	}
+	
+	if toolLogPath != "" {
+		toolLog, err := tools.NewToolLog(toolLogPath, o.state.RunID, o.opts.ToolLogMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		defer toolLog.Close()
+		toolLog.Out = o.out
+		o.toolExecutor.Log = toolLog
+		o.out.Printf("🧾 Tool log: %s\n", toolLogPath)
+	}
+	if tracePath != "" {
+		trace, err := agents.NewTrace(tracePath, o.state.RunID)
+		if err != nil {
+			return nil, err
+		}
+		defer trace.Close()
+		o.planner.Trace = trace
+		o.stepper.Trace = trace
+		o.executor.Trace = trace
+		o.out.Printf("🧭 Trace: %s\n", tracePath)
+	}
+	
+	scratchDir, err := os.MkdirTemp(o.opts.TempDir, "go-swe-agent-"+o.state.RunID+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	defer os.RemoveAll(scratchDir)
+	o.toolExecutor.ScratchDir = scratchDir
+	
+	if o.opts.MemoryLimitBytes > 0 || o.opts.CPULimit > 0 {
+		o.applyLimits()
+	}
+	if o.opts.DryRun {
+		o.out.Yellow("🧪 Dry run: files are not written and bash commands are not run\n")
+	}
+	if o.toolExecutor.Safety == nil {
+		o.out.Yellow("⚠️  Safety checks of bash commands are off\n")
+	} else if allow := o.toolExecutor.Safety.Allow; len(allow) > 0 {
+		o.out.Printf("🛡️  Bash commands limited to: %s\n", strings.Join(allow, ", "))
+	}
+	
+	o.untrackedAtStart = untrackedFiles(o.state.WorkingDir)
+	o.toolExecutor.SnapshotProtected()
+	o.monitor.Update(o.state, "", "analyzing the repository")
+	defer o.watchInspect()()
+	
+	ignore, err := o.loadPathRules()
+	if err != nil {
+		return nil, err
+	}
+	// Changes made in a sandbox are not in this working directory
+	if o.opts.ToolBackend == nil && !o.opts.DryRun && !isGitRepo(o.state.WorkingDir) {
+		if o.snapshot, err = takeSnapshot(o.state.WorkingDir, ignore); err != nil {
+			o.out.Yellow("⚠️  Not a git repository and its files could not be recorded, so the changes cannot be diffed: %v\n", err)
+		}
+	}
+	
+	metrics, err := analyzeRepo(o.state.WorkingDir, o.opts.IndexConcurrency, ignore, o.out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze repository: %w", err)
+	}
+	o.state.RepoMetrics = metrics
+	o.state.TestConventions = detectTestConventions(o.state.WorkingDir, ignore)
+	limits := adaptiveLimits(metrics, o.opts)
+	o.planner.MaxIterations = limits.PlannerIterations
+	o.stepper.MaxIterations = limits.PlannerIterations
+	o.executor.MaxIterations = limits.ExecutorIterations
+	if o.opts.MaxToolCalls > 0 {
+		o.executor.MaxToolCalls = o.opts.MaxToolCalls
+	}
+	if o.opts.DigestLength != 0 {
+		o.executor.DigestLength = o.opts.DigestLength
+	}
+	o.planner.ReproFirst = o.opts.ReproFirst
+	o.planner.MaxHistoryTurns = o.opts.MaxHistoryTurns
+	if err := o.applyMaxTokens(); err != nil {
+		return nil, err
+	}
+	o.executor.MaxHistoryTurns = o.opts.MaxHistoryTurns
+	o.verifier.MaxHistoryTurns = o.opts.MaxHistoryTurns
+	o.stepper.MaxHistoryTurns = o.opts.MaxHistoryTurns
+	o.executor.BlockedSignal = o.opts.BlockedSignal
+	o.executor.Nudge = o.opts.Nudge
+	if o.opts.MaxNudges > 0 {
+		o.executor.MaxNudges = o.opts.MaxNudges
+	}
+	if o.opts.MaxIdleTurns > 0 {
+		o.executor.MaxIdleTurns = o.opts.MaxIdleTurns
+	}
+	o.executor.ExplainActions = o.opts.ExplainActions
+	o.executor.Stream = o.opts.Stream
+	if o.opts.StallWindow >= 0 {
+		window, repeat := o.opts.StallWindow, o.opts.StallRepeat
+		if window == 0 {
+			window = agents.DefaultStallWindow
+		}
+		if repeat <= 0 {
+			repeat = agents.DefaultStallRepeat
+		}
+		o.executor.Watchdog = agents.NewWatchdog(window, repeat)
+	}
+	settings := o.client.Settings()
+	settings.Tags = llm.RequestTags{Base: o.opts.RequestTag, RunID: o.state.RunID}
+	settings.StopSequences = o.opts.StopSequences
+	if o.opts.BlockedSignal != "" {
+		settings.StopSequences = append(settings.StopSequences, o.opts.BlockedSignal)
+	}
+	o.displayPreflight(metrics, limits)
+	
+	if o.resumed {
+		if err := o.resume(); err != nil {
+			return nil, err
+		}
+	} else if o.opts.Interleaved {
+		if err := o.startInterleaved(); err != nil {
+			return nil, err
+		}
+	} else if o.opts.PlanIn != nil {
+		o.loadPlan()
+	} else {
+		proceed, err := o.plan()
+		if err != nil {
+			return nil, err
+		}
+		if !proceed {
+			return o.result, nil
+		}
+	}
+	
+	if o.opts.VerifyCommand != "" {
+		cadence := o.opts.VerifyCadence
+		if cadence == "" {
+			cadence = VerifyAuto
+		}
+		o.result.Verify = &VerifyResult{Command: o.opts.VerifyCommand, Cadence: cadence}
+		// A check between tasks would run while others are changing files
+		if o.parallelTasks() > 1 {
+			o.result.Verify.Cadence = VerifyEnd
+			o.result.Verify.Reason = "tasks run in parallel"
+		}
+	}
+	
+	o.selection = newTaskSelection(o.opts.OnlyTasks, o.opts.SkipTasks)
+	if o.selection.active() {
+		if err := o.selectTasks(); err != nil {
+			return nil, err
+		}
+	}
+	
+	// Phase 2: Execution
+	o.out.Yellow("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	o.out.Yellow("  Phase 2: Execution")
+	o.out.Yellow("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	
+	// With --approve-plan, tasks run in rounds of those the user approved
+	for round := 1; o.approveTasks(round); round++ {
+		// Execute each task, then again any a human unblocked
+		for unblocked := true; unblocked; unblocked = o.unblockTasks() {
+			if n := o.parallelTasks(); n > 1 {
+				halted, err := o.runParallel(n)
+				if err != nil {
+					return nil, err
+				}
+				if halted {
+					return o.result, nil
+				}
+				continue
+			}
+			// Follow-up tasks appended along the way are picked up too
+			held := make(map[int]bool)
+			for i := 0; i < len(o.state.Plan.Tasks); i++ {
+				if !o.scheduled(i) {
+					continue
+				}
+				// Tasks run in plan order, so their prerequisites have run
+				if deps, prerequisite := o.dependencyState(i, nil, held); deps == depsFailed {
+					held[i] = true
+					o.holdTask(i, prerequisite)
+					continue
+				}
+				halted, err := o.runTask(i)
+				if err != nil {
+					return nil, err
+				}
+				if halted {
+					return o.result, nil
+				}
+			}
+			if o.state.Plan.Interleaved {
+				halted, err := o.interleave()
+				if err != nil {
+					return nil, err
+				}
+				if halted {
+					return o.result, nil
+				}
+			}
+		}
+	}
+	halted, err := o.replanFailures()
+	if err != nil {
+		return nil, err
+	}
+	if halted {
+		return o.result, nil
+	}
+	o.checkScope()
+	o.verifyFinal()
+	halted, err = o.repairBuild()
+	if err != nil {
+		return nil, err
+	}
+	if halted {
+		return o.result, nil
+	}
+	o.suggestGitignore()
+	o.cleanScratch()
+	
+	if o.state.Plan.Reproduction != nil && o.state.Plan.Reproduction.TaskID != "" {
+		if err := o.checkReproPasses(); err != nil {
+			return nil, err
+		}
+	}
+	
+	if o.opts.DiffHook != "" {
+		if err := o.checkDiffHook(); err != nil {
+			return nil, err
+		}
+	}
+	
+	// Phase 3: Verification of acceptance criteria
+	if len(o.state.AcceptanceCriteria) > 0 {
+		o.out.Yellow("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		o.out.Yellow("  Phase 3: Verification")
+		o.out.Yellow("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		
+		o.client.Settings().Tags.Phase = state.PhaseVerification
+		o.client.Settings().Tags.TaskID = ""
+		o.monitor.Update(o.state, state.PhaseVerification, "checking acceptance criteria")
+		if err := o.verifier.VerifyCriteria(o.state); err != nil {
+			return nil, fmt.Errorf("verification failed: %w", err)
+		}
+		o.result.Criteria = o.state.CriteriaResults
+	}
+	
+	// Final summary
+	o.result.Termination = TerminationCompleted
+	if o.state.Plan.NoChangesNeeded {
+		o.result.Termination = TerminationNoChanges
+	}
+	o.displaySummary()
+	
+	return o.result, nil
+}
+
+// runTask executes the task at index i and the checks that follow it. It
+// reports true when the model signalled that it needs a human, after
+// showing the summary; the run must then stop.
+func (o *Orchestrator) runTask(i int) (bool, error) {
+	task := &o.state.Plan.Tasks[i]
+	o.out.Printf("\n[%d/%d] ", i+1, len(o.state.Plan.Tasks))
+	o.client.Settings().Tags.Phase = state.PhaseExecution
+	o.client.Settings().Tags.TaskID = task.ID
+	
+	// Continue with other tasks even if one fails, unless the model
+	// signalled that it needs a human
+	err := o.executor.ExecuteTask(o.state, task)
+	o.emit(hooks.TaskFinished, *task)
+	o.saveCheckpoint()
+	var blocked *agents.BlockedError
+	if errors.As(err, &blocked) {
+		o.result.Termination = TerminationBlocked
+		o.result.BlockedReason = blocked.Question
+		o.displaySummary()
+		return true, nil
+	}
+	var stalled *agents.StalledError
+	if errors.As(err, &stalled) {
+		o.result.Termination = TerminationStalled
+		o.result.Stall = stalled.Report
+		o.displaySummary()
+		return true, nil
+	}
+	if err != nil {
+		o.out.Red("  ❌ Task failed: %v\n", err)
+	}
+	if repro := o.state.Plan.Reproduction; repro != nil && task.ID == repro.TaskID {
+		if err := o.checkReproFails(task); err != nil {
+			o.saveCheckpoint()
+			return false, err
+		}
+	}
+	if task.Status != "blocked" {
+		o.verifyAfterTask()
+		o.explainFailure(task)
+	}
+	if task.Status == "partial" && o.opts.PartialTasks == PartialFollowUp && !o.state.Plan.Interleaved {
+		o.addFollowUp(i)
+		o.saveCheckpoint()
+	}
+	return false, nil
+}
+
+// plan runs the planning phase and the cost estimate. It reports false
+// when the run should stop before execution.
+func (o *Orchestrator) plan() (bool, error) {
+	if err := o.loadImages(); err != nil {
+		return false, err
+	}
+	if err := o.loadReferenceContext(); err != nil {
+		return false, err
 	}
 	
 	// Phase 1: Planning
-	color.Yellow("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	color.Yellow("  Phase 1: Planning")
-	color.Yellow("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	o.out.Yellow("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	o.out.Yellow("  Phase 1: Planning")
+	o.out.Yellow("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 	
+	o.client.Settings().Tags.Phase = state.PhasePlanning
+	o.monitor.Update(o.state, state.PhasePlanning, "exploring and planning")
 	if err := o.planner.GeneratePlan(o.state); err != nil {
-		return fmt.Errorf("planning failed: %w", err)
+		return false, fmt.Errorf("planning failed: %w", err)
 	}
 	
-	if o.state.Plan == nil || len(o.state.Plan.Tasks) == 0 {
-		return fmt.Errorf("no plan generated")
+	if o.state.Plan == nil {
+		return false, fmt.Errorf("no plan generated")
+	}
+	if o.state.Plan.NoChangesNeeded {
+		// Nothing to execute or estimate; criteria are still verified
+		o.out.Green("\n✅ No changes needed: %s\n", o.state.Plan.Summary)
+		o.emit(hooks.PlanReady, o.state.Plan)
+		o.saveCheckpoint()
+		emitted, err := o.emitPlan()
+		return !emitted, err
+	}
+	if len(o.state.Plan.Tasks) == 0 {
+		return false, fmt.Errorf("no plan generated")
+	}
+	if o.opts.ReproFirst {
+		if err := o.addReproTask(); err != nil {
+			return false, err
+		}
 	}
 	
 	// Display the plan
 	o.displayPlan()
+	o.emit(hooks.PlanReady, o.state.Plan)
+	o.saveCheckpoint()
 	
-	// Phase 2: Execution
-	color.Yellow("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	color.Yellow("  Phase 2: Execution")
-	color.Yellow("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	pricing, pricingKnown := o.pricing()
+	estimate := estimateExecution(o.state, pricing, pricingKnown, o.executor.MaxIterations)
+	o.result.Estimate = &estimate
+	o.displayEstimate(estimate, o.client.Model())
+	
+	if emitted, err := o.emitPlan(); emitted || err != nil {
+		return false, err
+	}
+	if o.opts.EstimateOnly {
+		o.out.Yellow("\nEstimate only: skipping execution\n")
+		o.result.Termination = TerminationEstimateOnly
+		return false, nil
+	}
+	if o.opts.MaxEstimatedCost > 0 && estimate.ExpectedCost > o.opts.MaxEstimatedCost {
+		return false, fmt.Errorf("estimated cost $%.2f exceeds the limit of $%.2f", estimate.ExpectedCost, o.opts.MaxEstimatedCost)
+	}
+	
+	return o.confirmPlan(), nil
+}
+
+// selectTasks validates --only-tasks and --skip-tasks against the plan and
+// reports which tasks will run. Failed and incomplete tasks named by --only-tasks are reset
+// so they run again.
+func (o *Orchestrator) selectTasks() error {
+	total := len(o.state.Plan.Tasks)
+	if err := o.selection.validate(total); err != nil {
+		return err
+	}
+	numbers := o.selection.numbers(total)
+	if len(numbers) == 0 {
+		return fmt.Errorf("--only-tasks and --skip-tasks leave no task to run")
+	}
+	
+	// Failed and incomplete tasks named explicitly are retried
+	for n := range o.selection.only {
+		if task := o.state.Plan.Tasks[n-1]; (task.Status == "failed" || task.Status == "incomplete") && o.selection.selected(n) {
+			o.state.RetryTask(task.ID)
+		}
+	}
+	
+	o.out.Cyan("\n🎯 Running tasks %s of %d\n", joinNumbers(numbers), total)
+	for _, warning := range o.selection.warnings(o.state.Plan.Tasks) {
+		o.out.Yellow("⚠️  %s\n", warning)
+	}
+	return nil
+}
+
+// resume continues a checkpointed run. Questions left by a blocked task are
+// asked first; without --interactive the run cannot continue past them.
+func (o *Orchestrator) resume() error {
+	o.out.Yellow("\n⏯️  Resuming run %s from checkpoint\n", o.state.RunID)
+	o.displayPlan()
 	
-	// Execute each task
-	for i := range o.state.Plan.Tasks {
-		fmt.Printf("\n[%d/%d] ", i+1, len(o.state.Plan.Tasks))
+	pending := o.state.PendingClarifications()
+	if len(pending) == 0 {
+		return nil
+	}
+	if o.input == nil {
+		for _, i := range pending {
+			o.out.Printf("  ❓ %s\n", o.state.Clarifications[i].Question)
+		}
+		return fmt.Errorf("%d question(s) are waiting for an answer; resume with --interactive to answer them", len(pending))
+	}
+	
+	o.out.Blue("\nThe agent needs your input before continuing:\n")
+	for _, i := range pending {
+		answer, err := o.ask(o.state.Clarifications[i].Question)
+		if err != nil {
+			return fmt.Errorf("failed to read answer: %w", err)
+		}
+		o.state.AnswerClarification(i, answer)
+	}
+	o.saveCheckpoint()
+	return nil
+}
+
+// ask prints a question from the model and reads a one-line answer.
+func (o *Orchestrator) ask(question string) (string, error) {
+	o.out.Magenta("\n❓ %s\n", question)
+	o.out.Print("> ")
+	answer, err := o.input.ReadString('\n')
+	if err != nil && answer == "" {
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// applyLimits caps the resources of bash commands as far as this machine
+// allows.
+func (o *Orchestrator) applyLimits() {
+	if o.opts.ToolBackend != nil {
+		o.out.Yellow("⚠️  Resource limits are not applied to a tool backend; the sandbox should enforce its own\n")
+		return
+	}
+	limits, warnings := tools.NewResourceLimits(o.opts.MemoryLimitBytes, o.opts.CPULimit)
+	for _, warning := range warnings {
+		o.out.Yellow("⚠️  %s\n", warning)
+	}
+	if limits != nil {
+		o.toolExecutor.Limits = limits
+		o.out.Printf("🧱 Commands limited to %s\n", limits)
+	}
+}
+
+// loadPathRules gives the tools the project's AgentIgnoreFile and its git
+// submodules, and returns the ignore rules, nil if there are none.
+func (o *Orchestrator) loadPathRules() (*tools.IgnoreRules, error) {
+	ignore, err := tools.LoadIgnoreRules(o.state.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+	if ignore != nil {
+		o.toolExecutor.Ignore = ignore
+		o.out.Printf("🙈 Hiding paths matching %d pattern(s) in %s\n", ignore.Len(), tools.AgentIgnoreFile)
+	}
+
+	submodules, err := tools.DetectSubmodules(o.state.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(submodules) > 0 {
+		o.toolExecutor.Submodules = submodules
+		paths := make([]string, len(submodules))
+		for i, s := range submodules {
+			paths[i] = s.Path
+		}
+		skipped := ""
+		if o.opts.Submodules == tools.SubmodulesSkip {
+			skipped = ", contents skipped"
+		}
+		o.out.Printf("📦 Git submodules%s: %s\n", skipped, strings.Join(paths, ", "))
+	}
+	return ignore, nil
+}
+
+// applyMaxTokens sets the output limits of the planning and execution
+// calls, after checking the model supports them.
+func (o *Orchestrator) applyMaxTokens() error {
+	for _, n := range []int{o.opts.PlannerMaxTokens, o.opts.ExecutorMaxTokens} {
+		if err := llm.ValidateMaxTokens(o.client.Model(), n); err != nil {
+			return fmt.Errorf("invalid max tokens: %w", err)
+		}
+	}
+	if o.opts.PlannerMaxTokens > 0 {
+		o.planner.MaxTokens = o.opts.PlannerMaxTokens
+		o.stepper.MaxTokens = o.opts.PlannerMaxTokens
+		o.explainer.MaxTokens = o.opts.PlannerMaxTokens
+	}
+	if o.opts.ExecutorMaxTokens > 0 {
+		o.executor.MaxTokens = o.opts.ExecutorMaxTokens
+	}
+	return nil
+}
+
+// extendBudget asks whether a task that reached its turn or tool call
+// limit may continue.
+func (o *Orchestrator) extendBudget(task *state.Task, reason string) bool {
+	answer, err := o.ask(fmt.Sprintf("The task stopped before it was done (%s). Continue with another %d turns and %d tool calls? [y/N]", reason, o.executor.MaxIterations, o.executor.MaxToolCalls))
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+// saveCheckpoint writes the current state when checkpointing is enabled.
+// A failed write is reported but does not stop the run.
+func (o *Orchestrator) saveCheckpoint() {
+	if o.checkpointPath == "" {
+		return
+	}
+	if err := o.state.SaveState(o.checkpointPath); err != nil {
+		o.out.Yellow("⚠️  %v\n", err)
+	}
+}
+
+// checkDiffHook runs the configured policy command over the full diff. When
+// it fails and feedback is enabled, the model gets one task to fix the
+// reported problems before the hook is re-run.
+func (o *Orchestrator) checkDiffHook() error {
+	hookResult := &DiffHookResult{Command: o.opts.DiffHook}
+	o.result.DiffHook = hookResult
+	
+	for {
+		diff, err := o.changes()
+		if err != nil {
+			return fmt.Errorf("diff hook: %w", err)
+		}
+		
+		o.out.Blue("\n🪝 Running diff hook: %s\n", o.opts.DiffHook)
+		hookResult.Attempts++
+		exitCode, output, err := runDiffHook(o.state.WorkingDir, o.opts.DiffHook, diff)
+		if err != nil {
+			return err
+		}
+		hookResult.ExitCode = exitCode
+		hookResult.Output = output
+		hookResult.Passed = exitCode == 0
+		
+		if strings.TrimSpace(output) != "" {
+			o.out.Println(strings.TrimRight(output, "\n"))
+		}
+		if hookResult.Passed {
+			o.out.Green("  ✅ Diff hook passed\n")
+			return nil
+		}
+		o.out.Red("  ❌ Diff hook failed (exit code %d)\n", exitCode)
 		
-		if err := o.executor.ExecuteTask(o.state, &o.state.Plan.Tasks[i]); err != nil {
-			color.Red("  ❌ Task failed: %v\n", err)
-			// Continue with other tasks even if one fails
-			continue
+		if !o.opts.DiffHookFeedback || hookResult.Attempts > 1 {
+			return nil
 		}
+		
+		fixTask := state.Task{
+			Description: fmt.Sprintf("Fix the problems reported by the diff policy check `%s` (exit code %d):\n%s", o.opts.DiffHook, exitCode, output),
+			Status:      "pending",
+		}
+		o.state.Plan.Tasks = append(o.state.Plan.Tasks, fixTask)
+		state.AssignTaskIDs(o.state.Plan.Tasks)
+		
+		o.out.Printf("\n[fix] ")
+		fixIndex := len(o.state.Plan.Tasks) - 1
+		o.client.Settings().Tags.TaskID = o.state.Plan.Tasks[fixIndex].ID
+		if err := o.executor.ExecuteTask(o.state, &o.state.Plan.Tasks[fixIndex]); err != nil {
+			o.out.Red("  ❌ Task failed: %v\n", err)
+		}
+		o.emit(hooks.TaskFinished, o.state.Plan.Tasks[fixIndex])
+	}
+}
+
+// saveArtifacts writes the end-of-run outputs and the manifest into the
+// artifact directory, if one is configured. Failures are reported but do
+// not change the run's outcome.
+func (o *Orchestrator) saveArtifacts(result *RunResult, runErr error) {
+	if o.artifacts == nil {
+		return
 	}
 	
-	// Final summary
-	o.displaySummary()
+	report := struct {
+		Result *RunResult `json:"result,omitempty"`
+		Error  string     `json:"error,omitempty"`
+	}{Result: result}
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	
+	var errs []error
+	errs = append(errs, o.artifacts.WriteJSON("result.json", "Run outcome: termination reason, task counts, criteria and estimate", report))
+	errs = append(errs, o.artifacts.WriteJSON("state.json", "Full agent state: request, plan, task outputs and usage", o.state))
+	if _, err := os.Stat(o.artifacts.Path(statusFile)); err == nil {
+		o.artifacts.Add(statusFile, "Run status at the last "+inspectSignal)
+	}
+	if diff, err := o.changes(); err == nil && diff != "" {
+		errs = append(errs, o.artifacts.WriteFile("changes.diff", "Unified diff of all changes in the working directory", []byte(diff)))
+	}
+	errs = append(errs, o.artifacts.Close())
+	
+	for _, err := range errs {
+		if err != nil {
+			o.out.Yellow("⚠️  %v\n", err)
+		}
+	}
+}
+
+func (o *Orchestrator) emit(eventType hooks.EventType, data interface{}) {
+	event := hooks.Event{
+		Type:  eventType,
+		RunID: o.state.RunID,
+		Time:  time.Now(),
+		Data:  data,
+	}
+	for _, hook := range o.opts.Hooks {
+		hook.OnEvent(event)
+	}
+	o.events.Log(string(eventType), data)
+}
+
+// loadImages attaches the request's images to the planner after checking
+// that the model can see them.
+func (o *Orchestrator) loadImages() error {
+	if len(o.state.Images) == 0 {
+		return nil
+	}
+	if !llm.SupportsVision(o.client.Model()) {
+		return fmt.Errorf("model %s does not accept images; remove --image or use a vision-capable model", o.client.Model())
+	}
 	
+	for _, path := range o.state.Images {
+		image, err := llm.LoadImage(path)
+		if err != nil {
+			return err
+		}
+		o.planner.Images = append(o.planner.Images, image)
+		o.out.Printf("🖼️  Image: %s\n", path)
+	}
+	return nil
+}
+
+// loadReferenceContext fetches the pull request or commit named in the
+// options so the planner can react to it.
+func (o *Orchestrator) loadReferenceContext() error {
+	if o.opts.PullRequest == 0 && o.opts.Commit == "" {
+		o.state.ReferenceContext = o.opts.BatchContext
+		return nil
+	}
+	
+	repo := o.opts.GitHubRepo
+	if repo == "" {
+		detected, err := github.DetectRepo(o.state.WorkingDir)
+		if err != nil && o.opts.PullRequest != 0 {
+			return fmt.Errorf("cannot determine GitHub repository (use --github-repo): %w", err)
+		}
+		repo = detected
+	}
+	
+	client := github.NewClient()
+	if o.opts.GitHubToken != "" {
+		client = github.NewClientWithToken(o.opts.GitHubToken)
+	}
+	var contexts []string
+	if o.opts.BatchContext != "" {
+		contexts = append(contexts, o.opts.BatchContext)
+	}
+	
+	if o.opts.PullRequest != 0 {
+		o.out.Printf("🔗 Fetching pull request #%d from %s\n", o.opts.PullRequest, repo)
+		pr, err := client.FetchPullRequest(repo, o.opts.PullRequest)
+		if err != nil {
+			return fmt.Errorf("failed to fetch pull request: %w", err)
+		}
+		contexts = append(contexts, pr.Context())
+	}
+	
+	if o.opts.Commit != "" {
+		o.out.Printf("🔗 Loading commit %s\n", o.opts.Commit)
+		commit, err := client.FetchCommit(o.state.WorkingDir, repo, o.opts.Commit)
+		if err != nil {
+			return fmt.Errorf("failed to load commit: %w", err)
+		}
+		contexts = append(contexts, commit.Context())
+	}
+	
+	o.state.ReferenceContext = strings.Join(contexts, "\n\n")
 	return nil
 }
 
 func (o *Orchestrator) displayPlan() {
-	color.Green("\n📋 Generated Plan:\n")
-	color.Green("─────────────────\n")
+	o.out.Green("\n📋 Generated Plan:\n")
+	o.out.Green("─────────────────\n")
 	
 	for i, task := range o.state.Plan.Tasks {
-		fmt.Printf("%d. %s\n", i+1, task.Description)
+		o.out.Printf("%d. %s\n", i+1, task.Description)
+		if after := o.dependencyNumbers(task); len(after) > 0 {
+			o.out.Printf("   ↳ after task %s\n", strings.Join(after, ", "))
+		}
 	}
 	
-	fmt.Printf("\nTotal tasks: %d\n", len(o.state.Plan.Tasks))
+	o.out.Printf("\nTotal tasks: %d\n", len(o.state.Plan.Tasks))
 }
 
 func (o *Orchestrator) displaySummary() {
-	color.Blue("\n═══════════════════════════════════════════")
-	color.Blue("       📊 Execution Summary")
-	color.Blue("═══════════════════════════════════════════\n")
+	o.out.Blue("\n═══════════════════════════════════════════")
+	o.out.Blue("       📊 Execution Summary")
+	o.out.Blue("═══════════════════════════════════════════\n")
 	
 	completed := 0
+	partial := 0
 	failed := 0
+	incomplete := 0
 	pending := 0
+	blocked := 0
+	skipped := 0
+	superseded := 0
 	
-	for _, task := range o.state.Plan.Tasks {
+	for i, task := range o.state.Plan.Tasks {
+		o.result.Turns += task.Turns
+		o.result.ToolCalls += task.ToolCalls
 		switch task.Status {
 		case "completed":
 			completed++
+		case "partial":
+			partial++
 		case "failed":
 			failed++
+		case "incomplete":
+			incomplete++
 		case "pending":
-			pending++
+			if o.selection.selected(i + 1) {
+				pending++
+			} else {
+				skipped++
+			}
+		case "blocked":
+			blocked++
+		case "superseded":
+			superseded++
 		}
 	}
 	
-	color.Green("  ✅ Completed: %d\n", completed)
+	o.result.Completed = completed
+	unresolved := unresolvedPartials(o.state.Plan.Tasks)
+	o.result.Partial = len(unresolved)
+	o.result.Failed = failed
+	o.result.Incomplete = incomplete
+	o.result.Pending = pending
+	o.result.Blocked = blocked
+	o.result.Skipped = skipped
+	o.result.Superseded = superseded
+	o.result.FilesChanged, o.result.FilesCreated = o.state.Journal.Counts()
+	
+	if o.state.Plan.NoChangesNeeded {
+		o.out.Green("  💤 No changes needed: %s\n", o.state.Plan.Summary)
+	}
+	o.out.Green("  ✅ Completed: %d\n", completed)
+	if partial > 0 {
+		o.out.Yellow("  🟡 Partial: %d (%d finished by a follow-up)\n", partial, partial-len(unresolved))
+	}
 	if failed > 0 {
-		color.Red("  ❌ Failed: %d\n", failed)
+		o.out.Red("  ❌ Failed: %d\n", failed)
+	}
+	if incomplete > 0 {
+		o.out.Red("  ⏹️  Incomplete: %d (stopped by the turn or tool call limit)\n", incomplete)
 	}
 	if pending > 0 {
-		color.Yellow("  ⏳ Pending: %d\n", pending)
+		o.out.Yellow("  ⏳ Pending: %d\n", pending)
+	}
+	if blocked > 0 {
+		o.out.Yellow("  ✋ Blocked: %d\n", blocked)
+	}
+	if skipped > 0 {
+		o.out.Yellow("  ⏭️  Not selected: %d\n", skipped)
+	}
+	if superseded > 0 {
+		o.out.Yellow("  🔀 Superseded by a re-plan: %d\n", superseded)
+	}
+	o.out.Printf("  🔁 LLM turns: %d, tool calls: %d\n", o.result.Turns, o.result.ToolCalls)
+	o.out.Printf("  📝 Files: %d modified, %d created", o.result.FilesChanged, o.result.FilesCreated)
+	if o.opts.MaxFilesChanged > 0 {
+		o.out.Printf(" (limit %d)", o.opts.MaxFilesChanged)
+	}
+	o.out.Println()
+	if o.opts.DryRun {
+		o.result.DryRunFiles = o.toolExecutor.DryRunFiles()
+		if len(o.result.DryRunFiles) > 0 {
+			o.out.Yellow("  🧪 Dry run, would have changed: %s\n", strings.Join(o.result.DryRunFiles, ", "))
+		}
+	}
+	// Changes made in a sandbox are not in this working directory
+	if o.opts.ToolBackend == nil {
+		o.result.DiffStat = diffStat(o.state.WorkingDir)
+	}
+	o.displayDiffStat(o.result.DiffStat)
+	if o.opts.ToolBackend == nil && !o.opts.DryRun {
+		o.displayChanges()
+	}
+	o.result.Tools = o.state.ToolUsage
+	o.displayToolUsage(o.state.ToolUsage)
+	o.result.Usage = o.usageSummary()
+	o.displayUsage(o.result.Usage)
+	o.displayVerify(o.result.Verify)
+	o.displayReplans()
+	o.displayRepairs()
+	if repro := o.result.Repro; repro != nil {
+		if repro.PassedAfter {
+			o.out.Green("  🐞 Reproduction: passes after the fix (%s)\n", repro.Command)
+		} else {
+			o.out.Red("  🐞 Reproduction: still fails (%s)\n", repro.Command)
+		}
 	}
 	
 	if len(o.state.Errors) > 0 {
-		color.Red("\n⚠️  Errors encountered:\n")
+		o.out.Red("\n⚠️  Errors encountered:\n")
 		for _, err := range o.state.Errors {
-			fmt.Printf("  - %s\n", err)
+			o.out.Printf("  - %s\n", err)
+		}
+	}
+	
+	o.displayExplanations()
+	o.result.HumanInput = o.humanInput()
+	o.displayHumanInput(o.result.HumanInput)
+	o.displayRemaining(unresolved)
+	
+	if len(o.result.Criteria) > 0 {
+		o.out.Blue("\n📐 Acceptance criteria:\n")
+		for _, c := range o.result.Criteria {
+			if c.Met {
+				o.out.Green("  ✅ %s\n", c.Criterion)
+			} else {
+				o.out.Red("  ❌ %s\n", c.Criterion)
+			}
+			if c.Evidence != "" {
+				o.out.Printf("     %s\n", c.Evidence)
+			}
 		}
 	}
 	
-	if completed == len(o.state.Plan.Tasks) {
-		color.Green("\n🎉 All tasks completed successfully!\n")
-	} else if completed > 0 {
-		color.Yellow("\n⚡ Partial completion: %d/%d tasks done\n", completed, len(o.state.Plan.Tasks))
+	if o.result.Termination == TerminationBlocked {
+		o.out.Red("\n✋ Run halted: the model needs human input\n")
+		if o.checkpointPath != "" {
+			o.out.Printf("\nAnswer and continue with: go-swe-agent --resume %s --interactive\n", o.checkpointPath)
+		}
+	} else if o.result.Termination == TerminationStalled {
+		o.displayStall(o.result.Stall)
+	} else if blocked > 0 {
+		o.out.Red("\n✋ %d task(s) blocked waiting for human input\n", blocked)
+		if o.checkpointPath != "" {
+			o.out.Printf("\nAnswer and continue with: go-swe-agent --resume %s --interactive\n", o.checkpointPath)
+		}
+	} else if hook := o.result.DiffHook; hook != nil && !hook.Passed {
+		o.out.Red("\n🚫 Diff hook `%s` rejected the changes (exit code %d)\n", hook.Command, hook.ExitCode)
+	} else if unmet := o.result.UnmetCriteria(); len(unmet) > 0 {
+		o.out.Red("\n🚫 %d of %d acceptance criteria not met\n", len(unmet), len(o.result.Criteria))
+	} else if done := completed + partial - len(unresolved); done == len(o.state.Plan.Tasks) {
+		o.out.Green("\n🎉 All tasks completed successfully!\n")
+	} else if done > 0 {
+		o.out.Yellow("\n⚡ Partial completion: %d/%d tasks done\n", done, len(o.state.Plan.Tasks))
 	}
 }
\ No newline at end of file
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/openswe/go-swe-agent/internal/llmtest"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// A plan whose tasks talk about completion runs every task: only
+// complete_task ends a task, and nothing ends execution early.
+func TestPlanMentioningCompletionRunsEveryTask(t *testing.T) {
+	dir := t.TempDir()
+	plan := &state.Plan{Tasks: []state.Task{
+		{Description: `Print "task completed" when the job finishes`, Status: "pending"},
+		{Description: "Mark the feature as done in the changelog", Status: "pending"},
+		{Description: "Add a test for the message", Status: "pending"},
+	}}
+	state.AssignTaskIDs(plan.Tasks)
+	client := &llmtest.Client{}
+	for range plan.Tasks {
+		client.Turns = append(client.Turns,
+			[]string{llmtest.TextBlock("Task completed."), llmtest.ToolUse("a", "list_files", "{}")},
+			[]string{llmtest.ToolUse("b", "complete_task", `{"summary":"done"}`)})
+	}
+	var out bytes.Buffer
+	o, err := NewOrchestrator(dir, &state.Request{Description: "report when the job is done"}, Options{
+		Client:         client,
+		PlanIn:         &state.PlanFile{Request: &state.Request{Description: "report when the job is done"}, Plan: plan},
+		AllowUnsafeDir: true,
+		Output:         &out,
+		StallWindow:    -1,
+	})
+	if err != nil {
+		t.Fatalf("NewOrchestrator: %v", err)
+	}
+	result, err := o.Run()
+	if err != nil {
+		t.Fatalf("Run: %v\n%s", err, out.String())
+	}
+	if result.Completed != len(plan.Tasks) {
+		t.Errorf("%d of %d tasks completed\n%s", result.Completed, len(plan.Tasks), out.String())
+	}
+	if client.Calls() != len(client.Turns) {
+		t.Errorf("%d model calls, want %d", client.Calls(), len(client.Turns))
+	}
+	for _, task := range o.state.Plan.Tasks {
+		if task.ToolCalls != 1 {
+			t.Errorf("%q: %d tool calls, want 1", task.Description, task.ToolCalls)
+		}
+	}
+}
+
+// A planner that finds the request already satisfied ends the run without
+// executing anything.
+func TestNoChangesNeeded(t *testing.T) {
+	dir := t.TempDir()
+	client := &llmtest.Client{Turns: [][]string{
+		{llmtest.ToolUse("a", "submit_plan", `{"no_changes_needed":true,"summary":"the flag already exists","tasks":[]}`)},
+	}}
+	var out bytes.Buffer
+	o, err := NewOrchestrator(dir, &state.Request{Description: "add the flag"}, Options{
+		Client:         client,
+		AllowUnsafeDir: true,
+		Output:         &out,
+		StallWindow:    -1,
+	})
+	if err != nil {
+		t.Fatalf("NewOrchestrator: %v", err)
+	}
+	result, err := o.Run()
+	if err != nil {
+		t.Fatalf("Run: %v\n%s", err, out.String())
+	}
+	if result.Termination != TerminationNoChanges {
+		t.Errorf("termination = %q, want %q\n%s", result.Termination, TerminationNoChanges, out.String())
+	}
+	if client.Calls() != 1 {
+		t.Errorf("%d model calls, want only the planner's", client.Calls())
+	}
+	if !strings.Contains(out.String(), "No changes needed: the flag already exists") {
+		t.Errorf("output does not report the planner's evidence:\n%s", out.String())
+	}
+}
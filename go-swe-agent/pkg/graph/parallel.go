@@ -0,0 +1,238 @@
+package graph
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/openswe/go-swe-agent/pkg/agents"
+	"github.com/openswe/go-swe-agent/pkg/hooks"
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// Whether a task's prerequisites allow it to start, see dependencyState.
+const (
+	depsMet = iota
+	depsWaiting
+	depsFailed
+)
+
+// taskWorker runs one task at a time alongside the other workers, with its
+// own client, tool executor and executor.
+type taskWorker struct {
+	client   llm.Client
+	usage    state.ToolUsage
+	executor *agents.Executor
+}
+
+// taskOutcome is what a worker reports once its task is done.
+type taskOutcome struct {
+	worker *taskWorker
+	index  int
+	err    error
+}
+
+// parallelTasks returns how many tasks may run at a time. Runs that talk
+// to the user, plan step by step, preview changes, stream the model's text
+// or check the build after every task run one task at a time, as do runs
+// with an injected client, which cannot be duplicated per task.
+func (o *Orchestrator) parallelTasks() int {
+	if o.opts.Parallel <= 1 || o.opts.Client != nil || o.opts.Interactive || o.state.Plan.Interleaved ||
+		o.opts.DryRun || o.opts.Stream || o.opts.ReproFirst || o.opts.VerifyCadence == VerifyEach {
+		return 1
+	}
+	return o.opts.Parallel
+}
+
+// scheduled reports whether the task at index i runs in this round of
+// execution: it has not run yet or was interrupted, and it was selected
+// and, with --approve-plan, approved.
+func (o *Orchestrator) scheduled(i int) bool {
+	task := &o.state.Plan.Tasks[i]
+	// Tasks finished before a resume are kept; interrupted ones restart
+	if task.Status != "pending" && task.Status != "in_progress" {
+		return false
+	}
+	if task.FollowUpOf == "" && !o.selection.selected(i+1) {
+		return false
+	}
+	return !o.opts.ApprovePlan || task.Approved
+}
+
+// dependencyState tells whether the task at index i can start: all the
+// tasks it depends on are done, some are still running or scheduled, or
+// one ended without being done or was held, in which case its index is
+// returned. Tasks this round leaves out do not hold up the tasks depending
+// on them. running holds the indexes of the tasks being executed, whose
+// status must not be read, and held those of the tasks not run because of
+// their own prerequisites.
+func (o *Orchestrator) dependencyState(i int, running, held map[int]bool) (int, int) {
+	tasks := o.state.Plan.Tasks
+	for _, id := range tasks[i].DependsOn {
+		for j := range tasks {
+			if tasks[j].ID != id {
+				continue
+			}
+			switch {
+			case held[j]:
+				return depsFailed, j
+			case running[j]:
+				return depsWaiting, -1
+			case tasks[j].Status == "completed" || tasks[j].Status == "partial":
+			case o.scheduled(j):
+				return depsWaiting, -1
+			case tasks[j].Status != "pending":
+				return depsFailed, j
+			}
+			break
+		}
+	}
+	return depsMet, -1
+}
+
+// holdTask reports a task that is not run because the task at index
+// prerequisite, which it depends on, was not done. It stays pending.
+func (o *Orchestrator) holdTask(i, prerequisite int) {
+	o.out.Yellow("\n⏭️  Not running task %d: it depends on task %d, which was not done (%s)\n", i+1, prerequisite+1, o.state.Plan.Tasks[prerequisite].Status)
+}
+
+// dependencyNumbers returns the plan numbers of the tasks task depends on.
+func (o *Orchestrator) dependencyNumbers(task state.Task) []string {
+	var numbers []string
+	for _, id := range task.DependsOn {
+		for j, t := range o.state.Plan.Tasks {
+			if t.ID == id {
+				numbers = append(numbers, strconv.Itoa(j+1))
+				break
+			}
+		}
+	}
+	return numbers
+}
+
+// newTaskWorkers creates n workers, each with its own client set up like
+// the run's client.
+func (o *Orchestrator) newTaskWorkers(n int) ([]*taskWorker, error) {
+	workers := make([]*taskWorker, n)
+	for k := range workers {
+		client, err := llm.NewClient(o.opts.Provider, o.opts.Model, o.opts.Endpoint, o.opts.Credentials, o.opts.APIKey)
+		if err != nil {
+			return nil, err
+		}
+		*client.Settings() = *o.client.Settings()
+		client.Settings().Tags.Phase = state.PhaseExecution
+		usage := state.ToolUsage{}
+		workers[k] = &taskWorker{
+			client:   client,
+			usage:    usage,
+			executor: o.executor.Fork(client, o.toolExecutor.Fork(usage)),
+		}
+	}
+	return workers, nil
+}
+
+// runParallel executes the tasks of this round with up to n running at a
+// time. A task starts once the tasks it depends on are done. Checkpoints
+// are saved, and follow-ups of partial tasks added, whenever no task is
+// running. Like runTask, it reports true when the run must stop, which
+// happens once the tasks already started have finished.
+func (o *Orchestrator) runParallel(n int) (bool, error) {
+	idle, err := o.newTaskWorkers(n)
+	if err != nil {
+		return false, err
+	}
+	outcomes := make(chan taskOutcome)
+	running := make(map[int]bool)
+	held := make(map[int]bool)
+	var partials []int
+	halted := false
+	for {
+		for i := 0; i < len(o.state.Plan.Tasks) && len(idle) > 0 && !halted; i++ {
+			if running[i] || held[i] || !o.scheduled(i) {
+				continue
+			}
+			deps, prerequisite := o.dependencyState(i, running, held)
+			if deps == depsWaiting {
+				continue
+			}
+			if deps == depsFailed {
+				held[i] = true
+				o.holdTask(i, prerequisite)
+				continue
+			}
+
+			w := idle[len(idle)-1]
+			idle = idle[:len(idle)-1]
+			running[i] = true
+			task := &o.state.Plan.Tasks[i]
+			w.client.Settings().Tags.TaskID = task.ID
+			o.out.Printf("\n[%d/%d] ", i+1, len(o.state.Plan.Tasks))
+			go func(i int) {
+				outcomes <- taskOutcome{worker: w, index: i, err: w.executor.ExecuteTask(o.state, task)}
+			}(i)
+		}
+		if len(running) == 0 {
+			break
+		}
+
+		outcome := <-outcomes
+		delete(running, outcome.index)
+		idle = append(idle, outcome.worker)
+		o.state.ToolUsage.Add(outcome.worker.usage)
+		clear(outcome.worker.usage)
+		if o.finishParallelTask(outcome) {
+			halted = true
+		}
+		task := &o.state.Plan.Tasks[outcome.index]
+		if task.Status == "partial" && o.opts.PartialTasks == PartialFollowUp {
+			partials = append(partials, outcome.index)
+		}
+
+		// Tasks being executed hold pointers into the plan and change
+		// their own task, so the plan only grows or is saved between them
+		if len(running) == 0 {
+			if !halted {
+				for _, i := range partials {
+					o.addFollowUp(i)
+				}
+				partials = nil
+			}
+			o.saveCheckpoint()
+		}
+	}
+	if halted {
+		o.displaySummary()
+	}
+	return halted, nil
+}
+
+// finishParallelTask handles the outcome of a task like runTask does and
+// reports whether the run must stop.
+func (o *Orchestrator) finishParallelTask(outcome taskOutcome) bool {
+	task := &o.state.Plan.Tasks[outcome.index]
+	o.emit(hooks.TaskFinished, *task)
+	var blocked *agents.BlockedError
+	if errors.As(outcome.err, &blocked) {
+		if o.result.Termination == "" {
+			o.result.Termination = TerminationBlocked
+			o.result.BlockedReason = blocked.Question
+		}
+		return true
+	}
+	var stalled *agents.StalledError
+	if errors.As(outcome.err, &stalled) {
+		if o.result.Termination == "" {
+			o.result.Termination = TerminationStalled
+			o.result.Stall = stalled.Report
+		}
+		return true
+	}
+	if outcome.err != nil {
+		o.out.Red("  ❌ Task %d failed: %v\n", outcome.index+1, outcome.err)
+	}
+	if task.Status != "blocked" {
+		o.verifyAfterTask()
+		o.explainFailure(task)
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// What to do with tasks the model reports as only partially done.
+const (
+	PartialFlag     = "flag"      // report the remaining work to the user
+	PartialFollowUp = "follow-up" // add a task that finishes the remaining work
+)
+
+// addFollowUp appends a task that finishes the remaining work of the
+// partial task at index i. Follow-ups of follow-ups are not added, so a
+// task that keeps falling short is left to the user.
+func (o *Orchestrator) addFollowUp(i int) {
+	task := o.state.Plan.Tasks[i]
+	if task.FollowUpOf != "" {
+		return
+	}
+	o.state.Plan.Tasks = append(o.state.Plan.Tasks, state.Task{
+		Description: fmt.Sprintf("Finish the remaining work of an earlier task.\nEarlier task: %s\nWhat it did: %s\nRemaining: %s", task.Description, task.Output, task.Remaining),
+		Status:      "pending",
+		FollowUpOf:  task.ID,
+		Replan:      task.Replan,
+	})
+	state.AssignTaskIDs(o.state.Plan.Tasks)
+	o.out.Yellow("  ➕ Added follow-up task %d for the remaining work\n", len(o.state.Plan.Tasks))
+}
+
+// unresolvedPartials returns the partial tasks whose remaining work was not
+// finished by a completed follow-up.
+func unresolvedPartials(tasks []state.Task) []state.Task {
+	finished := make(map[string]bool)
+	for _, task := range tasks {
+		if task.FollowUpOf != "" && task.Status == "completed" {
+			finished[task.FollowUpOf] = true
+		}
+	}
+	var unresolved []state.Task
+	for _, task := range tasks {
+		if task.Status == "partial" && !finished[task.ID] {
+			unresolved = append(unresolved, task)
+		}
+	}
+	return unresolved
+}
+
+// displayRemaining lists the work partial tasks left undone.
+func (o *Orchestrator) displayRemaining(partials []state.Task) {
+	if len(partials) == 0 {
+		return
+	}
+	o.out.Yellow("\n🟡 Remaining work from partially completed tasks:\n")
+	for _, task := range partials {
+		n := 0
+		for i := range o.state.Plan.Tasks {
+			if o.state.Plan.Tasks[i].ID == task.ID {
+				n = i + 1
+			}
+		}
+		o.out.Printf("  [task %d] %s\n", n, task.Remaining)
+	}
+}
@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/hooks"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// emitPlan writes the plan to --emit-plan with the request it was made
+// for. It reports true when a plan was written and the run should stop
+// before execution.
+func (o *Orchestrator) emitPlan() (bool, error) {
+	if o.opts.EmitPlanPath == "" {
+		return false, nil
+	}
+	request := &state.Request{
+		Description:        o.state.OriginalRequest,
+		AcceptanceCriteria: o.state.AcceptanceCriteria,
+		Images:             o.state.Images,
+	}
+	if o.state.Scope != nil {
+		request.TargetFiles = o.state.Scope.Targets
+		request.ForbiddenFiles = o.state.Scope.Forbidden
+	}
+	file := &state.PlanFile{
+		Request:    request,
+		WorkingDir: o.state.WorkingDir,
+		Plan:       o.state.Plan,
+	}
+	if head, err := gitOutput(o.state.WorkingDir, "rev-parse", "HEAD"); err == nil {
+		file.Head = strings.TrimSpace(head)
+	}
+	if err := file.SavePlanFile(o.opts.EmitPlanPath); err != nil {
+		return false, err
+	}
+
+	o.out.Green("\n📤 Plan written to %s\n", o.opts.EmitPlanPath)
+	o.out.Printf("   Edit it if needed, then execute with: go-swe-agent --plan-in %s\n", o.opts.EmitPlanPath)
+	o.result.Termination = TerminationPlanEmitted
+	return true, nil
+}
+
+// loadPlan installs the plan read with --plan-in instead of planning. The
+// plan's tasks were checked when the file was read; here it is checked
+// against the working directory, which may have moved on since.
+func (o *Orchestrator) loadPlan() {
+	file := o.opts.PlanIn
+	o.out.Yellow("\n📥 Executing the plan from %s\n", o.opts.PlanInPath)
+
+	if file.WorkingDir != "" && file.WorkingDir != o.state.WorkingDir {
+		o.out.Yellow("⚠️  The plan was made for %s, not this directory\n", file.WorkingDir)
+	}
+	if file.Head != "" {
+		head, err := gitOutput(o.state.WorkingDir, "rev-parse", "HEAD")
+		switch {
+		case err != nil:
+			o.out.Yellow("⚠️  The plan was made at commit %.12s, but the working directory is not a git repository\n", file.Head)
+		case strings.TrimSpace(head) != file.Head:
+			o.out.Yellow("⚠️  The plan was made at commit %.12s, but HEAD is now %.12s; tasks may no longer fit the code\n", file.Head, strings.TrimSpace(head))
+		}
+	}
+
+	o.state.Plan = file.Plan
+	o.state.Plan.IsApproved = true
+	if o.state.Plan.NoChangesNeeded {
+		o.out.Green("\n✅ No changes needed: %s\n", o.state.Plan.Summary)
+	} else {
+		o.displayPlan()
+	}
+	o.emit(hooks.PlanReady, o.state.Plan)
+	o.saveCheckpoint()
+}
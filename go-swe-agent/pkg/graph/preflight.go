@@ -0,0 +1,240 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/tools"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+const (
+	// Files above this size are counted but not read for line counts
+	maxLineCountBytes = 1 << 20
+
+	// Progress is shown while reading source files of repositories with
+	// at least this many of them
+	progressMinFiles = 2000
+)
+
+var preflightSkipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+	"__pycache__":  true,
+	".venv":        true,
+	".next":        true,
+}
+
+var extensionLanguages = map[string]string{
+	".go":    "Go",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".js":    "JavaScript",
+	".jsx":   "JavaScript",
+	".mjs":   "JavaScript",
+	".py":    "Python",
+	".rb":    "Ruby",
+	".rs":    "Rust",
+	".java":  "Java",
+	".kt":    "Kotlin",
+	".swift": "Swift",
+	".c":     "C",
+	".h":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".hpp":   "C++",
+	".cs":    "C#",
+	".php":   "PHP",
+	".scala": "Scala",
+	".sh":    "Shell",
+	".sql":   "SQL",
+	".css":   "CSS",
+	".scss":  "CSS",
+	".html":  "HTML",
+	".vue":   "Vue",
+}
+
+// Limits are the turn caps chosen for a run.
+type Limits struct {
+	PlannerIterations  int
+	ExecutorIterations int
+}
+
+// sizeClassLimits scales exploration depth and per-task iterations with
+// repository size.
+var sizeClassLimits = map[string]Limits{
+	"small":  {PlannerIterations: 4, ExecutorIterations: 12},
+	"medium": {PlannerIterations: 5, ExecutorIterations: 15},
+	"large":  {PlannerIterations: 8, ExecutorIterations: 20},
+	"huge":   {PlannerIterations: 10, ExecutorIterations: 25},
+}
+
+// analyzeRepo walks dir and collects file, line and language counts.
+// Symlinked directories inside dir are followed once; links leaving it are not.
+// Paths hidden by ignore are skipped.
+// Source files are read by up to concurrency workers (zero uses one per CPU);
+// the counts are sums, so they do not depend on the order files are read in.
+func analyzeRepo(dir string, concurrency int, ignore *tools.IgnoreRules, out *ui.Printer) (*state.RepoMetrics, error) {
+	metrics := &state.RepoMetrics{Languages: make(map[string]int)}
+
+	var sources []string
+	err := tools.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		hidden, _ := ignore.Match(rel, d.IsDir())
+		if d.IsDir() {
+			if path != dir && (preflightSkipDirs[d.Name()] || hidden) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if hidden {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		metrics.Files++
+		lang, ok := extensionLanguages[strings.ToLower(filepath.Ext(path))]
+		if !ok {
+			return nil
+		}
+		metrics.Languages[lang]++
+
+		if info, err := d.Info(); err == nil && info.Size() <= maxLineCountBytes {
+			sources = append(sources, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.Lines = countLines(sources, concurrency, out)
+
+	switch {
+	case metrics.Files < 200:
+		metrics.SizeClass = "small"
+	case metrics.Files < 2000:
+		metrics.SizeClass = "medium"
+	case metrics.Files < 10000:
+		metrics.SizeClass = "large"
+	default:
+		metrics.SizeClass = "huge"
+	}
+
+	return metrics, nil
+}
+
+// countLines counts the lines of files with a bounded pool of workers.
+func countLines(files []string, concurrency int, out *ui.Printer) int {
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	if concurrency > len(files) {
+		concurrency = len(files)
+	}
+
+	paths := make(chan string)
+	counts := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				lines := 0
+				if content, err := os.ReadFile(path); err == nil {
+					lines = bytes.Count(content, []byte("\n"))
+				}
+				counts <- lines
+			}
+		}()
+	}
+	go func() {
+		for _, path := range files {
+			paths <- path
+		}
+		close(paths)
+		wg.Wait()
+		close(counts)
+	}()
+
+	total, done := 0, 0
+	showProgress := len(files) >= progressMinFiles
+	for lines := range counts {
+		total += lines
+		done++
+		if showProgress && (done%500 == 0 || done == len(files)) {
+			out.Printf("\r   Indexing: %d/%d source files", done, len(files))
+		}
+	}
+	if showProgress {
+		out.Println()
+	}
+	return total
+}
+
+// adaptiveLimits picks turn caps for the repository size, letting any
+// explicitly configured value take precedence.
+func adaptiveLimits(metrics *state.RepoMetrics, opts Options) Limits {
+	limits := sizeClassLimits["medium"]
+	if metrics != nil {
+		limits = sizeClassLimits[metrics.SizeClass]
+	}
+	if opts.PlannerIterations > 0 {
+		limits.PlannerIterations = opts.PlannerIterations
+	}
+	if opts.MaxIterations > 0 {
+		limits.ExecutorIterations = opts.MaxIterations
+	}
+	return limits
+}
+
+func (o *Orchestrator) displayPreflight(metrics *state.RepoMetrics, limits Limits) {
+	type langCount struct {
+		name  string
+		files int
+	}
+	var langs []langCount
+	sourceFiles := 0
+	for name, files := range metrics.Languages {
+		langs = append(langs, langCount{name, files})
+		sourceFiles += files
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		if langs[i].files != langs[j].files {
+			return langs[i].files > langs[j].files
+		}
+		return langs[i].name < langs[j].name
+	})
+
+	var parts []string
+	for i, l := range langs {
+		if i == 3 {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s %d%%", l.name, l.files*100/sourceFiles))
+	}
+
+	o.out.Cyan("\n📏 Repository: %d files, %d lines of code (%s)\n", metrics.Files, metrics.Lines, metrics.SizeClass)
+	if len(parts) > 0 {
+		o.out.Printf("   Languages: %s\n", strings.Join(parts, ", "))
+	}
+	o.out.Printf("   Limits: %d exploration turns, %d iterations per task\n", limits.PlannerIterations, limits.ExecutorIterations)
+}
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// RepairAttempt records one repair pass made because the final build/test
+// check failed after every task completed.
+type RepairAttempt struct {
+	Attempt   int    `json:"attempt"`
+	Tasks     int    `json:"tasks"`
+	Completed int    `json:"completed"`
+	ExitCode  int    `json:"exit_code"` // of the check after the pass
+	Passed    bool   `json:"passed"`
+	Error     string `json:"error,omitempty"` // why no repair plan was made
+}
+
+// repairBuild, with --repair-attempts, plans and runs a focused fix when
+// the final build/test check fails although every task completed, then
+// re-runs the check, until it passes or the attempts run out. Such
+// failures usually come from changes that only break when combined. It
+// reports true when a repair task needs a human; the run must then stop.
+func (o *Orchestrator) repairBuild() (bool, error) {
+	v := o.result.Verify
+	if o.opts.RepairAttempts <= 0 || v == nil || v.Runs == 0 || v.Passed {
+		return false, nil
+	}
+	for _, task := range o.state.Plan.Tasks {
+		switch task.Status {
+		case "failed", "partial", "incomplete", "blocked":
+			o.out.Yellow("  ⚠️  Not repairing the build: not every task completed\n")
+			return false, nil
+		}
+	}
+
+	for attempt := 1; attempt <= o.opts.RepairAttempts && !v.Passed; attempt++ {
+		o.out.Yellow("\n🔧 Repair attempt %d/%d: the build/test check fails with every task complete\n", attempt, o.opts.RepairAttempts)
+		repair := RepairAttempt{Attempt: attempt}
+		tasks, err := o.planRepair(attempt)
+		if err != nil {
+			repair.Error = err.Error()
+			repair.ExitCode = v.ExitCode
+			o.result.Repairs = append(o.result.Repairs, repair)
+			o.out.Red("  ❌ %v\n", err)
+			return false, nil
+		}
+
+		first := len(o.state.Plan.Tasks)
+		o.state.Plan.Tasks = append(o.state.Plan.Tasks, tasks...)
+		state.AssignTaskIDs(o.state.Plan.Tasks)
+		o.saveCheckpoint()
+		for i := first; i < len(o.state.Plan.Tasks); i++ {
+			halted, err := o.runTask(i)
+			if err != nil || halted {
+				return halted, err
+			}
+			if o.state.Plan.Tasks[i].Status == "completed" {
+				repair.Completed++
+			}
+		}
+		o.verifyFinal()
+
+		repair.Tasks = len(tasks)
+		repair.ExitCode = v.ExitCode
+		repair.Passed = v.Passed
+		o.result.Repairs = append(o.result.Repairs, repair)
+	}
+	return false, nil
+}
+
+// planRepair asks the planner for the tasks that would make the failing
+// check pass, without undoing the work already done.
+func (o *Orchestrator) planRepair(attempt int) ([]state.Task, error) {
+	v := o.result.Verify
+	request := fmt.Sprintf(`Every task for the request below is done, but with all the changes combined the build/test check fails.
+
+ORIGINAL REQUEST: %s
+
+CHECK: `+"`%s`"+` (exit code %d)
+%s
+
+Plan only the changes needed to make the check pass. Keep the work already done: do not revert it or redo finished tasks.`, o.state.OriginalRequest, v.Command, v.ExitCode, tailOutput(v.Output, maxVerifyFeedback))
+
+	repairState := state.NewAgentState(o.state.WorkingDir, request)
+	repairState.Scope = o.state.Scope
+	reproFirst := o.planner.ReproFirst
+	o.planner.ReproFirst = false
+	err := o.planner.GeneratePlan(repairState)
+	o.planner.ReproFirst = reproFirst
+	o.state.AddUsage(repairState.Usage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan the repair: %w", err)
+	}
+
+	plan := repairState.Plan
+	if plan.NoChangesNeeded || len(plan.Tasks) == 0 {
+		return nil, fmt.Errorf("the repair planner found nothing to change: %s", plan.Summary)
+	}
+	tasks := make([]state.Task, len(plan.Tasks))
+	for i, t := range plan.Tasks {
+		tasks[i] = state.Task{Description: t.Description, Status: "pending", Creates: t.Creates, Repair: attempt}
+	}
+	return tasks, nil
+}
+
+// displayRepairs lists the repair attempts in the summary.
+func (o *Orchestrator) displayRepairs() {
+	for _, r := range o.result.Repairs {
+		switch {
+		case r.Error != "":
+			o.out.Red("  🔧 Repair %d: %s\n", r.Attempt, r.Error)
+		case r.Passed:
+			o.out.Green("  🔧 Repair %d: %d/%d task(s) completed, check passed\n", r.Attempt, r.Completed, r.Tasks)
+		default:
+			o.out.Red("  🔧 Repair %d: %d/%d task(s) completed, check still fails (exit code %d)\n", r.Attempt, r.Completed, r.Tasks, r.ExitCode)
+		}
+	}
+}
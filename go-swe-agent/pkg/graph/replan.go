@@ -0,0 +1,219 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// DefaultReplanThreshold is the share of the tasks run in a round that
+// must fail before --max-replans revises the plan.
+const DefaultReplanThreshold = 0.25
+
+// maxReplanError caps each failed task's error shown to the planner.
+const maxReplanError = 1500
+
+// ReplanRound records one revision of the plan made because too many of
+// the tasks run before it failed.
+type ReplanRound struct {
+	Round     int    `json:"round"`
+	Failed    int    `json:"failed"`            // tasks of the previous round that failed or ran out of turns
+	Ran       int    `json:"ran"`               // tasks of the previous round that ran
+	Dropped   int    `json:"dropped,omitempty"` // tasks held by a failed prerequisite, superseded by the revised plan
+	Tasks     int    `json:"tasks"`
+	Completed int    `json:"completed"`
+	Error     string `json:"error,omitempty"` // why no revised plan was made
+}
+
+// replanThreshold returns the failure ratio above which the plan is
+// revised. A negative threshold revises it after any failure.
+func (o *Orchestrator) replanThreshold() float64 {
+	if o.opts.ReplanThreshold == 0 {
+		return DefaultReplanThreshold
+	}
+	return o.opts.ReplanThreshold
+}
+
+// replanFailures, with --max-replans, hands the failed tasks and their
+// errors back to the planner when more of them failed than the threshold
+// allows, and runs the revised tasks it plans for the failed work and the
+// tasks held up by it. Each revision is judged by its own tasks and may be
+// revised again, until the rounds run out. The rounds are counted in the
+// state, so a resumed run does not start over. It reports true when a
+// task needs a human; the run must then stop.
+func (o *Orchestrator) replanFailures() (bool, error) {
+	if o.opts.MaxReplans <= 0 || o.state.Plan.Interleaved {
+		return false, nil
+	}
+	for o.state.Replans < o.opts.MaxReplans {
+		failed, held, ran := o.roundOutcome(o.state.Replans)
+		if len(failed) == 0 || float64(len(failed))/float64(ran) <= o.replanThreshold() {
+			return false, nil
+		}
+		o.state.Replans++
+		round := o.state.Replans
+		o.out.Yellow("\n🔁 Re-plan %d/%d: %d of %d task(s) failed\n", round, o.opts.MaxReplans, len(failed), ran)
+		replan := ReplanRound{Round: round, Failed: len(failed), Ran: ran}
+		tasks, err := o.planReplan(round, failed, held)
+		if err != nil {
+			replan.Error = err.Error()
+			o.result.Replans = append(o.result.Replans, replan)
+			o.out.Red("  ❌ %v\n", err)
+			o.saveCheckpoint()
+			return false, nil
+		}
+
+		// The revised plan covers the work of the held tasks, which stay in
+		// the plan as a record of the run
+		for i := range held {
+			o.state.Plan.Tasks[i].Status = "superseded"
+		}
+		replan.Dropped = len(held)
+		first := len(o.state.Plan.Tasks)
+		o.state.Plan.Tasks = appendReplanned(o.state.Plan.Tasks, tasks)
+		o.saveCheckpoint()
+
+		heldNow := make(map[int]bool)
+		for i := first; i < len(o.state.Plan.Tasks); i++ {
+			if deps, prerequisite := o.dependencyState(i, nil, heldNow); deps == depsFailed {
+				heldNow[i] = true
+				o.holdTask(i, prerequisite)
+				continue
+			}
+			halted, err := o.runTask(i)
+			if err != nil || halted {
+				return halted, err
+			}
+			if o.state.Plan.Tasks[i].Status == "completed" {
+				replan.Completed++
+			}
+		}
+		replan.Tasks = len(tasks)
+		o.result.Replans = append(o.result.Replans, replan)
+	}
+	return false, nil
+}
+
+// roundOutcome returns the indexes of the tasks added by the given re-plan
+// round, zero being the original plan, that failed or ran out of turns and
+// of those not run because a task they depend on was not done, and how
+// many ran.
+func (o *Orchestrator) roundOutcome(round int) (failed []int, held map[int]bool, ran int) {
+	held = make(map[int]bool)
+	for i, task := range o.state.Plan.Tasks {
+		if task.Replan != round || task.Repair != 0 {
+			continue
+		}
+		switch task.Status {
+		case "failed", "incomplete":
+			failed = append(failed, i)
+			ran++
+		case "completed", "partial":
+			ran++
+		case "pending":
+			if o.scheduled(i) {
+				if deps, _ := o.dependencyState(i, nil, held); deps == depsFailed {
+					held[i] = true
+				}
+			}
+		}
+	}
+	return failed, held, ran
+}
+
+// planReplan asks the planner for revised tasks that finish the request
+// after the failed tasks, given their errors, and the tasks they held up.
+func (o *Orchestrator) planReplan(round int, failed []int, held map[int]bool) ([]state.Task, error) {
+	var done, failures, notRun strings.Builder
+	for i, task := range o.state.Plan.Tasks {
+		switch {
+		case task.Status == "completed" || task.Status == "partial":
+			fmt.Fprintf(&done, "- %s\n", task.Description)
+		case held[i]:
+			fmt.Fprintf(&notRun, "- %s\n", task.Description)
+		}
+	}
+	for _, i := range failed {
+		task := o.state.Plan.Tasks[i]
+		fmt.Fprintf(&failures, "- %s\n  Status: %s\n  Error: %s\n", task.Description, task.Status, tailOutput(task.Error, maxReplanError))
+	}
+	if done.Len() == 0 {
+		done.WriteString("(none)\n")
+	}
+	if notRun.Len() == 0 {
+		notRun.WriteString("(none)\n")
+	}
+	request := fmt.Sprintf(`Too many tasks for the request below failed. Plan the work still needed to finish it.
+
+ORIGINAL REQUEST: %s
+
+DONE:
+%s
+FAILED:
+%s
+NOT RUN (a task they depend on failed):
+%s
+Plan only the work that is left: the failed tasks, taking a different approach that avoids their errors, and the tasks not run. Keep the work already done: do not revert it or redo finished tasks.`, o.state.OriginalRequest, done.String(), failures.String(), notRun.String())
+
+	replanState := state.NewAgentState(o.state.WorkingDir, request)
+	replanState.Scope = o.state.Scope
+	reproFirst := o.planner.ReproFirst
+	o.planner.ReproFirst = false
+	err := o.planner.GeneratePlan(replanState)
+	o.planner.ReproFirst = reproFirst
+	o.state.AddUsage(replanState.Usage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revise the plan: %w", err)
+	}
+
+	plan := replanState.Plan
+	if plan.NoChangesNeeded || len(plan.Tasks) == 0 {
+		return nil, fmt.Errorf("the planner found nothing left to do: %s", plan.Summary)
+	}
+	tasks := make([]state.Task, len(plan.Tasks))
+	for i, t := range plan.Tasks {
+		tasks[i] = state.Task{ID: t.ID, Description: t.Description, Status: "pending", Creates: t.Creates, DependsOn: t.DependsOn, Replan: round}
+	}
+	return tasks, nil
+}
+
+// appendReplanned adds the revised tasks to the plan with new IDs, which
+// must not clash with those of the tasks they revise, and points their
+// dependencies on each other at the new IDs.
+func appendReplanned(plan, tasks []state.Task) []state.Task {
+	first := len(plan)
+	planned := make([]string, len(tasks))
+	for i := range tasks {
+		planned[i] = tasks[i].ID
+		tasks[i].ID = ""
+	}
+	plan = append(plan, tasks...)
+	state.AssignTaskIDs(plan)
+
+	ids := make(map[string]string, len(tasks))
+	for i, id := range planned {
+		ids[id] = plan[first+i].ID
+	}
+	for i := first; i < len(plan); i++ {
+		deps := plan[i].DependsOn[:0:0]
+		for _, id := range plan[i].DependsOn {
+			if current, ok := ids[id]; ok {
+				deps = append(deps, current)
+			}
+		}
+		plan[i].DependsOn = deps
+	}
+	return plan
+}
+
+// displayReplans lists the re-plan rounds in the summary.
+func (o *Orchestrator) displayReplans() {
+	for _, r := range o.result.Replans {
+		if r.Error != "" {
+			o.out.Red("  🔁 Re-plan %d: %s\n", r.Round, r.Error)
+			continue
+		}
+		o.out.Printf("  🔁 Re-plan %d (%d of %d task(s) failed): %d/%d revised task(s) completed\n", r.Round, r.Failed, r.Ran, r.Completed, r.Tasks)
+	}
+}
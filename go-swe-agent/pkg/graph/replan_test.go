@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openswe/go-swe-agent/internal/llmtest"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// A task held by a failed prerequisite stays in the plan, checkpoint and
+// summary as superseded when a re-plan takes over its work.
+func TestReplanSupersedesHeldTasks(t *testing.T) {
+	dir := t.TempDir()
+	plan := &state.Plan{Tasks: []state.Task{
+		{Description: "Write the parser", Status: "pending"},
+		{Description: "Test the parser", Status: "pending"},
+	}}
+	state.AssignTaskIDs(plan.Tasks)
+	plan.Tasks[1].DependsOn = []string{plan.Tasks[0].ID}
+	client := &llmtest.Client{Turns: [][]string{
+		{llmtest.TextBlock("I could write the parser by hand.")},
+		{llmtest.TextBlock("Or generate it.")},
+		{llmtest.ToolUse("a", "submit_plan", `{"summary":"generate the parser","tasks":[{"description":"Generate and test the parser"}]}`)},
+		{llmtest.ToolUse("b", "complete_task", `{"summary":"generated"}`)},
+	}}
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+	var out bytes.Buffer
+	o, err := NewOrchestrator(dir, &state.Request{Description: "add a parser"}, Options{
+		Client:         client,
+		PlanIn:         &state.PlanFile{Request: &state.Request{Description: "add a parser"}, Plan: plan},
+		MaxNudges:      1,
+		MaxReplans:     1,
+		CheckpointPath: checkpoint,
+		AllowUnsafeDir: true,
+		Output:         &out,
+		StallWindow:    -1,
+	})
+	if err != nil {
+		t.Fatalf("NewOrchestrator: %v", err)
+	}
+	result, err := o.Run()
+	if err != nil {
+		t.Fatalf("Run: %v\n%s", err, out.String())
+	}
+
+	saved, err := state.LoadState(checkpoint)
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	for name, tasks := range map[string][]state.Task{"plan": o.state.Plan.Tasks, "checkpoint": saved.Plan.Tasks} {
+		var statuses []string
+		for _, task := range tasks {
+			statuses = append(statuses, task.Status)
+		}
+		if got := strings.Join(statuses, " "); got != "failed superseded completed" {
+			t.Errorf("%s statuses = %s, want failed superseded completed\n%s", name, got, out.String())
+		}
+	}
+	if result.Superseded != 1 || result.Completed != 1 || result.Failed != 1 {
+		t.Errorf("result = %d superseded, %d completed, %d failed; want 1 each", result.Superseded, result.Completed, result.Failed)
+	}
+	if len(result.Replans) != 1 || result.Replans[0].Dropped != 1 {
+		t.Errorf("replans = %+v, want one superseding 1 task", result.Replans)
+	}
+	if !strings.Contains(out.String(), "Superseded by a re-plan: 1") {
+		t.Errorf("summary does not list the superseded task:\n%s", out.String())
+	}
+}
@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+const reproTimeout = 10 * time.Minute
+
+// ReproResult records the checks of a --repro-first run: the reproduction
+// must fail before the fix and pass after it.
+type ReproResult struct {
+	Command      string `json:"command"`
+	FailedBefore bool   `json:"failed_before"`
+	PassedAfter  bool   `json:"passed_after"`
+	Output       string `json:"output,omitempty"` // output of the last run
+}
+
+// addReproTask puts the task that writes the reproducing test at the front
+// of the plan.
+func (o *Orchestrator) addReproTask() error {
+	repro := o.state.Plan.Reproduction
+	if repro == nil {
+		return fmt.Errorf("--repro-first: the plan has no command to run the reproduction")
+	}
+
+	task := state.Task{
+		Description: fmt.Sprintf("Write a test that reproduces the bug described in the request. Do not fix the bug yet: the test must fail because of it. It must run with `%s`.", repro.Command),
+		Status:      "pending",
+	}
+	o.state.Plan.Tasks = append([]state.Task{task}, o.state.Plan.Tasks...)
+	state.AssignTaskIDs(o.state.Plan.Tasks)
+	repro.TaskID = o.state.Plan.Tasks[0].ID
+	return nil
+}
+
+// runRepro runs the reproduction command and reports whether it passed.
+func (o *Orchestrator) runRepro() (bool, error) {
+	command := o.state.Plan.Reproduction.Command
+	if o.result.Repro == nil {
+		o.result.Repro = &ReproResult{Command: command}
+	}
+	o.out.Blue("\n🐞 Running reproduction: %s\n", command)
+	exitCode, output, err := runShell(o.state.WorkingDir, command, nil, reproTimeout)
+	if err != nil {
+		return false, fmt.Errorf("reproduction: %w", err)
+	}
+	o.result.Repro.Output = output
+	if strings.TrimSpace(output) != "" {
+		o.out.Println(strings.TrimRight(output, "\n"))
+	}
+	return exitCode == 0, nil
+}
+
+// checkReproFails confirms that the freshly written reproduction fails.
+// A reproduction that passes does not show the bug, so the run stops.
+func (o *Orchestrator) checkReproFails(task *state.Task) error {
+	if task.Status != "completed" {
+		return fmt.Errorf("--repro-first: the reproduction task did not complete, so the bug was not reproduced")
+	}
+	passed, err := o.runRepro()
+	if err != nil {
+		return err
+	}
+	o.result.Repro.FailedBefore = !passed
+	if passed {
+		o.state.MarkTaskFailed(task.ID, "the reproduction passes before the fix")
+		return fmt.Errorf("--repro-first: `%s` passes before the fix, so it does not reproduce the bug", o.result.Repro.Command)
+	}
+	o.out.Green("  ✅ Reproduction fails as expected\n")
+	return nil
+}
+
+// checkReproPasses confirms that the reproduction passes after the fix.
+func (o *Orchestrator) checkReproPasses() error {
+	resumed := o.result.Repro == nil
+	passed, err := o.runRepro()
+	if err != nil {
+		return err
+	}
+	if resumed {
+		// The failing check ran before the resume; the task only stays
+		// completed when it succeeded
+		for _, t := range o.state.Plan.Tasks {
+			if t.ID == o.state.Plan.Reproduction.TaskID {
+				o.result.Repro.FailedBefore = t.Status == "completed"
+			}
+		}
+	}
+	o.result.Repro.PassedAfter = passed
+	if passed {
+		o.out.Green("  ✅ Reproduction passes after the fix\n")
+	} else {
+		o.out.Red("  ❌ Reproduction still fails after the fix\n")
+	}
+	return nil
+}
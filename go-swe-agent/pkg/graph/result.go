@@ -0,0 +1,85 @@
+package graph
+
+import "github.com/openswe/go-swe-agent/pkg/state"
+
+// Termination reasons reported in RunResult.
+const (
+	TerminationCompleted    = "completed"     // every phase ran to the end
+	TerminationEstimateOnly = "estimate_only" // stopped after the cost estimate
+	TerminationBlocked      = "blocked"       // the model asked for human input
+	TerminationNoChanges    = "no_changes"    // the request was already satisfied
+	TerminationStalled      = "stalled"       // the watchdog saw no progress
+	TerminationPlanEmitted  = "plan_emitted"  // stopped after writing the plan with --emit-plan
+	TerminationRejected     = "plan_rejected" // the plan was rejected at the confirmation prompt
+)
+
+// RunResult summarizes the outcome of an orchestrator run.
+type RunResult struct {
+	Termination   string                   `json:"termination"`
+	BlockedReason string                   `json:"blocked_reason,omitempty"`
+	Completed     int                      `json:"completed"`
+	Partial       int                      `json:"partial,omitempty"` // partial tasks with remaining work left undone
+	Failed        int                      `json:"failed"`
+	Incomplete    int                      `json:"incomplete,omitempty"` // tasks stopped by the turn or tool call limit
+	Pending       int                      `json:"pending"`
+	Blocked       int                      `json:"blocked,omitempty"`
+	Skipped       int                      `json:"skipped,omitempty"`    // pending tasks left out by --only-tasks/--skip-tasks
+	Superseded    int                      `json:"superseded,omitempty"` // held tasks replaced by a re-plan
+	Turns         int                      `json:"turns"`
+	ToolCalls     int                      `json:"tool_calls"`
+	FilesChanged  int                      `json:"files_changed"` // existing files modified
+	FilesCreated  int                      `json:"files_created"`
+	DryRunFiles   []string                 `json:"dry_run_files,omitempty"` // files a --dry-run would have written
+	DiffStat      *DiffStat                `json:"diff_stat,omitempty"`     // changed lines per directory
+	DiffPath      string                   `json:"diff_path,omitempty"`     // where --diff-output wrote the diff of the run
+	Criteria      []state.CriterionResult  `json:"criteria,omitempty"`
+	Estimate      *CostEstimate            `json:"estimate,omitempty"`
+	DiffHook      *DiffHookResult          `json:"diff_hook,omitempty"`
+	Repro         *ReproResult             `json:"repro,omitempty"`
+	Verify        *VerifyResult            `json:"verify,omitempty"`
+	Repairs       []RepairAttempt          `json:"repairs,omitempty"` // passes to fix a failing final check, with --repair-attempts
+	Replans       []ReplanRound            `json:"replans,omitempty"` // revisions of the plan after too many failures, with --max-replans
+	Tools         state.ToolUsage          `json:"tools,omitempty"`
+	Usage         *UsageSummary            `json:"usage,omitempty"`       // model calls and tokens per phase, and their cost
+	HumanInput    []state.Clarification    `json:"human_input,omitempty"` // unanswered questions from blocked tasks
+	Stall         *state.StallReport       `json:"stall,omitempty"`
+	Scratch       []ScratchFile            `json:"scratch,omitempty"` // left-over files the agent created
+	Gitignore     *GitignoreSuggestion     `json:"gitignore,omitempty"`
+	Scope         *ScopeResult             `json:"scope,omitempty"`        // changes outside the request's target files
+	Explanations  []state.ErrorExplanation `json:"explanations,omitempty"` // why tasks failed, with --explain-errors
+}
+
+// UnmetCriteria returns the acceptance criteria that were not met.
+func (r *RunResult) UnmetCriteria() []state.CriterionResult {
+	var unmet []state.CriterionResult
+	for _, c := range r.Criteria {
+		if !c.Met {
+			unmet = append(unmet, c)
+		}
+	}
+	return unmet
+}
+
+// Success reports whether the run was not blocked or stalled, no task is waiting for
+// human input, left work undone or ran out of turns, every acceptance criterion was met, the diff hook and the
+// build/test check, if any, passed, the reproduction, if any, passes
+// after the fix and the changes stayed within the request's file scope,
+// or were reverted.
+func (r *RunResult) Success() bool {
+	if r.Termination == TerminationBlocked || r.Termination == TerminationStalled || r.Blocked > 0 || r.Partial > 0 || r.Incomplete > 0 {
+		return false
+	}
+	if r.Repro != nil && !r.Repro.PassedAfter {
+		return false
+	}
+	if r.DiffHook != nil && !r.DiffHook.Passed {
+		return false
+	}
+	if r.Verify != nil && r.Verify.Runs > 0 && !r.Verify.Passed {
+		return false
+	}
+	if r.Scope != nil && !r.Scope.Passed {
+		return false
+	}
+	return len(r.UnmetCriteria()) == 0
+}
@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// broadSystemDirs are top-level directories no project lives directly in.
+var broadSystemDirs = []string{
+	"/bin", "/boot", "/dev", "/etc", "/home", "/lib", "/lib64", "/opt",
+	"/proc", "/sbin", "/srv", "/sys", "/tmp", "/usr", "/var",
+	"/Applications", "/Library", "/System", "/Users", "/Volumes",
+}
+
+// broadHomeDirs are folders in the home directory that collect many
+// unrelated things rather than a single project.
+var broadHomeDirs = []string{"Desktop", "Documents", "Downloads"}
+
+// maxSuggestedDirs caps the projects suggested when refusing a directory.
+const maxSuggestedDirs = 3
+
+// checkWorkingDir refuses directories too broad for an agent that changes
+// files: the filesystem root, the home directory or one containing it, and
+// system or catch-all folders.
+func checkWorkingDir(dir string) error {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		resolved = dir
+	}
+	resolved = filepath.Clean(resolved)
+
+	reason := broadDirReason(resolved)
+	if reason == "" {
+		return nil
+	}
+	msg := fmt.Sprintf("refusing to run in %s: it is %s, too broad a tree for autonomous changes", dir, reason)
+	if suggestions := projectDirsIn(resolved); len(suggestions) > 0 {
+		msg += fmt.Sprintf(". Point --dir at the project instead, e.g. %s", strings.Join(suggestions, " or "))
+	} else {
+		msg += ". Point --dir at the project to change"
+	}
+	return fmt.Errorf("%s, or pass --allow-unsafe-dir if this is intended", msg)
+}
+
+// broadDirReason describes why dir is too broad, or returns "".
+func broadDirReason(dir string) string {
+	if filepath.Dir(dir) == dir {
+		return "the filesystem root"
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		if resolved, err := filepath.EvalSymlinks(home); err == nil {
+			home = resolved
+		}
+		home = filepath.Clean(home)
+		if dir == home {
+			return "your home directory"
+		}
+		if rel, err := filepath.Rel(dir, home); err == nil && !strings.HasPrefix(rel, "..") {
+			return "a directory containing your home directory"
+		}
+		for _, name := range broadHomeDirs {
+			if dir == filepath.Join(home, name) {
+				return fmt.Sprintf("your %s folder", name)
+			}
+		}
+	}
+	for _, system := range broadSystemDirs {
+		if dir == system {
+			return "a system directory"
+		}
+	}
+	return ""
+}
+
+// projectDirsIn returns a few subdirectories of dir that are git
+// repositories, as narrower alternatives.
+func projectDirsIn(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var projects []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+			projects = append(projects, path)
+			if len(projects) == maxSuggestedDirs {
+				break
+			}
+		}
+	}
+	return projects
+}
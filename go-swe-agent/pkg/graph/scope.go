@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// What happens to changes outside the request's file scope.
+const (
+	ScopeWarn   = "warn"   // report them (default)
+	ScopeFail   = "fail"   // report them and fail the run
+	ScopeRevert = "revert" // undo them; the run fails if that is not possible
+)
+
+// ScopeViolation is a file the run changed outside the request's scope.
+type ScopeViolation struct {
+	Path     string `json:"path"`
+	Reason   string `json:"reason"`
+	Reverted bool   `json:"reverted,omitempty"`
+}
+
+// ScopeResult records the check of the changed files against the
+// request's target and forbidden files.
+type ScopeResult struct {
+	Policy     string           `json:"policy"`
+	Violations []ScopeViolation `json:"violations,omitempty"`
+	Passed     bool             `json:"passed"`
+}
+
+// checkScope compares the files in the journal with the request's file
+// scope, and reports, fails on or reverts the ones outside it.
+func (o *Orchestrator) checkScope() {
+	if o.state.Scope.Empty() {
+		return
+	}
+	policy := o.opts.ScopeViolations
+	if policy == "" {
+		policy = ScopeWarn
+	}
+	scope := &ScopeResult{Policy: policy, Passed: true}
+	o.result.Scope = scope
+
+	var out []state.FileChange
+	for _, c := range o.state.Journal.Files {
+		if reason := o.state.Scope.Violation(c.Path); reason != "" {
+			scope.Violations = append(scope.Violations, ScopeViolation{Path: c.Path, Reason: reason})
+			out = append(out, c)
+		}
+	}
+	if len(out) == 0 {
+		o.out.Green("\n🎯 All changed files are within the request's scope\n")
+		return
+	}
+
+	o.out.Red("\n🎯 Files changed outside the request's scope:\n")
+	for _, v := range scope.Violations {
+		o.out.Printf("   %s (%s)\n", v.Path, v.Reason)
+	}
+	switch policy {
+	case ScopeWarn:
+		return
+	case ScopeFail:
+		scope.Passed = false
+		return
+	}
+
+	for i, c := range out {
+		if err := o.revertChange(c); err != nil {
+			o.out.Yellow("   ⚠️  %v\n", err)
+			scope.Passed = false
+			continue
+		}
+		scope.Violations[i].Reverted = true
+		o.state.Journal.Forget(c.Path)
+		o.out.Green("   ↩️  Reverted %s\n", c.Path)
+	}
+}
+
+// revertChange undoes the agent's change to a file: a created file is
+// removed, and a modified one is restored from the last commit.
+func (o *Orchestrator) revertChange(c state.FileChange) error {
+	// Files changed in a sandbox cannot be restored from here
+	if o.opts.ToolBackend != nil {
+		return fmt.Errorf("cannot revert %s in a sandbox", c.Path)
+	}
+	if c.Created {
+		if err := os.Remove(filepath.Join(o.state.WorkingDir, c.Path)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", c.Path, err)
+		}
+		return nil
+	}
+	if _, err := gitOutput(o.state.WorkingDir, "checkout", "HEAD", "--", filepath.ToSlash(c.Path)); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", c.Path, err)
+	}
+	return nil
+}
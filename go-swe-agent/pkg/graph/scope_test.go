@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openswe/go-swe-agent/internal/llmtest"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// runScoped runs a one-task plan that writes the given files, with a scope
+// that only allows changes to main.go.
+func runScoped(t *testing.T, policy string, files ...string) (string, *RunResult) {
+	t.Helper()
+	dir := t.TempDir()
+	var turns [][]string
+	for _, file := range files {
+		turns = append(turns, []string{llmtest.ToolUse("w-"+file, "write_file", `{"path":"`+file+`","content":"package main\n"}`)})
+	}
+	turns = append(turns, []string{llmtest.ToolUse("done", "complete_task", `{"summary":"written"}`)})
+	plan := &state.Plan{Tasks: []state.Task{{Description: "Write the files", Status: "pending"}}}
+	state.AssignTaskIDs(plan.Tasks)
+	request := &state.Request{Description: "write main.go", TargetFiles: []string{"main.go"}}
+
+	var out bytes.Buffer
+	o, err := NewOrchestrator(dir, request, Options{
+		Client:          &llmtest.Client{Turns: turns},
+		PlanIn:          &state.PlanFile{Request: request, Plan: plan},
+		ScopeViolations: policy,
+		AllowUnsafeDir:  true,
+		Output:          &out,
+		StallWindow:     -1,
+	})
+	if err != nil {
+		t.Fatalf("NewOrchestrator: %v", err)
+	}
+	result, err := o.Run()
+	if err != nil {
+		t.Fatalf("Run: %v\n%s", err, out.String())
+	}
+	if result.Scope == nil {
+		t.Fatalf("no scope check in the result\n%s", out.String())
+	}
+	return dir, result
+}
+
+func TestScopeWithinTargets(t *testing.T) {
+	_, result := runScoped(t, ScopeFail, "main.go")
+	if !result.Scope.Passed || len(result.Scope.Violations) != 0 {
+		t.Errorf("scope = %+v, want it passed", result.Scope)
+	}
+}
+
+func TestScopeFail(t *testing.T) {
+	dir, result := runScoped(t, ScopeFail, "main.go", "extra.go")
+	if result.Scope.Passed {
+		t.Error("scope passed with a file outside the targets")
+	}
+	if v := result.Scope.Violations; len(v) != 1 || v[0].Path != "extra.go" || v[0].Reason != "not a target file" {
+		t.Errorf("violations = %+v, want extra.go", v)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "extra.go")); err != nil {
+		t.Errorf("extra.go was not kept: %v", err)
+	}
+}
+
+func TestScopeRevertRemovesCreatedFiles(t *testing.T) {
+	dir, result := runScoped(t, ScopeRevert, "main.go", "extra.go")
+	if !result.Scope.Passed {
+		t.Errorf("scope = %+v, want the violation reverted", result.Scope)
+	}
+	if v := result.Scope.Violations; len(v) != 1 || !v[0].Reverted {
+		t.Errorf("violations = %+v, want extra.go reverted", v)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "extra.go")); !os.IsNotExist(err) {
+		t.Errorf("extra.go was not removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main.go")); err != nil {
+		t.Errorf("main.go was removed: %v", err)
+	}
+}
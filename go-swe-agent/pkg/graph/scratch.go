@@ -0,0 +1,154 @@
+package graph
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Why a file the agent created is offered for removal.
+const (
+	scratchNamed        = "looks like scratch output"
+	scratchUnreferenced = "created by a command, not mentioned by any task"
+)
+
+// ScratchFile is a file the agent created during the run that looks left
+// over from its work rather than part of the change.
+type ScratchFile struct {
+	Path    string `json:"path"` // relative to the working directory
+	Reason  string `json:"reason"`
+	Removed bool   `json:"removed"`
+}
+
+// scratchPatterns match the base names of logs, captured output, backups
+// and editor leftovers.
+var scratchPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\.(log|out|tmp|temp|bak|orig|rej|swp)$`),
+	regexp.MustCompile(`~$`),
+	regexp.MustCompile(`^(debug|scratch|tmp|temp|test_output|output)([._-]|$)`),
+	regexp.MustCompile(`[._-](backup|copy|old)(\.|$)`),
+}
+
+func looksLikeScratch(rel string) bool {
+	name := strings.ToLower(path.Base(rel))
+	for _, re := range scratchPatterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// untrackedFiles returns the files in dir that git neither tracks nor
+// ignores, or nil when dir is not a git repository.
+func untrackedFiles(dir string) map[string]bool {
+	out, err := gitOutput(dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil
+	}
+	files := make(map[string]bool)
+	for _, file := range strings.Split(strings.TrimSpace(out), "\n") {
+		if file != "" {
+			files[file] = true
+		}
+	}
+	return files
+}
+
+// findScratch returns the files created during the run that look left over.
+// Files written with write_file are deliberate, so only their names count;
+// new files from commands also count when no task summary mentions them.
+func (o *Orchestrator) findScratch() []ScratchFile {
+	dir := o.state.WorkingDir
+	var found []ScratchFile
+	seen := make(map[string]bool)
+	for _, c := range o.state.Journal.Files {
+		seen[filepath.ToSlash(c.Path)] = true
+		if c.Created && looksLikeScratch(c.Path) {
+			found = append(found, ScratchFile{Path: c.Path, Reason: scratchNamed})
+		}
+	}
+
+	if o.untrackedAtStart != nil {
+		var summaries strings.Builder
+		for _, task := range o.state.CompletedTasks {
+			summaries.WriteString(task.Output)
+			summaries.WriteString("\n")
+		}
+		var created []string
+		for file := range untrackedFiles(dir) {
+			// Build output and dependencies belong in .gitignore instead
+			if _, artifact := artifactEntry(file); artifact {
+				continue
+			}
+			if !o.untrackedAtStart[file] && !seen[file] {
+				created = append(created, file)
+			}
+		}
+		sort.Strings(created)
+		for _, file := range created {
+			switch {
+			case looksLikeScratch(file):
+				found = append(found, ScratchFile{Path: filepath.FromSlash(file), Reason: scratchNamed})
+			case !strings.Contains(summaries.String(), path.Base(file)):
+				found = append(found, ScratchFile{Path: filepath.FromSlash(file), Reason: scratchUnreferenced})
+			}
+		}
+	}
+
+	// Some were already cleaned up by the agent itself
+	var existing []ScratchFile
+	for _, f := range found {
+		if _, err := os.Stat(filepath.Join(dir, f.Path)); err == nil {
+			existing = append(existing, f)
+		}
+	}
+	return existing
+}
+
+// cleanScratch shows the files the agent left over and removes them with
+// --clean-scratch, or when the user agrees in interactive mode.
+func (o *Orchestrator) cleanScratch() {
+	// Files created in a sandbox never reach this machine
+	if o.opts.ToolBackend != nil {
+		return
+	}
+	scratch := o.findScratch()
+	if len(scratch) == 0 {
+		return
+	}
+
+	o.out.Yellow("\n🧹 Files created during the run that look left over:\n")
+	for _, f := range scratch {
+		o.out.Printf("   %s (%s)\n", f.Path, f.Reason)
+	}
+	remove := o.opts.CleanScratch
+	if !remove && o.input != nil {
+		answer, err := o.ask("Remove these files? [y/N]")
+		answer = strings.ToLower(answer)
+		remove = err == nil && (answer == "y" || answer == "yes")
+	}
+	if !remove {
+		if o.input == nil {
+			o.out.Println("   Kept; use --clean-scratch to remove such files")
+		}
+		o.result.Scratch = scratch
+		return
+	}
+
+	removed := 0
+	for i, f := range scratch {
+		if err := os.Remove(filepath.Join(o.state.WorkingDir, f.Path)); err != nil {
+			o.out.Yellow("   ⚠️  %v\n", err)
+			continue
+		}
+		scratch[i].Removed = true
+		o.state.Journal.Forget(f.Path)
+		removed++
+	}
+	o.out.Green("   Removed %d file(s)\n", removed)
+	o.result.Scratch = scratch
+}
@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// taskSelection restricts execution to part of the plan. Tasks are named by
+// their 1-based number in the displayed plan.
+type taskSelection struct {
+	only map[int]bool
+	skip map[int]bool
+}
+
+func newTaskSelection(only, skip []int) taskSelection {
+	s := taskSelection{skip: make(map[int]bool)}
+	if len(only) > 0 {
+		s.only = make(map[int]bool)
+		for _, n := range only {
+			s.only[n] = true
+		}
+	}
+	for _, n := range skip {
+		s.skip[n] = true
+	}
+	return s
+}
+
+// active reports whether any selection was requested.
+func (s taskSelection) active() bool {
+	return s.only != nil || len(s.skip) > 0
+}
+
+// selected reports whether task number n should run.
+func (s taskSelection) selected(n int) bool {
+	if s.only != nil && !s.only[n] {
+		return false
+	}
+	return !s.skip[n]
+}
+
+// validate checks that every task number exists in a plan of total tasks.
+func (s taskSelection) validate(total int) error {
+	for _, set := range []map[int]bool{s.only, s.skip} {
+		for n := range set {
+			if n < 1 || n > total {
+				return fmt.Errorf("task %d does not exist; the plan has %d tasks", n, total)
+			}
+		}
+	}
+	return nil
+}
+
+// numbers lists the selected task numbers in plan order.
+func (s taskSelection) numbers(total int) []int {
+	var numbers []int
+	for n := 1; n <= total; n++ {
+		if s.selected(n) {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers
+}
+
+// warnings flags selected tasks that follow an unfinished task left out of
+// the selection. Plans are ordered, so later tasks may build on it.
+func (s taskSelection) warnings(tasks []state.Task) []string {
+	var warnings []string
+	var missing []int
+	for i, task := range tasks {
+		n := i + 1
+		if !s.selected(n) {
+			if task.Status != "completed" {
+				missing = append(missing, n)
+			}
+			continue
+		}
+		if len(missing) > 0 && (task.Status == "pending" || task.Status == "in_progress") {
+			warnings = append(warnings, fmt.Sprintf("task %d runs after unfinished task(s) %s that are not selected", n, joinNumbers(missing)))
+		}
+	}
+	return warnings
+}
+
+func joinNumbers(numbers []int) string {
+	sorted := append([]int{}, numbers...)
+	sort.Ints(sorted)
+	parts := make([]string, len(sorted))
+	for i, n := range sorted {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ", ")
+}
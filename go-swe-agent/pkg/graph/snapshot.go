@@ -0,0 +1,192 @@
+package graph
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/tools"
+)
+
+const (
+	// maxSnapshotFileBytes is the largest file whose content a snapshot
+	// keeps; larger files are only hashed, so a change to them is reported
+	// without its lines
+	maxSnapshotFileBytes = 1 << 20
+
+	// maxSnapshotBytes bounds the content a snapshot keeps in total
+	maxSnapshotBytes = 64 << 20
+)
+
+// fileSnapshot records the files of a working directory that is not a git
+// repository when the run starts, so the run's changes can still be shown
+// as a diff.
+type fileSnapshot struct {
+	dir    string
+	ignore *tools.IgnoreRules
+	files  map[string]snapshotFile // by slash-separated path relative to dir
+}
+
+type snapshotFile struct {
+	hash    [sha256.Size]byte
+	binary  bool
+	content []byte // nil when binary or too large to keep
+}
+
+// isGitRepo reports whether dir is inside a git work tree.
+func isGitRepo(dir string) bool {
+	_, err := gitOutput(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil
+}
+
+// takeSnapshot records the files in dir, skipping the directories the
+// preflight analysis skips and paths hidden by ignore.
+func takeSnapshot(dir string, ignore *tools.IgnoreRules) (*fileSnapshot, error) {
+	s := &fileSnapshot{dir: dir, ignore: ignore}
+	files, err := s.scan(maxSnapshotBytes)
+	if err != nil {
+		return nil, err
+	}
+	s.files = files
+	return s, nil
+}
+
+// scan hashes the files in the snapshot's directory, keeping the content of
+// text files until budget bytes are kept.
+func (s *fileSnapshot) scan(budget int) (map[string]snapshotFile, error) {
+	files := make(map[string]snapshotFile)
+	err := tools.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(s.dir, path)
+		hidden, _ := s.ignore.Match(rel, d.IsDir())
+		if d.IsDir() {
+			if path != s.dir && (preflightSkipDirs[d.Name()] || hidden) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if hidden || !d.Type().IsRegular() {
+			return nil
+		}
+		file, err := readSnapshotFile(path, &budget)
+		if err != nil {
+			return nil
+		}
+		files[filepath.ToSlash(rel)] = file
+		return nil
+	})
+	return files, err
+}
+
+// readSnapshotFile hashes the file at path and keeps its content when it is
+// text, small enough and fits in what is left of budget.
+func readSnapshotFile(path string, budget *int) (snapshotFile, error) {
+	var file snapshotFile
+	f, err := os.Open(path)
+	if err != nil {
+		return file, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return file, err
+	}
+	if info.Size() > maxSnapshotFileBytes || info.Size() > int64(*budget) {
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return file, err
+		}
+		copy(file.hash[:], h.Sum(nil))
+		return file, nil
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return file, err
+	}
+	file.hash = sha256.Sum256(data)
+	if bytes.IndexByte(data, 0) >= 0 {
+		file.binary = true
+		return file, nil
+	}
+	file.content = data
+	*budget -= len(data)
+	return file, nil
+}
+
+// diff returns the changes since the snapshot as a unified diff: modified,
+// created and deleted files in path order. Binary files, and files too
+// large to have been kept, are reported as differing without their lines.
+func (s *fileSnapshot) diff() (string, error) {
+	now, err := s.scan(0)
+	if err != nil {
+		return "", fmt.Errorf("failed to scan working directory: %w", err)
+	}
+
+	var paths []string
+	for path, before := range s.files {
+		if after, ok := now[path]; !ok || after.hash != before.hash {
+			paths = append(paths, path)
+		}
+	}
+	for path := range now {
+		if _, ok := s.files[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var out strings.Builder
+	for _, path := range paths {
+		before, existed := s.files[path]
+		_, exists := now[path]
+
+		oldName, newName := path, path
+		var old, new []byte
+		if existed {
+			old = before.content
+		} else {
+			oldName = ""
+		}
+		if exists {
+			data, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(path)))
+			if err != nil {
+				return "", err
+			}
+			new = data
+		} else {
+			newName = ""
+		}
+
+		fmt.Fprintf(&out, "diff --git a/%s b/%s\n", path, path)
+		switch {
+		case existed && before.content == nil, bytes.IndexByte(new, 0) >= 0:
+			if before.binary || bytes.IndexByte(new, 0) >= 0 {
+				fmt.Fprintf(&out, "Binary files %s and %s differ\n", diffSide("a/", oldName), diffSide("b/", newName))
+			} else {
+				fmt.Fprintf(&out, "Files %s and %s differ (too large to diff)\n", diffSide("a/", oldName), diffSide("b/", newName))
+			}
+		default:
+			out.WriteString(tools.UnifiedDiff(oldName, newName, string(old), string(new)))
+		}
+	}
+	return out.String(), nil
+}
+
+// diffSide names one side of a diff, or /dev/null when the file does not
+// exist on that side.
+func diffSide(prefix, name string) string {
+	if name == "" {
+		return "/dev/null"
+	}
+	return prefix + name
+}
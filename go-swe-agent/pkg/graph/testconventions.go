@@ -0,0 +1,197 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/openswe/go-swe-agent/pkg/tools"
+)
+
+const (
+	// maxTestSamples caps the test files read per language
+	maxTestSamples = 5
+
+	// maxTestSampleBytes caps how much of each sampled test file is read
+	maxTestSampleBytes = 16 * 1024
+)
+
+// testStyle recognizes the test files of one language and the patterns
+// they follow.
+type testStyle struct {
+	language string
+	isTest   func(rel string) bool
+	traits   []testTrait
+}
+
+// testTrait is a pattern a test file may follow, recognized by its source.
+type testTrait struct {
+	description string
+	re          *regexp.Regexp
+}
+
+var (
+	jsSource   = regexp.MustCompile(`\.[cm]?[jt]sx?$`)
+	jsTestName = regexp.MustCompile(`\.(test|spec)\.[cm]?[jt]sx?$`)
+)
+
+var testStyles = []testStyle{
+	{
+		language: "Go",
+		isTest:   func(rel string) bool { return strings.HasSuffix(rel, "_test.go") },
+		traits: []testTrait{
+			{"table-driven tests (a slice of cases looped over)", regexp.MustCompile(`(?m)(\[\]struct\s*\{|range (tests|tt|cases|testCases)\b)`)},
+			{"subtests with t.Run", regexp.MustCompile(`\bt\.Run\(`)},
+			{"assertions with testify", regexp.MustCompile(`"github\.com/stretchr/testify/(assert|require)"`)},
+			{"plain t.Errorf/t.Fatalf assertions", regexp.MustCompile(`\bt\.(Errorf|Fatalf)\(`)},
+			{"external _test packages", regexp.MustCompile(`(?m)^package \w+_test$`)},
+			{"t.Parallel()", regexp.MustCompile(`\bt\.Parallel\(\)`)},
+		},
+	},
+	{
+		language: "JavaScript/TypeScript",
+		isTest: func(rel string) bool {
+			return jsSource.MatchString(rel) && (jsTestName.MatchString(rel) || strings.Contains("/"+rel, "/__tests__/"))
+		},
+		traits: []testTrait{
+			{"Vitest", regexp.MustCompile(`from ['"]vitest['"]`)},
+			{"Jest", regexp.MustCompile(`(from ['"]@jest/globals['"]|\bjest\.(fn|mock|spyOn)\()`)},
+			{"Mocha/Chai", regexp.MustCompile(`(from ['"]chai['"]|require\(['"]chai['"]\))`)},
+			{"Testing Library", regexp.MustCompile(`['"]@testing-library/`)},
+			{"describe/it blocks", regexp.MustCompile(`\bdescribe\(`)},
+			{"top-level test() calls", regexp.MustCompile(`(?m)^test\(`)},
+		},
+	},
+	{
+		language: "Python",
+		isTest: func(rel string) bool {
+			base := path.Base(rel)
+			return strings.HasSuffix(base, ".py") && (strings.HasPrefix(base, "test_") || strings.HasSuffix(base, "_test.py"))
+		},
+		traits: []testTrait{
+			{"pytest with plain assert statements", regexp.MustCompile(`(?m)^\s+assert `)},
+			{"pytest fixtures", regexp.MustCompile(`@pytest\.fixture`)},
+			{"pytest.mark.parametrize", regexp.MustCompile(`@pytest\.mark\.parametrize`)},
+			{"unittest.TestCase classes", regexp.MustCompile(`\bunittest\.TestCase\b|\(TestCase\)`)},
+			{"unittest.mock", regexp.MustCompile(`unittest\.mock|from mock import`)},
+		},
+	},
+	{
+		language: "Ruby",
+		isTest: func(rel string) bool {
+			return strings.HasSuffix(rel, "_spec.rb") || strings.HasSuffix(rel, "_test.rb")
+		},
+		traits: []testTrait{
+			{"RSpec with expect()", regexp.MustCompile(`\bexpect\(`)},
+			{"Minitest", regexp.MustCompile(`Minitest::Test`)},
+		},
+	},
+}
+
+// detectTestConventions samples a few existing test files per language and
+// describes how they are named, laid out and written, so that new tests
+// can follow suit. It returns "" when dir has no recognizable tests.
+func detectTestConventions(dir string, ignore *tools.IgnoreRules) string {
+	samples := make(map[string][]string)
+	counts := make(map[string]int)
+	tools.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		hidden, _ := ignore.Match(rel, d.IsDir())
+		if d.IsDir() {
+			if p != dir && (preflightSkipDirs[d.Name()] || hidden) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if hidden || !d.Type().IsRegular() {
+			return nil
+		}
+		for _, style := range testStyles {
+			if style.isTest(rel) {
+				counts[style.language]++
+				if len(samples[style.language]) < maxTestSamples {
+					samples[style.language] = append(samples[style.language], rel)
+				}
+				break
+			}
+		}
+		return nil
+	})
+	if len(samples) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Existing tests follow these conventions; new tests should match them:\n")
+	for _, style := range testStyles {
+		files := samples[style.language]
+		if len(files) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s: %d test file(s), %s", style.language, counts[style.language], testLayout(files))
+		if traits := sampleTraits(dir, files, style.traits); len(traits) > 0 {
+			fmt.Fprintf(&b, "; %s", strings.Join(traits, ", "))
+		}
+		fmt.Fprintf(&b, ". Examples: %s\n", strings.Join(files, ", "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// testLayout tells whether the sampled tests sit in a separate test
+// directory or next to the code they test.
+func testLayout(files []string) string {
+	separate := 0
+	for _, f := range files {
+		for _, part := range strings.Split(path.Dir(f), "/") {
+			if part == "test" || part == "tests" || part == "spec" || part == "__tests__" {
+				separate++
+				break
+			}
+		}
+	}
+	if separate*2 > len(files) {
+		return "kept in separate test directories"
+	}
+	return "kept next to the code they test"
+}
+
+// sampleTraits returns the traits that at least half of the sampled files
+// show, in the order they are listed.
+func sampleTraits(dir string, files []string, traits []testTrait) []string {
+	seen := make([]int, len(traits))
+	for _, f := range files {
+		content := readHead(filepath.Join(dir, filepath.FromSlash(f)), maxTestSampleBytes)
+		for i, trait := range traits {
+			if trait.re.MatchString(content) {
+				seen[i]++
+			}
+		}
+	}
+	var found []string
+	for i, trait := range traits {
+		if seen[i]*2 >= len(files) {
+			found = append(found, trait.description)
+		}
+	}
+	return found
+}
+
+func readHead(p string, limit int) string {
+	file, err := os.Open(p)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	buf := make([]byte, limit)
+	n, _ := io.ReadFull(file, buf)
+	return string(buf[:n])
+}
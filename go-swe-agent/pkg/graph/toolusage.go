@@ -0,0 +1,46 @@
+package graph
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+func (o *Orchestrator) displayToolUsage(usage state.ToolUsage) {
+	if len(usage) == 0 {
+		return
+	}
+	o.out.Blue("\n🧰 Tool usage:\n")
+	o.out.Printf("  %-14s %6s %7s %9s %10s\n", "tool", "calls", "failed", "time", "output")
+	for _, name := range usage.Names() {
+		stats := usage[name]
+		line := fmt.Sprintf("  %-14s %6d %7d %9s %10s", name, stats.Calls, stats.Failures,
+			stats.Duration.Round(10*time.Millisecond), formatBytes(stats.OutputBytes))
+		if stats.Failures*2 > stats.Calls {
+			// Mostly failing tools usually point at a prompt or setup problem
+			o.out.Yellow("%s\n", line)
+		} else {
+			o.out.Println(line)
+		}
+	}
+}
+
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// displayStall explains why the watchdog halted the run.
+func (o *Orchestrator) displayStall(report *state.StallReport) {
+	o.out.Red("\n🛑 Run halted: no progress in the last %d tool calls\n", report.Window)
+	o.out.Printf("  No task finished and no file changed while only %d distinct calls were made. Most repeated:\n", report.Distinct)
+	for _, c := range report.Calls {
+		o.out.Printf("  %4dx %s\n", c.Count, c.Call)
+	}
+}
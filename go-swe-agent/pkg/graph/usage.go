@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/llm"
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// UsageSummary is the model usage of a run and what it cost.
+type UsageSummary struct {
+	Phases       map[string]state.PhaseUsage `json:"phases"`
+	Calls        int                         `json:"calls"`
+	InputTokens  int                         `json:"input_tokens"`
+	OutputTokens int                         `json:"output_tokens"`
+	Cost         float64                     `json:"cost_usd,omitempty"` // zero when the model's price is unknown
+	PricingKnown bool                        `json:"pricing_known"`
+}
+
+// phaseOrder lists the phases in the order they run.
+var phaseOrder = []string{state.PhasePlanning, state.PhaseExecution, state.PhaseVerification}
+
+// pricing returns the price of the model in use, from Options.Pricing or
+// the built-in list prices.
+func (o *Orchestrator) pricing() (llm.Pricing, bool) {
+	model := o.client.Model()
+	if p, ok := o.opts.Pricing[model]; ok {
+		return p, true
+	}
+	return llm.PricingFor(model)
+}
+
+// usageSummary totals the usage recorded by every phase. Every provider
+// reports tokens the same way, so the totals do not depend on which one ran.
+func (o *Orchestrator) usageSummary() *UsageSummary {
+	if len(o.state.Usage) == 0 {
+		return nil
+	}
+	u := &UsageSummary{Phases: make(map[string]state.PhaseUsage, len(o.state.Usage))}
+	for phase, p := range o.state.Usage {
+		u.Phases[phase] = p
+		u.Calls += p.Calls
+		u.InputTokens += p.InputTokens
+		u.OutputTokens += p.OutputTokens
+	}
+	if pricing, ok := o.pricing(); ok {
+		u.PricingKnown = true
+		u.Cost = pricing.Cost(u.InputTokens, u.OutputTokens)
+	}
+	return u
+}
+
+// displayUsage prints the calls and tokens per phase and per executed task,
+// the totals, and their cost when the model's price is known.
+func (o *Orchestrator) displayUsage(u *UsageSummary) {
+	if u == nil {
+		return
+	}
+	o.out.Blue("\n🪙 Model usage:\n")
+	o.out.Printf("  %-14s %6s %10s %10s %9s\n", "phase", "calls", "input", "output", "time")
+	for _, phase := range usagePhases(u.Phases) {
+		p := u.Phases[phase]
+		o.out.Printf("  %-14s %6d %10d %10d %9s\n", phase, p.Calls, p.InputTokens, p.OutputTokens, p.Duration.Round(time.Second))
+	}
+	o.out.Printf("  %-14s %6d %10d %10d\n", "total", u.Calls, u.InputTokens, u.OutputTokens)
+
+	if o.state.Plan != nil {
+		var lines []string
+		for i, task := range o.state.Plan.Tasks {
+			if task.InputTokens+task.OutputTokens == 0 {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("    %d. %d in / %d out: %s", i+1, task.InputTokens, task.OutputTokens, task.Description))
+		}
+		if len(lines) > 0 {
+			o.out.Printf("  Per task:\n")
+			for _, line := range lines {
+				o.out.Println(line)
+			}
+		}
+	}
+
+	if u.PricingKnown {
+		o.out.Printf("  💵 Cost: $%.2f\n", u.Cost)
+	} else {
+		o.out.Printf("  💵 Cost: unknown (no pricing for model %s; set one with --price)\n", o.client.Model())
+	}
+}
+
+// usagePhases returns the phases in phases, known ones in the order they
+// run, then any others by name.
+func usagePhases(phases map[string]state.PhaseUsage) []string {
+	var names, others []string
+	known := make(map[string]bool, len(phaseOrder))
+	for _, phase := range phaseOrder {
+		known[phase] = true
+		if _, ok := phases[phase]; ok {
+			names = append(names, phase)
+		}
+	}
+	for phase := range phases {
+		if !known[phase] {
+			others = append(others, phase)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
@@ -0,0 +1,138 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cadences for the build/test check.
+const (
+	VerifyAuto = "auto" // time the first run, then pick each or end
+	VerifyEach = "each" // after every task
+	VerifyEnd  = "end"  // once, after all tasks
+)
+
+const (
+	// slowVerify is how long the first check may take before auto cadence
+	// stops running it after every task.
+	slowVerify = 2 * time.Minute
+
+	verifyTimeout = 30 * time.Minute
+
+	// maxVerifyFeedback bounds the failing output shown to the next task.
+	maxVerifyFeedback = 4000
+)
+
+// VerifyResult records the runs of the --verify-command build/test check.
+// The outcome is that of the last run.
+type VerifyResult struct {
+	Command  string        `json:"command"`
+	Cadence  string        `json:"cadence"`          // each or end once auto has decided
+	Reason   string        `json:"reason,omitempty"` // why auto chose the cadence
+	Runs     int           `json:"runs"`
+	Duration time.Duration `json:"first_run_ns"` // time taken by the first run
+	ExitCode int           `json:"exit_code"`
+	Passed   bool          `json:"passed"`
+	Output   string        `json:"output,omitempty"`
+}
+
+// verifyAfterTask runs the check after a task unless it is only run at the
+// end. Until auto cadence has timed a run, it runs after every task.
+func (o *Orchestrator) verifyAfterTask() {
+	v := o.result.Verify
+	if v == nil {
+		return
+	}
+	o.verifyStale = true
+	if v.Cadence != VerifyEnd {
+		o.runVerify()
+	}
+}
+
+// verifyFinal runs the check once more if anything ran since the last run.
+func (o *Orchestrator) verifyFinal() {
+	v := o.result.Verify
+	if v == nil || (!o.verifyStale && v.Runs > 0) {
+		return
+	}
+	o.runVerify()
+}
+
+// runVerify runs the check, records the outcome and passes a failure on to
+// the next task.
+func (o *Orchestrator) runVerify() {
+	v := o.result.Verify
+	o.out.Blue("\n🧪 Running build/test check: %s\n", v.Command)
+	start := time.Now()
+	exitCode, output, err := runShell(o.state.WorkingDir, v.Command, nil, verifyTimeout)
+	elapsed := time.Since(start)
+	if err != nil {
+		output = strings.TrimRight(output, "\n") + "\n" + err.Error()
+		if exitCode == 0 {
+			exitCode = -1
+		}
+	}
+
+	v.Runs++
+	v.ExitCode = exitCode
+	v.Passed = exitCode == 0
+	v.Output = output
+	o.verifyStale = false
+
+	if v.Passed {
+		o.out.Green("  ✅ Check passed in %s\n", elapsed.Round(100*time.Millisecond))
+		o.executor.VerifyFailure = ""
+	} else {
+		o.out.Red("  ❌ Check failed in %s (exit code %d)\n", elapsed.Round(100*time.Millisecond), exitCode)
+		o.executor.VerifyFailure = fmt.Sprintf("`%s` (exit code %d):\n%s", v.Command, exitCode, tailOutput(output, maxVerifyFeedback))
+	}
+
+	if v.Runs > 1 {
+		return
+	}
+	v.Duration = elapsed
+	if v.Cadence == VerifyAuto {
+		if elapsed > slowVerify {
+			v.Cadence = VerifyEnd
+			v.Reason = fmt.Sprintf("the first run took %s, over %s", elapsed.Round(100*time.Millisecond), slowVerify)
+		} else {
+			v.Cadence = VerifyEach
+			v.Reason = fmt.Sprintf("the first run took %s", elapsed.Round(100*time.Millisecond))
+		}
+		o.out.Cyan("  ⏱️  %s; checking %s\n", strings.ToUpper(v.Reason[:1])+v.Reason[1:], cadenceLabel(v.Cadence))
+	}
+}
+
+// displayVerify prints the summary line for the build/test check.
+func (o *Orchestrator) displayVerify(v *VerifyResult) {
+	if v == nil || v.Runs == 0 {
+		return
+	}
+	outcome := o.out.GreenString("passed")
+	if !v.Passed {
+		outcome = o.out.RedString("failed (exit code %d)", v.ExitCode)
+	}
+	o.out.Printf("  🧪 Build/test check: %s, `%s`, %d run(s), %s\n", outcome, v.Command, v.Runs, cadenceLabel(v.Cadence))
+}
+
+func cadenceLabel(cadence string) string {
+	if cadence == VerifyEnd {
+		return "only at the end"
+	}
+	return "after every task"
+}
+
+// tailOutput keeps the last max bytes of output, where test runners
+// usually report failures.
+func tailOutput(output string, max int) string {
+	output = strings.TrimRight(output, "\n")
+	if len(output) <= max {
+		return output
+	}
+	cut := len(output) - max
+	if i := strings.IndexByte(output[cut:], '\n'); i >= 0 {
+		cut += i + 1
+	}
+	return "... (earlier output omitted)\n" + output[cut:]
+}
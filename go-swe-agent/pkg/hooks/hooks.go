@@ -0,0 +1,34 @@
+package hooks
+
+import "time"
+
+// EventType identifies a point in the run lifecycle.
+type EventType string
+
+const (
+	RunStarted   EventType = "run_started"
+	PlanReady    EventType = "plan_ready"
+	TaskFinished EventType = "task_finished"
+	RunFinished  EventType = "run_finished"
+)
+
+// Event is delivered to every registered Hook as the run progresses.
+type Event struct {
+	Type  EventType   `json:"event"`
+	RunID string      `json:"run_id"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// Hook receives lifecycle events. OnEvent is called synchronously from the
+// run, so implementations that do slow work must hand it off.
+type Hook interface {
+	OnEvent(event Event)
+}
+
+// Func adapts a plain function to the Hook interface.
+type Func func(event Event)
+
+func (f Func) OnEvent(event Event) {
+	f(event)
+}
@@ -7,12 +7,22 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"time"
 )
 
 type AnthropicClient struct {
 	apiKey  string
 	baseURL string
 	model   string
+
+	ClientSettings
+
+	// Throttle spaces requests out before the API's rate limits are hit.
+	// It is shared by all clients by default; nil disables it.
+	Throttle *Throttle
+
+	// unstructured is set once the API rejected structured output
+	unstructured bool
 }
 
 type AnthropicMessage struct {
@@ -40,20 +50,31 @@ type ToolResultContent struct {
 }
 
 type AnthropicRequest struct {
-	Model     string              `json:"model"`
-	MaxTokens int                 `json:"max_tokens"`
-	Messages  []AnthropicMessage  `json:"messages"`
-	System    string              `json:"system,omitempty"`
-	Tools     []Tool              `json:"tools,omitempty"`
+	Model         string             `json:"model"`
+	MaxTokens     int                `json:"max_tokens"`
+	Messages      []AnthropicMessage `json:"messages"`
+	System        string             `json:"system,omitempty"`
+	Tools         []Tool             `json:"tools,omitempty"`
+	ToolChoice    *ToolChoice        `json:"tool_choice,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Metadata      *AnthropicMetadata `json:"metadata,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+}
+
+// AnthropicMetadata is the request metadata accepted by the Anthropic API.
+type AnthropicMetadata struct {
+	UserID string `json:"user_id,omitempty"`
 }
 
 type AnthropicResponse struct {
-	ID      string               `json:"id"`
-	Type    string               `json:"type"`
-	Role    string               `json:"role"`
-	Content []json.RawMessage    `json:"content"`
-	Model   string               `json:"model"`
-	Usage   Usage                `json:"usage"`
+	ID           string            `json:"id"`
+	Type         string            `json:"type"`
+	Role         string            `json:"role"`
+	Content      []json.RawMessage `json:"content"`
+	Model        string            `json:"model"`
+	StopReason   string            `json:"stop_reason,omitempty"`
+	StopSequence string            `json:"stop_sequence,omitempty"` // set when StopReason is "stop_sequence"
+	Usage        Usage             `json:"usage"`
 }
 
 type Usage struct {
@@ -65,28 +86,93 @@ type Tool struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	InputSchema map[string]interface{} `json:"input_schema"`
+	Strict      bool                   `json:"strict,omitempty"`
+}
+
+// NewAnthropicClient creates a client for the Anthropic API using model,
+// or DefaultAnthropicModel when model is empty. It fails when
+// ANTHROPIC_API_KEY is not set.
+func NewAnthropicClient(endpointConfig EndpointConfig, model string) (*AnthropicClient, error) {
+	return NewAnthropicClientWithKey(endpointConfig, model, "")
 }
 
-func NewAnthropicClient() *AnthropicClient {
-	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+// NewAnthropicClientWithKey is NewAnthropicClient authenticating with
+// apiKey instead of ANTHROPIC_API_KEY, when apiKey is not empty.
+func NewAnthropicClientWithKey(endpointConfig EndpointConfig, model, apiKey string) (*AnthropicClient, error) {
+	if apiKey == "" {
+		apiKey = os.Getenv("ANTHROPIC_API_KEY")
+	}
 	if apiKey == "" {
-		panic("ANTHROPIC_API_KEY environment variable is required")
+		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is required for provider %s", ProviderAnthropic)
 	}
 	
-	return &AnthropicClient{
-		apiKey:  apiKey,
-		baseURL: "https://api.anthropic.com/v1/messages",
-		model:   "claude-3-5-sonnet-20241022",
+	endpointConfig = endpointConfig.ForAnthropic()
+	if model == "" {
+		model = DefaultAnthropicModel
 	}
+	
+	return &AnthropicClient{
+		apiKey:   apiKey,
+		baseURL:  endpointConfig.Endpoint + "/v1/messages",
+		model:    model,
+		Throttle: sharedThrottle,
+	}, nil
+}
+
+func (c *AnthropicClient) Model() string {
+	return c.model
 }
 
 func (c *AnthropicClient) CreateMessage(messages []AnthropicMessage, system string, tools []Tool) (*AnthropicResponse, error) {
+	return c.CreateMessageWith(messages, system, tools, CallOptions{})
+}
+
+// CreateMessageWith is CreateMessage with structured output options. When
+// the API rejects them, the call is repeated without them, and later calls
+// leave them out.
+func (c *AnthropicClient) CreateMessageWith(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions) (*AnthropicResponse, error) {
+	return c.create(messages, system, tools, opts, nil)
+}
+
+// CreateMessageStream is CreateMessageWith with the response streamed:
+// handler is called with text and tool calls as they arrive, and the
+// whole response is returned once it is complete.
+func (c *AnthropicClient) CreateMessageStream(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions, handler StreamHandler) (*AnthropicResponse, error) {
+	return c.create(messages, system, tools, opts, &handler)
+}
+
+func (c *AnthropicClient) create(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions, handler *StreamHandler) (*AnthropicResponse, error) {
+	if opts.structured() && !c.unstructured {
+		response, err := c.send(messages, system, tools, opts, handler)
+		if err == nil || !rejectedRequest(err) {
+			return response, err
+		}
+		c.unstructured = true
+		c.Out.Yellow("  ⚠️  Structured output is not supported here; falling back to plain tool calls\n")
+	}
+	return c.send(messages, system, tools, CallOptions{MaxTokens: opts.MaxTokens}, handler)
+}
+
+// send makes one request, streaming the response to handler when it is
+// not nil.
+func (c *AnthropicClient) send(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions, handler *StreamHandler) (*AnthropicResponse, error) {
+	var beta string
+	if opts.Strict && opts.ToolChoice != nil {
+		tools = strictTools(tools, opts.ToolChoice.Name)
+		beta = structuredOutputsBeta
+	}
 	req := AnthropicRequest{
-		Model:     c.model,
-		MaxTokens: 8192,
-		Messages:  messages,
-		System:    system,
-		Tools:     tools,
+		Model:         c.model,
+		MaxTokens:     maxTokens(c.model, opts.MaxTokens),
+		Messages:      repairConversation(messages, c.Out),
+		System:        system,
+		Tools:         tools,
+		ToolChoice:    opts.ToolChoice,
+		StopSequences: c.StopSequences,
+		Stream:        handler != nil,
+	}
+	if userID := c.Tags.userID(); userID != "" {
+		req.Metadata = &AnthropicMetadata{UserID: userID}
 	}
 
 	jsonData, err := json.Marshal(req)
@@ -94,40 +180,75 @@ func (c *AnthropicClient) CreateMessage(messages []AnthropicMessage, system stri
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", c.baseURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	newRequest := func() (*http.Request, error) {
+		httpReq, err := http.NewRequest("POST", c.baseURL, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("x-api-key", c.apiKey)
+		httpReq.Header.Set("anthropic-version", "2023-06-01")
+		if beta != "" {
+			httpReq.Header.Set("anthropic-beta", beta)
+		}
+		httpReq.Header.Set("User-Agent", UserAgent)
+		if tags := c.Tags.String(); tags != "" {
+			httpReq.Header.Set(TagsHeader, tags)
+		}
+		return httpReq, nil
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", c.apiKey)
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
-
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	resp, err := sendRequest(newRequest, c.Throttle, c.Out)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusOK && handler != nil {
+		stream := &streamAccumulator{handler: *handler}
+		if err := stream.readEvents(resp.Body); err != nil {
+			return nil, err
+		}
+		anthropicResp, err := stream.result()
+		if err != nil {
+			return nil, err
+		}
+		c.observeRateLimits(resp.Header, anthropicResp.Usage.InputTokens+anthropicResp.Usage.OutputTokens)
+		return anthropicResp, nil
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		c.observeRateLimits(resp.Header, 0)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var anthropicResp AnthropicResponse
 	if err := json.Unmarshal(body, &anthropicResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	c.observeRateLimits(resp.Header, anthropicResp.Usage.InputTokens+anthropicResp.Usage.OutputTokens)
 
 	return &anthropicResp, nil
 }
 
+// observeRateLimits feeds the rate limit headers of a response to the
+// throttle. tokens is what the request used, as an estimate for the next.
+func (c *AnthropicClient) observeRateLimits(header http.Header, tokens int) {
+	c.Throttle.Observe(header, tokens, time.Now())
+}
+
 func (c *AnthropicClient) ParseContent(content []json.RawMessage) (string, []ToolUseContent, error) {
+	return parseContent(content)
+}
+
+// parseContent returns the text and tool calls of response content in the
+// Messages API format, which every client returns.
+func parseContent(content []json.RawMessage) (string, []ToolUseContent, error) {
 	var text string
 	var toolCalls []ToolUseContent
 
@@ -148,12 +269,25 @@ func (c *AnthropicClient) ParseContent(content []json.RawMessage) (string, []Too
 				text += textVal
 			}
 		case "tool_use":
-			var toolUse ToolUseContent
-			if err := json.Unmarshal(raw, &toolUse); err == nil {
-				toolCalls = append(toolCalls, toolUse)
-			}
+			toolCalls = append(toolCalls, parseToolUse(raw, base))
 		}
 	}
 
 	return text, toolCalls, nil
-}
\ No newline at end of file
+}
+
+// parseToolUse decodes a tool_use block. A block whose input can't be
+// decoded (e.g. cut off by max_tokens) is still returned with a nil Input,
+// so callers can ask the model to re-issue it instead of losing the call.
+func parseToolUse(raw json.RawMessage, base map[string]interface{}) ToolUseContent {
+	var toolUse ToolUseContent
+	if err := json.Unmarshal(raw, &toolUse); err == nil {
+		return toolUse
+	}
+
+	toolUse.Type = "tool_use"
+	toolUse.ID, _ = base["id"].(string)
+	toolUse.Name, _ = base["name"].(string)
+	toolUse.Input = nil
+	return toolUse
+}
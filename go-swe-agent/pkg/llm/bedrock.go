@@ -4,18 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 )
 
 // BedrockClient implements the same interface as AnthropicClient but uses AWS Bedrock
 type BedrockClient struct {
-	client  *bedrockruntime.Client
-	model   string
-	region  string
+	client   *bedrockruntime.Client
+	model    string
+	region   string
+	endpoint string
+
+	// Its Tags are added to the User-Agent of every request
+	ClientSettings
+
+	// unstructured is set once Bedrock rejected a forced tool choice
+	unstructured bool
 }
 
 // BedrockRequest matches Anthropic's API format for easier compatibility
@@ -25,50 +34,114 @@ type BedrockRequest struct {
 	Messages         []AnthropicMessage `json:"messages"`
 	System           string             `json:"system,omitempty"`
 	Tools            []Tool             `json:"tools,omitempty"`
+	ToolChoice       *ToolChoice        `json:"tool_choice,omitempty"`
+	StopSequences    []string           `json:"stop_sequences,omitempty"`
 }
 
 // BedrockResponse matches Anthropic's response format
 type BedrockResponse struct {
-	ID      string            `json:"id"`
-	Type    string            `json:"type"`
-	Role    string            `json:"role"`
-	Content []json.RawMessage `json:"content"`
-	Model   string            `json:"model"`
-	Usage   struct {
+	ID           string            `json:"id"`
+	Type         string            `json:"type"`
+	Role         string            `json:"role"`
+	Content      []json.RawMessage `json:"content"`
+	Model        string            `json:"model"`
+	StopReason   string            `json:"stop_reason,omitempty"`
+	StopSequence string            `json:"stop_sequence,omitempty"`
+	Usage        struct {
 		InputTokens  int `json:"input_tokens"`
 		OutputTokens int `json:"output_tokens"`
 	} `json:"usage"`
 }
 
-func NewBedrockClient() *BedrockClient {
-	region := os.Getenv("AWS_REGION")
-	if region == "" {
-		region = "us-west-2" // Default region
+// NewBedrockClient creates a Bedrock client using model, or
+// DefaultBedrockModel when model is empty. It fails when the AWS
+// configuration cannot be loaded.
+func NewBedrockClient(endpointConfig EndpointConfig, model string) (*BedrockClient, error) {
+	return NewBedrockClientWithCredentials(endpointConfig, model, nil)
+}
+
+// NewBedrockClientWithCredentials is NewBedrockClient signing requests
+// with credentials instead of those found in the environment and shared
+// config, when credentials is not nil.
+func NewBedrockClientWithCredentials(endpointConfig EndpointConfig, model string, credentials aws.CredentialsProvider) (*BedrockClient, error) {
+	endpointConfig = endpointConfig.ForBedrock()
+	if model == "" {
+		model = DefaultBedrockModel
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(region),
-	)
+	loadOptions := []func(*config.LoadOptions) error{config.WithRegion(endpointConfig.Region)}
+	if credentials != nil {
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(credentials))
+	}
+	cfg, err := config.LoadDefaultConfig(context.TODO(), loadOptions...)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to load AWS config: %v", err))
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
+	client := bedrockruntime.NewFromConfig(cfg, func(o *bedrockruntime.Options) {
+		if endpointConfig.Endpoint != "" {
+			o.BaseEndpoint = aws.String(endpointConfig.Endpoint)
+		}
+	})
+
 	return &BedrockClient{
-		client: bedrockruntime.NewFromConfig(cfg),
-		model:  "anthropic.claude-3-opus-20240229",
-		region: region,
-	}
+		client:   client,
+		model:    model,
+		region:   endpointConfig.Region,
+		endpoint: endpointConfig.Endpoint,
+	}, nil
+}
+
+// Model returns the Bedrock model ID used for requests
+func (c *BedrockClient) Model() string {
+	return c.model
 }
 
 // CreateMessage sends a message to Bedrock using the same interface as AnthropicClient
 func (c *BedrockClient) CreateMessage(messages []AnthropicMessage, system string, tools []Tool) (*AnthropicResponse, error) {
+	return c.CreateMessageWith(messages, system, tools, CallOptions{})
+}
+
+// CreateMessageWith is CreateMessage with structured output options.
+// Bedrock supports a forced tool choice but not strict tool schemas. When
+// the model rejects the tool choice, the call is repeated without it, and
+// later calls leave it out.
+func (c *BedrockClient) CreateMessageWith(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions) (*AnthropicResponse, error) {
+	return c.create(messages, system, tools, opts, nil)
+}
+
+// CreateMessageStream is CreateMessageWith with the response streamed
+// through InvokeModelWithResponseStream: handler is called with text and
+// tool calls as they arrive, and the whole response is returned once it is
+// complete.
+func (c *BedrockClient) CreateMessageStream(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions, handler StreamHandler) (*AnthropicResponse, error) {
+	return c.create(messages, system, tools, opts, &handler)
+}
+
+func (c *BedrockClient) create(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions, handler *StreamHandler) (*AnthropicResponse, error) {
+	if opts.ToolChoice != nil && !c.unstructured {
+		response, err := c.invoke(messages, system, tools, opts, handler)
+		if err == nil || !rejectedRequest(err) {
+			return response, err
+		}
+		c.unstructured = true
+		c.Out.Yellow("  ⚠️  Forced tool choice is not supported by %s; falling back to plain tool calls\n", c.model)
+	}
+	return c.invoke(messages, system, tools, CallOptions{MaxTokens: opts.MaxTokens}, handler)
+}
+
+// invoke sends one request, streaming the response to handler when it is
+// not nil. Of the structured output options, only the tool choice is sent.
+func (c *BedrockClient) invoke(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions, handler *StreamHandler) (*AnthropicResponse, error) {
 	// Build the request in Anthropic format
 	req := BedrockRequest{
 		AnthropicVersion: "bedrock-2023-05-31",
-		MaxTokens:        8192,
-		Messages:         messages,
+		MaxTokens:        maxTokens(c.model, opts.MaxTokens),
+		Messages:         repairConversation(messages, c.Out),
 		System:           system,
 		Tools:            tools,
+		ToolChoice:       opts.ToolChoice,
+		StopSequences:    c.StopSequences,
 	}
 
 	// Marshal the request
@@ -77,6 +150,10 @@ func (c *BedrockClient) CreateMessage(messages []AnthropicMessage, system string
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	if handler != nil {
+		return c.invokeStream(jsonData, handler)
+	}
+
 	// Call Bedrock InvokeModel API
 	input := &bedrockruntime.InvokeModelInput{
 		ModelId:     aws.String(c.model),
@@ -85,7 +162,7 @@ func (c *BedrockClient) CreateMessage(messages []AnthropicMessage, system string
 		Body:        jsonData,
 	}
 
-	resp, err := c.client.InvokeModel(context.TODO(), input)
+	resp, err := c.client.InvokeModel(context.TODO(), input, c.tagRequest)
 	if err != nil {
 		return nil, fmt.Errorf("bedrock invoke error: %w", err)
 	}
@@ -98,11 +175,13 @@ func (c *BedrockClient) CreateMessage(messages []AnthropicMessage, system string
 
 	// Convert to AnthropicResponse format
 	return &AnthropicResponse{
-		ID:      bedrockResp.ID,
-		Type:    bedrockResp.Type,
-		Role:    bedrockResp.Role,
-		Content: bedrockResp.Content,
-		Model:   c.model,
+		ID:           bedrockResp.ID,
+		Type:         bedrockResp.Type,
+		Role:         bedrockResp.Role,
+		Content:      bedrockResp.Content,
+		Model:        c.model,
+		StopReason:   bedrockResp.StopReason,
+		StopSequence: bedrockResp.StopSequence,
 		Usage: Usage{
 			InputTokens:  bedrockResp.Usage.InputTokens,
 			OutputTokens: bedrockResp.Usage.OutputTokens,
@@ -110,34 +189,56 @@ func (c *BedrockClient) CreateMessage(messages []AnthropicMessage, system string
 	}, nil
 }
 
-// ParseContent parses the response content - same implementation as AnthropicClient
-func (c *BedrockClient) ParseContent(content []json.RawMessage) (string, []ToolUseContent, error) {
-	var text string
-	var toolCalls []ToolUseContent
+// invokeStream sends a request body with InvokeModelWithResponseStream.
+// Each chunk of the stream is one Anthropic streaming event.
+func (c *BedrockClient) invokeStream(body []byte, handler *StreamHandler) (*AnthropicResponse, error) {
+	input := &bedrockruntime.InvokeModelWithResponseStreamInput{
+		ModelId:     aws.String(c.model),
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+		Body:        body,
+	}
 
-	for _, raw := range content {
-		var base map[string]interface{}
-		if err := json.Unmarshal(raw, &base); err != nil {
-			continue
-		}
+	resp, err := c.client.InvokeModelWithResponseStream(context.TODO(), input, c.tagRequest)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock invoke error: %w", err)
+	}
+	events := resp.GetStream()
+	defer events.Close()
 
-		contentType, ok := base["type"].(string)
+	stream := &streamAccumulator{handler: *handler}
+	for event := range events.Events() {
+		chunk, ok := event.(*types.ResponseStreamMemberChunk)
 		if !ok {
 			continue
 		}
-
-		switch contentType {
-		case "text":
-			if textVal, ok := base["text"].(string); ok {
-				text += textVal
-			}
-		case "tool_use":
-			var toolUse ToolUseContent
-			if err := json.Unmarshal(raw, &toolUse); err == nil {
-				toolCalls = append(toolCalls, toolUse)
-			}
+		if err := stream.add(chunk.Value.Bytes); err != nil {
+			return nil, err
 		}
 	}
+	if err := events.Err(); err != nil {
+		return nil, fmt.Errorf("bedrock stream error: %w", err)
+	}
+
+	response, err := stream.result()
+	if err != nil {
+		return nil, err
+	}
+	response.Model = c.model
+	return response, nil
+}
 
-	return text, toolCalls, nil
-}
\ No newline at end of file
+// tagRequest adds the agent and its request tags to the SDK's User-Agent,
+// e.g. "go-swe-agent/1.0 md/run#... md/phase#planning".
+func (c *BedrockClient) tagRequest(o *bedrockruntime.Options) {
+	product := strings.SplitN(UserAgent, "/", 2)
+	o.APIOptions = append(o.APIOptions, awsmiddleware.AddUserAgentKeyValue(product[0], product[1]))
+	for _, kv := range c.Tags.pairs() {
+		o.APIOptions = append(o.APIOptions, awsmiddleware.AddSDKAgentKeyValue(awsmiddleware.AdditionalMetadata, kv[0], kv[1]))
+	}
+}
+
+// ParseContent parses the response content - same implementation as AnthropicClient
+func (c *BedrockClient) ParseContent(content []json.RawMessage) (string, []ToolUseContent, error) {
+	return parseContent(content)
+}
@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+// Providers a Client can be created for.
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderBedrock   = "bedrock"
+	ProviderOpenAI    = "openai"
+)
+
+// Models used when none is given.
+const (
+	DefaultAnthropicModel = "claude-3-5-sonnet-20241022"
+	DefaultBedrockModel   = "anthropic.claude-3-opus-20240229"
+	DefaultOpenAIModel    = "gpt-4o"
+)
+
+// Client is a connection to a model through one of the providers.
+// AnthropicClient, BedrockClient and OpenAIClient implement it, so the
+// agents work with any of them.
+type Client interface {
+	// Model returns the model ID used for requests
+	Model() string
+
+	CreateMessage(messages []AnthropicMessage, system string, tools []Tool) (*AnthropicResponse, error)
+	CreateMessageWith(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions) (*AnthropicResponse, error)
+	CreateMessageStream(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions, handler StreamHandler) (*AnthropicResponse, error)
+	ParseContent(content []json.RawMessage) (string, []ToolUseContent, error)
+
+	// Settings returns the settings of the client, which callers change
+	// between requests
+	Settings() *ClientSettings
+}
+
+var (
+	_ Client = (*AnthropicClient)(nil)
+	_ Client = (*BedrockClient)(nil)
+	_ Client = (*OpenAIClient)(nil)
+)
+
+// ClientSettings are the settings shared by the clients of every provider.
+type ClientSettings struct {
+	// StopSequences end generation when the model emits any of them
+	StopSequences []string
+
+	// Out receives progress output; nil writes to standard output
+	Out *ui.Printer
+
+	// Tags are sent with every request for attribution by gateways
+	Tags RequestTags
+}
+
+// Settings returns s, so clients embedding ClientSettings implement
+// Client.Settings.
+func (s *ClientSettings) Settings() *ClientSettings {
+	return s
+}
+
+// NewClient creates a client for provider, Bedrock when it is empty, using
+// model, or the provider's default model when model is empty. Bedrock
+// requests are signed with credentials when it is not nil; Anthropic and
+// OpenAI requests carry apiKey, or ANTHROPIC_API_KEY or OPENAI_API_KEY
+// when it is empty. An unknown provider or model, a missing API key, or
+// an AWS configuration that cannot be loaded is an error.
+func NewClient(provider, model string, endpointConfig EndpointConfig, credentials aws.CredentialsProvider, apiKey string) (Client, error) {
+	if provider == "" {
+		provider = ProviderBedrock
+	}
+	if err := ValidateModel(provider, model); err != nil {
+		return nil, err
+	}
+	// Each constructor returns a typed nil on error, which must not become
+	// a non-nil Client
+	switch provider {
+	case ProviderAnthropic:
+		client, err := NewAnthropicClientWithKey(endpointConfig, model, apiKey)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case ProviderOpenAI:
+		client, err := NewOpenAIClientWithKey(endpointConfig, model, apiKey)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+	client, err := NewBedrockClientWithCredentials(endpointConfig, model, credentials)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// ValidateModel checks that provider is known and that model, unless
+// empty, is not a model ID of another provider. A model whose provider
+// cannot be told from its ID is left to the provider to accept.
+func ValidateModel(provider, model string) error {
+	if provider != ProviderAnthropic && provider != ProviderBedrock && provider != ProviderOpenAI {
+		return fmt.Errorf("unknown provider %q: use %s, %s or %s", provider, ProviderAnthropic, ProviderBedrock, ProviderOpenAI)
+	}
+	if model == "" {
+		return nil
+	}
+	if p := modelProvider(model); p != "" && p != provider {
+		return fmt.Errorf("model %s is a %s model ID, not a %s one; known %s models: %s", model, p, provider, provider, strings.Join(Models(provider), ", "))
+	}
+	return nil
+}
+
+// Models returns the known model IDs of provider, sorted.
+func Models(provider string) []string {
+	var models []string
+	for model := range modelMaxOutput {
+		if modelProvider(model) == provider {
+			models = append(models, model)
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+// modelProvider tells which provider a model ID is for, or "" when the ID
+// does not say: Bedrock IDs carry the "anthropic." vendor prefix, behind a
+// region such as "us." for cross-region inference profiles; OpenAI's are
+// GPT and o-series models; and Anthropic's start with "claude-".
+func modelProvider(model string) string {
+	switch {
+	case bedrockModelID.MatchString(model):
+		return ProviderBedrock
+	case openAIModelID.MatchString(model):
+		return ProviderOpenAI
+	case strings.HasPrefix(model, "claude-"):
+		return ProviderAnthropic
+	}
+	return ""
+}
+
+var (
+	bedrockModelID = regexp.MustCompile(`^([a-z-]+\.)?anthropic\.`)
+	openAIModelID  = regexp.MustCompile(`^(gpt-|chatgpt-|o[0-9])`)
+)
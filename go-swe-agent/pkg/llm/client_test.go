@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+func TestNewClientWithoutAPIKeyIsAnError(t *testing.T) {
+	for _, c := range []struct{ provider, env string }{
+		{ProviderAnthropic, "ANTHROPIC_API_KEY"},
+		{ProviderOpenAI, "OPENAI_API_KEY"},
+	} {
+		t.Setenv(c.env, "")
+		client, err := NewClient(c.provider, "", EndpointConfig{}, nil, "")
+		if err == nil {
+			t.Errorf("%s: no error without %s", c.provider, c.env)
+		}
+		if client != nil {
+			t.Errorf("%s: client = %#v, want nil", c.provider, client)
+		}
+	}
+}
+
+// A model the agent has no limits for is accepted, and gets the default
+// output limit.
+func TestNewClientUnknownModel(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "key")
+	client, err := NewClient(ProviderAnthropic, "claude-sonnet-4-20250514", EndpointConfig{}, nil, "")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if got := maxTokens(client.Model(), 0); got != DefaultMaxTokens {
+		t.Errorf("max tokens = %d, want the default %d", got, DefaultMaxTokens)
+	}
+}
+
+func TestValidateModel(t *testing.T) {
+	for _, c := range []struct {
+		provider, model string
+		ok              bool
+	}{
+		{ProviderBedrock, "anthropic.claude-3-opus-20240229", true},
+		{ProviderBedrock, "us.anthropic.claude-3-5-sonnet-20241022-v2:0", true},
+		{ProviderBedrock, "eu.anthropic.claude-3-haiku-20240307-v1:0", true},
+		{ProviderBedrock, "apac.anthropic.claude-sonnet-4-20250514-v1:0", true},
+		{ProviderBedrock, "meta.llama3-70b-instruct-v1:0", true},
+		{ProviderBedrock, "claude-3-opus-20240229", false},
+		{ProviderBedrock, "gpt-4o", false},
+		{ProviderAnthropic, "claude-3-5-haiku-20241022", true},
+		{ProviderAnthropic, "claude-opus-4-20250514", true},
+		{ProviderAnthropic, "us.anthropic.claude-3-5-sonnet-20241022-v2:0", false},
+		{ProviderAnthropic, "o3", false},
+		{ProviderOpenAI, "gpt-4o", true},
+		{ProviderOpenAI, "o1", true},
+		{ProviderOpenAI, "o3-mini", true},
+		{ProviderOpenAI, "o4-mini", true},
+		{ProviderOpenAI, "claude-3-opus-20240229", false},
+		{ProviderOpenAI, "anthropic.claude-3-opus-20240229", false},
+		{"vertex", "claude-3-opus-20240229", false},
+	} {
+		err := ValidateModel(c.provider, c.model)
+		if (err == nil) != c.ok {
+			t.Errorf("ValidateModel(%s, %s) = %v, want ok %v", c.provider, c.model, err, c.ok)
+		}
+	}
+}
+
+func TestMaxOutputTokensOfInferenceProfile(t *testing.T) {
+	if n, ok := MaxOutputTokens("us.anthropic.claude-3-opus-20240229"); !ok || n != 4096 {
+		t.Errorf("MaxOutputTokens = %d, %v; want the base model's 4096", n, ok)
+	}
+}
+
+// Each client uses the API key it is given, falling back to the
+// environment, so runs in one process can use different keys.
+func TestNewClientAPIKey(t *testing.T) {
+	for _, c := range []struct{ provider, env, header, prefix string }{
+		{ProviderAnthropic, "ANTHROPIC_API_KEY", "x-api-key", ""},
+		{ProviderOpenAI, "OPENAI_API_KEY", "Authorization", "Bearer "},
+	} {
+		t.Setenv(c.env, "from-env")
+		for _, key := range []string{"run-1", "run-2", ""} {
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get(c.header)
+				w.WriteHeader(http.StatusBadRequest)
+			}))
+			client, err := NewClient(c.provider, "", EndpointConfig{Endpoint: server.URL}, nil, key)
+			if err != nil {
+				t.Fatalf("%s: NewClient: %v", c.provider, err)
+			}
+			client.Settings().Out = ui.NewPrinter(io.Discard)
+			client.CreateMessage([]AnthropicMessage{{Role: "user", Content: "hello"}}, "", nil)
+			server.Close()
+
+			want := key
+			if want == "" {
+				want = "from-env"
+			}
+			if got != c.prefix+want {
+				t.Errorf("%s with key %q: sent %q, want %q", c.provider, key, got, c.prefix+want)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultAnthropicEndpoint = "https://api.anthropic.com"
+	DefaultOpenAIEndpoint    = "https://api.openai.com/v1"
+	DefaultBedrockRegion     = "us-west-2"
+)
+
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// EndpointConfig holds the network location of an LLM provider. Empty
+// fields are filled from the provider's environment variables and then
+// from built-in defaults, so --endpoint/--region flags always win.
+type EndpointConfig struct {
+	// Endpoint overrides the provider's base URL, e.g. an internal gateway
+	Endpoint string
+	// Region selects the provider region where applicable (Bedrock)
+	Region string
+}
+
+// Validate checks that an explicitly configured endpoint and region are
+// well formed.
+func (c EndpointConfig) Validate() error {
+	if c.Endpoint != "" {
+		u, err := url.Parse(c.Endpoint)
+		if err != nil {
+			return fmt.Errorf("invalid endpoint %q: %w", c.Endpoint, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid endpoint %q: scheme must be http or https", c.Endpoint)
+		}
+		if u.Host == "" {
+			return fmt.Errorf("invalid endpoint %q: missing host", c.Endpoint)
+		}
+	}
+	if c.Region != "" && !regionPattern.MatchString(c.Region) {
+		return fmt.Errorf("invalid region %q: expected a value like us-west-2", c.Region)
+	}
+	return nil
+}
+
+// ForAnthropic resolves the config for the Anthropic API, reading
+// ANTHROPIC_BASE_URL when no endpoint was given.
+func (c EndpointConfig) ForAnthropic() EndpointConfig {
+	if c.Endpoint == "" {
+		c.Endpoint = os.Getenv("ANTHROPIC_BASE_URL")
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = DefaultAnthropicEndpoint
+	}
+	c.Endpoint = strings.TrimRight(c.Endpoint, "/")
+	return c
+}
+
+// ForOpenAI resolves the config for the OpenAI API, reading
+// OPENAI_BASE_URL when no endpoint was given. As with OpenAI's own SDKs,
+// the endpoint includes the API version, e.g. https://gateway/v1.
+func (c EndpointConfig) ForOpenAI() EndpointConfig {
+	if c.Endpoint == "" {
+		c.Endpoint = os.Getenv("OPENAI_BASE_URL")
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = DefaultOpenAIEndpoint
+	}
+	c.Endpoint = strings.TrimRight(c.Endpoint, "/")
+	return c
+}
+
+// ForBedrock resolves the config for AWS Bedrock, reading AWS_REGION and
+// AWS_ENDPOINT_URL_BEDROCK_RUNTIME when not given. An empty endpoint
+// leaves endpoint resolution to the AWS SDK.
+func (c EndpointConfig) ForBedrock() EndpointConfig {
+	if c.Region == "" {
+		c.Region = os.Getenv("AWS_REGION")
+	}
+	if c.Region == "" {
+		c.Region = DefaultBedrockRegion
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = os.Getenv("AWS_ENDPOINT_URL_BEDROCK_RUNTIME")
+	}
+	c.Endpoint = strings.TrimRight(c.Endpoint, "/")
+	return c
+}
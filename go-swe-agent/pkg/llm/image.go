@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxImageBytes is the provider limit for a single image block.
+const maxImageBytes = 5 * 1024 * 1024
+
+var imageMediaTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// visionModels lists the models that accept image content blocks.
+var visionModels = map[string]bool{
+	"anthropic.claude-3-opus-20240229":          true,
+	"claude-3-opus-20240229":                    true,
+	"anthropic.claude-3-5-sonnet-20241022-v2:0": true,
+	"claude-3-5-sonnet-20241022":                true,
+	"anthropic.claude-3-haiku-20240307-v1:0":    true,
+	"claude-3-haiku-20240307":                   true,
+	"gpt-4o":                                    true,
+	"gpt-4o-mini":                               true,
+	"gpt-4.1":                                   true,
+	"gpt-4-turbo":                               true,
+}
+
+// SupportsVision reports whether model accepts image content blocks.
+func SupportsVision(model string) bool {
+	return visionModels[model]
+}
+
+type ImageContent struct {
+	Type   string      `json:"type"`
+	Source ImageSource `json:"source"`
+}
+
+type ImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// LoadImage reads an image file into a base64 image content block. The
+// media type is sniffed from the file contents, not its extension.
+func LoadImage(path string) (ImageContent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImageContent{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > maxImageBytes {
+		return ImageContent{}, fmt.Errorf("image %s is %d bytes, the limit is %d", filepath.Base(path), len(data), maxImageBytes)
+	}
+
+	mediaType := http.DetectContentType(data)
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	if !imageMediaTypes[mediaType] {
+		return ImageContent{}, fmt.Errorf("image %s has unsupported type %s (use PNG, JPEG, GIF or WebP)", filepath.Base(path), mediaType)
+	}
+
+	return ImageContent{
+		Type: "image",
+		Source: ImageSource{
+			Type:      "base64",
+			MediaType: mediaType,
+			Data:      base64.StdEncoding.EncodeToString(data),
+		},
+	}, nil
+}
@@ -0,0 +1,539 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// OpenAIClient calls the OpenAI chat completions API, or a gateway that
+// speaks it. Requests are translated from the Messages API format the
+// agents use, and responses back into it, so tool calls and their results
+// round-trip as tool_use and tool_result blocks.
+type OpenAIClient struct {
+	apiKey  string
+	baseURL string
+	model   string
+
+	// Its stop sequences are applied to the response text here, as the
+	// API does not say which one ended a response
+	ClientSettings
+
+	// Throttle spaces requests out before the API's rate limits are hit.
+	// It is shared by all OpenAI clients by default; nil disables it.
+	Throttle *Throttle
+
+	// unstructured is set once the API rejected a forced tool choice
+	unstructured bool
+}
+
+// openAIMessage is a message of the chat completions API, and the delta of
+// a streamed one.
+type openAIMessage struct {
+	Role       string           `json:"role,omitempty"`
+	Content    interface{}      `json:"content"` // a string, content parts, or null beside tool calls
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	Index    *int   `json:"index,omitempty"` // set in streamed deltas only
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+type openAIRequest struct {
+	Model               string          `json:"model"`
+	Messages            []openAIMessage `json:"messages"`
+	Tools               []openAITool    `json:"tools,omitempty"`
+	ToolChoice          interface{}     `json:"tool_choice,omitempty"`
+	MaxCompletionTokens int             `json:"max_completion_tokens"`
+	User                string          `json:"user,omitempty"`
+	Stream              bool            `json:"stream,omitempty"`
+	StreamOptions       *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+}
+
+// openAIResponse is a chat completion, or one chunk of a streamed one.
+type openAIResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		Delta        openAIMessage `json:"delta"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// messageBlock is a content block of the Messages API format, with the
+// fields of every block type the agents send.
+type messageBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	Source    ImageSource     `json:"source"`
+}
+
+// NewOpenAIClient creates a client for the OpenAI API using model, or
+// DefaultOpenAIModel when model is empty. It fails when OPENAI_API_KEY is
+// not set.
+func NewOpenAIClient(endpointConfig EndpointConfig, model string) (*OpenAIClient, error) {
+	return NewOpenAIClientWithKey(endpointConfig, model, "")
+}
+
+// NewOpenAIClientWithKey is NewOpenAIClient authenticating with apiKey
+// instead of OPENAI_API_KEY, when apiKey is not empty.
+func NewOpenAIClientWithKey(endpointConfig EndpointConfig, model, apiKey string) (*OpenAIClient, error) {
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is required for provider %s", ProviderOpenAI)
+	}
+
+	endpointConfig = endpointConfig.ForOpenAI()
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+
+	return &OpenAIClient{
+		apiKey:   apiKey,
+		baseURL:  endpointConfig.Endpoint + "/chat/completions",
+		model:    model,
+		Throttle: sharedOpenAIThrottle,
+	}, nil
+}
+
+func (c *OpenAIClient) Model() string {
+	return c.model
+}
+
+func (c *OpenAIClient) CreateMessage(messages []AnthropicMessage, system string, tools []Tool) (*AnthropicResponse, error) {
+	return c.CreateMessageWith(messages, system, tools, CallOptions{})
+}
+
+// CreateMessageWith is CreateMessage with structured output options. A
+// forced tool choice is sent as OpenAI's tool_choice; strict schemas are
+// not, as OpenAI's strict mode needs every property to be required. When
+// the API rejects the tool choice, the call is repeated without it, and
+// later calls leave it out.
+func (c *OpenAIClient) CreateMessageWith(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions) (*AnthropicResponse, error) {
+	return c.create(messages, system, tools, opts, nil)
+}
+
+// CreateMessageStream is CreateMessageWith with the response streamed:
+// handler is called with text as it arrives and with the tool calls once
+// the response is complete, as OpenAI does not mark where each one ends.
+func (c *OpenAIClient) CreateMessageStream(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions, handler StreamHandler) (*AnthropicResponse, error) {
+	return c.create(messages, system, tools, opts, &handler)
+}
+
+func (c *OpenAIClient) create(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions, handler *StreamHandler) (*AnthropicResponse, error) {
+	if opts.ToolChoice != nil && !c.unstructured {
+		response, err := c.send(messages, system, tools, opts, handler)
+		if err == nil || !rejectedRequest(err) {
+			return response, err
+		}
+		c.unstructured = true
+		c.Out.Yellow("  ⚠️  Forced tool choice is not supported by %s; falling back to plain tool calls\n", c.model)
+	}
+	return c.send(messages, system, tools, CallOptions{MaxTokens: opts.MaxTokens}, handler)
+}
+
+// send makes one request, streaming the response to handler when it is
+// not nil.
+func (c *OpenAIClient) send(messages []AnthropicMessage, system string, tools []Tool, opts CallOptions, handler *StreamHandler) (*AnthropicResponse, error) {
+	req := openAIRequest{
+		Model:               c.model,
+		Messages:            openAIMessages(repairConversation(messages, c.Out), system),
+		Tools:               openAITools(tools),
+		MaxCompletionTokens: maxTokens(c.model, opts.MaxTokens),
+		User:                c.Tags.userID(),
+		Stream:              handler != nil,
+	}
+	if len(tools) > 0 {
+		req.ToolChoice = openAIToolChoice(opts.ToolChoice)
+	}
+	if handler != nil {
+		req.StreamOptions = &struct {
+			IncludeUsage bool `json:"include_usage"`
+		}{IncludeUsage: true}
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	newRequest := func() (*http.Request, error) {
+		httpReq, err := http.NewRequest("POST", c.baseURL, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+		httpReq.Header.Set("User-Agent", UserAgent)
+		if tags := c.Tags.String(); tags != "" {
+			httpReq.Header.Set(TagsHeader, tags)
+		}
+		return httpReq, nil
+	}
+
+	resp, err := sendRequest(newRequest, c.Throttle, c.Out)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK && handler != nil {
+		stream := &openAIStream{onText: handler.Text}
+		if err := readServerSentEvents(resp.Body, stream.add); err != nil {
+			return nil, err
+		}
+		response, err := stream.result(c, *handler)
+		if err == nil {
+			c.Throttle.Observe(resp.Header, response.Usage.InputTokens+response.Usage.OutputTokens, time.Now())
+		}
+		return response, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		c.Throttle.Observe(resp.Header, 0, time.Now())
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var openAIResp openAIResponse
+	if err := json.Unmarshal(body, &openAIResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(openAIResp.Choices) == 0 {
+		return nil, fmt.Errorf("response has no choices")
+	}
+	var usage Usage
+	if openAIResp.Usage != nil {
+		usage = Usage{InputTokens: openAIResp.Usage.PromptTokens, OutputTokens: openAIResp.Usage.CompletionTokens}
+	}
+	c.Throttle.Observe(resp.Header, usage.InputTokens+usage.OutputTokens, time.Now())
+	choice := openAIResp.Choices[0]
+	return c.response(openAIResp.ID, choice.Message, choice.FinishReason, usage), nil
+}
+
+func (c *OpenAIClient) ParseContent(content []json.RawMessage) (string, []ToolUseContent, error) {
+	return parseContent(content)
+}
+
+// response translates a completion's message into a response of the
+// Messages API format. Text after a stop sequence is cut, along with the
+// tool calls, as generation would have ended there.
+func (c *OpenAIClient) response(id string, message openAIMessage, finishReason string, usage Usage) *AnthropicResponse {
+	response := &AnthropicResponse{
+		ID:         id,
+		Type:       "message",
+		Role:       "assistant",
+		Model:      c.model,
+		StopReason: openAIStopReason(finishReason),
+		Usage:      usage,
+	}
+	text, _ := message.Content.(string)
+	calls := message.ToolCalls
+	if i, sequence := firstStopSequence(text, c.StopSequences); i >= 0 {
+		text = text[:i]
+		calls = nil
+		response.StopReason = "stop_sequence"
+		response.StopSequence = sequence
+	}
+
+	if text != "" {
+		block, _ := json.Marshal(TextContent{Type: "text", Text: text})
+		response.Content = append(response.Content, block)
+	}
+	for _, call := range calls {
+		// Arguments cut off by the token limit are left out, so the call
+		// is reported without input and the model asked to send it again
+		use := map[string]interface{}{"type": "tool_use", "id": call.ID, "name": call.Function.Name}
+		if args := strings.TrimSpace(call.Function.Arguments); json.Valid([]byte(args)) {
+			use["input"] = json.RawMessage(args)
+		}
+		block, _ := json.Marshal(use)
+		response.Content = append(response.Content, block)
+	}
+	return response
+}
+
+// firstStopSequence returns where the earliest of sequences starts in
+// text, and which one it is, or -1.
+func firstStopSequence(text string, sequences []string) (int, string) {
+	first, found := -1, ""
+	for _, sequence := range sequences {
+		if i := strings.Index(text, sequence); sequence != "" && i >= 0 && (first < 0 || i < first) {
+			first, found = i, sequence
+		}
+	}
+	return first, found
+}
+
+// openAIStopReason maps a finish reason to the stop reason of the
+// Messages API.
+func openAIStopReason(finishReason string) string {
+	switch finishReason {
+	case "stop":
+		return "end_turn"
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	}
+	return finishReason
+}
+
+// openAIMessages translates a conversation, with the system prompt as its
+// first message.
+func openAIMessages(messages []AnthropicMessage, system string) []openAIMessage {
+	var translated []openAIMessage
+	if system != "" {
+		translated = append(translated, openAIMessage{Role: "system", Content: system})
+	}
+	for _, message := range messages {
+		raws, _, ok := decodeBlocks(message.Content)
+		if !ok {
+			translated = append(translated, openAIMessage{Role: message.Role, Content: message.Content})
+			continue
+		}
+		blocks := make([]messageBlock, len(raws))
+		for i, raw := range raws {
+			json.Unmarshal(raw, &blocks[i])
+		}
+		if message.Role == "assistant" {
+			translated = append(translated, openAIAssistantMessage(blocks))
+		} else {
+			translated = append(translated, openAIUserMessages(blocks)...)
+		}
+	}
+	return translated
+}
+
+// openAIAssistantMessage joins the text of an assistant turn and turns its
+// tool_use blocks into tool calls.
+func openAIAssistantMessage(blocks []messageBlock) openAIMessage {
+	message := openAIMessage{Role: "assistant"}
+	var text strings.Builder
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			call := openAIToolCall{ID: block.ID, Type: "function"}
+			call.Function.Name = block.Name
+			call.Function.Arguments = string(block.Input)
+			if len(block.Input) == 0 || string(block.Input) == "null" {
+				call.Function.Arguments = "{}"
+			}
+			message.ToolCalls = append(message.ToolCalls, call)
+		}
+	}
+	if text.Len() > 0 {
+		message.Content = text.String()
+	}
+	return message
+}
+
+// openAIUserMessages turns a user turn into a tool message per tool result,
+// which must directly follow the assistant's tool calls, then a user
+// message with its text and images.
+func openAIUserMessages(blocks []messageBlock) []openAIMessage {
+	var messages []openAIMessage
+	var parts []map[string]interface{}
+	hasImage := false
+	for _, block := range blocks {
+		switch block.Type {
+		case "tool_result":
+			messages = append(messages, openAIMessage{Role: "tool", ToolCallID: block.ToolUseID, Content: toolResultText(block.Content)})
+		case "text":
+			parts = append(parts, map[string]interface{}{"type": "text", "text": block.Text})
+		case "image":
+			hasImage = true
+			url := "data:" + block.Source.MediaType + ";base64," + block.Source.Data
+			parts = append(parts, map[string]interface{}{"type": "image_url", "image_url": map[string]string{"url": url}})
+		}
+	}
+	if len(parts) == 0 {
+		return messages
+	}
+	if hasImage {
+		return append(messages, openAIMessage{Role: "user", Content: parts})
+	}
+	var text []string
+	for _, part := range parts {
+		text = append(text, part["text"].(string))
+	}
+	return append(messages, openAIMessage{Role: "user", Content: strings.Join(text, "\n\n")})
+}
+
+// toolResultText returns the content of a tool_result block, a string or
+// a list of text blocks, as text.
+func toolResultText(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+	var blocks []messageBlock
+	json.Unmarshal(content, &blocks)
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func openAITools(tools []Tool) []openAITool {
+	var translated []openAITool
+	for _, tool := range tools {
+		translated = append(translated, openAITool{
+			Type:     "function",
+			Function: openAIFunction{Name: tool.Name, Description: tool.Description, Parameters: tool.InputSchema},
+		})
+	}
+	return translated
+}
+
+// openAIToolChoice translates a tool choice; nil leaves the choice to the
+// model.
+func openAIToolChoice(choice *ToolChoice) interface{} {
+	if choice == nil {
+		return nil
+	}
+	switch choice.Type {
+	case "tool":
+		return map[string]interface{}{"type": "function", "function": map[string]string{"name": choice.Name}}
+	case "any":
+		return "required"
+	}
+	return "auto"
+}
+
+// openAIStream rebuilds a streamed completion from its chunks.
+type openAIStream struct {
+	id     string
+	text   strings.Builder
+	calls  []openAIToolCall // by index
+	finish string
+	usage  Usage
+	done   bool // [DONE] was received
+
+	// onText is called with each piece of text as it arrives
+	onText func(delta string)
+}
+
+// add applies one chunk, given as its JSON data.
+func (s *openAIStream) add(data []byte) error {
+	if strings.TrimSpace(string(data)) == "[DONE]" {
+		s.done = true
+		return nil
+	}
+	var chunk openAIResponse
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return fmt.Errorf("failed to parse stream event: %w", err)
+	}
+	if chunk.Error != nil {
+		return fmt.Errorf("stream error: %s: %s", chunk.Error.Type, chunk.Error.Message)
+	}
+	if chunk.ID != "" {
+		s.id = chunk.ID
+	}
+	if chunk.Usage != nil {
+		s.usage = Usage{InputTokens: chunk.Usage.PromptTokens, OutputTokens: chunk.Usage.CompletionTokens}
+	}
+	for _, choice := range chunk.Choices {
+		if text, _ := choice.Delta.Content.(string); text != "" {
+			s.text.WriteString(text)
+			if s.onText != nil {
+				s.onText(text)
+			}
+		}
+		for _, delta := range choice.Delta.ToolCalls {
+			i := len(s.calls)
+			if delta.Index != nil {
+				i = *delta.Index
+			}
+			// A call continues an earlier one or starts the next; any other
+			// index would make the stream allocate calls it never sends
+			if i < 0 || i > len(s.calls) {
+				return fmt.Errorf("stream sent a tool call with index %d after %d tool calls", i, len(s.calls))
+			}
+			if i == len(s.calls) {
+				s.calls = append(s.calls, openAIToolCall{})
+			}
+			call := &s.calls[i]
+			if delta.ID != "" {
+				call.ID = delta.ID
+			}
+			if delta.Function.Name != "" {
+				call.Function.Name = delta.Function.Name
+			}
+			call.Function.Arguments += delta.Function.Arguments
+		}
+		if choice.FinishReason != "" {
+			s.finish = choice.FinishReason
+		}
+	}
+	return nil
+}
+
+// result returns the response once the stream has ended and passes its
+// tool calls to the handler.
+func (s *openAIStream) result(c *OpenAIClient, handler StreamHandler) (*AnthropicResponse, error) {
+	if !s.done {
+		return nil, fmt.Errorf("stream ended before the response was complete")
+	}
+	message := openAIMessage{Content: s.text.String(), ToolCalls: s.calls}
+	response := c.response(s.id, message, s.finish, s.usage)
+	if handler.ToolUse != nil {
+		for _, raw := range response.Content {
+			var base map[string]interface{}
+			json.Unmarshal(raw, &base)
+			if base["type"] == "tool_use" {
+				handler.ToolUse(parseToolUse(raw, base))
+			}
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,20 @@
+package llm
+
+import "testing"
+
+func TestOpenAIStreamRejectsToolCallIndexGaps(t *testing.T) {
+	s := &openAIStream{}
+	first := `{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"list_files","arguments":"{}"}}]}}]}`
+	if err := s.add([]byte(first)); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if err := s.add([]byte(`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":""}}]}}]}`)); err != nil {
+		t.Fatalf("continuing the first call: %v", err)
+	}
+	if err := s.add([]byte(`{"choices":[{"delta":{"tool_calls":[{"index":1000000000,"id":"call_2"}]}}]}`)); err == nil {
+		t.Error("no error for an index past the next call")
+	}
+	if len(s.calls) != 1 {
+		t.Errorf("%d tool calls, want 1", len(s.calls))
+	}
+}
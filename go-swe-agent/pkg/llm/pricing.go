@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pricing is the list price of a model in USD per million tokens.
+type Pricing struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+var modelPricing = map[string]Pricing{
+	"anthropic.claude-3-opus-20240229":          {InputPerMillion: 15, OutputPerMillion: 75},
+	"claude-3-opus-20240229":                    {InputPerMillion: 15, OutputPerMillion: 75},
+	"anthropic.claude-3-5-sonnet-20241022-v2:0": {InputPerMillion: 3, OutputPerMillion: 15},
+	"claude-3-5-sonnet-20241022":                {InputPerMillion: 3, OutputPerMillion: 15},
+	"anthropic.claude-3-5-haiku-20241022-v1:0":  {InputPerMillion: 0.8, OutputPerMillion: 4},
+	"claude-3-5-haiku-20241022":                 {InputPerMillion: 0.8, OutputPerMillion: 4},
+	"anthropic.claude-3-haiku-20240307-v1:0":    {InputPerMillion: 0.25, OutputPerMillion: 1.25},
+	"claude-3-haiku-20240307":                   {InputPerMillion: 0.25, OutputPerMillion: 1.25},
+	"gpt-4o":                                    {InputPerMillion: 2.5, OutputPerMillion: 10},
+	"gpt-4o-mini":                               {InputPerMillion: 0.15, OutputPerMillion: 0.6},
+	"gpt-4.1":                                   {InputPerMillion: 2, OutputPerMillion: 8},
+	"gpt-4-turbo":                               {InputPerMillion: 10, OutputPerMillion: 30},
+}
+
+// PricingFor returns the known list price for model.
+func PricingFor(model string) (Pricing, bool) {
+	p, ok := modelPricing[model]
+	return p, ok
+}
+
+// Cost returns the USD cost of the given token counts.
+func (p Pricing) Cost(inputTokens, outputTokens int) float64 {
+	return float64(inputTokens)/1e6*p.InputPerMillion + float64(outputTokens)/1e6*p.OutputPerMillion
+}
+
+// ParsePrice reads a price given as MODEL=INPUT,OUTPUT in USD per million
+// tokens, e.g. "anthropic.claude-3-opus-20240229=15,75".
+func ParsePrice(spec string) (string, Pricing, error) {
+	model, prices, ok := strings.Cut(spec, "=")
+	input, output, ok2 := strings.Cut(prices, ",")
+	if !ok || !ok2 || strings.TrimSpace(model) == "" {
+		return "", Pricing{}, fmt.Errorf("price %q must be MODEL=INPUT,OUTPUT in USD per million tokens", spec)
+	}
+	var p Pricing
+	var err error
+	if p.InputPerMillion, err = strconv.ParseFloat(strings.TrimSpace(input), 64); err != nil || p.InputPerMillion < 0 {
+		return "", Pricing{}, fmt.Errorf("price %q has an invalid input price %q", spec, input)
+	}
+	if p.OutputPerMillion, err = strconv.ParseFloat(strings.TrimSpace(output), 64); err != nil || p.OutputPerMillion < 0 {
+		return "", Pricing{}, fmt.Errorf("price %q has an invalid output price %q", spec, output)
+	}
+	return strings.TrimSpace(model), p, nil
+}
+
+// DefaultMaxTokens caps the output of a call that sets no limit of its own.
+const DefaultMaxTokens = 8192
+
+// modelMaxOutput is the most output tokens each model produces in one
+// response.
+var modelMaxOutput = map[string]int{
+	"anthropic.claude-3-opus-20240229":          4096,
+	"claude-3-opus-20240229":                    4096,
+	"anthropic.claude-3-5-sonnet-20241022-v2:0": 8192,
+	"claude-3-5-sonnet-20241022":                8192,
+	"anthropic.claude-3-5-haiku-20241022-v1:0":  8192,
+	"claude-3-5-haiku-20241022":                 8192,
+	"anthropic.claude-3-haiku-20240307-v1:0":    4096,
+	"claude-3-haiku-20240307":                   4096,
+	"gpt-4o":                                    16384,
+	"gpt-4o-mini":                               16384,
+	"gpt-4.1":                                   32768,
+	"gpt-4-turbo":                               4096,
+}
+
+// MaxOutputTokens returns the known output limit of model. A Bedrock
+// cross-region inference profile has the limit of the model it routes to.
+func MaxOutputTokens(model string) (int, bool) {
+	if i := strings.Index(model, ".anthropic."); i >= 0 {
+		model = model[i+1:]
+	}
+	n, ok := modelMaxOutput[model]
+	return n, ok
+}
+
+// ValidateMaxTokens checks that model can produce maxTokens output tokens.
+// Unknown models are not checked.
+func ValidateMaxTokens(model string, maxTokens int) error {
+	if limit, ok := MaxOutputTokens(model); ok && maxTokens > limit {
+		return fmt.Errorf("%s produces at most %d output tokens, not %d", model, limit, maxTokens)
+	}
+	return nil
+}
+
+// maxTokens is the output limit sent for a call: the one it asks for, or
+// the default, capped at what model supports.
+func maxTokens(model string, requested int) int {
+	n := requested
+	if n <= 0 {
+		n = DefaultMaxTokens
+	}
+	if limit, ok := MaxOutputTokens(model); ok && n > limit {
+		n = limit
+	}
+	return n
+}
@@ -0,0 +1,141 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+type blockHeader struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	ToolUseID string `json:"tool_use_id"`
+}
+
+// decodeBlocks splits message content into raw blocks and their headers.
+// String content has no blocks and returns ok=false.
+func decodeBlocks(content interface{}) ([]json.RawMessage, []blockHeader, bool) {
+	if _, isString := content.(string); isString || content == nil {
+		return nil, nil, false
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, nil, false
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, nil, false
+	}
+
+	headers := make([]blockHeader, len(raws))
+	for i, raw := range raws {
+		json.Unmarshal(raw, &headers[i])
+	}
+	return raws, headers, true
+}
+
+func toContent(raws []json.RawMessage) []interface{} {
+	content := make([]interface{}, len(raws))
+	for i, raw := range raws {
+		content[i] = raw
+	}
+	return content
+}
+
+// RepairToolPairing makes sure every tool_use in an assistant message is
+// answered by a tool_result in the following user message and every
+// tool_result answers a tool_use, which the API requires. Orphaned results
+// are dropped and missing results are replaced by error placeholders. It
+// returns the repaired conversation and a description of each repair.
+func RepairToolPairing(messages []AnthropicMessage) ([]AnthropicMessage, []string) {
+	var repairs []string
+	repaired := make([]AnthropicMessage, 0, len(messages))
+
+	// tool_use IDs from the preceding assistant message still awaiting a result
+	var pending []string
+
+	for i, msg := range messages {
+		raws, headers, ok := decodeBlocks(msg.Content)
+
+		if msg.Role == "user" {
+			answered := make(map[string]bool)
+			var kept []json.RawMessage
+			if ok {
+				pendingSet := make(map[string]bool, len(pending))
+				for _, id := range pending {
+					pendingSet[id] = true
+				}
+				for j, raw := range raws {
+					if headers[j].Type == "tool_result" {
+						if !pendingSet[headers[j].ToolUseID] || answered[headers[j].ToolUseID] {
+							repairs = append(repairs, fmt.Sprintf("dropped orphaned tool_result %s", headers[j].ToolUseID))
+							continue
+						}
+						answered[headers[j].ToolUseID] = true
+					}
+					kept = append(kept, raw)
+				}
+			}
+
+			placeholders := placeholderResults(pending, answered, &repairs)
+			if len(placeholders) > 0 || (ok && len(kept) != len(raws)) {
+				blocks := append(placeholders, kept...)
+				if len(blocks) == 0 {
+					data, _ := json.Marshal(TextContent{Type: "text", Text: "(orphaned tool results removed)"})
+					blocks = append(blocks, data)
+				}
+				msg.Content = toContent(blocks)
+			}
+			pending = nil
+			repaired = append(repaired, msg)
+			continue
+		}
+
+		// An assistant turn follows another assistant turn: answer the
+		// earlier tool calls in a synthesized user message first.
+		if placeholders := placeholderResults(pending, nil, &repairs); len(placeholders) > 0 {
+			repaired = append(repaired, AnthropicMessage{Role: "user", Content: toContent(placeholders)})
+		}
+
+		pending = nil
+		if ok && i < len(messages)-1 {
+			for _, h := range headers {
+				if h.Type == "tool_use" {
+					pending = append(pending, h.ID)
+				}
+			}
+		}
+		repaired = append(repaired, msg)
+	}
+
+	return repaired, repairs
+}
+
+func placeholderResults(pending []string, answered map[string]bool, repairs *[]string) []json.RawMessage {
+	var placeholders []json.RawMessage
+	for _, id := range pending {
+		if answered[id] {
+			continue
+		}
+		data, _ := json.Marshal(ToolResultContent{
+			Type:      "tool_result",
+			ToolUseID: id,
+			Content:   "Error: no result was recorded for this tool call",
+			IsError:   true,
+		})
+		placeholders = append(placeholders, data)
+		*repairs = append(*repairs, fmt.Sprintf("added placeholder result for tool_use %s", id))
+	}
+	return placeholders
+}
+
+// repairConversation applies RepairToolPairing and logs what it changed.
+func repairConversation(messages []AnthropicMessage, out *ui.Printer) []AnthropicMessage {
+	repaired, repairs := RepairToolPairing(messages)
+	for _, r := range repairs {
+		out.Yellow("  🩹 Repaired conversation: %s\n", r)
+	}
+	return repaired
+}
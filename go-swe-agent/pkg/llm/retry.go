@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+const (
+	// requestTimeout bounds a whole request to the Anthropic or OpenAI API,
+	// response included. Long outputs take minutes to generate, so it is
+	// generous; it only stops a request that hangs.
+	requestTimeout = 10 * time.Minute
+
+	// maxRequestAttempts is how often a request is sent before a rate
+	// limit, server error or network error is returned
+	maxRequestAttempts = 4
+)
+
+// retryDelay is the pause before the first retry; it doubles with each
+// further one.
+var retryDelay = 2 * time.Second
+
+// httpClient sends the requests of the Anthropic and OpenAI clients.
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+// retryable reports whether a response with status is worth sending again:
+// it was rate limited or failed on the server.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// sendRequest sends the request newRequest builds, pausing first for as
+// long as throttle asks. Rate limits, server errors and network errors are
+// retried with exponential backoff, honoring a retry-after header through
+// the throttle, and the last response or error is returned. The caller
+// reads the rate limits of a successful response itself, as only it knows
+// the tokens the request used.
+func sendRequest(newRequest func() (*http.Request, error), throttle *Throttle, out *ui.Printer) (*http.Response, error) {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		if wait := throttle.Wait(time.Now()); wait > 0 {
+			out.Yellow("  ⏳ Waiting %s to stay under the API rate limits\n", wait.Round(time.Second))
+			time.Sleep(wait)
+		}
+		req, err := newRequest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err == nil && (!retryable(resp.StatusCode) || attempt == maxRequestAttempts) {
+			return resp, nil
+		}
+		if err != nil && attempt == maxRequestAttempts {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+
+		reason := fmt.Sprint(err)
+		if err == nil {
+			reason = fmt.Sprintf("status %d", resp.StatusCode)
+			throttle.Observe(resp.Header, 0, time.Now())
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		out.Yellow("  🔁 Request failed (%s); retrying in %s (%d/%d)\n", reason, delay, attempt, maxRequestAttempts-1)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+const openAICompletion = `{"id":"c1","model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":2}}`
+
+// fastRetries shortens the retry delay for the test.
+func fastRetries(t *testing.T) {
+	delay := retryDelay
+	retryDelay = time.Millisecond
+	t.Cleanup(func() { retryDelay = delay })
+}
+
+// failingServer answers with each of statuses in turn, then with body.
+func failingServer(t *testing.T, body string, statuses ...int) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if int(n) <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			fmt.Fprint(w, `{"error":{"message":"try again"}}`)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestOpenAIRetriesRateLimitsAndServerErrors(t *testing.T) {
+	fastRetries(t)
+	server, requests := failingServer(t, openAICompletion, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	var out bytes.Buffer
+	client := &OpenAIClient{apiKey: "key", baseURL: server.URL, model: DefaultOpenAIModel, Throttle: &Throttle{}}
+	client.Out = ui.NewPrinter(&out)
+
+	response, err := client.CreateMessage([]AnthropicMessage{{Role: "user", Content: "hello"}}, "", nil)
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if *requests != 3 {
+		t.Errorf("%d requests, want 3", *requests)
+	}
+	if text, _, _ := client.ParseContent(response.Content); text != "hi" {
+		t.Errorf("text = %q, want hi", text)
+	}
+	if !strings.Contains(out.String(), "Request failed (status 429)") || !strings.Contains(out.String(), "Request failed (status 503)") {
+		t.Errorf("output does not report the retries:\n%s", out.String())
+	}
+}
+
+func TestOpenAIGivesUpAfterMaxAttempts(t *testing.T) {
+	fastRetries(t)
+	statuses := make([]int, maxRequestAttempts+1)
+	for i := range statuses {
+		statuses[i] = http.StatusInternalServerError
+	}
+	server, requests := failingServer(t, openAICompletion, statuses...)
+	client := &OpenAIClient{apiKey: "key", baseURL: server.URL, model: DefaultOpenAIModel}
+	client.Out = ui.NewPrinter(&bytes.Buffer{})
+
+	_, err := client.CreateMessage([]AnthropicMessage{{Role: "user", Content: "hello"}}, "", nil)
+	var status *StatusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusInternalServerError {
+		t.Errorf("error = %v, want the last 500", err)
+	}
+	if *requests != maxRequestAttempts {
+		t.Errorf("%d requests, want %d", *requests, maxRequestAttempts)
+	}
+}
+
+func TestOpenAIDoesNotRetryBadRequests(t *testing.T) {
+	fastRetries(t)
+	server, requests := failingServer(t, openAICompletion, http.StatusBadRequest, http.StatusBadRequest)
+	client := &OpenAIClient{apiKey: "key", baseURL: server.URL, model: DefaultOpenAIModel}
+	client.Out = ui.NewPrinter(&bytes.Buffer{})
+
+	if _, err := client.CreateMessage([]AnthropicMessage{{Role: "user", Content: "hello"}}, "", nil); err == nil {
+		t.Error("no error for a bad request")
+	}
+	if *requests != 1 {
+		t.Errorf("%d requests, want 1", *requests)
+	}
+}
+
+func TestAnthropicRetriesServerErrors(t *testing.T) {
+	fastRetries(t)
+	server, requests := failingServer(t, `{"role":"assistant","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn"}`, http.StatusBadGateway)
+	client := &AnthropicClient{apiKey: "key", baseURL: server.URL, model: DefaultAnthropicModel}
+	client.Out = ui.NewPrinter(&bytes.Buffer{})
+
+	if _, err := client.CreateMessage([]AnthropicMessage{{Role: "user", Content: "hello"}}, "", nil); err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if *requests != 2 {
+		t.Errorf("%d requests, want 2", *requests)
+	}
+}
+
+func TestThrottleReadsOpenAIRateLimits(t *testing.T) {
+	now := time.Now()
+	header := http.Header{}
+	header.Set("x-ratelimit-limit-requests", "500")
+	header.Set("x-ratelimit-remaining-requests", "0")
+	header.Set("x-ratelimit-reset-requests", "2s")
+	throttle := &Throttle{}
+	throttle.Observe(header, 100, now)
+	if wait := throttle.Wait(now); wait != 2*time.Second {
+		t.Errorf("wait = %s, want the 2s until the requests limit resets", wait)
+	}
+
+	header = http.Header{}
+	header.Set("retry-after", "3")
+	throttle.Observe(header, 0, now)
+	if wait := throttle.Wait(now); wait != 3*time.Second {
+		t.Errorf("wait = %s, want the 3s of retry-after", wait)
+	}
+}
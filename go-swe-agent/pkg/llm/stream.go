@@ -0,0 +1,217 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamHandler receives a response while the model generates it. Either
+// function may be nil.
+type StreamHandler struct {
+	// Text is called with each piece of text as it arrives
+	Text func(delta string)
+
+	// ToolUse is called with each tool call once its input is complete
+	ToolUse func(call ToolUseContent)
+}
+
+// streamEvent is one event of a streamed response. The Anthropic API sends
+// them as server-sent events; Bedrock sends the same JSON as the chunks of
+// InvokeModelWithResponseStream.
+type streamEvent struct {
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	Message      *AnthropicResponse `json:"message"`
+	ContentBlock json.RawMessage    `json:"content_block"`
+	Delta        struct {
+		Type         string `json:"type"`
+		Text         string `json:"text"`
+		PartialJSON  string `json:"partial_json"`
+		StopReason   string `json:"stop_reason"`
+		StopSequence string `json:"stop_sequence"`
+	} `json:"delta"`
+	Usage *Usage `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// streamBlock is a content block being streamed. Text and tool input
+// arrive in pieces and are put back into the block when it stops.
+type streamBlock struct {
+	kind   string
+	fields map[string]json.RawMessage
+	text   strings.Builder
+	input  strings.Builder // partial JSON of a tool_use input
+	raw    json.RawMessage // the finished block
+}
+
+// finish assembles the block. Tool input that is missing or cut off
+// mid-JSON, as when the response hits max_tokens, becomes an empty
+// object, so the call is reported as incomplete and the message can still
+// be sent back to the model.
+func (b *streamBlock) finish() json.RawMessage {
+	if b.raw != nil {
+		return b.raw
+	}
+	switch b.kind {
+	case "text":
+		b.fields["text"], _ = json.Marshal(b.text.String())
+	case "tool_use":
+		input := json.RawMessage(strings.TrimSpace(b.input.String()))
+		if len(input) == 0 || !json.Valid(input) {
+			input = json.RawMessage("{}")
+		}
+		b.fields["input"] = input
+	}
+	b.raw, _ = json.Marshal(b.fields)
+	return b.raw
+}
+
+// streamAccumulator rebuilds the response of a stream from its events and
+// passes text and tool calls to the handler as they complete.
+type streamAccumulator struct {
+	handler  StreamHandler
+	response AnthropicResponse
+	blocks   []*streamBlock // by index
+	stopped  bool           // message_stop was received
+}
+
+// add applies one event, given as its JSON data.
+func (a *streamAccumulator) add(data []byte) error {
+	var event streamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("failed to parse stream event: %w", err)
+	}
+
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			a.response = *event.Message
+			a.response.Content = nil
+		}
+	case "content_block_start":
+		block := &streamBlock{}
+		if err := json.Unmarshal(event.ContentBlock, &block.fields); err != nil {
+			return fmt.Errorf("failed to parse content block: %w", err)
+		}
+		json.Unmarshal(block.fields["type"], &block.kind)
+		if block.kind == "text" {
+			var text string
+			json.Unmarshal(block.fields["text"], &text)
+			block.text.WriteString(text)
+		}
+		for len(a.blocks) <= event.Index {
+			a.blocks = append(a.blocks, nil)
+		}
+		a.blocks[event.Index] = block
+	case "content_block_delta":
+		block := a.block(event.Index)
+		if block == nil {
+			return fmt.Errorf("stream sent a delta for unknown content block %d", event.Index)
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			block.text.WriteString(event.Delta.Text)
+			if a.handler.Text != nil {
+				a.handler.Text(event.Delta.Text)
+			}
+		case "input_json_delta":
+			block.input.WriteString(event.Delta.PartialJSON)
+		}
+	case "content_block_stop":
+		block := a.block(event.Index)
+		if block == nil {
+			return fmt.Errorf("stream stopped unknown content block %d", event.Index)
+		}
+		raw := block.finish()
+		if block.kind == "tool_use" && a.handler.ToolUse != nil {
+			var base map[string]interface{}
+			json.Unmarshal(raw, &base)
+			a.handler.ToolUse(parseToolUse(raw, base))
+		}
+	case "message_delta":
+		if event.Delta.StopReason != "" {
+			a.response.StopReason = event.Delta.StopReason
+			a.response.StopSequence = event.Delta.StopSequence
+		}
+		if event.Usage != nil {
+			a.response.Usage.OutputTokens = event.Usage.OutputTokens
+		}
+	case "message_stop":
+		a.stopped = true
+	case "error":
+		if event.Error != nil {
+			return fmt.Errorf("stream error: %s: %s", event.Error.Type, event.Error.Message)
+		}
+		return fmt.Errorf("stream error: %s", data)
+	}
+	return nil
+}
+
+func (a *streamAccumulator) block(index int) *streamBlock {
+	if index < 0 || index >= len(a.blocks) {
+		return nil
+	}
+	return a.blocks[index]
+}
+
+// result returns the response once the stream has ended.
+func (a *streamAccumulator) result() (*AnthropicResponse, error) {
+	if !a.stopped {
+		return nil, fmt.Errorf("stream ended before the response was complete")
+	}
+	response := a.response
+	for _, block := range a.blocks {
+		if block != nil {
+			response.Content = append(response.Content, block.finish())
+		}
+	}
+	return &response, nil
+}
+
+// readEvents feeds the data of each server-sent event in r to the
+// accumulator until the stream ends.
+func (a *streamAccumulator) readEvents(r io.Reader) error {
+	return readServerSentEvents(r, a.add)
+}
+
+// readServerSentEvents passes the data of each server-sent event in r to
+// add until the stream ends.
+func readServerSentEvents(r io.Reader, add func(data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var data bytes.Buffer
+	dispatch := func() error {
+		if data.Len() == 0 {
+			return nil
+		}
+		err := add(data.Bytes())
+		data.Reset()
+		return err
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return err
+			}
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(value, " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return dispatch()
+}
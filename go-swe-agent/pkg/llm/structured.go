@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// structuredOutputsBeta enables strict tool use on the Anthropic API.
+const structuredOutputsBeta = "structured-outputs-2025-11-13"
+
+// ToolChoice constrains which tool the model answers with, in the format
+// of the Messages API.
+type ToolChoice struct {
+	Type string `json:"type"` // auto, any or tool
+	Name string `json:"name,omitempty"`
+}
+
+// ForceTool makes the model answer by calling the named tool.
+func ForceTool(name string) *ToolChoice {
+	return &ToolChoice{Type: "tool", Name: name}
+}
+
+// CallOptions tune a single call. The zero value is a plain call.
+type CallOptions struct {
+	// MaxTokens caps the output of the call. Zero uses DefaultMaxTokens;
+	// either is lowered to what the model supports.
+	MaxTokens int
+
+	// ToolChoice, when set, forces the model to call a tool instead of
+	// answering in prose, e.g. the tool that carries a plan.
+	ToolChoice *ToolChoice
+
+	// Strict asks the provider to decode the forced tool's input against
+	// its schema, so it always validates. Only the Anthropic API supports
+	// it; Bedrock and OpenAI ignore it.
+	Strict bool
+}
+
+func (o CallOptions) structured() bool {
+	return o.ToolChoice != nil || o.Strict
+}
+
+// StatusError is a non-200 response from the Anthropic or OpenAI API.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// rejectedRequest reports whether the provider refused the request as
+// invalid, as it does for structured output options it does not support.
+func rejectedRequest(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode == 400
+	}
+	// Bedrock reports invalid requests as a ValidationException
+	var coded interface{ ErrorCode() string }
+	return errors.As(err, &coded) && coded.ErrorCode() == "ValidationException"
+}
+
+// strictTools returns a copy of tools with the named tool marked strict.
+// Strict schemas must close every object to properties they list.
+func strictTools(tools []Tool, name string) []Tool {
+	strict := make([]Tool, len(tools))
+	for i, tool := range tools {
+		strict[i] = tool
+		if tool.Name == name {
+			strict[i].Strict = true
+			strict[i].InputSchema = closedSchema(tool.InputSchema)
+		}
+	}
+	return strict
+}
+
+// closedSchema copies a JSON schema, setting additionalProperties to false
+// on every object in it.
+func closedSchema(schema map[string]interface{}) map[string]interface{} {
+	closed := make(map[string]interface{}, len(schema)+1)
+	for key, value := range schema {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if key == "properties" {
+				properties := make(map[string]interface{}, len(v))
+				for name, property := range v {
+					if p, ok := property.(map[string]interface{}); ok {
+						properties[name] = closedSchema(p)
+					} else {
+						properties[name] = property
+					}
+				}
+				closed[key] = properties
+			} else {
+				closed[key] = closedSchema(v)
+			}
+		default:
+			closed[key] = value
+		}
+	}
+	if closed["type"] == "object" {
+		closed["additionalProperties"] = false
+	}
+	return closed
+}
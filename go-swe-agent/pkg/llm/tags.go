@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// UserAgent identifies the agent's HTTP traffic to providers and gateways.
+const UserAgent = "go-swe-agent/1.0"
+
+// TagsHeader carries the request tags on calls to the Anthropic API, so
+// gateways can attribute traffic without inspecting request bodies.
+const TagsHeader = "X-Go-Swe-Agent-Tags"
+
+// maxTagLength bounds each tag value.
+const maxTagLength = 64
+
+var unsafeTagChars = regexp.MustCompile(`[^A-Za-z0-9._:-]+`)
+
+// RequestTags describe an LLM call for observability and quota
+// attribution. They only ever hold identifiers, never request content.
+type RequestTags struct {
+	// Base is a caller-chosen tag, e.g. a team or pipeline name
+	Base   string
+	RunID  string
+	Phase  string
+	TaskID string
+}
+
+// pairs returns the non-empty tags as sanitized key/value pairs.
+func (t RequestTags) pairs() [][2]string {
+	var pairs [][2]string
+	for _, kv := range [][2]string{
+		{"tag", t.Base},
+		{"run", t.RunID},
+		{"phase", t.Phase},
+		{"task", t.TaskID},
+	} {
+		if v := sanitizeTag(kv[1]); v != "" {
+			pairs = append(pairs, [2]string{kv[0], v})
+		}
+	}
+	return pairs
+}
+
+// String renders the tags as "key=value" pairs separated by semicolons.
+func (t RequestTags) String() string {
+	var parts []string
+	for _, kv := range t.pairs() {
+		parts = append(parts, kv[0]+"="+kv[1])
+	}
+	return strings.Join(parts, ";")
+}
+
+// userID is the opaque end-user identifier sent as Anthropic request
+// metadata: the base tag and run ID, which are stable for a whole run.
+func (t RequestTags) userID() string {
+	var parts []string
+	for _, v := range []string{sanitizeTag(t.Base), sanitizeTag(t.RunID)} {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// sanitizeTag restricts a tag to a safe character set and length.
+func sanitizeTag(value string) string {
+	value = strings.Trim(unsafeTagChars.ReplaceAllString(value, "-"), "-")
+	if len(value) > maxTagLength {
+		value = value[:maxTagLength]
+	}
+	return value
+}
@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limits the Anthropic API reports on every response, as
+// anthropic-ratelimit-<name>-limit, -remaining and -reset headers.
+var rateLimitNames = []string{"requests", "tokens", "input-tokens", "output-tokens"}
+
+// Rate limits the OpenAI API reports on every response, as
+// x-ratelimit-limit-<name>, x-ratelimit-remaining-<name> and
+// x-ratelimit-reset-<name> headers, the reset being a duration such as
+// "6m0s".
+var openAIRateLimitNames = []string{"requests", "tokens"}
+
+const (
+	// lowRateLimit is the fraction of a limit below which requests are
+	// spread out over the time left until it resets
+	lowRateLimit = 0.1
+
+	// maxThrottleWait caps a single pause, in case a reset time is off
+	maxThrottleWait = time.Minute
+)
+
+// RateLimit is one limit as last reported by the API.
+type RateLimit struct {
+	Name      string
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// Throttle slows requests down before the API's rate limits are hit,
+// based on what earlier responses said was left. It is safe for
+// concurrent use, so clients in one process can share it. A nil Throttle
+// never waits.
+type Throttle struct {
+	mu    sync.Mutex
+	until time.Time // no request is sent before this
+}
+
+// sharedThrottle paces every AnthropicClient in the process, as they draw
+// on the same limits; sharedOpenAIThrottle does the same for OpenAIClient.
+var (
+	sharedThrottle       = &Throttle{}
+	sharedOpenAIThrottle = &Throttle{}
+)
+
+// Wait returns how long the next request must wait, and reserves nothing:
+// callers sleep for it themselves so they can report the pause.
+func (t *Throttle) Wait(now time.Time) time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if wait := t.until.Sub(now); wait > 0 {
+		return min(wait, maxThrottleWait)
+	}
+	return 0
+}
+
+// Observe reads the rate limit headers of an Anthropic or OpenAI response
+// to a request that used tokens. When a limit runs low, later requests are
+// spaced so that what remains lasts until it resets; when the next request
+// would not fit, they wait for the reset. A retry-after header, as sent
+// with a 429, is honored as well.
+func (t *Throttle) Observe(header http.Header, tokens int, now time.Time) {
+	if t == nil {
+		return
+	}
+	until := now
+	for _, l := range parseRateLimits(header, now) {
+		untilReset := l.Reset.Sub(now)
+		if untilReset <= 0 || l.Limit <= 0 {
+			continue
+		}
+		next := 1
+		if l.Name != "requests" {
+			next = tokens
+		}
+		var pause time.Duration
+		switch {
+		case l.Remaining < next:
+			pause = untilReset
+		case float64(l.Remaining) < lowRateLimit*float64(l.Limit):
+			pause = time.Duration(float64(untilReset) * float64(next) / float64(l.Remaining+next))
+		}
+		if now.Add(pause).After(until) {
+			until = now.Add(pause)
+		}
+	}
+	if seconds, err := strconv.Atoi(header.Get("retry-after")); err == nil && seconds > 0 {
+		if retry := now.Add(time.Duration(seconds) * time.Second); retry.After(until) {
+			until = retry
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until.After(t.until) {
+		t.until = until
+	}
+}
+
+func parseRateLimits(header http.Header, now time.Time) []RateLimit {
+	var limits []RateLimit
+	for _, name := range rateLimitNames {
+		prefix := "anthropic-ratelimit-" + name + "-"
+		limit, err1 := strconv.Atoi(header.Get(prefix + "limit"))
+		remaining, err2 := strconv.Atoi(header.Get(prefix + "remaining"))
+		reset, err3 := time.Parse(time.RFC3339, strings.TrimSpace(header.Get(prefix+"reset")))
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		limits = append(limits, RateLimit{Name: name, Limit: limit, Remaining: remaining, Reset: reset})
+	}
+	for _, name := range openAIRateLimitNames {
+		limit, err1 := strconv.Atoi(header.Get("x-ratelimit-limit-" + name))
+		remaining, err2 := strconv.Atoi(header.Get("x-ratelimit-remaining-" + name))
+		reset, err3 := time.ParseDuration(strings.TrimSpace(header.Get("x-ratelimit-reset-" + name)))
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		limits = append(limits, RateLimit{Name: name, Limit: limit, Remaining: remaining, Reset: now.Add(reset)})
+	}
+	return limits
+}
@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Record holds the aggregates of one run. It carries no request text,
+// paths or code, so the metrics file says nothing about what was worked on.
+type Record struct {
+	Time         time.Time     `json:"time"` // when the run finished
+	Model        string        `json:"model"`
+	Termination  string        `json:"termination"` // "error" when the run failed
+	Success      bool          `json:"success"`
+	Duration     time.Duration `json:"duration_ns"`
+	Tasks        int           `json:"tasks"`
+	Completed    int           `json:"completed"`
+	Failed       int           `json:"failed"`
+	Calls        int           `json:"llm_calls"`
+	InputTokens  int           `json:"input_tokens"`
+	OutputTokens int           `json:"output_tokens"`
+	Cost         float64       `json:"cost_usd,omitempty"` // at list price; zero when the model's price is unknown
+}
+
+// DefaultPath is where metrics are kept unless a path is given:
+// ~/.go-swe-agent/metrics.jsonl.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "go-swe-agent-metrics.jsonl"
+	}
+	return filepath.Join(home, ".go-swe-agent", "metrics.jsonl")
+}
+
+// Append adds r as one JSON line to the file at path, creating the file and
+// its directory if needed.
+func Append(path string, r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open metrics file: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return f.Close()
+}
+
+// Load reads the records in the file at path. Lines that cannot be parsed,
+// such as one cut short by a crash, are skipped and counted.
+func Load(path string) (records []Record, skipped int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read metrics file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			skipped++
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read metrics file: %w", err)
+	}
+	return records, skipped, nil
+}
+
+// Totals aggregates a set of runs.
+type Totals struct {
+	Runs         int
+	Successes    int
+	Duration     time.Duration
+	Tasks        int
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+}
+
+func (t *Totals) add(r Record) {
+	t.Runs++
+	if r.Success {
+		t.Successes++
+	}
+	t.Duration += r.Duration
+	t.Tasks += r.Tasks
+	t.InputTokens += r.InputTokens
+	t.OutputTokens += r.OutputTokens
+	t.Cost += r.Cost
+}
+
+// SuccessRate is the share of successful runs, from 0 to 1.
+func (t Totals) SuccessRate() float64 {
+	if t.Runs == 0 {
+		return 0
+	}
+	return float64(t.Successes) / float64(t.Runs)
+}
+
+// Period is the totals of the runs in one week, starting on Monday.
+type Period struct {
+	Start time.Time
+	Totals
+}
+
+// Summary aggregates all runs, per model, and per week for trends.
+type Summary struct {
+	Totals
+	Models map[string]*Totals
+	Weeks  []Period // oldest first
+}
+
+// Summarize aggregates records.
+func Summarize(records []Record) Summary {
+	s := Summary{Models: make(map[string]*Totals)}
+	weeks := make(map[time.Time]*Totals)
+	for _, r := range records {
+		s.add(r)
+		if s.Models[r.Model] == nil {
+			s.Models[r.Model] = &Totals{}
+		}
+		s.Models[r.Model].add(r)
+		week := weekStart(r.Time)
+		if weeks[week] == nil {
+			weeks[week] = &Totals{}
+		}
+		weeks[week].add(r)
+	}
+	for start, totals := range weeks {
+		s.Weeks = append(s.Weeks, Period{Start: start, Totals: *totals})
+	}
+	sort.Slice(s.Weeks, func(i, j int) bool { return s.Weeks[i].Start.Before(s.Weeks[j].Start) })
+	return s
+}
+
+// weekStart returns midnight on the Monday of t's week, in t's location.
+func weekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// maxWeeks is how many recent weeks Write shows.
+const maxWeeks = 8
+
+// Write prints the summary as text: totals, averages per run, a line per
+// model and the most recent weeks.
+func (s Summary) Write(w io.Writer) {
+	if s.Runs == 0 {
+		fmt.Fprintln(w, "No runs recorded yet.")
+		return
+	}
+	runs := float64(s.Runs)
+	fmt.Fprintf(w, "Runs:         %d (%d successful, %.0f%%)\n", s.Runs, s.Successes, s.SuccessRate()*100)
+	fmt.Fprintf(w, "Tasks:        %d (%.1f per run)\n", s.Tasks, float64(s.Tasks)/runs)
+	fmt.Fprintf(w, "Tokens:       %d in, %d out (%.0f per run)\n", s.InputTokens, s.OutputTokens, float64(s.InputTokens+s.OutputTokens)/runs)
+	fmt.Fprintf(w, "Cost:         $%.2f ($%.2f per run)\n", s.Cost, s.Cost/runs)
+	fmt.Fprintf(w, "Time:         %s (%s per run)\n", s.Duration.Round(time.Second), (s.Duration / time.Duration(s.Runs)).Round(time.Second))
+
+	models := make([]string, 0, len(s.Models))
+	for model := range s.Models {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	fmt.Fprintln(w, "\nBy model:")
+	for _, model := range models {
+		t := s.Models[model]
+		fmt.Fprintf(w, "  %-45s %4d runs  %3.0f%% success  $%.2f per run\n", model, t.Runs, t.SuccessRate()*100, t.Cost/float64(t.Runs))
+	}
+
+	weeks := s.Weeks
+	if len(weeks) > maxWeeks {
+		weeks = weeks[len(weeks)-maxWeeks:]
+	}
+	fmt.Fprintln(w, "\nBy week:")
+	for _, p := range weeks {
+		fmt.Fprintf(w, "  %s  %4d runs  %3.0f%% success  $%.2f per run  %s per run\n", p.Start.Format("2006-01-02"), p.Runs, p.SuccessRate()*100, p.Cost/float64(p.Runs), (p.Duration / time.Duration(p.Runs)).Round(time.Second))
+	}
+}
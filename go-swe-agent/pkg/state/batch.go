@@ -0,0 +1,67 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadBatch reads the requests of a batch run from a file. The file holds
+// either one request per line, or a YAML list of strings whose items may
+// continue on more indented lines. Blank lines and # comments are skipped.
+func LoadBatch(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch file: %w", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("batch file %s has no requests", path)
+	}
+
+	if !isListItem(lines[0]) {
+		requests := make([]string, len(lines))
+		for i, line := range lines {
+			requests[i] = strings.TrimSpace(line)
+		}
+		return requests, nil
+	}
+
+	var requests []string
+	for n, line := range lines {
+		switch {
+		case isListItem(line):
+			requests = append(requests, strings.TrimSpace(strings.TrimSpace(line)[1:]))
+		case line[0] == ' ' || line[0] == '\t':
+			// A folded continuation of the previous item
+			requests[len(requests)-1] += " " + strings.TrimSpace(line)
+		default:
+			return nil, fmt.Errorf("batch file %s: line %q is neither a list item nor part of one", path, lines[n])
+		}
+	}
+	for i, request := range requests {
+		if unquoted, err := strconv.Unquote(request); err == nil {
+			requests[i] = unquoted
+		} else if len(request) >= 2 && request[0] == '\'' && request[len(request)-1] == '\'' {
+			requests[i] = strings.ReplaceAll(request[1:len(request)-1], "''", "'")
+		}
+		if requests[i] == "" {
+			return nil, fmt.Errorf("batch file %s: request %d is empty", path, i+1)
+		}
+	}
+	return requests, nil
+}
+
+func isListItem(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+}
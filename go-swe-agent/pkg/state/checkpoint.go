@@ -0,0 +1,71 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CheckpointVersion is the schema version written by SaveState.
+const CheckpointVersion = 1
+
+// SaveState writes the full agent state to path as JSON. The file is
+// replaced atomically so a crash mid-write never corrupts a checkpoint.
+// Task outcomes are not recorded while the state is being marshaled, so
+// the checkpoint never holds a half-updated task.
+func (s *AgentState) SaveState(path string) error {
+	s.mu.Lock()
+	s.SchemaVersion = CheckpointVersion
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LoadState reads a checkpoint written by SaveState.
+func LoadState(path string) (*AgentState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	if err := migrateCheckpoint(raw); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate checkpoint %s: %w", path, err)
+	}
+	var s AgentState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	if s.Plan == nil {
+		return nil, fmt.Errorf("checkpoint %s has no plan to resume", path)
+	}
+	return &s, nil
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Every schema version has a checkpoint in testdata, named
+// checkpoint_v<version>.json, saved as that version wrote it. Add one
+// when bumping CheckpointVersion.
+func TestCheckpointFixturesCoverEveryVersion(t *testing.T) {
+	for v := 0; v <= CheckpointVersion; v++ {
+		path := filepath.Join("testdata", fmt.Sprintf("checkpoint_v%d.json", v))
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("no checkpoint fixture for schema version %d: %v", v, err)
+			continue
+		}
+		s, err := LoadState(path)
+		if err != nil {
+			t.Errorf("LoadState(%s): %v", path, err)
+			continue
+		}
+		if s.SchemaVersion != CheckpointVersion {
+			t.Errorf("%s: schema version %d after loading, want %d", path, s.SchemaVersion, CheckpointVersion)
+		}
+	}
+}
+
+func TestLoadCheckpointV0(t *testing.T) {
+	s, err := LoadState(filepath.Join("testdata", "checkpoint_v0.json"))
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	want := []string{"completed", "blocked", "failed", "pending"}
+	for i, task := range s.Plan.Tasks {
+		if task.Status != want[i] {
+			t.Errorf("%s: status %q, want %q", task.ID, task.Status, want[i])
+		}
+	}
+	if len(s.Clarifications) != 1 {
+		t.Fatalf("%d clarifications, want 1", len(s.Clarifications))
+	}
+	c := s.Clarifications[0]
+	if c.TaskID != "task-2" || c.Question != "Which AWS account should the service be deployed to?" || c.AskedAt.IsZero() {
+		t.Errorf("clarification = %+v, want the blocked question of task-2", c)
+	}
+	if s.RunID != "20240301-101500-a1b2c3" || s.Plan.Tasks[0].ToolCalls != 6 {
+		t.Errorf("the rest of the checkpoint was not kept: run %q, %d tool calls", s.RunID, s.Plan.Tasks[0].ToolCalls)
+	}
+}
+
+func TestLoadCheckpointV1(t *testing.T) {
+	s, err := LoadState(filepath.Join("testdata", "checkpoint_v1.json"))
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if got := s.Plan.Tasks[1]; got.Status != "blocked" || len(got.DependsOn) != 1 {
+		t.Errorf("task-2 = %+v, want blocked on task-1", got)
+	}
+	if len(s.Clarifications) != 1 || s.Clarifications[0].TaskID != "task-2" {
+		t.Errorf("clarifications = %+v, want the one of task-2", s.Clarifications)
+	}
+	if s.Plan.Tasks[0].InputTokens != 1200 {
+		t.Errorf("input tokens = %d, want 1200", s.Plan.Tasks[0].InputTokens)
+	}
+}
+
+func TestLoadCheckpointWithoutRunIDIsTooOld(t *testing.T) {
+	_, err := LoadState(filepath.Join("testdata", "checkpoint_no_run_id.json"))
+	if !errors.Is(err, ErrCheckpointTooOld) {
+		t.Errorf("LoadState error = %v, want ErrCheckpointTooOld", err)
+	}
+}
+
+func TestLoadCheckpointFromNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	data := fmt.Sprintf(`{"schema_version": %d, "run_id": "r", "plan": {"tasks": []}}`, CheckpointVersion+1)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadState(path); err == nil {
+		t.Error("LoadState loaded a checkpoint from a newer schema version")
+	}
+}
@@ -0,0 +1,113 @@
+package state
+
+import "time"
+
+// Clarification is a question the model asked a human during a task. It
+// stays pending until it has an answer, which lets a run that was halted
+// in non-interactive mode be resumed once a human has replied.
+type Clarification struct {
+	TaskID     string     `json:"task_id"`
+	Question   string     `json:"question"`
+	Reason     string     `json:"reason,omitempty"` // what blocks the task, e.g. missing credentials
+	Answer     string     `json:"answer,omitempty"`
+	AskedAt    time.Time  `json:"asked_at"`
+	AnsweredAt *time.Time `json:"answered_at,omitempty"`
+}
+
+// Pending reports whether the question is still waiting for an answer.
+func (c Clarification) Pending() bool {
+	return c.AnsweredAt == nil
+}
+
+// AskClarification records a question for a task and returns its index.
+func (s *AgentState) AskClarification(taskID, question string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.askClarification(taskID, question)
+}
+
+func (s *AgentState) askClarification(taskID, question string) int {
+	s.Clarifications = append(s.Clarifications, Clarification{
+		TaskID:   taskID,
+		Question: question,
+		AskedAt:  time.Now(),
+	})
+	return len(s.Clarifications) - 1
+}
+
+// AnswerClarification stores the human's answer to a recorded question.
+// Once a blocked task has no pending questions left it becomes pending
+// again so it is picked up when the run continues.
+func (s *AgentState) AnswerClarification(index int, answer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	c := &s.Clarifications[index]
+	c.Answer = answer
+	c.AnsweredAt = &now
+
+	for _, other := range s.Clarifications {
+		if other.TaskID == c.TaskID && other.Pending() {
+			return
+		}
+	}
+	if s.Plan == nil {
+		return
+	}
+	for i := range s.Plan.Tasks {
+		if s.Plan.Tasks[i].ID == c.TaskID && s.Plan.Tasks[i].Status == "blocked" {
+			s.Plan.Tasks[i].Status = "pending"
+			s.Plan.Tasks[i].Error = ""
+			s.Plan.Tasks[i].CompletedAt = nil
+			s.logTask(&s.Plan.Tasks[i])
+		}
+	}
+}
+
+// PendingClarifications returns the indexes of unanswered questions.
+func (s *AgentState) PendingClarifications() []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var pending []int
+	for i, c := range s.Clarifications {
+		if c.Pending() {
+			pending = append(pending, i)
+		}
+	}
+	return pending
+}
+
+// AnsweredClarifications returns the answered questions for a task.
+func (s *AgentState) AnsweredClarifications(taskID string) []Clarification {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var answered []Clarification
+	for _, c := range s.Clarifications {
+		if c.TaskID == taskID && !c.Pending() {
+			answered = append(answered, c)
+		}
+	}
+	return answered
+}
+
+// MarkTaskBlocked stops a task until a human answers its question. The
+// reason, if any, says what kind of input is missing.
+func (s *AgentState) MarkTaskBlocked(taskID, reason, question string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Plan == nil {
+		return
+	}
+	now := time.Now()
+	for i := range s.Plan.Tasks {
+		if s.Plan.Tasks[i].ID == taskID {
+			s.Plan.Tasks[i].Status = "blocked"
+			s.Plan.Tasks[i].Error = "blocked: " + question
+			s.Plan.Tasks[i].CompletedAt = &now
+			s.logTask(&s.Plan.Tasks[i])
+			break
+		}
+	}
+	i := s.askClarification(taskID, question)
+	s.Clarifications[i].Reason = reason
+}
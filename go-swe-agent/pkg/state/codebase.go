@@ -0,0 +1,26 @@
+package state
+
+// CodebaseExplanation answers a question about the codebase, from the
+// explain command. It is read-only: nothing was planned or changed.
+type CodebaseExplanation struct {
+	Question string               `json:"question"`
+	Summary  string               `json:"summary"`
+	Sections []ExplanationSection `json:"sections"`
+}
+
+// ExplanationSection is one part of an explanation, such as a component or
+// a step of a flow, with the code it is based on.
+type ExplanationSection struct {
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Citations []Citation `json:"citations,omitempty"`
+}
+
+// Citation points at the lines of a file that back a statement. Path is
+// relative to the working directory; EndLine is zero for a single line.
+type Citation struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
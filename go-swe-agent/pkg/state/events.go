@@ -0,0 +1,28 @@
+package state
+
+import "time"
+
+// TaskStatusEvent is logged to the run's event log whenever a task
+// changes status.
+type TaskStatusEvent struct {
+	TaskID string `json:"task_id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LLMCallEvent is logged to the run's event log for every model call.
+type LLMCallEvent struct {
+	Phase        string `json:"phase"`
+	InputTokens  int    `json:"input_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+	DurationMs   int64  `json:"duration_ms"`
+}
+
+// logTask records the status a task was just given. The caller holds mu.
+func (s *AgentState) logTask(task *Task) {
+	s.Events.Log("task_status", TaskStatusEvent{TaskID: task.ID, Status: task.Status, Error: task.Error})
+}
+
+func (s *AgentState) logCall(phase string, inputTokens, outputTokens int, elapsed time.Duration) {
+	s.Events.Log("llm_call", LLMCallEvent{Phase: phase, InputTokens: inputTokens, OutputTokens: outputTokens, DurationMs: elapsed.Milliseconds()})
+}
@@ -0,0 +1,132 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// FileChange records a file the agent's tools wrote during the run.
+type FileChange struct {
+	Path      string    `json:"path"` // relative to the working directory
+	Created   bool      `json:"created"`
+	TaskID    string    `json:"task_id,omitempty"` // task that first changed the file
+	Writes    int       `json:"writes"`
+	FirstTime time.Time `json:"first_time"`
+	LastTime  time.Time `json:"last_time"`
+}
+
+// FileJournal is the list of files changed by file tools, in the order they
+// were first touched. Changes made through bash are not recorded. Its
+// methods may be called by tasks running in parallel.
+type FileJournal struct {
+	Files []FileChange `json:"files"`
+
+	mu sync.Mutex
+}
+
+// Writes returns the number of writes to all files in this run.
+func (j *FileJournal) Writes() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	writes := 0
+	for _, c := range j.Files {
+		writes += c.Writes
+	}
+	return writes
+}
+
+// Record notes a write to path. created says whether the file did not exist
+// before its first write.
+func (j *FileJournal) Record(path string, created bool, taskID string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	if c := j.find(path); c != nil {
+		if c.Writes == 0 {
+			// The first write of a claimed file
+			*c = FileChange{Path: path, Created: created, TaskID: taskID, FirstTime: now}
+		}
+		c.Writes++
+		c.LastTime = now
+		return
+	}
+	j.Files = append(j.Files, FileChange{
+		Path:      path,
+		Created:   created,
+		TaskID:    taskID,
+		Writes:    1,
+		FirstTime: now,
+		LastTime:  now,
+	})
+}
+
+// Claim reserves path for a write once at most limit distinct files are
+// changed, checking and reserving in one step so that tasks writing in
+// parallel cannot go past the limit together. It fails when path was not
+// changed or claimed yet and limit files already were, and returns their
+// number. Record keeps the claim; Release gives it back when the write
+// does not happen.
+func (j *FileJournal) Claim(path string, limit int) (int, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.find(path) != nil {
+		return len(j.Files), true
+	}
+	if len(j.Files) >= limit {
+		return len(j.Files), false
+	}
+	j.Files = append(j.Files, FileChange{Path: path})
+	return len(j.Files), true
+}
+
+// Release drops the claim on path if it was never written.
+func (j *FileJournal) Release(path string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if c := j.find(path); c != nil && c.Writes == 0 {
+		j.forget(path)
+	}
+}
+
+// Counts returns how many existing files were modified and how many new
+// files were created.
+func (j *FileJournal) Counts() (modified, created int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, c := range j.Files {
+		if c.Writes == 0 {
+			continue
+		}
+		if c.Created {
+			created++
+		} else {
+			modified++
+		}
+	}
+	return modified, created
+}
+
+// Forget drops path from the journal, e.g. after the file was removed.
+func (j *FileJournal) Forget(path string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.forget(path)
+}
+
+func (j *FileJournal) forget(path string) {
+	for i := range j.Files {
+		if j.Files[i].Path == path {
+			j.Files = append(j.Files[:i], j.Files[i+1:]...)
+			return
+		}
+	}
+}
+
+func (j *FileJournal) find(path string) *FileChange {
+	for i := range j.Files {
+		if j.Files[i].Path == path {
+			return &j.Files[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestJournalClaimIsAtomic(t *testing.T) {
+	const limit = 5
+	j := &FileJournal{}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	granted := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			if _, ok := j.Claim(path, limit); ok {
+				mu.Lock()
+				granted++
+				mu.Unlock()
+			}
+		}(fmt.Sprintf("file-%d", i))
+	}
+	wg.Wait()
+	if granted != limit {
+		t.Errorf("%d claims granted, want %d", granted, limit)
+	}
+}
+
+func TestJournalReleaseKeepsWrittenFiles(t *testing.T) {
+	j := &FileJournal{}
+	j.Claim("written.go", 2)
+	j.Record("written.go", true, "task-1")
+	j.Release("written.go")
+	j.Claim("abandoned.go", 2)
+	j.Release("abandoned.go")
+
+	if len(j.Files) != 1 || j.Files[0].Path != "written.go" || !j.Files[0].Created || j.Files[0].Writes != 1 {
+		t.Errorf("files = %+v, want only written.go, created with one write", j.Files)
+	}
+	if _, ok := j.Claim("another.go", 2); !ok {
+		t.Error("the abandoned claim still counts against the limit")
+	}
+}
@@ -0,0 +1,73 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrCheckpointTooOld is returned for checkpoints that predate the oldest
+// schema a migration exists for.
+var ErrCheckpointTooOld = errors.New("checkpoint is too old to migrate; start a new run")
+
+// checkpointMigrations[v] upgrades a decoded checkpoint from schema version
+// v to v+1. Add a step here, and bump CheckpointVersion, whenever a change
+// to AgentState would otherwise make older checkpoints load incorrectly.
+var checkpointMigrations = []func(raw map[string]interface{}) error{
+	migrateV0,
+}
+
+// migrateCheckpoint upgrades a decoded checkpoint in place to
+// CheckpointVersion.
+func migrateCheckpoint(raw map[string]interface{}) error {
+	version := 0
+	if v, ok := raw["schema_version"].(float64); ok {
+		version = int(v)
+	}
+	if version > CheckpointVersion {
+		return fmt.Errorf("schema version %d is newer than the supported %d; upgrade go-swe-agent", version, CheckpointVersion)
+	}
+
+	for ; version < CheckpointVersion; version++ {
+		if err := checkpointMigrations[version](raw); err != nil {
+			return fmt.Errorf("migrating from schema version %d: %w", version, err)
+		}
+	}
+	raw["schema_version"] = CheckpointVersion
+	return nil
+}
+
+// migrateV0 handles state saved before versioning (such as the state.json
+// artifact). Tasks halted by the blocked signal were recorded as failed with
+// a "blocked: " error; they become blocked tasks with a pending question so
+// the run can be resumed.
+func migrateV0(raw map[string]interface{}) error {
+	if id, _ := raw["run_id"].(string); id == "" {
+		return ErrCheckpointTooOld
+	}
+
+	plan, _ := raw["plan"].(map[string]interface{})
+	tasks, _ := plan["tasks"].([]interface{})
+	clarifications, _ := raw["clarifications"].([]interface{})
+	for _, t := range tasks {
+		task, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		errText, _ := task["error"].(string)
+		if task["status"] != "failed" || !strings.HasPrefix(errText, "blocked: ") {
+			continue
+		}
+		task["status"] = "blocked"
+		clarifications = append(clarifications, map[string]interface{}{
+			"task_id":  task["id"],
+			"question": strings.TrimPrefix(errText, "blocked: "),
+			"asked_at": time.Now().Format(time.RFC3339Nano),
+		})
+	}
+	if len(clarifications) > 0 {
+		raw["clarifications"] = clarifications
+	}
+	return nil
+}
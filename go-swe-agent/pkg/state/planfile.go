@@ -0,0 +1,87 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PlanFileVersion is the schema version written by SavePlanFile.
+const PlanFileVersion = 1
+
+// PlanFile is a plan written with --emit-plan and executed later with
+// --plan-in, possibly after a human edited it. It carries the request the
+// plan was made for and the repository state it was made against.
+type PlanFile struct {
+	SchemaVersion int      `json:"schema_version"`
+	Request       *Request `json:"request"`
+	WorkingDir    string   `json:"working_dir,omitempty"`
+	Head          string   `json:"head,omitempty"` // git HEAD when the plan was made
+	Plan          *Plan    `json:"plan"`
+}
+
+// SavePlanFile writes f to path as indented JSON, so it is easy to edit.
+func (f *PlanFile) SavePlanFile(path string) error {
+	f.SchemaVersion = PlanFileVersion
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	return nil
+}
+
+// LoadPlanFile reads a plan written by SavePlanFile and checks that it can
+// be executed. Tasks added by hand may leave out their ID and status; they
+// get a content-derived ID and start pending.
+func LoadPlanFile(path string) (*PlanFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	var f PlanFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file %s: %w", path, err)
+	}
+	if f.SchemaVersion > PlanFileVersion {
+		return nil, fmt.Errorf("plan file %s has schema version %d; this build reads up to %d", path, f.SchemaVersion, PlanFileVersion)
+	}
+	if f.Request == nil || strings.TrimSpace(f.Request.Description) == "" {
+		return nil, fmt.Errorf("plan file %s has an empty 'request' field", path)
+	}
+	if f.Plan == nil {
+		return nil, fmt.Errorf("plan file %s has no plan", path)
+	}
+	if f.Plan.Interleaved {
+		return nil, fmt.Errorf("plan file %s holds an interleaved run, which has no upfront plan", path)
+	}
+	if len(f.Plan.Tasks) == 0 && !f.Plan.NoChangesNeeded {
+		return nil, fmt.Errorf("plan file %s has no tasks", path)
+	}
+
+	ids := make(map[string]bool, len(f.Plan.Tasks))
+	for i, t := range f.Plan.Tasks {
+		if strings.TrimSpace(t.Description) == "" {
+			return nil, fmt.Errorf("plan file %s: task %d has no description", path, i+1)
+		}
+		if t.ID != "" {
+			if ids[t.ID] {
+				return nil, fmt.Errorf("plan file %s: task %d reuses the ID %s", path, i+1, t.ID)
+			}
+			ids[t.ID] = true
+		}
+		switch t.Status {
+		case "":
+			f.Plan.Tasks[i].Status = "pending"
+		case "pending", "completed":
+		default:
+			return nil, fmt.Errorf("plan file %s: task %d has status %q; use pending, or completed to skip it", path, i+1, t.Status)
+		}
+	}
+	AssignTaskIDs(f.Plan.Tasks)
+	return &f, nil
+}
@@ -0,0 +1,40 @@
+package state
+
+// The progress of a running task is recorded through these methods rather
+// than by the executor writing to the task, so that snapshots, checkpoints
+// and tasks finishing alongside it read the task consistently. task points
+// to the task being executed; only its executor changes it.
+
+// CountTurn records the start of a model turn of task.
+func (s *AgentState) CountTurn(task *Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task.Turns++
+}
+
+// AddTaskTokens adds the tokens of a model call to task.
+func (s *AgentState) AddTaskTokens(task *Task, inputTokens, outputTokens int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task.InputTokens += inputTokens
+	task.OutputTokens += outputTokens
+}
+
+// CountToolCall records a tool execution of task and, when action is not
+// nil, the high-risk action it takes.
+func (s *AgentState) CountToolCall(task *Task, action *Action) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task.ToolCalls++
+	if action != nil {
+		task.Actions = append(task.Actions, *action)
+	}
+}
+
+// SetCreateConflicts records the files task was planned to create that
+// already existed when it started.
+func (s *AgentState) SetCreateConflicts(task *Task, files []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task.CreateConflicts = files
+}
@@ -0,0 +1,37 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Request is a structured task request. It can be built from CLI flags or
+// loaded from a JSON file with LoadRequest.
+type Request struct {
+	Description        string   `json:"request"`
+	AcceptanceCriteria []string `json:"acceptance_criteria,omitempty"`
+	Images             []string `json:"images,omitempty"` // screenshots shown to the planner
+	TargetFiles        []string `json:"target_files,omitempty"`
+	ForbiddenFiles     []string `json:"forbidden_files,omitempty"`
+}
+
+// LoadRequest reads a structured request from a JSON file.
+func LoadRequest(path string) (*Request, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request file: %w", err)
+	}
+
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse request file: %w", err)
+	}
+
+	if strings.TrimSpace(req.Description) == "" {
+		return nil, fmt.Errorf("request file %s has an empty 'request' field", path)
+	}
+
+	return &req, nil
+}
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// FileScope limits which files a run may change. Patterns are paths
+// relative to the working directory and may use path.Match wildcards; a
+// pattern naming a directory covers every file below it, and one without
+// a slash matches that name in any directory.
+type FileScope struct {
+	Targets   []string `json:"target_files,omitempty"`    // when set, the only files that may change
+	Forbidden []string `json:"forbidden_files,omitempty"` // files that must not change
+}
+
+// Empty reports whether the scope allows every file.
+func (s *FileScope) Empty() bool {
+	return s == nil || (len(s.Targets) == 0 && len(s.Forbidden) == 0)
+}
+
+// Violation returns why changing the file at rel is out of scope, or ""
+// when it is allowed.
+func (s *FileScope) Violation(rel string) string {
+	if s.Empty() {
+		return ""
+	}
+	rel = path.Clean(strings.ReplaceAll(rel, "\\", "/"))
+	for _, pattern := range s.Forbidden {
+		if matchesScope(pattern, rel) {
+			return "forbidden by " + pattern
+		}
+	}
+	if len(s.Targets) == 0 {
+		return ""
+	}
+	for _, pattern := range s.Targets {
+		if matchesScope(pattern, rel) {
+			return ""
+		}
+	}
+	return "not a target file"
+}
+
+// Note describes the scope to the model.
+func (s *FileScope) Note() string {
+	if s.Empty() {
+		return ""
+	}
+	var b strings.Builder
+	if len(s.Targets) > 0 {
+		fmt.Fprintf(&b, "Only these files may be changed: %s.", strings.Join(s.Targets, ", "))
+	}
+	if len(s.Forbidden) > 0 {
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(&b, "These files must not be changed: %s.", strings.Join(s.Forbidden, ", "))
+	}
+	return b.String()
+}
+
+// matchesScope reports whether pattern matches rel or one of the
+// directories it lies in.
+func matchesScope(pattern, rel string) bool {
+	pattern = path.Clean(strings.TrimPrefix(strings.ReplaceAll(pattern, "\\", "/"), "./"))
+	anywhere := !strings.Contains(pattern, "/")
+	for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+		name := p
+		if anywhere {
+			name = path.Base(p)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
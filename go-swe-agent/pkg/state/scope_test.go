@@ -0,0 +1,44 @@
+package state
+
+import "testing"
+
+func TestFileScopeViolation(t *testing.T) {
+	scope := &FileScope{
+		Targets:   []string{"pkg/api", "cmd/*.go", "README.md"},
+		Forbidden: []string{"pkg/api/generated.go", "*.lock"},
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"pkg/api/handler.go", ""},
+		{"pkg/api/v2/routes.go", ""},
+		{"./pkg/api/handler.go", ""},
+		{`pkg\api\handler.go`, ""},
+		{"cmd/main.go", ""},
+		{"README.md", ""},
+		{"docs/README.md", ""},
+		{"cmd/tool/main.go", "not a target file"},
+		{"pkg/apiv2/handler.go", "not a target file"},
+		{"go.mod", "not a target file"},
+		{"pkg/api/generated.go", "forbidden by pkg/api/generated.go"},
+		{"pkg/api/deps.lock", "forbidden by *.lock"},
+	}
+	for _, tt := range tests {
+		if got := scope.Violation(tt.path); got != tt.want {
+			t.Errorf("Violation(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	forbidOnly := &FileScope{Forbidden: []string{"./vendor"}}
+	if got := forbidOnly.Violation("vendor/lib/a.go"); got != "forbidden by ./vendor" {
+		t.Errorf("Violation(vendor/lib/a.go) = %q, want it forbidden", got)
+	}
+	if got := forbidOnly.Violation("main.go"); got != "" {
+		t.Errorf("Violation(main.go) = %q, want it allowed", got)
+	}
+	var empty *FileScope
+	if !empty.Empty() || empty.Violation("anything") != "" || empty.Note() != "" {
+		t.Error("a nil scope does not allow every file")
+	}
+}
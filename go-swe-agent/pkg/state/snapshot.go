@@ -0,0 +1,112 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// Snapshot is what a run is doing at one moment: enough to tell a stuck
+// run from a slow one without stopping it.
+type Snapshot struct {
+	RunID          string                `json:"run_id"`
+	Time           time.Time             `json:"time"`
+	Phase          string                `json:"phase,omitempty"`
+	Activity       string                `json:"activity,omitempty"`
+	ActivitySince  time.Time             `json:"activity_since"`
+	ActiveTask     *TaskSnapshot         `json:"active_task,omitempty"`
+	LastToolCall   string                `json:"last_tool_call,omitempty"`
+	Usage          map[string]PhaseUsage `json:"usage,omitempty"`
+	TotalTokens    int                   `json:"total_tokens"`
+	PendingTasks   []string              `json:"pending_tasks,omitempty"`
+	CompletedTasks int                   `json:"completed_tasks"`
+}
+
+// TaskSnapshot is the progress of the task being worked on.
+type TaskSnapshot struct {
+	ID          string     `json:"id"`
+	Description string     `json:"description"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	Turns       int        `json:"turns"`
+	ToolCalls   int        `json:"tool_calls"`
+}
+
+// Monitor keeps the latest Snapshot of a run so that another goroutine
+// can report it while the run goes on. A nil Monitor ignores updates.
+type Monitor struct {
+	mu       sync.Mutex
+	snapshot Snapshot
+}
+
+func NewMonitor() *Monitor {
+	return &Monitor{}
+}
+
+// Update records that the run has started activity in phase. It must be
+// called from the goroutine that changes s.
+func (m *Monitor) Update(s *AgentState, phase, activity string) {
+	if m == nil {
+		return
+	}
+	snapshot := s.snapshot()
+	snapshot.Phase = phase
+	snapshot.Activity = activity
+	snapshot.ActivitySince = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot.LastToolCall = m.snapshot.LastToolCall
+	m.snapshot = snapshot
+}
+
+// ToolCall is Update for a tool call, which is also kept as the last one.
+func (m *Monitor) ToolCall(s *AgentState, phase, call string) {
+	if m == nil {
+		return
+	}
+	m.Update(s, phase, call)
+	m.mu.Lock()
+	m.snapshot.LastToolCall = call
+	m.mu.Unlock()
+}
+
+// Snapshot returns the latest snapshot, stamped with the current time.
+func (m *Monitor) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := m.snapshot
+	snapshot.Time = time.Now()
+	return snapshot
+}
+
+// snapshot copies the parts of s a Snapshot reports, so it can be read
+// while s keeps changing.
+func (s *AgentState) snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := Snapshot{
+		RunID:          s.RunID,
+		Usage:          make(map[string]PhaseUsage, len(s.Usage)),
+		CompletedTasks: len(s.CompletedTasks),
+	}
+	for phase, u := range s.Usage {
+		snapshot.Usage[phase] = u
+		snapshot.TotalTokens += u.InputTokens + u.OutputTokens
+	}
+	if task := s.CurrentTask; task != nil && task.Status == "in_progress" {
+		snapshot.ActiveTask = &TaskSnapshot{
+			ID:          task.ID,
+			Description: task.Description,
+			StartedAt:   task.StartedAt,
+			Turns:       task.Turns,
+			ToolCalls:   task.ToolCalls,
+		}
+	}
+	if s.Plan != nil {
+		for _, task := range s.Plan.Tasks {
+			if task.Status == "pending" {
+				snapshot.PendingTasks = append(snapshot.PendingTasks, task.ID+": "+task.Description)
+			}
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,27 @@
+package state
+
+import "time"
+
+// StallReport describes the tool calls that made the stall watchdog halt
+// a run, for debugging.
+type StallReport struct {
+	Time     time.Time   `json:"time"`
+	TaskID   string      `json:"task_id"`
+	Window   int         `json:"window"`   // calls examined
+	Distinct int         `json:"distinct"` // distinct calls among them
+	Calls    []CallCount `json:"calls"`    // most repeated first
+}
+
+// CallCount is a tool call, with its input, and how often it was made.
+type CallCount struct {
+	Call  string `json:"call"`
+	Count int    `json:"count"`
+}
+
+// SetStall records the report of the watchdog that halted the run. Tasks
+// running in parallel may set it while the state is being saved.
+func (s *AgentState) SetStall(report *StallReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Stall = report
+}
@@ -1,7 +1,13 @@
 package state
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
 	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/ui"
 )
 
 type Message struct {
@@ -22,62 +28,199 @@ type ToolResult struct {
 }
 
 type Plan struct {
-	Tasks       []Task    `json:"tasks"`
-	Summary     string    `json:"summary"`
-	CreatedAt   time.Time `json:"created_at"`
-	IsApproved  bool      `json:"is_approved"`
+	Tasks       []Task            `json:"tasks"`
+	Summary     string            `json:"summary"`
+	CreatedAt   time.Time         `json:"created_at"`
+	IsApproved  bool              `json:"is_approved"`
+	Revisions   map[string]string `json:"revisions,omitempty"` // replaced task ID -> revised task ID
+
+	// Findings is the planner's digest of what exploration revealed (key
+	// files, conventions, commands), passed to every task
+	Findings string `json:"findings,omitempty"`
+
+	// Reproduction is the failing test written before the fix in
+	// reproduce-first runs
+	Reproduction *Reproduction `json:"reproduction,omitempty"`
+
+	// NoChangesNeeded marks an intentionally empty plan: the codebase
+	// already satisfies the request, and Summary explains why
+	NoChangesNeeded bool `json:"no_changes_needed,omitempty"`
+
+	// Interleaved marks a plan built one step at a time during execution
+	// rather than upfront
+	Interleaved bool `json:"interleaved,omitempty"`
+}
+
+// Reproduction describes the test that reproduces a bug: the command that
+// runs it, which must fail before the fix and pass after it, and the task
+// that writes it.
+type Reproduction struct {
+	Command string `json:"command"`
+	TaskID  string `json:"task_id,omitempty"`
 }
 
 type Task struct {
 	ID          string    `json:"id"`
 	Description string    `json:"description"`
-	Status      string    `json:"status"` // pending, in_progress, completed, failed
+	Status      string    `json:"status"` // pending, in_progress, completed, partial, failed, incomplete, blocked, superseded
 	Output      string    `json:"output,omitempty"`
 	Error       string    `json:"error,omitempty"`
 	StartedAt   *time.Time `json:"started_at,omitempty"`
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Turns       int        `json:"turns,omitempty"`      // LLM turns spent on the task
+	ToolCalls   int        `json:"tool_calls,omitempty"` // tool executions during the task
+	RevisedFrom string     `json:"revised_from,omitempty"` // ID of the task this one replaces after a re-plan
+	Actions     []Action   `json:"actions,omitempty"`      // high-risk tool calls with their stated intent
+	Remaining   string     `json:"remaining,omitempty"`    // work a partial task left undone
+	FollowUpOf  string     `json:"follow_up_of,omitempty"` // ID of the partial task this one finishes
+	DependsOn   []string   `json:"depends_on,omitempty"`   // IDs of the tasks that must finish before this one starts
+	Approved    bool       `json:"approved,omitempty"`     // approved to run with --approve-plan
+	Repair      int        `json:"repair,omitempty"`       // repair attempt that added the task after the final build/test check failed
+	Replan      int        `json:"replan,omitempty"`       // re-plan round that added the task after too many tasks failed
+
+	// InputTokens and OutputTokens are the model tokens spent executing
+	// the task
+	InputTokens  int `json:"input_tokens,omitempty"`
+	OutputTokens int `json:"output_tokens,omitempty"`
+
+	// Creates lists the new files the plan says the task creates;
+	// CreateConflicts are those that already existed when it started
+	Creates         []string `json:"creates,omitempty"`
+	CreateConflicts []string `json:"create_conflicts,omitempty"`
+}
+
+// Action pairs a high-risk tool call with the intent the model stated
+// before making it, as an audit trail.
+type Action struct {
+	Time   time.Time `json:"time"`
+	Tool   string    `json:"tool"`
+	Target string    `json:"target"`
+	Intent string    `json:"intent,omitempty"`
+}
+
+// CriterionResult records whether a single acceptance criterion was met at
+// the end of a run, along with the evidence the verifier found.
+type CriterionResult struct {
+	Criterion string `json:"criterion"`
+	Met       bool   `json:"met"`
+	Evidence  string `json:"evidence,omitempty"`
+}
+
+// ErrorExplanation is a plain-language account of why a task failed and
+// what would fix it, written for whoever takes over the run.
+type ErrorExplanation struct {
+	TaskID       string `json:"task_id"`
+	Task         string `json:"task"` // the task's description
+	Error        string `json:"error"`
+	Explanation  string `json:"explanation"`
+	SuggestedFix string `json:"suggested_fix"`
+}
+
+// RepoMetrics is a quick size/complexity snapshot of the working directory
+// taken before planning.
+type RepoMetrics struct {
+	Files     int            `json:"files"`
+	Lines     int            `json:"lines"`
+	Languages map[string]int `json:"languages,omitempty"` // files per language
+	SizeClass string         `json:"size_class"`
 }
 
 type AgentState struct {
-	Messages        []Message  `json:"messages"`
-	Plan            *Plan      `json:"plan,omitempty"`
-	CurrentTask     *Task      `json:"current_task,omitempty"`
-	WorkingDir      string     `json:"working_dir"`
-	OriginalRequest string     `json:"original_request"`
-	Errors          []string   `json:"errors"`
-	CompletedTasks  []Task     `json:"completed_tasks"`
+	SchemaVersion      int               `json:"schema_version"`
+	RunID              string            `json:"run_id"`
+	Messages           []Message         `json:"messages"`
+	Plan               *Plan             `json:"plan,omitempty"`
+	CurrentTask        *Task             `json:"current_task,omitempty"`
+	WorkingDir         string            `json:"working_dir"`
+	OriginalRequest    string            `json:"original_request"`
+	AcceptanceCriteria []string          `json:"acceptance_criteria,omitempty"`
+	Images             []string          `json:"images,omitempty"`
+	Scope              *FileScope        `json:"file_scope,omitempty"`
+	ReferenceContext   string            `json:"reference_context,omitempty"`
+	RepoMetrics        *RepoMetrics      `json:"repo_metrics,omitempty"`
+	TestConventions    string            `json:"test_conventions,omitempty"` // how existing tests are written, for test-writing tasks
+	CriteriaResults    []CriterionResult `json:"criteria_results,omitempty"`
+	Clarifications     []Clarification   `json:"clarifications,omitempty"`
+	Journal            *FileJournal      `json:"file_journal,omitempty"`
+	Usage              map[string]PhaseUsage `json:"usage,omitempty"`
+	ToolUsage          ToolUsage         `json:"tool_usage,omitempty"`
+	Stall              *StallReport      `json:"stall,omitempty"`
+	Replans            int               `json:"replans,omitempty"` // times the plan was revised after too many tasks failed
+	Errors             []string          `json:"errors"`
+	CompletedTasks     []Task            `json:"completed_tasks"`
+
+	// Events, when set, receives every task status change and model call
+	Events *ui.EventLog `json:"-"`
+
+	// mu guards Messages, the plan's tasks, Errors, CompletedTasks,
+	// Clarifications and Usage, so tasks running in parallel can record
+	// their outcomes while others read them
+	mu sync.RWMutex
 }
 
 func NewAgentState(workingDir, request string) *AgentState {
 	return &AgentState{
+		RunID:           newRunID(),
 		Messages:        []Message{},
 		WorkingDir:      workingDir,
 		OriginalRequest: request,
 		Errors:          []string{},
 		CompletedTasks:  []Task{},
+		Journal:         &FileJournal{},
+		ToolUsage:       ToolUsage{},
 	}
 }
 
+// newRunID returns an identifier for a run, sortable by start time.
+func newRunID() string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%s", time.Now().Format("20060102-150405"), hex.EncodeToString(suffix))
+}
+
 func (s *AgentState) AddMessage(role string, content interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.Messages = append(s.Messages, Message{
 		Role:    role,
 		Content: content,
 	})
 }
 
-func (s *AgentState) GetNextPendingTask() *Task {
+// GetNextPendingTask returns a copy of the first pending task. The task
+// stays pending, and changing the copy does not change the plan.
+func (s *AgentState) GetNextPendingTask() (Task, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if s.Plan == nil {
-		return nil
+		return Task{}, false
 	}
-	for i := range s.Plan.Tasks {
-		if s.Plan.Tasks[i].Status == "pending" {
-			return &s.Plan.Tasks[i]
+	for _, task := range s.Plan.Tasks {
+		if task.Status == "pending" {
+			return task, true
 		}
 	}
-	return nil
+	return Task{}, false
+}
+
+// StatusCounts returns the number of tasks in each status, read as one
+// consistent view.
+func (s *AgentState) StatusCounts() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := make(map[string]int)
+	if s.Plan == nil {
+		return counts
+	}
+	for _, task := range s.Plan.Tasks {
+		counts[task.Status]++
+	}
+	return counts
 }
 
 func (s *AgentState) MarkTaskComplete(taskID string, output string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.Plan == nil {
 		return
 	}
@@ -88,12 +231,58 @@ func (s *AgentState) MarkTaskComplete(taskID string, output string) {
 			s.Plan.Tasks[i].Output = output
 			s.Plan.Tasks[i].CompletedAt = &now
 			s.CompletedTasks = append(s.CompletedTasks, s.Plan.Tasks[i])
+			s.logTask(&s.Plan.Tasks[i])
+			break
+		}
+	}
+}
+
+// MarkTaskPartial records a task that did part of its work. Remaining
+// describes what is left; later tasks see it with the output.
+func (s *AgentState) MarkTaskPartial(taskID, output, remaining string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Plan == nil {
+		return
+	}
+	now := time.Now()
+	for i := range s.Plan.Tasks {
+		if s.Plan.Tasks[i].ID == taskID {
+			s.Plan.Tasks[i].Status = "partial"
+			s.Plan.Tasks[i].Output = output
+			s.Plan.Tasks[i].Remaining = remaining
+			s.Plan.Tasks[i].CompletedAt = &now
+			s.CompletedTasks = append(s.CompletedTasks, s.Plan.Tasks[i])
+			s.logTask(&s.Plan.Tasks[i])
+			break
+		}
+	}
+}
+
+// MarkTaskIncomplete records a task that ran out of turns or tool calls
+// before the model said it was done.
+func (s *AgentState) MarkTaskIncomplete(taskID, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Plan == nil {
+		return
+	}
+	now := time.Now()
+	for i := range s.Plan.Tasks {
+		if s.Plan.Tasks[i].ID == taskID {
+			s.Plan.Tasks[i].Status = "incomplete"
+			s.Plan.Tasks[i].Error = reason
+			s.Plan.Tasks[i].CompletedAt = &now
+			s.Errors = append(s.Errors, reason)
+			s.logTask(&s.Plan.Tasks[i])
 			break
 		}
 	}
 }
 
 func (s *AgentState) MarkTaskFailed(taskID string, err string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.Plan == nil {
 		return
 	}
@@ -104,32 +293,69 @@ func (s *AgentState) MarkTaskFailed(taskID string, err string) {
 			s.Plan.Tasks[i].Error = err
 			s.Plan.Tasks[i].CompletedAt = &now
 			s.Errors = append(s.Errors, err)
+			s.logTask(&s.Plan.Tasks[i])
+			break
+		}
+	}
+}
+
+// RetryTask returns a failed or incomplete task to pending so it runs again. Earlier
+// errors stay in Errors.
+func (s *AgentState) RetryTask(taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Plan == nil {
+		return
+	}
+	for i := range s.Plan.Tasks {
+		if s.Plan.Tasks[i].ID == taskID && (s.Plan.Tasks[i].Status == "failed" || s.Plan.Tasks[i].Status == "incomplete") {
+			s.Plan.Tasks[i].Status = "pending"
+			s.Plan.Tasks[i].Error = ""
+			s.Plan.Tasks[i].CompletedAt = nil
+			s.logTask(&s.Plan.Tasks[i])
 			break
 		}
 	}
 }
 
 func (s *AgentState) StartTask(taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.Plan == nil {
 		return
 	}
-	now := time.Now()
 	for i := range s.Plan.Tasks {
 		if s.Plan.Tasks[i].ID == taskID {
-			s.Plan.Tasks[i].Status = "in_progress"
-			s.Plan.Tasks[i].StartedAt = &now
-			s.CurrentTask = &s.Plan.Tasks[i]
+			s.startTask(i)
 			break
 		}
 	}
 }
 
+func (s *AgentState) startTask(i int) {
+	now := time.Now()
+	s.Plan.Tasks[i].Status = "in_progress"
+	s.Plan.Tasks[i].StartedAt = &now
+	s.CurrentTask = &s.Plan.Tasks[i]
+	s.logTask(&s.Plan.Tasks[i])
+}
+
+// FinishedTasks returns a copy of CompletedTasks, read as one consistent
+// view while other tasks may be finishing.
+func (s *AgentState) FinishedTasks() []Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Task(nil), s.CompletedTasks...)
+}
+
 func (s *AgentState) AllTasksComplete() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if s.Plan == nil {
 		return false
 	}
 	for _, task := range s.Plan.Tasks {
-		if task.Status != "completed" && task.Status != "failed" {
+		if task.Status != "completed" && task.Status != "partial" && task.Status != "failed" && task.Status != "incomplete" && task.Status != "blocked" && task.Status != "superseded" {
 			return false
 		}
 	}
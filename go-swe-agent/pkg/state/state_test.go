@@ -0,0 +1,114 @@
+package state
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func newTestState(n int) *AgentState {
+	s := NewAgentState("/tmp", "request")
+	s.Plan = &Plan{}
+	for i := 0; i < n; i++ {
+		s.Plan.Tasks = append(s.Plan.Tasks, Task{ID: fmt.Sprintf("task-%d", i), Status: "pending"})
+	}
+	return s
+}
+
+// TestConcurrentTaskOutcomes finishes many tasks at once while their
+// statuses are read. Run with -race.
+func TestConcurrentTaskOutcomes(t *testing.T) {
+	const n = 100
+	s := newTestState(n)
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			total := 0
+			for _, count := range s.StatusCounts() {
+				total += count
+			}
+			if total != n {
+				t.Errorf("status counts add up to %d, want %d", total, n)
+				return
+			}
+			s.FinishedTasks()
+			s.AllTasksComplete()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(id string, fail bool) {
+			defer wg.Done()
+			s.StartTask(id)
+			if fail {
+				s.MarkTaskFailed(id, "error in "+id)
+			} else {
+				s.MarkTaskComplete(id, "done")
+			}
+		}(fmt.Sprintf("task-%d", i), i%2 == 1)
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	counts := s.StatusCounts()
+	if counts["completed"] != n/2 || counts["failed"] != n/2 {
+		t.Errorf("counts = %v, want %d completed and %d failed", counts, n/2, n/2)
+	}
+	if !s.AllTasksComplete() {
+		t.Error("not every task is finished")
+	}
+	seen := make(map[string]bool)
+	for _, task := range s.FinishedTasks() {
+		if seen[task.ID] {
+			t.Errorf("%s recorded twice", task.ID)
+		}
+		seen[task.ID] = true
+	}
+	if len(seen) != n/2 {
+		t.Errorf("%d completed tasks recorded, want %d", len(seen), n/2)
+	}
+	errors := make(map[string]bool)
+	for _, err := range s.Errors {
+		errors[err] = true
+	}
+	if len(s.Errors) != n/2 || len(errors) != n/2 {
+		t.Errorf("%d errors recorded, %d distinct, want %d", len(s.Errors), len(errors), n/2)
+	}
+}
+
+func TestGetNextPendingTaskReturnsACopy(t *testing.T) {
+	s := newTestState(3)
+	s.StartTask("task-0")
+
+	task, ok := s.GetNextPendingTask()
+	if !ok || task.ID != "task-1" {
+		t.Fatalf("GetNextPendingTask = %s, %v; want task-1", task.ID, ok)
+	}
+	task.Status = "completed"
+	task.Description = "changed"
+	if got := s.Plan.Tasks[1]; got.Status != "pending" || got.Description != "" {
+		t.Errorf("plan task = %+v, want it unchanged", got)
+	}
+
+	for _, id := range []string{"task-1", "task-2"} {
+		s.StartTask(id)
+	}
+	if task, ok := s.GetNextPendingTask(); ok {
+		t.Errorf("GetNextPendingTask = %s, want none pending", task.ID)
+	}
+	if _, ok := (&AgentState{}).GetNextPendingTask(); ok {
+		t.Error("GetNextPendingTask found a task without a plan")
+	}
+}
@@ -0,0 +1,107 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// normalizeDescription makes IDs insensitive to case and whitespace edits.
+func normalizeDescription(description string) string {
+	return strings.ToLower(strings.Join(strings.Fields(description), " "))
+}
+
+// NewTaskID derives a stable ID from a task description, so the same task
+// gets the same ID whenever a plan is generated. IDs already in taken get a
+// numeric suffix; the new ID is added to taken.
+func NewTaskID(description string, taken map[string]bool) string {
+	sum := sha256.Sum256([]byte(normalizeDescription(description)))
+	base := "task-" + hex.EncodeToString(sum[:4])
+
+	id := base
+	for n := 2; taken[id]; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	taken[id] = true
+	return id
+}
+
+// AssignTaskIDs gives every task without an ID a content-derived one.
+func AssignTaskIDs(tasks []Task) {
+	taken := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		if t.ID != "" {
+			taken[t.ID] = true
+		}
+	}
+	for i := range tasks {
+		if tasks[i].ID == "" {
+			tasks[i].ID = NewTaskID(tasks[i].Description, taken)
+		}
+	}
+}
+
+// ReplacePlan installs a revised plan. Tasks whose description is unchanged
+// keep their ID and progress from the current plan; each current task is
+// kept by at most one new task, and further new tasks with its description
+// get IDs of their own. A task that names the
+// task it revises in RevisedFrom is recorded in Plan.Revisions, so records
+// keyed by the old ID can be followed to the new one with CurrentTaskID.
+func (s *AgentState) ReplacePlan(plan *Plan) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Plan != nil {
+		previous := make(map[string][]Task, len(s.Plan.Tasks))
+		for _, t := range s.Plan.Tasks {
+			key := normalizeDescription(t.Description)
+			previous[key] = append(previous[key], t)
+		}
+		for i, t := range plan.Tasks {
+			key := normalizeDescription(t.Description)
+			if matches := previous[key]; len(matches) > 0 {
+				old := matches[0]
+				previous[key] = matches[1:]
+				old.DependsOn = t.DependsOn
+				plan.Tasks[i] = old
+			}
+		}
+
+		for old, current := range s.Plan.Revisions {
+			plan.revise(old, current)
+		}
+	}
+
+	AssignTaskIDs(plan.Tasks)
+	for _, t := range plan.Tasks {
+		if t.RevisedFrom != "" && t.RevisedFrom != t.ID {
+			plan.revise(t.RevisedFrom, t.ID)
+		}
+	}
+	s.Plan = plan
+}
+
+func (p *Plan) revise(oldID, newID string) {
+	if p.Revisions == nil {
+		p.Revisions = make(map[string]string)
+	}
+	p.Revisions[oldID] = newID
+}
+
+// CurrentTaskID follows plan revisions from a task ID recorded earlier in
+// the run to the ID of the task that replaced it.
+func (s *AgentState) CurrentTaskID(id string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.Plan == nil {
+		return id
+	}
+	for seen := map[string]bool{id: true}; ; {
+		next, ok := s.Plan.Revisions[id]
+		if !ok || seen[next] {
+			return id
+		}
+		seen[next] = true
+		id = next
+	}
+}
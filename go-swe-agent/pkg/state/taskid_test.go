@@ -0,0 +1,99 @@
+package state
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewTaskIDIsStable(t *testing.T) {
+	a := NewTaskID("Add the --dry-run flag", map[string]bool{})
+	b := NewTaskID("  add the   --DRY-RUN flag\n", map[string]bool{})
+	if a != b {
+		t.Errorf("IDs %q and %q differ for the same description", a, b)
+	}
+	if !strings.HasPrefix(a, "task-") || len(a) != len("task-")+8 {
+		t.Errorf("ID %q, want task- and 8 hex digits", a)
+	}
+	if c := NewTaskID("Add the --verbose flag", map[string]bool{}); c == a {
+		t.Errorf("different descriptions share the ID %q", a)
+	}
+}
+
+func TestAssignTaskIDsDuplicates(t *testing.T) {
+	tasks := []Task{
+		{Description: "Run the tests"},
+		{ID: "custom", Description: "Fix the build"},
+		{Description: "run the tests"},
+		{Description: "Run the tests"},
+	}
+	AssignTaskIDs(tasks)
+	base := tasks[0].ID
+	want := []string{base, "custom", base + "-2", base + "-3"}
+	for i, task := range tasks {
+		if task.ID != want[i] {
+			t.Errorf("task %d: ID %q, want %q", i, task.ID, want[i])
+		}
+	}
+}
+
+func TestReplacePlanKeepsProgress(t *testing.T) {
+	s := NewAgentState("/tmp", "request")
+	first := &Plan{Tasks: []Task{
+		{Description: "Add the flag", Status: "pending"},
+		{Description: "Write the docs", Status: "pending"},
+	}}
+	s.ReplacePlan(first)
+	done := s.Plan.Tasks[0].ID
+	old := s.Plan.Tasks[1].ID
+	s.StartTask(done)
+	s.MarkTaskComplete(done, "added")
+
+	s.ReplacePlan(&Plan{Tasks: []Task{
+		{Description: "add the  flag", Status: "pending"},
+		{Description: "Write the docs and examples", Status: "pending", RevisedFrom: old},
+	}})
+	if got := s.Plan.Tasks[0]; got.ID != done || got.Status != "completed" || got.Output != "added" {
+		t.Errorf("unchanged task = %+v, want %s completed with its output", got, done)
+	}
+	revised := s.Plan.Tasks[1].ID
+	if revised == old {
+		t.Fatalf("the revised task kept the ID %s", old)
+	}
+	if got := s.CurrentTaskID(old); got != revised {
+		t.Errorf("CurrentTaskID(%s) = %s, want %s", old, got, revised)
+	}
+
+	// Revisions carry over into later plans and are followed to the end
+	s.ReplacePlan(&Plan{Tasks: []Task{
+		{Description: "Add the flag", Status: "pending"},
+		{Description: "Document the flag", Status: "pending", RevisedFrom: revised},
+	}})
+	if got, want := s.CurrentTaskID(old), s.Plan.Tasks[1].ID; got != want {
+		t.Errorf("CurrentTaskID(%s) after two revisions = %s, want %s", old, got, want)
+	}
+	if got := s.CurrentTaskID("unknown"); got != "unknown" {
+		t.Errorf("CurrentTaskID(unknown) = %s", got)
+	}
+}
+
+// Two new tasks with the description of one current task do not both take
+// its ID and progress: the second is a new task.
+func TestReplacePlanMatchesEachTaskOnce(t *testing.T) {
+	s := NewAgentState("/tmp", "request")
+	s.ReplacePlan(&Plan{Tasks: []Task{{Description: "Run the tests", Status: "pending"}}})
+	done := s.Plan.Tasks[0].ID
+	s.StartTask(done)
+	s.MarkTaskComplete(done, "passed")
+
+	s.ReplacePlan(&Plan{Tasks: []Task{
+		{Description: "Run the tests", Status: "pending"},
+		{Description: "run the tests", Status: "pending"},
+	}})
+	first, second := s.Plan.Tasks[0], s.Plan.Tasks[1]
+	if first.ID != done || first.Status != "completed" {
+		t.Errorf("first task = %+v, want %s completed", first, done)
+	}
+	if second.ID == done || second.Status != "pending" || second.Output != "" {
+		t.Errorf("second task = %+v, want a pending task with a new ID", second)
+	}
+}
@@ -0,0 +1,57 @@
+package state
+
+import (
+	"sort"
+	"time"
+)
+
+// ToolStats aggregates the calls of one tool during a run.
+type ToolStats struct {
+	Calls       int           `json:"calls"`
+	Failures    int           `json:"failures"`
+	Duration    time.Duration `json:"duration_ns"`
+	OutputBytes int           `json:"output_bytes"`
+}
+
+// ToolUsage maps tool names to their stats.
+type ToolUsage map[string]ToolStats
+
+// Record adds one call of tool.
+func (u ToolUsage) Record(tool string, failed bool, elapsed time.Duration, outputBytes int) {
+	stats := u[tool]
+	stats.Calls++
+	if failed {
+		stats.Failures++
+	}
+	stats.Duration += elapsed
+	stats.OutputBytes += outputBytes
+	u[tool] = stats
+}
+
+// Add adds the calls recorded in other, e.g. by a task that ran in parallel
+// with its own ToolUsage.
+func (u ToolUsage) Add(other ToolUsage) {
+	for tool, s := range other {
+		stats := u[tool]
+		stats.Calls += s.Calls
+		stats.Failures += s.Failures
+		stats.Duration += s.Duration
+		stats.OutputBytes += s.OutputBytes
+		u[tool] = stats
+	}
+}
+
+// Names returns the tools by number of calls, most used first.
+func (u ToolUsage) Names() []string {
+	names := make([]string, 0, len(u))
+	for name := range u {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if u[names[i]].Calls != u[names[j]].Calls {
+			return u[names[i]].Calls > u[names[j]].Calls
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
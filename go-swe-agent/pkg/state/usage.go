@@ -0,0 +1,52 @@
+package state
+
+import "time"
+
+// Phases of a run, used to bucket LLM usage.
+const (
+	PhasePlanning     = "planning"
+	PhaseExecution    = "execution"
+	PhaseVerification = "verification"
+)
+
+// PhaseUsage aggregates the LLM calls made during one phase of a run.
+type PhaseUsage struct {
+	Calls        int           `json:"calls"`
+	InputTokens  int           `json:"input_tokens"`
+	OutputTokens int           `json:"output_tokens"`
+	Duration     time.Duration `json:"duration_ns"`
+}
+
+// RecordUsage adds the token counts and latency of one LLM call to phase.
+func (s *AgentState) RecordUsage(phase string, inputTokens, outputTokens int, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Usage == nil {
+		s.Usage = make(map[string]PhaseUsage)
+	}
+	u := s.Usage[phase]
+	u.Calls++
+	u.InputTokens += inputTokens
+	u.OutputTokens += outputTokens
+	u.Duration += elapsed
+	s.Usage[phase] = u
+	s.logCall(phase, inputTokens, outputTokens, elapsed)
+}
+
+// AddUsage adds usage recorded elsewhere, such as by a separate planning
+// call, to the run's totals.
+func (s *AgentState) AddUsage(usage map[string]PhaseUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Usage == nil {
+		s.Usage = make(map[string]PhaseUsage)
+	}
+	for phase, add := range usage {
+		u := s.Usage[phase]
+		u.Calls += add.Calls
+		u.InputTokens += add.InputTokens
+		u.OutputTokens += add.OutputTokens
+		u.Duration += add.Duration
+		s.Usage[phase] = u
+	}
+}
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// AgentIgnoreFile lists paths, in .gitignore syntax, that are hidden from
+// the agent without affecting version control.
+const AgentIgnoreFile = ".go-swe-agentignore"
+
+// IgnoreRules are the patterns of an AgentIgnoreFile. A nil *IgnoreRules
+// ignores nothing.
+type IgnoreRules struct {
+	patterns []ignorePattern
+}
+
+type ignorePattern struct {
+	text    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// LoadIgnoreRules reads the AgentIgnoreFile of dir. It returns nil without
+// an error when there is none.
+func LoadIgnoreRules(dir string) (*IgnoreRules, error) {
+	data, err := os.ReadFile(filepath.Join(dir, AgentIgnoreFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ParseIgnoreRules(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", AgentIgnoreFile, err)
+	}
+	return rules, nil
+}
+
+// ParseIgnoreRules parses patterns in .gitignore syntax: blank lines and
+// lines starting with # are skipped, ! re-includes, a trailing / matches
+// only directories, a / elsewhere anchors the pattern to the root, and *,
+// ? and ** match as in git.
+func ParseIgnoreRules(content string) (*IgnoreRules, error) {
+	rules := &IgnoreRules{}
+	for n, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := ignorePattern{text: line}
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		re, err := regexp.Compile(ignoreRegexp(line))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %q: %w", n+1, p.text, err)
+		}
+		p.re = re
+		rules.patterns = append(rules.patterns, p)
+	}
+	return rules, nil
+}
+
+// ignoreRegexp translates a pattern into a regular expression matched
+// against slash-separated paths relative to the root.
+func ignoreRegexp(pattern string) string {
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "/**") && i+3 == len(pattern):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			if end := strings.IndexByte(pattern[i:], ']'); end > 1 {
+				class := pattern[i+1 : i+end]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				b.WriteString("[" + class + "]")
+				i += end
+			} else {
+				b.WriteString(`\[`)
+			}
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// Len returns the number of patterns.
+func (r *IgnoreRules) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.patterns)
+}
+
+// Match reports whether rel, a path relative to the root, is hidden, and
+// the pattern that hides it. As in git, a path inside a hidden directory
+// stays hidden even if a later pattern re-includes it.
+func (r *IgnoreRules) Match(rel string, isDir bool) (bool, string) {
+	if r == nil {
+		return false, ""
+	}
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return false, ""
+	}
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		last := i == len(parts)-1
+		if ignored, pattern := r.matchOne(strings.Join(parts[:i+1], "/"), !last || isDir); ignored {
+			return true, pattern
+		}
+	}
+	return false, ""
+}
+
+// matchOne applies the patterns to a single path; the last match wins.
+func (r *IgnoreRules) matchOne(rel string, isDir bool) (bool, string) {
+	ignored, matched := false, ""
+	for _, p := range r.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			ignored, matched = !p.negate, p.text
+		}
+	}
+	return ignored, matched
+}
+
+// hidden reports whether the AgentIgnoreFile hides path, an absolute path,
+// with the pattern that hides it.
+func (t *ToolExecutor) hidden(path string, isDir bool) (bool, string) {
+	if t.Ignore == nil || !within(t.workingDir, path) {
+		return false, ""
+	}
+	rel, err := filepath.Rel(t.workingDir, path)
+	if err != nil {
+		return false, ""
+	}
+	return t.Ignore.Match(rel, isDir)
+}
+
+// checkHidden refuses paths the AgentIgnoreFile hides.
+func (t *ToolExecutor) checkHidden(path string) error {
+	isDir := false
+	if info, err := t.Backend.Stat(path); err == nil {
+		isDir = info.IsDir()
+	}
+	if hidden, pattern := t.hidden(path, isDir); hidden {
+		return fmt.Errorf("%s is hidden from the agent by pattern %q in %s; the user chose not to share it", path, pattern, AgentIgnoreFile)
+	}
+	return nil
+}
+
+// filterHidden drops search result lines from hidden files.
+func (t *ToolExecutor) filterHidden(output string) string {
+	if t.Ignore == nil {
+		return output
+	}
+	var kept []string
+	for _, line := range strings.Split(output, "\n") {
+		if prefix := locationPrefix.FindString(line); prefix != "" {
+			file := strings.SplitN(prefix, ":", 2)[0]
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(t.workingDir, file)
+			}
+			if hidden, _ := t.hidden(file, false); hidden {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
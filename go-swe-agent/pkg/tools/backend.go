@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// ToolBackend is where tools touch files and run commands: the local
+// machine, or a sandbox such as a container or a remote host. Paths are
+// absolute paths as seen by the backend.
+type ToolBackend interface {
+	// Run runs command with bash in dir. env holds extra KEY=value
+	// variables. A non-zero exit is reported as an error.
+	Run(dir, command string, env []string) (stdout, stderr string, err error)
+
+	ReadFile(path string) ([]byte, error)
+
+	// WriteFile writes data to path, creating missing parent directories.
+	WriteFile(path string, data []byte) error
+
+	// ReadDir lists a directory sorted by name.
+	ReadDir(path string) ([]fs.DirEntry, error)
+
+	Stat(path string) (fs.FileInfo, error)
+}
+
+// LocalBackend runs tools directly on this machine.
+type LocalBackend struct{}
+
+func (b LocalBackend) Run(dir, command string, env []string) (string, string, error) {
+	return b.RunTimeout(dir, command, env, 0)
+}
+
+// RunTimeout runs command in its own process group and kills the whole
+// group when the timeout fires.
+func (LocalBackend) RunTimeout(dir, command string, env []string, timeout time.Duration) (string, string, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	// Stop waiting for output held open by a process that escaped the group
+	cmd.WaitDelay = 5 * time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("%w after %s", ErrTimedOut, timeout)
+	}
+	return stdout.String(), stderr.String(), err
+}
+
+func (LocalBackend) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (LocalBackend) WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (LocalBackend) ReadDir(path string) ([]fs.DirEntry, error) {
+	return os.ReadDir(path)
+}
+
+func (LocalBackend) Stat(path string) (fs.FileInfo, error) {
+	return os.Stat(path)
+}
+
+// local reports whether tools run on this machine. Symlink containment
+// and the project-wide walks only apply then; a sandbox is expected to
+// enforce its own boundaries.
+func (t *ToolExecutor) local() bool {
+	_, ok := t.Backend.(LocalBackend)
+	return ok
+}
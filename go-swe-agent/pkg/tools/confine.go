@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// ErrOutsideWorkDir is returned for paths given to the file tools that lie
+// outside the working directory and the scratch directory.
+var ErrOutsideWorkDir = errors.New("path is outside the working directory")
+
+// resolvePath turns a path given to a file tool into an absolute, clean
+// one; relative paths are taken from the working directory. Unless
+// AllowOutsideWorkDir is set, the path must stay inside the working
+// directory or the scratch directory, before and after its symlinks are
+// resolved, so neither "../" nor an absolute path nor a symlink reaches
+// other files.
+func (t *ToolExecutor) resolvePath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(t.workingDir, path)
+	}
+	path = filepath.Clean(path)
+	if t.AllowOutsideWorkDir {
+		return path, nil
+	}
+	if !t.confined(path) {
+		return "", fmt.Errorf("%s: %w; use a path inside %s", path, ErrOutsideWorkDir, t.workingDir)
+	}
+	if err := t.checkSymlinks(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// confined reports whether path lies in the working directory or the
+// scratch directory. A path outside them by name is still confined when,
+// with symlinks resolved, it lands inside one: /private/tmp/project is
+// /tmp/project on macOS.
+func (t *ToolExecutor) confined(path string) bool {
+	roots := []string{t.workingDir}
+	if t.ScratchDir != "" {
+		roots = append(roots, t.ScratchDir)
+	}
+	for _, root := range roots {
+		if within(root, path) {
+			return true
+		}
+	}
+	if !t.local() {
+		return false
+	}
+	resolved, err := resolveExisting(path)
+	if err != nil {
+		return false
+	}
+	for _, root := range roots {
+		if real, err := filepath.EvalSymlinks(root); err == nil && within(real, resolved) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePathConfinement(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "repo")
+	scratch := filepath.Join(base, "scratch")
+	for _, dir := range []string{root, filepath.Join(root, "sub"), filepath.Join(base, "repo2"), scratch} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	executor := NewToolExecutor(root)
+	executor.ScratchDir = scratch
+
+	tests := []struct {
+		name string
+		path string
+		want string // resolved path, or "" when refused
+	}{
+		{"relative", "a.txt", filepath.Join(root, "a.txt")},
+		{"nested", "sub/a.txt", filepath.Join(root, "sub", "a.txt")},
+		{"dot dot staying inside", "sub/../a.txt", filepath.Join(root, "a.txt")},
+		{"dot dot leaving and coming back", "../repo/a.txt", filepath.Join(root, "a.txt")},
+		{"file named with dots", "..a.txt", filepath.Join(root, "..a.txt")},
+		{"root itself", ".", root},
+		{"dot dot traversal", "../secret.txt", ""},
+		{"deep dot dot traversal", "sub/../../../etc/passwd", ""},
+		{"absolute inside", filepath.Join(root, "a.txt"), filepath.Join(root, "a.txt")},
+		{"absolute outside", "/etc/passwd", ""},
+		{"absolute parent", base, ""},
+		{"sibling sharing the root as prefix", filepath.Join(base, "repo2", "a.txt"), ""},
+		{"relative sibling sharing the root as prefix", "../repo2/a.txt", ""},
+		{"scratch directory", filepath.Join(scratch, "out.txt"), filepath.Join(scratch, "out.txt")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := executor.resolvePath(tt.path)
+			if tt.want == "" {
+				if !errors.Is(err, ErrOutsideWorkDir) {
+					t.Errorf("resolvePath(%q) = %q, %v; want ErrOutsideWorkDir", tt.path, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("resolvePath(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePathAllowOutsideWorkDir(t *testing.T) {
+	root := t.TempDir()
+	executor := NewToolExecutor(root)
+	executor.AllowOutsideWorkDir = true
+	if got, err := executor.resolvePath("../elsewhere/a.txt"); err != nil || got != filepath.Join(filepath.Dir(root), "elsewhere", "a.txt") {
+		t.Errorf("resolvePath = %q, %v; want the path outside the working directory", got, err)
+	}
+}
+
+func TestWithin(t *testing.T) {
+	tests := []struct {
+		root, path string
+		want       bool
+	}{
+		{"/repo", "/repo", true},
+		{"/repo", "/repo/a/b.txt", true},
+		{"/repo", "/repo/..a", true},
+		{"/repo", "/repo2", false},
+		{"/repo", "/repo2/a.txt", false},
+		{"/repo", "/re", false},
+		{"/repo", "/", false},
+		{"/repo", "/other/repo", false},
+	}
+	for _, tt := range tests {
+		if got := within(tt.root, tt.path); got != tt.want {
+			t.Errorf("within(%q, %q) = %v, want %v", tt.root, tt.path, got, tt.want)
+		}
+	}
+}
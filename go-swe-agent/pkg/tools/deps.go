@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxDepsLines caps the dependencies the deps tool returns per ecosystem.
+const maxDepsLines = 200
+
+// depsSource describes how to list the dependencies of one ecosystem.
+type depsSource struct {
+	name      string
+	manifests []string // files whose presence in the working directory selects it
+	command   string
+}
+
+var depsSources = []depsSource{
+	{"go", []string{"go.mod"}, "go list -m all"},
+	{"node", []string{"package.json"}, "npm ls --depth=0"},
+	{"python", []string{"requirements.txt", "pyproject.toml", "setup.py", "setup.cfg", "Pipfile"}, "pip freeze 2>/dev/null || python3 -m pip freeze"},
+	{"rust", []string{"Cargo.toml"}, "cargo tree --depth 1 --prefix none"},
+}
+
+// depsManifests are the files whose change makes cached dependency lists stale.
+var depsManifests = map[string]bool{
+	"go.mod": true, "go.sum": true,
+	"package.json": true, "package-lock.json": true, "yarn.lock": true, "pnpm-lock.yaml": true,
+	"requirements.txt": true, "pyproject.toml": true, "setup.py": true, "setup.cfg": true, "Pipfile": true, "Pipfile.lock": true, "poetry.lock": true,
+	"Cargo.toml": true, "Cargo.lock": true,
+}
+
+// deps lists the installed dependencies of the project and their versions,
+// for each ecosystem with a manifest in the working directory. Lists are
+// cached for the run; writing a manifest or passing refresh lists again.
+func (t *ToolExecutor) deps(args map[string]interface{}) (string, error) {
+	ecosystem, _ := args["ecosystem"].(string)
+	filter, _ := args["filter"].(string)
+	refresh, _ := args["refresh"].(bool)
+
+	var sources []depsSource
+	for _, source := range depsSources {
+		if ecosystem != "" && source.name != ecosystem {
+			continue
+		}
+		if ecosystem != "" || t.hasManifest(source) {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		if ecosystem != "" {
+			return "", fmt.Errorf("unknown ecosystem %q: use go, node, python or rust", ecosystem)
+		}
+		return "", fmt.Errorf("no go.mod, package.json, Python or Cargo manifest found in %s", t.workingDir)
+	}
+
+	if t.depsCache == nil || refresh {
+		t.depsCache = make(map[string][]string)
+	}
+	var b strings.Builder
+	for _, source := range sources {
+		list, ok := t.depsCache[source.name]
+		if !ok {
+			var err error
+			if list, err = t.listDeps(source); err != nil {
+				fmt.Fprintf(&b, "== %s: %v\n", source.name, err)
+				continue
+			}
+			t.depsCache[source.name] = list
+		}
+		writeDeps(&b, source.name, list, filter)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func (t *ToolExecutor) hasManifest(source depsSource) bool {
+	for _, manifest := range source.manifests {
+		if _, err := t.Backend.Stat(filepath.Join(t.workingDir, manifest)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// listDeps runs the list command of an ecosystem and reduces its output to
+// one "name version" entry per line.
+func (t *ToolExecutor) listDeps(source depsSource) ([]string, error) {
+	stdout, stderr, err := t.Backend.Run(t.workingDir, source.command, nil)
+	// npm ls exits non-zero on missing or invalid packages but still lists them
+	if strings.TrimSpace(stdout) == "" {
+		if err == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s failed: %s", source.command, strings.TrimSpace(tailOf(stderr, 500)))
+	}
+
+	seen := make(map[string]bool)
+	var list []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if source.name == "node" {
+			line = strings.TrimLeft(line, "├└│─┬+`|- ")
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		list = append(list, line)
+	}
+	// The first line of go, npm and cargo output is the project itself
+	if source.name != "python" && len(list) > 0 {
+		list = list[1:]
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// writeDeps writes the entries of one ecosystem that contain filter, up to
+// maxDepsLines of them.
+func writeDeps(b *strings.Builder, name string, list []string, filter string) {
+	var matched []string
+	for _, entry := range list {
+		if filter == "" || strings.Contains(strings.ToLower(entry), strings.ToLower(filter)) {
+			matched = append(matched, entry)
+		}
+	}
+	fmt.Fprintf(b, "== %s (%d dependencies", name, len(list))
+	if filter != "" {
+		fmt.Fprintf(b, ", %d matching %q", len(matched), filter)
+	}
+	b.WriteString(")\n")
+	for i, entry := range matched {
+		if i == maxDepsLines {
+			fmt.Fprintf(b, "... %d more; pass filter to narrow the list\n", len(matched)-maxDepsLines)
+			break
+		}
+		b.WriteString(entry)
+		b.WriteString("\n")
+	}
+}
+
+// forgetDeps drops the cached dependency lists when path is a manifest.
+func (t *ToolExecutor) forgetDeps(path string) {
+	if depsManifests[filepath.Base(path)] {
+		t.depsCache = nil
+	}
+}
+
+func tailOf(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "..." + s[len(s)-n:]
+}
@@ -0,0 +1,234 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines around each change in
+	// a diff
+	diffContext = 3
+
+	// maxDiffCells bounds the line comparison of a diff; larger
+	// changes are shown as the old lines removed and the new ones added
+	maxDiffCells = 4 << 20
+
+	// maxPreviewLines caps the diff passed to Preview
+	maxPreviewLines = 500
+)
+
+// readContent reads path as the dry run left it: what write_file or
+// edit_file would have written, or else the file on disk.
+func (t *ToolExecutor) readContent(path string) ([]byte, error) {
+	if data, ok := t.dryRunFiles[path]; ok {
+		return data, nil
+	}
+	return t.Backend.ReadFile(path)
+}
+
+// dryRunWrite keeps data as the content of path instead of writing it and
+// previews the change as a diff.
+func (t *ToolExecutor) dryRunWrite(tool, path string, data []byte) {
+	name := t.journalPath(path)
+	oldName := name
+	old, err := t.readContent(path)
+	if err != nil {
+		oldName = ""
+	}
+	if t.dryRunFiles == nil {
+		t.dryRunFiles = make(map[string][]byte)
+	}
+	t.dryRunFiles[path] = data
+	if t.Preview != nil {
+		t.Preview(tool, capLines(UnifiedDiff(oldName, name, string(old), string(data)), maxPreviewLines))
+	}
+}
+
+// dryRunBash previews a command instead of running it.
+func (t *ToolExecutor) dryRunBash(command string) {
+	if t.Preview != nil {
+		t.Preview("bash", "$ "+command)
+	}
+}
+
+// DryRunFiles returns the files write_file and edit_file would have
+// changed in a dry run, relative to the working directory where possible.
+func (t *ToolExecutor) DryRunFiles() []string {
+	files := make([]string, 0, len(t.dryRunFiles))
+	for path := range t.dryRunFiles {
+		files = append(files, t.journalPath(path))
+	}
+	sort.Strings(files)
+	return files
+}
+
+// diffOp is one line of a diff: ' ' kept, '-' removed or '+' added.
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// UnifiedDiff returns the change from old to new as a unified diff with
+// diffContext lines of context, as git shows it. An empty oldName marks a
+// created file and an empty newName a deleted one.
+func UnifiedDiff(oldName, newName, old, new string) string {
+	ops := diffLines(splitLines(old), splitLines(new))
+
+	// Lines of the old and new file before each op, for hunk headers
+	oldLine := make([]int, len(ops)+1)
+	newLine := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", diffName("a/", oldName), diffName("b/", newName))
+	changed := false
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		changed = true
+		start := max(i-diffContext, 0)
+		end := i + 1
+		// Changes separated by at most twice the context share a hunk
+		for j := i; j < len(ops) && j-end <= 2*diffContext; j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			}
+		}
+		end = min(end+diffContext, len(ops))
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldLine[start], oldLine[end]), hunkRange(newLine[start], newLine[end]))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.text)
+			out.WriteByte('\n')
+		}
+		i = end
+	}
+	if !changed {
+		out.WriteString("(no changes)\n")
+	}
+	return out.String()
+}
+
+// diffName is the name of one side of a diff: /dev/null when the file
+// does not exist on that side.
+func diffName(prefix, name string) string {
+	if name == "" {
+		return "/dev/null"
+	}
+	return prefix + name
+}
+
+// hunkRange renders the lines from (before the first) to of a hunk header.
+func hunkRange(from, to int) string {
+	if to == from {
+		return fmt.Sprintf("%d,0", from)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
+
+// diffLines pairs the lines of a and b along their longest common
+// subsequence. The common start and end are matched first, so a small edit
+// to a large file stays cheap.
+func diffLines(a, b []string) []diffOp {
+	var prefix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	var suffix int
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// splitLines splits text into lines without their line endings.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// capLines shortens text to at most limit lines.
+func capLines(text string, limit int) string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) <= limit {
+		return text
+	}
+	return strings.Join(lines[:limit], "") + fmt.Sprintf("... (%d more lines)\n", len(lines)-limit)
+}
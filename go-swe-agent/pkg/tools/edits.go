@@ -0,0 +1,39 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// taskEdits counts the write_file and edit_file calls per file in the current task, so
+// the model can be told when it keeps rewriting the same file.
+type taskEdits struct {
+	taskID string
+	counts map[string]int
+}
+
+// countEdit records a write to path and returns the note for its result.
+func (t *ToolExecutor) countEdit(path, content string) string {
+	if t.edits.counts == nil || t.edits.taskID != t.TaskID {
+		t.edits = taskEdits{taskID: t.TaskID, counts: make(map[string]int)}
+	}
+	t.edits.counts[path]++
+	return t.editNote(path, content)
+}
+
+// editNote reminds the model how often it wrote path in this task, once it
+// has done so more than once. content is the file's current content.
+func (t *ToolExecutor) editNote(path, content string) string {
+	if t.edits.taskID != t.TaskID {
+		return ""
+	}
+	count := t.edits.counts[path]
+	if count < 2 {
+		return ""
+	}
+	lines := strings.Count(content, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		lines++
+	}
+	return fmt.Sprintf("\n\n[You have written %s %d times in this task; it now has %d lines. If the edits keep undoing each other, step back and reconsider the approach.]", t.journalPath(path), count, lines)
+}
@@ -0,0 +1,235 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Text encodings understood by read_file and write_file.
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingLatin1      = "latin-1"
+	EncodingWindows1252 = "windows-1252"
+	EncodingUTF16LE     = "utf-16le"
+	EncodingUTF16BE     = "utf-16be"
+
+	// encodingAuto detects the encoding of an existing file
+	encodingAuto = "auto"
+)
+
+var encodingAliases = map[string]string{
+	"utf-8":        EncodingUTF8,
+	"utf8":         EncodingUTF8,
+	"latin-1":      EncodingLatin1,
+	"latin1":       EncodingLatin1,
+	"iso-8859-1":   EncodingLatin1,
+	"iso8859-1":    EncodingLatin1,
+	"windows-1252": EncodingWindows1252,
+	"cp1252":       EncodingWindows1252,
+	"utf-16":       EncodingUTF16LE,
+	"utf16":        EncodingUTF16LE,
+	"utf-16le":     EncodingUTF16LE,
+	"utf-16be":     EncodingUTF16BE,
+	"auto":         encodingAuto,
+}
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// windows1252 maps bytes 0x80-0x9F to Unicode. Undefined bytes map to the
+// C1 control of the same value, so they round-trip.
+var windows1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// encodingArg returns the normalized encoding requested by a tool call.
+// Without one, files are auto-detected when DetectEncoding is set and
+// handled as raw bytes ("") otherwise.
+func (t *ToolExecutor) encodingArg(args map[string]interface{}) (string, error) {
+	name, _ := args["encoding"].(string)
+	if name == "" {
+		if t.DetectEncoding {
+			return encodingAuto, nil
+		}
+		return "", nil
+	}
+	enc, ok := encodingAliases[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", fmt.Errorf("unsupported encoding %q; use utf-8, latin-1, windows-1252, utf-16le, utf-16be or auto", name)
+	}
+	return enc, nil
+}
+
+// detectEncoding guesses the encoding of data from its byte order mark,
+// the distribution of NUL bytes, and UTF-8 validity. It also reports
+// whether data starts with a byte order mark.
+func detectEncoding(data []byte) (string, bool) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return EncodingUTF8, true
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return EncodingUTF16LE, true
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return EncodingUTF16BE, true
+	}
+
+	// ASCII-heavy UTF-16 text without a BOM has a NUL in every other byte
+	if len(data) >= 4 && len(data)%2 == 0 {
+		evenNUL, oddNUL := 0, 0
+		for i := 0; i < len(data); i += 2 {
+			if data[i] == 0 {
+				evenNUL++
+			}
+			if data[i+1] == 0 {
+				oddNUL++
+			}
+		}
+		pairs := len(data) / 2
+		if oddNUL*10 >= pairs*7 && evenNUL == 0 {
+			return EncodingUTF16LE, false
+		}
+		if evenNUL*10 >= pairs*7 && oddNUL == 0 {
+			return EncodingUTF16BE, false
+		}
+	}
+
+	if utf8.Valid(data) {
+		return EncodingUTF8, false
+	}
+	// Windows-1252 is a superset of the printable Latin-1 range
+	return EncodingWindows1252, false
+}
+
+// decodeText converts data in the given encoding to UTF-8, dropping any
+// byte order mark.
+func decodeText(data []byte, enc string) (string, error) {
+	switch enc {
+	case EncodingUTF8:
+		data = bytes.TrimPrefix(data, bomUTF8)
+		if !utf8.Valid(data) {
+			return "", fmt.Errorf("file is not valid UTF-8; try encoding \"auto\" or a legacy encoding")
+		}
+		return string(data), nil
+	case EncodingLatin1, EncodingWindows1252:
+		var b strings.Builder
+		for _, c := range data {
+			if enc == EncodingWindows1252 && c >= 0x80 && c <= 0x9F {
+				b.WriteRune(windows1252[c-0x80])
+			} else {
+				b.WriteRune(rune(c))
+			}
+		}
+		return b.String(), nil
+	case EncodingUTF16LE, EncodingUTF16BE:
+		data = bytes.TrimPrefix(data, bomUTF16LE)
+		data = bytes.TrimPrefix(data, bomUTF16BE)
+		if len(data)%2 != 0 {
+			return "", fmt.Errorf("file has an odd number of bytes and is not valid %s", enc)
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			if enc == EncodingUTF16LE {
+				units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+			} else {
+				units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+			}
+		}
+		return string(utf16.Decode(units)), nil
+	}
+	return "", fmt.Errorf("unsupported encoding %q", enc)
+}
+
+// encodeText converts UTF-8 text to the given encoding, optionally with a
+// byte order mark. Characters the encoding cannot represent are an error
+// rather than being replaced silently.
+func encodeText(text string, enc string, bom bool) ([]byte, error) {
+	var out bytes.Buffer
+	switch enc {
+	case EncodingUTF8:
+		if bom {
+			out.Write(bomUTF8)
+		}
+		out.WriteString(text)
+	case EncodingLatin1, EncodingWindows1252:
+		for _, r := range text {
+			c, ok := singleByte(r, enc)
+			if !ok {
+				return nil, fmt.Errorf("character %q cannot be written in %s", r, enc)
+			}
+			out.WriteByte(c)
+		}
+	case EncodingUTF16LE, EncodingUTF16BE:
+		if bom {
+			if enc == EncodingUTF16LE {
+				out.Write(bomUTF16LE)
+			} else {
+				out.Write(bomUTF16BE)
+			}
+		}
+		for _, u := range utf16.Encode([]rune(text)) {
+			if enc == EncodingUTF16LE {
+				out.WriteByte(byte(u))
+				out.WriteByte(byte(u >> 8))
+			} else {
+				out.WriteByte(byte(u >> 8))
+				out.WriteByte(byte(u))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", enc)
+	}
+	return out.Bytes(), nil
+}
+
+// singleByte maps r to its byte in a single-byte encoding.
+func singleByte(r rune, enc string) (byte, bool) {
+	if enc == EncodingWindows1252 {
+		for i, w := range windows1252 {
+			if w == r {
+				return byte(0x80 + i), true
+			}
+		}
+		if r >= 0x80 && r <= 0x9F {
+			return 0, false
+		}
+	}
+	if r > 0xFF {
+		return 0, false
+	}
+	return byte(r), true
+}
+
+// encodeFor encodes content for the file at path. With encodingAuto the
+// existing file's encoding and byte order mark are kept; new files are
+// UTF-8. It returns the data and the encoding used.
+func (t *ToolExecutor) encodeFor(path, content, enc string) ([]byte, string, error) {
+	existing, err := t.readContent(path)
+	hasFile := err == nil
+	var bom bool
+	if hasFile {
+		var detected string
+		detected, bom = detectEncoding(existing)
+		if enc == encodingAuto {
+			enc = detected
+		} else if detected != enc {
+			bom = false
+		}
+	} else if enc == encodingAuto {
+		enc = EncodingUTF8
+	} else {
+		// New UTF-16 files get a byte order mark so editors recognize them
+		bom = enc == EncodingUTF16LE || enc == EncodingUTF16BE
+	}
+
+	data, err := encodeText(content, enc, bom)
+	return data, enc, err
+}
@@ -0,0 +1,17 @@
+package tools
+
+import "github.com/openswe/go-swe-agent/pkg/state"
+
+// Fork returns a ToolExecutor with the same settings, journal and log for a
+// task that runs alongside others. It keeps its own per-task state and
+// records tool usage into usage, which the caller merges once the task is
+// done.
+func (t *ToolExecutor) Fork(usage state.ToolUsage) *ToolExecutor {
+	fork := *t
+	fork.Usage = usage
+	fork.TaskID = ""
+	fork.createConflicts = nil
+	fork.depsCache = nil
+	fork.edits = taskEdits{}
+	return &fork
+}
@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Policies for writes to generated or vendored files.
+const (
+	GeneratedWarn  = "warn"  // write, but tell the model to edit the source
+	GeneratedBlock = "block" // refuse the write
+	GeneratedOff   = "off"
+)
+
+var generatedNamePatterns = []string{
+	"*.pb.go", "*.pb.gw.go", "*_generated.go", "*.gen.go", "zz_generated*",
+	"*_pb2.py", "*_pb2_grpc.py", "*.g.dart", "*.min.js", "*.min.css", "*.bundle.js",
+}
+
+var vendoredDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"third_party":  true,
+	"dist":         true,
+}
+
+var generatedHeader = regexp.MustCompile(`(?i)(code generated .*do not edit|@generated|auto-generated|autogenerated)`)
+
+// generatedReason explains why the file at path looks generated or
+// vendored, or returns "" when it looks like ordinary source.
+func (t *ToolExecutor) generatedReason(path string) string {
+	rel := t.journalPath(path)
+	name := filepath.Base(rel)
+
+	for _, dir := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if vendoredDirs[dir] {
+			return fmt.Sprintf("it is under %s/", dir)
+		}
+	}
+	for _, pattern := range generatedNamePatterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return fmt.Sprintf("its name matches %s", pattern)
+		}
+	}
+	if attr := t.linguistAttribute(rel); attr != "" {
+		return fmt.Sprintf(".gitattributes marks it %s", attr)
+	}
+	if header := t.generatedHeaderLine(path); header != "" {
+		return fmt.Sprintf("its header says %q", header)
+	}
+	return ""
+}
+
+// generatedHeaderLine returns the generated-code marker among the first
+// lines of an existing file.
+func (t *ToolExecutor) generatedHeaderLine(path string) string {
+	data, err := t.Backend.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for i := 0; i < 10 && scanner.Scan(); i++ {
+		if line := strings.TrimSpace(scanner.Text()); generatedHeader.MatchString(line) {
+			return line
+		}
+	}
+	return ""
+}
+
+// linguistAttribute returns "linguist-generated" or "linguist-vendored" when
+// the working directory's .gitattributes sets one for rel.
+func (t *ToolExecutor) linguistAttribute(rel string) string {
+	data, err := t.Backend.ReadFile(filepath.Join(t.workingDir, ".gitattributes"))
+	if err != nil {
+		return ""
+	}
+
+	var matched string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || !attributePatternMatches(fields[0], rel) {
+			continue
+		}
+		// Later lines override earlier ones, as in git
+		for _, attr := range fields[1:] {
+			switch attr {
+			case "linguist-generated", "linguist-generated=true", "linguist-vendored", "linguist-vendored=true":
+				matched = strings.TrimSuffix(attr, "=true")
+			case "-linguist-generated", "linguist-generated=false", "-linguist-vendored", "linguist-vendored=false":
+				matched = ""
+			}
+		}
+	}
+	return matched
+}
+
+// attributePatternMatches approximates gitattributes matching: patterns
+// without a slash match the file name at any depth, others match the path
+// from the repository root; a trailing /** matches everything below.
+func attributePatternMatches(pattern, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	if strings.HasSuffix(pattern, "/**") {
+		return strings.HasPrefix(rel, strings.TrimPrefix(strings.TrimSuffix(pattern, "**"), "/"))
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := filepath.Match(pattern, filepath.Base(rel))
+		return ok
+	}
+	ok, _ := filepath.Match(strings.TrimPrefix(pattern, "/"), rel)
+	return ok
+}
+
+// checkGenerated applies GeneratedPolicy to a write to path. It returns a
+// warning to append to the tool output, or an error when writes are blocked.
+func (t *ToolExecutor) checkGenerated(path string) (string, error) {
+	if t.GeneratedPolicy == GeneratedOff {
+		return "", nil
+	}
+	reason := t.generatedReason(path)
+	if reason == "" {
+		return "", nil
+	}
+
+	message := fmt.Sprintf("%s looks generated or vendored (%s). Change the source it is generated from (or the upstream dependency) and regenerate instead of editing it directly.", t.journalPath(path), reason)
+	if t.GeneratedPolicy == GeneratedBlock {
+		return "", fmt.Errorf("refusing to edit %s", message)
+	}
+	return "\nWarning: " + message, nil
+}
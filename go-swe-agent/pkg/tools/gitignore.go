@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultIgnoredDirs are directories of version control, dependencies and
+// build output that list_files, search and glob leave out even when no
+// .gitignore names them.
+var DefaultIgnoredDirs = []string{".git", "node_modules", "vendor", "dist", "build", "target", "__pycache__", ".venv"}
+
+// maxIgnoredNames caps the ignored entries named in a listing.
+const maxIgnoredNames = 5
+
+// includeIgnoredProperty is the include_ignored argument of list_files,
+// search and glob.
+var includeIgnoredProperty = map[string]interface{}{
+	"type":        "boolean",
+	"description": "Also include files ignored by .gitignore and dependency or build directories such as node_modules (optional)",
+}
+
+// gitignoreLevel holds the .gitignore rules of one directory, which apply
+// to paths below it.
+type gitignoreLevel struct {
+	dir   string // slash-separated, relative to the working directory
+	rules *IgnoreRules
+}
+
+// ignoreFilter tells which paths below root list_files, search and glob
+// leave out: those a .gitignore of the working directory ignores, and
+// directories named in IgnoredDirs. It is off when include_ignored is set
+// or root itself is ignored, as asking for an ignored directory by name
+// means its content is wanted.
+type ignoreFilter struct {
+	t      *ToolExecutor
+	root   string
+	off    bool
+	levels map[string][]gitignoreLevel // by directory, slash-separated and relative to the working directory
+}
+
+// newIgnoreFilter returns the filter for paths below root, an absolute
+// path, honoring the include_ignored argument.
+func (t *ToolExecutor) newIgnoreFilter(root string, args map[string]interface{}) *ignoreFilter {
+	f := &ignoreFilter{t: t, root: root, levels: make(map[string][]gitignoreLevel)}
+	if include, _ := args["include_ignored"].(bool); include {
+		f.off = true
+		return f
+	}
+	if within(t.workingDir, root) && root != t.workingDir {
+		f.off = f.match(t.workingDir, root, true)
+	}
+	return f
+}
+
+// ignored reports whether path, below the filter's root, is left out.
+func (f *ignoreFilter) ignored(path string, isDir bool) bool {
+	return !f.off && f.match(f.root, path, isDir)
+}
+
+// match reports whether path is ignored, looking for ignored directories
+// only below from.
+func (f *ignoreFilter) match(from, path string, isDir bool) bool {
+	rel, err := filepath.Rel(from, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i, part := range parts {
+		if (i < len(parts)-1 || isDir) && f.ignoredDir(part) {
+			return true
+		}
+	}
+	if !within(f.t.workingDir, path) {
+		return false
+	}
+	return gitignored(f.rules(filepath.Dir(path)), f.t.workingDir, path, isDir)
+}
+
+func (f *ignoreFilter) ignoredDir(name string) bool {
+	for _, dir := range f.t.IgnoredDirs {
+		if name == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// rules returns the .gitignore rules that apply in dir, an absolute path
+// within the working directory: those of dir and of the directories
+// above it.
+func (f *ignoreFilter) rules(dir string) []gitignoreLevel {
+	rel, _ := filepath.Rel(f.t.workingDir, dir)
+	if rel = filepath.ToSlash(rel); rel == "." {
+		rel = ""
+	}
+	if levels, ok := f.levels[rel]; ok {
+		return levels
+	}
+	var parent []gitignoreLevel
+	if rel != "" {
+		parent = f.rules(filepath.Dir(dir))
+	}
+	levels := f.t.loadGitignore(parent, rel)
+	f.levels[rel] = levels
+	return levels
+}
+
+// filterIgnored drops search result lines from files the filter leaves
+// out.
+func (f *ignoreFilter) filterIgnored(output string) string {
+	if f.off {
+		return output
+	}
+	var kept []string
+	for _, line := range strings.Split(output, "\n") {
+		if prefix := locationPrefix.FindString(line); prefix != "" {
+			file := strings.SplitN(prefix, ":", 2)[0]
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(f.t.workingDir, file)
+			}
+			if f.ignored(file, false) {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// ignoredNote tells the model which entries a listing left out.
+func ignoredNote(names []string) string {
+	shown := names
+	if len(shown) > maxIgnoredNames {
+		shown = shown[:maxIgnoredNames]
+	}
+	note := fmt.Sprintf("(%d ignored entries not shown: %s", len(names), strings.Join(shown, ", "))
+	if len(names) > len(shown) {
+		note += ", ..."
+	}
+	return note + "; set include_ignored to list them)\n"
+}
+
+// excludeFlags returns the flags that keep rg, or grep when rg is false,
+// out of the directories in IgnoredDirs, so they are not searched at all.
+func (f *ignoreFilter) excludeFlags(rg bool) string {
+	if f.off {
+		return ""
+	}
+	var flags string
+	for _, dir := range f.t.IgnoredDirs {
+		if rg {
+			flags += " -g " + shellQuote("!"+dir+"/")
+		} else {
+			flags += " --exclude-dir=" + shellQuote(dir)
+		}
+	}
+	return flags
+}
+
+// loadGitignore adds the rules of the .gitignore in dir, a slash-separated
+// path relative to the working directory, to levels.
+func (t *ToolExecutor) loadGitignore(levels []gitignoreLevel, dir string) []gitignoreLevel {
+	data, err := t.Backend.ReadFile(filepath.Join(t.workingDir, filepath.FromSlash(dir), ".gitignore"))
+	if err != nil {
+		return levels
+	}
+	rules, err := ParseIgnoreRules(string(data))
+	if err != nil {
+		return levels
+	}
+	// Copied so that sibling directories do not share the appended rules
+	return append(levels[:len(levels):len(levels)], gitignoreLevel{dir: dir, rules: rules})
+}
+
+// gitignored reports whether any .gitignore in levels ignores path.
+func gitignored(levels []gitignoreLevel, workingDir, path string, isDir bool) bool {
+	for _, level := range levels {
+		rel, err := filepath.Rel(filepath.Join(workingDir, filepath.FromSlash(level.dir)), path)
+		if err != nil {
+			continue
+		}
+		if ignored, _ := level.rules.Match(rel, isDir); ignored {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxGlobResults caps the paths glob returns
+	maxGlobResults = 200
+
+	noGlobMatches = "No files match"
+)
+
+// glob lists the files under path whose path relative to it matches
+// pattern, shown relative to the working directory. * and ? stay within a
+// directory and ** crosses directories. Files hidden by the
+// AgentIgnoreFile or inside skipped submodules, and .git directories, are
+// left out, and so are ignored files unless include_ignored is set.
+func (t *ToolExecutor) glob(args map[string]interface{}) (string, error) {
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		return "", fmt.Errorf("pattern is required")
+	}
+	re, err := globRegexp(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	root := t.workingDir
+	if p, ok := args["path"].(string); ok && p != "" {
+		root = p
+	}
+	root, err = t.resolvePath(root)
+	if err != nil {
+		return "", err
+	}
+	if err := t.checkHidden(root); err != nil {
+		return "", err
+	}
+	if err := t.checkSubmodule(root); err != nil {
+		return "", err
+	}
+	info, err := t.Backend.Stat(root)
+	if err != nil {
+		return "", t.notFound(root, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", root)
+	}
+
+	var matches []string
+	total := 0
+	t.globDir(root, root, t.newIgnoreFilter(root, args), func(rel string) {
+		if !re.MatchString(rel) {
+			return
+		}
+		total++
+		if len(matches) < maxGlobResults {
+			matches = append(matches, t.journalPath(filepath.Join(root, filepath.FromSlash(rel))))
+		}
+	})
+	if total == 0 {
+		return noGlobMatches, nil
+	}
+	output := strings.Join(matches, "\n") + "\n"
+	if total > len(matches) {
+		output += fmt.Sprintf("... %d more files match; narrow the pattern or path\n", total-len(matches))
+	}
+	return output, nil
+}
+
+// globDir passes every file below dir that is not ignored to match, with
+// its slash-separated path relative to root. Symlinked directories are not
+// followed.
+func (t *ToolExecutor) globDir(root, dir string, filter *ignoreFilter, match func(rel string)) {
+	entries, err := t.Backend.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		full := filepath.Join(dir, entry.Name())
+		if entry.Name() == ".git" || filter.ignored(full, entry.IsDir()) {
+			continue
+		}
+		if hidden, _ := t.hidden(full, entry.IsDir()); hidden {
+			continue
+		}
+		if entry.IsDir() {
+			if _, skipped := t.skippedSubmodule(full); !skipped {
+				t.globDir(root, full, filter, match)
+			}
+			continue
+		}
+		rel, _ := filepath.Rel(root, full)
+		match(filepath.ToSlash(rel))
+	}
+}
+
+// globRegexp translates a glob pattern into a regular expression matched
+// against whole slash-separated paths, with the wildcards of .gitignore
+// patterns.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	re, err := regexp.Compile(ignoreRegexp("/" + strings.TrimPrefix(pattern, "/")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// How resource limits are enforced.
+const (
+	limitCgroup = "cgroup" // a transient systemd scope caps memory and CPU
+	limitRlimit = "rlimit" // ulimit caps the address space; CPU is not capped
+)
+
+// ResourceLimits caps the memory and CPU of the commands the bash tool
+// runs, so a runaway build or test cannot take down the machine.
+type ResourceLimits struct {
+	MemoryBytes int64   // zero for no memory limit
+	CPUs        float64 // zero for no CPU limit, e.g. 1.5 for one and a half cores
+
+	method string
+}
+
+// NewResourceLimits picks how to enforce the limits on this machine: a
+// transient systemd scope, which puts commands in their own cgroup, or
+// failing that ulimit, which can only limit memory. It returns nil limits
+// when nothing can be enforced, and warnings for limits that will not be.
+func NewResourceLimits(memoryBytes int64, cpus float64) (*ResourceLimits, []string) {
+	if memoryBytes <= 0 && cpus <= 0 {
+		return nil, nil
+	}
+	if runtime.GOOS != "linux" {
+		return nil, []string{"resource limits are only supported on Linux; commands run without them"}
+	}
+	limits := &ResourceLimits{MemoryBytes: memoryBytes, CPUs: cpus}
+	if systemdScopes() {
+		limits.method = limitCgroup
+		return limits, nil
+	}
+
+	var warnings []string
+	if cpus > 0 {
+		warnings = append(warnings, "the CPU limit needs cgroups through systemd-run, which are not available; commands run without it")
+	}
+	if memoryBytes <= 0 {
+		return nil, warnings
+	}
+	limits.CPUs = 0
+	limits.method = limitRlimit
+	warnings = append(warnings, "without cgroups through systemd-run, the memory limit caps each process's address space, which can stop programs that reserve much more memory than they use")
+	return limits, warnings
+}
+
+// systemdScopes reports whether commands can be started in a transient
+// systemd scope by this user.
+func systemdScopes() bool {
+	if _, err := exec.LookPath("systemd-run"); err != nil {
+		return false
+	}
+	return exec.Command("systemd-run", append(scopeArgs(), "true")...).Run() == nil
+}
+
+func scopeArgs() []string {
+	args := []string{"--scope", "--quiet", "--collect"}
+	if os.Geteuid() != 0 {
+		args = append([]string{"--user"}, args...)
+	}
+	return args
+}
+
+// wrap returns command changed to run under the limits.
+func (l *ResourceLimits) wrap(command string) string {
+	switch l.method {
+	case limitCgroup:
+		args := scopeArgs()
+		if l.MemoryBytes > 0 {
+			args = append(args, fmt.Sprintf("-p MemoryMax=%d", l.MemoryBytes), "-p MemorySwapMax=0")
+		}
+		if l.CPUs > 0 {
+			args = append(args, fmt.Sprintf("-p CPUQuota=%d%%", int(l.CPUs*100)))
+		}
+		return "systemd-run " + strings.Join(args, " ") + " -- bash -c " + shellQuote(command)
+	case limitRlimit:
+		return fmt.Sprintf("ulimit -v %d\n%s", l.MemoryBytes/1024, command)
+	}
+	return command
+}
+
+// String describes the limits, e.g. "2048 MB memory, 2 CPUs (cgroup)".
+func (l *ResourceLimits) String() string {
+	var parts []string
+	if l.MemoryBytes > 0 {
+		parts = append(parts, fmt.Sprintf("%d MB memory", l.MemoryBytes/(1024*1024)))
+	}
+	if l.CPUs > 0 {
+		parts = append(parts, fmt.Sprintf("%g CPUs", l.CPUs))
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, ", "), l.method)
+}
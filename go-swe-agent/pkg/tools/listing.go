@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// Orders list_files can sort entries in.
+const (
+	SortByName  = "name"  // alphabetical (default)
+	SortBySize  = "size"  // largest files first, then directories by name
+	SortByMtime = "mtime" // most recently modified first
+)
+
+// listedEntry is a directory entry with the metadata list_files sorts by.
+type listedEntry struct {
+	entry fs.DirEntry
+	info  fs.FileInfo // nil when it could not be read
+}
+
+func (e listedEntry) size() int64 {
+	if e.info == nil {
+		return 0
+	}
+	return e.info.Size()
+}
+
+func (e listedEntry) modTime() time.Time {
+	if e.info == nil {
+		return time.Time{}
+	}
+	return e.info.ModTime()
+}
+
+// sortEntries orders entries for list_files. Ties keep name order.
+func sortEntries(entries []listedEntry, by string) error {
+	var less func(a, b listedEntry) bool
+	switch by {
+	case "", SortByName:
+		return nil
+	case SortBySize:
+		less = func(a, b listedEntry) bool {
+			if a.entry.IsDir() != b.entry.IsDir() {
+				return !a.entry.IsDir()
+			}
+			return !a.entry.IsDir() && a.size() > b.size()
+		}
+	case SortByMtime:
+		less = func(a, b listedEntry) bool { return a.modTime().After(b.modTime()) }
+	default:
+		return fmt.Errorf("unknown sort order %q (use name, size or mtime)", by)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return less(entries[i], entries[j]) })
+	return nil
+}
+
+// formatModTime renders a modification time for list_files, in local time
+// to the minute.
+func formatModTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
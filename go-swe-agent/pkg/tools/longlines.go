@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxLineLength is the longest line, in bytes, that read_file and
+// search return in full. Minified and data files can have lines that are
+// megabytes long.
+const DefaultMaxLineLength = 2000
+
+// shortenLines cuts every line longer than maxLen down to about maxLen
+// bytes, keeping the line structure intact. A line is shortened around
+// the first match of match when given, and to its head and tail otherwise.
+// maxLen <= 0 disables shortening.
+func shortenLines(text string, maxLen int, match *regexp.Regexp) string {
+	if maxLen <= 0 || len(text) <= maxLen {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	changed := false
+	for i, line := range lines {
+		if len(line) > maxLen {
+			lines[i] = shortenLine(line, maxLen, match)
+			changed = true
+		}
+	}
+	if !changed {
+		return text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// locationPrefix matches the "path:line:" prefix of search results.
+var locationPrefix = regexp.MustCompile(`^[^:\n]{1,500}:\d+:`)
+
+func shortenLine(line string, maxLen int, match *regexp.Regexp) string {
+	if match != nil {
+		// Keep the location of a search result in front of the excerpt
+		prefix := locationPrefix.FindString(line)
+		line = line[len(prefix):]
+		if loc := match.FindStringIndex(line); loc != nil {
+			start := runeStart(line, loc[0]-maxLen/2)
+			if start < 0 {
+				start = 0
+			}
+			end := runeStart(line, start+maxLen)
+			if end > len(line) {
+				end = len(line)
+			}
+			var b strings.Builder
+			b.WriteString(prefix)
+			if start > 0 {
+				fmt.Fprintf(&b, "[… %d bytes] ", start)
+			}
+			b.WriteString(line[start:end])
+			if end < len(line) {
+				fmt.Fprintf(&b, " [%d bytes …]", len(line)-end)
+			}
+			return b.String()
+		}
+		line = prefix + line
+	}
+
+	head := runeStart(line, maxLen/2)
+	tail := runeStart(line, len(line)-maxLen/2)
+	return fmt.Sprintf("%s [… %d bytes of a %d-byte line omitted …] %s", line[:head], tail-head, len(line), line[tail:])
+}
+
+// runeStart moves i back to the start of the UTF-8 sequence it points into.
+func runeStart(s string, i int) int {
+	if i <= 0 || i >= len(s) {
+		return i
+	}
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
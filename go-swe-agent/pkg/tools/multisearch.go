@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// searchPatterns returns the patterns of a search call: the single
+// pattern, the patterns array, or both.
+func searchPatterns(args map[string]interface{}) ([]string, error) {
+	var patterns []string
+	if p, ok := args["pattern"].(string); ok && p != "" {
+		patterns = append(patterns, p)
+	}
+	if list, ok := args["patterns"].([]interface{}); ok {
+		for _, item := range list {
+			if p, ok := item.(string); ok && p != "" {
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("search requires 'pattern' or 'patterns' parameter")
+	}
+	return patterns, nil
+}
+
+// combinedRegexp matches any of the patterns that are valid Go regular
+// expressions, or returns nil if none are.
+func combinedRegexp(patterns []string) *regexp.Regexp {
+	var parts []string
+	for _, p := range patterns {
+		if _, err := regexp.Compile(p); err == nil {
+			parts = append(parts, "(?:"+p+")")
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(strings.Join(parts, "|"))
+}
+
+// groupByPattern splits search output into a section per pattern so the
+// model can tell which pattern each match came from. A line matching
+// several patterns is listed under each. Patterns that are not valid Go
+// regular expressions are matched as plain text; lines still unattributed,
+// and messages from the search tool, are kept in a final section.
+func groupByPattern(output string, patterns []string) string {
+	regexps := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		regexps[i], _ = regexp.Compile(p)
+	}
+
+	groups := make([][]string, len(patterns))
+	var other []string
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		prefix := locationPrefix.FindString(line)
+		if prefix == "" {
+			if line != "" {
+				other = append(other, line)
+			}
+			continue
+		}
+		text := line[len(prefix):]
+		matched := false
+		for i, re := range regexps {
+			if (re != nil && re.MatchString(text)) || (re == nil && strings.Contains(text, patterns[i])) {
+				groups[i] = append(groups[i], line)
+				matched = true
+			}
+		}
+		if !matched {
+			other = append(other, line)
+		}
+	}
+
+	var b strings.Builder
+	for i, p := range patterns {
+		fmt.Fprintf(&b, "== %s (%s)\n", p, matchCount(len(groups[i])))
+		for _, line := range groups[i] {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	if len(other) > 0 {
+		b.WriteString("== other output\n")
+		b.WriteString(strings.Join(other, "\n"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func matchCount(n int) string {
+	switch n {
+	case 0:
+		return "no matches"
+	case 1:
+		return "1 match"
+	}
+	return fmt.Sprintf("%d matches", n)
+}
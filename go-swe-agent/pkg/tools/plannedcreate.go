@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// maxConflictContent caps the contents shown when a file planned as new
+// already exists.
+const maxConflictContent = 4000
+
+// PlanCreates tells the tools which files the current task was planned to
+// create, as paths relative to the working directory, and returns those
+// that already exist. The first write_file to one of them is refused with
+// the file's current contents, so the model adapts to what is there
+// instead of overwriting it blindly.
+func (t *ToolExecutor) PlanCreates(paths []string) []string {
+	t.createConflicts = make(map[string]bool)
+	var existing []string
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(t.workingDir, path)
+		}
+		path = filepath.Clean(path)
+		if _, err := t.Backend.Stat(path); err == nil && !t.createConflicts[path] {
+			t.createConflicts[path] = true
+			existing = append(existing, t.journalPath(path))
+		}
+	}
+	return existing
+}
+
+// checkPlannedCreate refuses the first write to a file the task planned
+// to create but found already present, showing its contents instead.
+func (t *ToolExecutor) checkPlannedCreate(path string) error {
+	path = filepath.Clean(path)
+	if !t.createConflicts[path] {
+		return nil
+	}
+	delete(t.createConflicts, path)
+
+	data, err := t.Backend.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	content := string(data)
+	if len(content) > maxConflictContent {
+		content = content[:maxConflictContent] + "\n... (truncated; use read_file for the rest)"
+	}
+	return fmt.Errorf("the plan says this task creates %s, but it already exists, from an earlier task or a stale tree. Nothing was written. Its current contents:\n%s\nAdapt to what is there: keep what is still needed and call write_file again with the merged content", t.journalPath(path), content)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package tools
+
+import "os/exec"
+
+// setProcessGroup does nothing here; only cmd itself is stopped.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills cmd. Processes it started may outlive it.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultProtectedPaths are the files that configure the agent itself and
+// that it must not change on its own.
+var DefaultProtectedPaths = []string{AgentIgnoreFile}
+
+// protectedPattern returns the ProtectedPaths pattern that covers path, or
+// "" when the file may be changed. Patterns follow the rules of
+// attributePatternMatches.
+func (t *ToolExecutor) protectedPattern(path string) string {
+	rel := t.journalPath(path)
+	for _, pattern := range t.ProtectedPaths {
+		if attributePatternMatches(pattern, rel) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// checkProtected refuses a write to a protected file.
+func (t *ToolExecutor) checkProtected(path string) error {
+	pattern := t.protectedPattern(path)
+	if pattern == "" {
+		return nil
+	}
+	return fmt.Errorf("refusing to change %s: it is protected (%s) because it configures the agent itself, such as which paths it may see. Leave it unchanged; if changing it is part of the request, say so in complete_task so a human can make the change or rerun with it unprotected", t.journalPath(path), pattern)
+}
+
+// SnapshotProtected remembers the protected files at the root of the
+// working directory and the literal paths among ProtectedPaths, so that
+// changes bash makes to them can be undone. Call it before the first tool
+// runs.
+func (t *ToolExecutor) SnapshotProtected() {
+	t.protected = make(map[string][]byte)
+	candidates := make(map[string]bool)
+	for _, pattern := range t.ProtectedPaths {
+		if !strings.ContainsAny(pattern, "*?[") {
+			candidates[filepath.Join(t.workingDir, filepath.FromSlash(strings.TrimPrefix(pattern, "/")))] = true
+		}
+	}
+	if entries, err := t.Backend.ReadDir(t.workingDir); err == nil {
+		for _, entry := range entries {
+			if path := filepath.Join(t.workingDir, entry.Name()); !entry.IsDir() && t.protectedPattern(path) != "" {
+				candidates[path] = true
+			}
+		}
+	}
+	for path := range candidates {
+		data, err := t.Backend.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		t.protected[path] = data // nil when the file does not exist
+	}
+}
+
+// restoreProtected undoes changes to the snapshotted protected files and
+// returns a note for the model naming them.
+func (t *ToolExecutor) restoreProtected() string {
+	var restored []string
+	for path, original := range t.protected {
+		current, err := t.Backend.ReadFile(path)
+		exists := err == nil
+		if exists == (original != nil) && bytes.Equal(current, original) {
+			continue
+		}
+		if original == nil {
+			_, _, err = t.Backend.Run(t.workingDir, "rm -f -- "+shellQuote(path), nil)
+		} else {
+			err = t.Backend.WriteFile(path, original)
+		}
+		if err == nil {
+			restored = append(restored, t.journalPath(path))
+		}
+	}
+	if len(restored) == 0 {
+		return ""
+	}
+	sort.Strings(restored)
+	return fmt.Sprintf("\n\nNote: the command changed protected files that configure the agent itself (%s). The change was undone.", strings.Join(restored, ", "))
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultReadConcurrency is how many files read_files reads at once.
+const DefaultReadConcurrency = 8
+
+// DefaultReadBudget is the most file content, in bytes, one read_files
+// call returns.
+const DefaultReadBudget = 50 * 1024
+
+// fileRead is the outcome of reading one file for read_files.
+type fileRead struct {
+	output string
+	err    error
+}
+
+// readFilePaths returns the paths of a read_files call.
+func readFilePaths(args map[string]interface{}) ([]string, error) {
+	var paths []string
+	if list, ok := args["paths"].([]interface{}); ok {
+		for _, item := range list {
+			if p, ok := item.(string); ok && p != "" {
+				paths = append(paths, p)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("read_files requires a non-empty 'paths' array")
+	}
+	return paths, nil
+}
+
+// readFiles reads several files with a bounded pool of workers and returns
+// them in the order given. Once the content returned would exceed
+// ReadBudget, the remaining files are skipped and listed, so the model can
+// read them separately.
+func (t *ToolExecutor) readFiles(args map[string]interface{}) (string, error) {
+	paths, err := readFilePaths(args)
+	if err != nil {
+		return "", err
+	}
+	if _, err := t.encodingArg(args); err != nil {
+		return "", err
+	}
+	concurrency := t.ReadConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultReadConcurrency
+	}
+	if concurrency > len(paths) {
+		concurrency = len(paths)
+	}
+	budget := t.ReadBudget
+	if budget <= 0 {
+		budget = DefaultReadBudget
+	}
+
+	// Each file has its own buffered channel, so results are consumed in
+	// order however the workers finish. Workers stop reading once the
+	// budget is spent.
+	results := make([]chan fileRead, len(paths))
+	for i := range results {
+		results[i] = make(chan fileRead, 1)
+	}
+	indexes := make(chan int)
+	var full atomic.Bool
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if full.Load() {
+					results[i] <- fileRead{}
+					continue
+				}
+				fileArgs := map[string]interface{}{"path": paths[i]}
+				if enc, ok := args["encoding"]; ok {
+					fileArgs["encoding"] = enc
+				}
+				output, err := t.readFile(fileArgs)
+				results[i] <- fileRead{output: output, err: err}
+			}
+		}()
+	}
+	go func() {
+		for i := range paths {
+			indexes <- i
+		}
+		close(indexes)
+	}()
+
+	var out strings.Builder
+	var skipped []string
+	used := 0
+	for i, path := range paths {
+		r := <-results[i]
+		if full.Load() {
+			skipped = append(skipped, path)
+			continue
+		}
+		if r.err == nil && used+len(r.output) > budget {
+			full.Store(true)
+			skipped = append(skipped, path)
+			continue
+		}
+		fmt.Fprintf(&out, "==> %s <==\n", path)
+		if r.err != nil {
+			fmt.Fprintf(&out, "Error: %v\n\n", r.err)
+			continue
+		}
+		used += len(r.output)
+		out.WriteString(r.output)
+		if !strings.HasSuffix(r.output, "\n") {
+			out.WriteString("\n")
+		}
+		out.WriteString("\n")
+	}
+	wg.Wait()
+
+	if len(skipped) > 0 {
+		fmt.Fprintf(&out, "[Skipped %d of %d files to stay within %d bytes: %s. Read them with read_file or another read_files call.]\n", len(skipped), len(paths), budget, strings.Join(skipped, ", "))
+	}
+	return strings.TrimRight(out.String(), "\n"), nil
+}
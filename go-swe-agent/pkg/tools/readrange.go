@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxReadLines is the most lines read_file returns when no range is
+// given. Longer files are cut to their first lines, with a note on how to
+// read the rest.
+const DefaultMaxReadLines = 1000
+
+// lineRange returns the start_line and end_line arguments of read_file, both
+// 1-based and inclusive. ok is false when neither is given; a missing end
+// reads to the end of the file.
+func lineRange(args map[string]interface{}) (start, end int, ok bool, err error) {
+	startArg, hasStart := args["start_line"].(float64)
+	endArg, hasEnd := args["end_line"].(float64)
+	if !hasStart && !hasEnd {
+		return 0, 0, false, nil
+	}
+	start, end = 1, -1
+	if hasStart {
+		start = int(startArg)
+	}
+	if hasEnd {
+		end = int(endArg)
+	}
+	if start < 1 {
+		return 0, 0, false, fmt.Errorf("start_line must be at least 1")
+	}
+	if hasEnd && end < start {
+		return 0, 0, false, fmt.Errorf("end_line must not be before start_line")
+	}
+	return start, end, true, nil
+}
+
+// selectLines returns what read_file shows of text: the lines from start to
+// end, each prefixed with its number, when a range was given, and the first
+// MaxReadLines lines of a longer file otherwise, followed by a note.
+func (t *ToolExecutor) selectLines(text string, args map[string]interface{}) (string, error) {
+	start, end, ranged, err := lineRange(args)
+	if err != nil {
+		return "", err
+	}
+	if !ranged {
+		if t.MaxReadLines <= 0 {
+			return text, nil
+		}
+		lines := splitLines(text)
+		if len(lines) <= t.MaxReadLines {
+			return text, nil
+		}
+		return strings.Join(lines[:t.MaxReadLines], "\n") +
+			fmt.Sprintf("\n\n[Showing lines 1-%d of %d (%d bytes). Pass start_line and end_line to read other parts.]", t.MaxReadLines, len(lines), len(text)), nil
+	}
+
+	lines := splitLines(text)
+	if start > len(lines) {
+		return "", fmt.Errorf("start_line %d is past the end of the file, which has %d lines", start, len(lines))
+	}
+	if end < 0 || end > len(lines) {
+		end = len(lines)
+	}
+	width := len(fmt.Sprint(end))
+	var b strings.Builder
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&b, "%*d\t%s\n", width, i, lines[i-1])
+	}
+	if start > 1 || end < len(lines) {
+		fmt.Fprintf(&b, "\n[Lines %d-%d of %d]", start, end, len(lines))
+	}
+	return b.String(), nil
+}
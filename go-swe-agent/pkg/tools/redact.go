@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
+	regexp.MustCompile(`sk-[A-Za-z0-9_\-]{20,}`),
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{30,}`),
+	regexp.MustCompile(`xox[baprs]-[A-Za-z0-9\-]{10,}`),
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._\-]{16,}`),
+	regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api[_-]?key)\s*[=:]\s*)["']?[^\s"']{4,}`),
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+}
+
+var secretEnvVars = []string{
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"ANTHROPIC_API_KEY",
+	"OPENAI_API_KEY",
+	"GITHUB_TOKEN",
+}
+
+// RedactSecrets masks common credential formats and the values of known
+// secret environment variables in s.
+func RedactSecrets(s string) string {
+	for _, name := range secretEnvVars {
+		if value := os.Getenv(name); len(value) >= 8 {
+			s = strings.ReplaceAll(s, value, "[REDACTED]")
+		}
+	}
+
+	for _, re := range secretPatterns {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			// Keep a leading "key=" or "Bearer " prefix so the context stays readable
+			if sub := re.FindStringSubmatch(match); len(sub) > 1 {
+				return sub[1] + "[REDACTED]"
+			}
+			return "[REDACTED]"
+		})
+	}
+
+	return s
+}
@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transport runs a shell script inside a sandbox.
+type Transport interface {
+	Exec(script string, stdin io.Reader) (stdout, stderr []byte, err error)
+}
+
+// CommandTransport runs scripts through a local launcher command, such as
+// `docker exec -i <container>` or `ssh <host>`.
+type CommandTransport struct {
+	// Launcher is the command and arguments placed before `bash -c <script>`
+	Launcher []string
+
+	// QuoteScript passes the script as one shell-quoted word, for
+	// launchers like ssh that hand their arguments to a remote shell
+	QuoteScript bool
+}
+
+// NewDockerTransport runs scripts in a running container.
+func NewDockerTransport(container string) *CommandTransport {
+	return &CommandTransport{Launcher: []string{"docker", "exec", "-i", container}}
+}
+
+// NewSSHTransport runs scripts on a host reachable with ssh.
+func NewSSHTransport(host string) *CommandTransport {
+	return &CommandTransport{Launcher: []string{"ssh", "-T", host}, QuoteScript: true}
+}
+
+func (c *CommandTransport) Exec(script string, stdin io.Reader) ([]byte, []byte, error) {
+	if len(c.Launcher) == 0 {
+		return nil, nil, fmt.Errorf("transport has no launcher command")
+	}
+	args := append([]string{}, c.Launcher[1:]...)
+	if c.QuoteScript {
+		args = append(args, "bash -c "+shellQuote(script))
+	} else {
+		args = append(args, "bash", "-c", script)
+	}
+
+	cmd := exec.Command(c.Launcher[0], args...)
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// RemoteBackend runs tools in a sandbox through a Transport. File
+// operations are shell scripts, so the sandbox needs bash and GNU
+// coreutils and findutils.
+type RemoteBackend struct {
+	Transport Transport
+}
+
+// exitNotExist is the exit code scripts use for a missing path.
+const exitNotExist = 44
+
+func (r *RemoteBackend) Run(dir, command string, env []string) (string, string, error) {
+	var script strings.Builder
+	for _, kv := range env {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			fmt.Fprintf(&script, "export %s=%s\n", key, shellQuote(value))
+		}
+	}
+	fmt.Fprintf(&script, "cd %s || exit 1\n%s", shellQuote(dir), command)
+	stdout, stderr, err := r.Transport.Exec(script.String(), nil)
+	return string(stdout), string(stderr), err
+}
+
+// exitTimedOut is the exit code of coreutils timeout when it fires.
+const exitTimedOut = 124
+
+// RunTimeout runs command under coreutils timeout in the sandbox, which
+// kills it 5 seconds after asking it to stop.
+func (r *RemoteBackend) RunTimeout(dir, command string, env []string, timeout time.Duration) (string, string, error) {
+	if timeout <= 0 {
+		return r.Run(dir, command, env)
+	}
+	wrapped := fmt.Sprintf("timeout -k 5 %d bash -c %s", int(timeout.Seconds()+0.5), shellQuote(command))
+	stdout, stderr, err := r.Run(dir, wrapped, env)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == exitTimedOut {
+		err = fmt.Errorf("%w after %s", ErrTimedOut, timeout)
+	}
+	return stdout, stderr, err
+}
+
+func (r *RemoteBackend) ReadFile(path string) ([]byte, error) {
+	q := shellQuote(path)
+	stdout, _, err := r.exec("open", path, fmt.Sprintf("[ -e %s ] || exit %d\ncat -- %s", q, exitNotExist, q), nil)
+	return stdout, err
+}
+
+func (r *RemoteBackend) WriteFile(path string, data []byte) error {
+	script := fmt.Sprintf("mkdir -p -- %s && cat > %s", shellQuote(filepath.Dir(path)), shellQuote(path))
+	_, _, err := r.exec("write", path, script, bytes.NewReader(data))
+	return err
+}
+
+func (r *RemoteBackend) ReadDir(path string) ([]fs.DirEntry, error) {
+	q := shellQuote(path)
+	script := fmt.Sprintf("[ -d %s ] || exit %d\nfind %s -mindepth 1 -maxdepth 1 -printf %s", q, exitNotExist, q, shellQuote(findFormat))
+	stdout, _, err := r.exec("open", path, script, nil)
+	if err != nil {
+		return nil, err
+	}
+	entries := parseFindOutput(stdout)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (r *RemoteBackend) Stat(path string) (fs.FileInfo, error) {
+	q := shellQuote(path)
+	script := fmt.Sprintf("[ -e %s ] || exit %d\nfind %s -maxdepth 0 -printf %s", q, exitNotExist, q, shellQuote(findFormat))
+	stdout, _, err := r.exec("stat", path, script, nil)
+	if err != nil {
+		return nil, err
+	}
+	entries := parseFindOutput(stdout)
+	if len(entries) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: path, Err: fmt.Errorf("unexpected output %q", stdout)}
+	}
+	return entries[0].(remoteEntry), nil
+}
+
+// exec runs a file operation script, mapping its exit codes to fs errors.
+func (r *RemoteBackend) exec(op, path, script string, stdin io.Reader) ([]byte, []byte, error) {
+	stdout, stderr, err := r.Transport.Exec(script, stdin)
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == exitNotExist:
+		return nil, nil, &fs.PathError{Op: op, Path: path, Err: fs.ErrNotExist}
+	case err != nil:
+		return nil, nil, &fs.PathError{Op: op, Path: path, Err: fmt.Errorf("%v: %s", err, strings.TrimSpace(string(stderr)))}
+	}
+	return stdout, stderr, nil
+}
+
+// findFormat makes find print the type, size, modification time and name
+// of each entry.
+const findFormat = `%y\t%s\t%T@\t%f\n`
+
+// parseFindOutput reads lines printed by find with findFormat.
+func parseFindOutput(out []byte) []fs.DirEntry {
+	var entries []fs.DirEntry
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 || fields[0] == "" {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[1], 10, 64)
+		seconds, _ := strconv.ParseFloat(fields[2], 64)
+		entries = append(entries, remoteEntry{
+			name:    fields[3],
+			kind:    fields[0][0],
+			size:    size,
+			modTime: time.Unix(0, int64(seconds*float64(time.Second))),
+		})
+	}
+	return entries
+}
+
+// remoteEntry is a directory entry and file info reported by a sandbox.
+type remoteEntry struct {
+	name    string
+	kind    byte // find's %y: 'f' file, 'd' directory, 'l' symlink
+	size    int64
+	modTime time.Time
+}
+
+func (e remoteEntry) Name() string               { return e.name }
+func (e remoteEntry) IsDir() bool                { return e.kind == 'd' }
+func (e remoteEntry) Type() fs.FileMode          { return e.Mode().Type() }
+func (e remoteEntry) Info() (fs.FileInfo, error) { return e, nil }
+func (e remoteEntry) Size() int64                { return e.size }
+func (e remoteEntry) ModTime() time.Time         { return e.modTime }
+func (e remoteEntry) Sys() interface{}           { return nil }
+
+func (e remoteEntry) Mode() fs.FileMode {
+	switch e.kind {
+	case 'd':
+		return fs.ModeDir | 0755
+	case 'l':
+		return fs.ModeSymlink | 0777
+	case 'f':
+		return 0644
+	}
+	return fs.ModeIrregular
+}
+
+// shellQuote quotes s as a single bash word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
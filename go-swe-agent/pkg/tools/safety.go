@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CommandRule refuses the bash commands matching Pattern, for Reason.
+type CommandRule struct {
+	Pattern *regexp.Regexp
+	Reason  string
+}
+
+// SafetyPolicy decides which bash commands may run. It is a guard against
+// mistakes, not a sandbox: a determined command can get around pattern
+// checks, so untrusted repositories still belong in a ToolBackend sandbox.
+type SafetyPolicy struct {
+	// Deny refuses any command matching one of its rules
+	Deny []CommandRule
+
+	// Allow, when not empty, switches to allowlist mode: every command in
+	// the line (split at ;, &&, ||, | and subshells) must be one of these
+	// programs, such as "go" or "make", or a harmless shell builtin such
+	// as cd or echo. Programs are matched as written: a bare name runs
+	// through PATH, and a path such as ./gradlew must be listed as is, so
+	// listing go does not allow /tmp/evil/go. Deny still applies.
+	Allow []string
+}
+
+// commandStart matches where a command begins in a command line, so rules
+// for programs do not fire on their names in arguments, as in
+// grep -r shutdown.
+const commandStart = `(?:^|[;&|(\n]|\$\()\s*(?:sudo\s+)?`
+
+// DefaultDenyRules block commands that damage the machine rather than the
+// project, or that run code fetched from the network.
+var DefaultDenyRules = []CommandRule{
+	{regexp.MustCompile(`\brm\s+(?:-\S+\s+)*(?:/\*?|~/?|\$HOME/?|/(?:bin|boot|dev|etc|lib|proc|sys|usr|var)/?)(?:\s|;|&|\||$)`), "it deletes the filesystem root, the home directory or a system directory"},
+	{regexp.MustCompile(`--no-preserve-root`), "it disables rm's protection of the filesystem root"},
+	{regexp.MustCompile(`\b(?:curl|wget)\b[^;&]*\|\s*(?:sudo\s+)?(?:ba|z|da|k)?sh\b`), "it pipes a download into a shell"},
+	{regexp.MustCompile(`(?:^|[;&|(\n]|\$\()\s*(?:sudo|doas|su)\b`), "it runs a command as another user"},
+	{regexp.MustCompile(commandStart + `(?:shutdown|reboot|halt|poweroff|init\s+[06])\b`), "it shuts down or restarts the machine"},
+	{regexp.MustCompile(commandStart + `(?:mkfs(?:\.\w+)?\b|dd\b[^;&|]*\bof=/dev/)|>\s*/dev/(?:sd|nvme|hd|disk)`), "it writes to a disk device"},
+	{regexp.MustCompile(`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`), "it is a fork bomb"},
+	{regexp.MustCompile(`\bchmod\s+(?:-\S+\s+)*[0-7]*777\s+/(?:\s|;|&|\||$)`), "it makes the filesystem root writable by everyone"},
+}
+
+// DefaultSafetyPolicy denies DefaultDenyRules and allows everything else.
+func DefaultSafetyPolicy() *SafetyPolicy {
+	return &SafetyPolicy{Deny: DefaultDenyRules}
+}
+
+var (
+	// redirections such as 2>&1 and &> contain separator characters
+	redirections = regexp.MustCompile(`\d*[<>]&\d*-?|&>>?`)
+
+	// commandSeparators split a command line into the commands it runs
+	commandSeparators = regexp.MustCompile("&&|\\|\\||[;&|\\n(`]|\\$\\(")
+)
+
+// shellKeywords start or end compound commands; the program follows them.
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true,
+	"for": true, "while": true, "until": true, "do": true, "done": true,
+	"case": true, "esac": true, "!": true, "time": true, "{": true, "}": true,
+}
+
+// safeBuiltins are allowed in allowlist mode without being listed.
+var safeBuiltins = map[string]bool{
+	"cd": true, "pwd": true, "echo": true, "printf": true, "true": true, "false": true,
+	"test": true, "[": true, "[[": true, "export": true, "exit": true, ":": true,
+}
+
+// Check returns an error explaining why command may not run, or nil. A nil
+// policy allows everything.
+func (p *SafetyPolicy) Check(command string) error {
+	if p == nil {
+		return nil
+	}
+	for _, rule := range p.Deny {
+		if rule.Pattern.MatchString(command) {
+			return fmt.Errorf("command blocked by the safety policy because %s. Find a safer way to do this within the project", rule.Reason)
+		}
+	}
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	allowed := make(map[string]bool, len(p.Allow))
+	for _, name := range p.Allow {
+		allowed[name] = true
+	}
+	for _, part := range commandSeparators.Split(redirections.ReplaceAllString(command, " "), -1) {
+		name := commandName(part)
+		if name != "" && !allowed[name] && !safeBuiltins[name] {
+			return fmt.Errorf("command blocked by the safety policy: %s is not an allowed command. Allowed commands: %s", name, strings.Join(p.Allow, ", "))
+		}
+	}
+	return nil
+}
+
+// commandName returns the program a simple command runs, as written,
+// skipping shell keywords and variable assignments, or "" for an empty
+// command. The words of a for loop's list are skipped too.
+func commandName(command string) string {
+	words := strings.Fields(command)
+	for i := 0; i < len(words); i++ {
+		word := strings.Trim(words[i], ")")
+		switch {
+		case word == "" || shellKeywords[word]:
+			if word == "for" || word == "case" {
+				return ""
+			}
+		case strings.Contains(word, "=") && !strings.HasPrefix(word, "="):
+		default:
+			return word
+		}
+	}
+	return ""
+}
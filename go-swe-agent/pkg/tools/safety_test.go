@@ -0,0 +1,27 @@
+package tools
+
+import "testing"
+
+func TestAllowlistMatchesCommandsAsWritten(t *testing.T) {
+	policy := &SafetyPolicy{Allow: []string{"go", "git", "./gradlew"}}
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{"go test ./...", true},
+		{"cd pkg && go vet ./... | git diff", true},
+		{"./gradlew build", true},
+		{"/tmp/evil/go test ./...", false},
+		{"go build && /tmp/evil/go run .", false},
+		{"./go test", false},
+		{"bin/git status", false},
+		{"gradlew build", false},
+		{"make", false},
+	}
+	for _, tt := range tests {
+		err := policy.Check(tt.command)
+		if (err == nil) != tt.allowed {
+			t.Errorf("Check(%q) = %v, want allowed %v", tt.command, err, tt.allowed)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// toolSchemas holds the input_schema of each tool in GetAvailableTools, by
+// tool name.
+var toolSchemas = func() map[string]map[string]interface{} {
+	schemas := make(map[string]map[string]interface{})
+	for _, tool := range GetAvailableTools() {
+		schemas[tool["name"].(string)], _ = tool["input_schema"].(map[string]interface{})
+	}
+	return schemas
+}()
+
+// validateInput checks the arguments of a call to the named tool against
+// the tool's input_schema: every required field must be given, and every
+// declared field that is given must have the declared type and, for an
+// enum, one of its values. The error lists every problem found, so the
+// model can fix the call in one go. Fields the schema does not declare
+// are left alone, as are unknown tools, which dispatch rejects.
+func validateInput(name string, args map[string]interface{}) error {
+	schema, ok := toolSchemas[name]
+	if !ok {
+		return nil
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	var problems []string
+	var missing []string
+	required, _ := schema["required"].([]string)
+	for _, field := range required {
+		if args[field] == nil {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		problems = append(problems, "missing required "+strings.Join(missing, ", "))
+	}
+
+	fields := make([]string, 0, len(args))
+	for field := range args {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		property, ok := properties[field].(map[string]interface{})
+		if !ok || args[field] == nil {
+			continue
+		}
+		if problem := checkValue(field, args[field], property); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid input for %s: %s", name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkValue returns what is wrong with value, the argument field, given
+// its property in the schema, or "" if nothing is.
+func checkValue(field string, value interface{}, property map[string]interface{}) string {
+	kind, _ := property["type"].(string)
+	if !hasType(value, kind) {
+		return fmt.Sprintf("%s must be %s, got %s", field, article(kind), jsonType(value))
+	}
+	if values, ok := property["enum"].([]string); ok {
+		for _, v := range values {
+			if value == v {
+				return ""
+			}
+		}
+		return fmt.Sprintf("%s must be one of %s, got %q", field, strings.Join(values, ", "), value)
+	}
+	if items, ok := property["items"].(map[string]interface{}); ok {
+		itemKind, _ := items["type"].(string)
+		for i, item := range value.([]interface{}) {
+			if !hasType(item, itemKind) {
+				return fmt.Sprintf("%s[%d] must be %s, got %s", field, i, article(itemKind), jsonType(item))
+			}
+		}
+	}
+	return ""
+}
+
+// hasType reports whether value, decoded from JSON, is of the JSON schema
+// type kind. An empty kind allows any value.
+func hasType(value interface{}, kind string) bool {
+	switch kind {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+// jsonType names the JSON type of value, for error messages.
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case float64:
+		if v != math.Trunc(v) {
+			return "a number with a fraction"
+		}
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func article(kind string) string {
+	switch kind {
+	case "integer", "array", "object":
+		return "an " + kind
+	}
+	return "a " + kind
+}
@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateInput(t *testing.T) {
+	tests := []struct {
+		name string
+		tool string
+		args map[string]interface{}
+		want string // the error, or "" when the input is valid
+	}{
+		{"valid", "read_file", map[string]interface{}{"path": "a.go", "start_line": 1.0, "end_line": 20.0}, ""},
+		{"optional fields left out", "list_files", map[string]interface{}{}, ""},
+		{"undeclared field", "read_file", map[string]interface{}{"path": "a.go", "verbose": true}, ""},
+		{"unknown tool", "no_such_tool", map[string]interface{}{"x": 1.0}, ""},
+		{"missing required", "write_file", map[string]interface{}{"path": "a.go"},
+			"invalid input for write_file: missing required content"},
+		{"several missing required", "edit_file", map[string]interface{}{},
+			"invalid input for edit_file: missing required path, old_string, new_string"},
+		{"null required", "read_file", map[string]interface{}{"path": nil},
+			"invalid input for read_file: missing required path"},
+		{"wrong type", "read_file", map[string]interface{}{"path": 3.0},
+			"invalid input for read_file: path must be a string, got a number"},
+		{"non-integral integer", "read_file", map[string]interface{}{"path": "a.go", "start_line": 1.5},
+			"invalid input for read_file: start_line must be an integer, got a number with a fraction"},
+		{"integer as string", "bash", map[string]interface{}{"command": "ls", "timeout_seconds": "30"},
+			"invalid input for bash: timeout_seconds must be an integer, got a string"},
+		{"enum miss", "list_files", map[string]interface{}{"sort": "date"},
+			`invalid input for list_files: sort must be one of name, size, mtime, got "date"`},
+		{"array item type", "search", map[string]interface{}{"patterns": []interface{}{"foo", 2.0}},
+			"invalid input for search: patterns[1] must be a string, got a number"},
+		{"array not an array", "read_files", map[string]interface{}{"paths": "a.go"},
+			"invalid input for read_files: paths must be an array, got a string"},
+		{"every problem listed", "read_file", map[string]interface{}{"start_line": "1", "end_line": 2.5},
+			"invalid input for read_file: missing required path; end_line must be an integer, got a number with a fraction; start_line must be an integer, got a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateInput(tt.tool, tt.args)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("validateInput: %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("validateInput error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		property map[string]interface{}
+		want     string
+	}{
+		{"integer", 3.0, map[string]interface{}{"type": "integer"}, ""},
+		{"negative integer", -2.0, map[string]interface{}{"type": "integer"}, ""},
+		{"fractional integer", 0.5, map[string]interface{}{"type": "integer"}, "n must be an integer, got a number with a fraction"},
+		{"number", 0.5, map[string]interface{}{"type": "number"}, ""},
+		{"boolean", "true", map[string]interface{}{"type": "boolean"}, "n must be a boolean, got a string"},
+		{"object", []interface{}{}, map[string]interface{}{"type": "object"}, "n must be an object, got an array"},
+		{"untyped", 1.0, map[string]interface{}{}, ""},
+		{"enum hit", "go", map[string]interface{}{"type": "string", "enum": []string{"go", "rust"}}, ""},
+		{"enum miss", "java", map[string]interface{}{"type": "string", "enum": []string{"go", "rust"}}, `n must be one of go, rust, got "java"`},
+		{"empty array", []interface{}{}, map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}, ""},
+		{"array items", []interface{}{"a", "b"}, map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}, ""},
+		{"array item type", []interface{}{"a", true}, map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}, "n[1] must be a string, got a boolean"},
+		{"array item null", []interface{}{nil}, map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}}, "n[0] must be an integer, got null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkValue("n", tt.value, tt.property); got != tt.want {
+				t.Errorf("checkValue = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// An invalid call is refused before the tool runs.
+func TestExecuteRejectsInvalidInput(t *testing.T) {
+	dir := t.TempDir()
+	executor := NewToolExecutor(dir)
+
+	_, err := executor.Execute("write_file", map[string]interface{}{"path": "a.txt", "content": 42.0})
+	if err == nil || !strings.Contains(err.Error(), "content must be a string, got a number") {
+		t.Errorf("Execute error = %v, want the invalid content reported", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("the file was written despite the invalid input: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// What list_files and search do with git submodules.
+const (
+	SubmodulesRecurse = "recurse" // list and search their contents (default)
+	SubmodulesSkip    = "skip"    // show them, but leave their contents out
+)
+
+// Submodule is a git submodule of the working directory.
+type Submodule struct {
+	Path        string // relative to the working directory, slash-separated
+	URL         string
+	Initialized bool // checked out; otherwise its directory is empty
+}
+
+// DetectSubmodules lists the submodules declared in dir's .gitmodules. A
+// repository without one has none.
+func DetectSubmodules(dir string) ([]Submodule, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".gitmodules")); err != nil {
+		return nil, nil
+	}
+	cmd := exec.Command("git", "config", "--file", ".gitmodules", "--get-regexp", `^submodule\..*\.(path|url)$`)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gitmodules: %w", err)
+	}
+
+	var submodules []Submodule
+	byName := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		name := strings.TrimPrefix(key[:strings.LastIndex(key, ".")], "submodule.")
+		i, seen := byName[name]
+		if !seen {
+			i = len(submodules)
+			byName[name] = i
+			submodules = append(submodules, Submodule{})
+		}
+		if strings.HasSuffix(key, ".path") {
+			submodules[i].Path = strings.Trim(filepath.ToSlash(value), "/")
+		} else {
+			submodules[i].URL = value
+		}
+	}
+
+	var declared []Submodule
+	for _, s := range submodules {
+		if s.Path == "" {
+			continue
+		}
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(s.Path), ".git"))
+		s.Initialized = err == nil
+		declared = append(declared, s)
+	}
+	return declared, nil
+}
+
+// submoduleAt returns the submodule rooted at path, if any.
+func (t *ToolExecutor) submoduleAt(path string) (Submodule, bool) {
+	rel, err := filepath.Rel(t.workingDir, path)
+	if err != nil {
+		return Submodule{}, false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, s := range t.Submodules {
+		if s.Path == rel {
+			return s, true
+		}
+	}
+	return Submodule{}, false
+}
+
+// skippedSubmodule returns the submodule whose contents path lies in when
+// submodules are skipped.
+func (t *ToolExecutor) skippedSubmodule(path string) (Submodule, bool) {
+	if t.SubmodulePolicy != SubmodulesSkip {
+		return Submodule{}, false
+	}
+	for _, s := range t.Submodules {
+		if within(filepath.Join(t.workingDir, filepath.FromSlash(s.Path)), path) {
+			return s, true
+		}
+	}
+	return Submodule{}, false
+}
+
+// checkSubmodule refuses to list or search inside a skipped submodule.
+func (t *ToolExecutor) checkSubmodule(path string) error {
+	if s, ok := t.skippedSubmodule(path); ok {
+		return fmt.Errorf("%s is in the git submodule %s, whose contents are skipped in this run; read its files directly if needed", t.journalPath(path), s.Path)
+	}
+	return nil
+}
+
+// describeSubmodule renders a list_files entry for a submodule.
+func describeSubmodule(s Submodule, name string) string {
+	switch {
+	case !s.Initialized:
+		return fmt.Sprintf("[SUBMODULE] %s (not checked out)\n", name)
+	case s.URL != "":
+		return fmt.Sprintf("[SUBMODULE] %s (separate git repository from %s)\n", name, s.URL)
+	default:
+		return fmt.Sprintf("[SUBMODULE] %s (separate git repository)\n", name)
+	}
+}
+
+// filterSubmodules drops search result lines from skipped submodules.
+func (t *ToolExecutor) filterSubmodules(output string) string {
+	if t.SubmodulePolicy != SubmodulesSkip || len(t.Submodules) == 0 {
+		return output
+	}
+	var kept []string
+	for _, line := range strings.Split(output, "\n") {
+		if prefix := locationPrefix.FindString(line); prefix != "" {
+			file := strings.SplitN(prefix, ":", 2)[0]
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(t.workingDir, file)
+			}
+			if _, skipped := t.skippedSubmodule(file); skipped {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// SubmoduleNote tells the model which directories are submodules and how
+// the tools treat them. It is empty without submodules.
+func (t *ToolExecutor) SubmoduleNote() string {
+	if len(t.Submodules) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("These directories are git submodules, separate repositories pinned to a commit:\n")
+	for _, s := range t.Submodules {
+		if s.Initialized {
+			fmt.Fprintf(&b, "- %s\n", s.Path)
+		} else {
+			fmt.Fprintf(&b, "- %s (not checked out, so empty)\n", s.Path)
+		}
+	}
+	b.WriteString("Changes inside a submodule are not part of this repository's history; only edit them if the request needs it.")
+	if t.SubmodulePolicy == SubmodulesSkip {
+		b.WriteString(" list_files and search leave their contents out; read_file still reads them.")
+	}
+	return b.String()
+}
@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions bounds the similar paths offered for a missing path.
+const maxSuggestions = 5
+
+// maxSuggestionWalk bounds the entries visited when looking for a file of
+// the same name elsewhere in the tree.
+const maxSuggestionWalk = 20000
+
+// notFound wraps a not-exist error for path with a list of similarly named
+// paths, so a slightly wrong guess can be corrected in one step. Other
+// errors are returned unchanged.
+func (t *ToolExecutor) notFound(path string, err error) error {
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	suggestions := t.suggestPaths(path)
+	if len(suggestions) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w\nDid you mean one of these?\n  %s", err, strings.Join(suggestions, "\n  "))
+}
+
+// suggestPaths returns paths, relative to the working directory, that look
+// like the missing path: close names in its nearest existing parent
+// directory, then files with the same name anywhere in the project.
+func (t *ToolExecutor) suggestPaths(path string) []string {
+	if !within(t.workingDir, path) {
+		return nil
+	}
+	name := strings.ToLower(filepath.Base(path))
+
+	var candidates []suggestion
+	seen := make(map[string]bool)
+	add := func(p string, score int) {
+		if hidden, _ := t.hidden(p, false); hidden {
+			return
+		}
+		if rel, err := filepath.Rel(t.workingDir, p); err == nil && !seen[rel] {
+			seen[rel] = true
+			candidates = append(candidates, suggestion{rel, score})
+		}
+	}
+
+	dir := filepath.Dir(path)
+	for within(t.workingDir, dir) {
+		if entries, err := t.Backend.ReadDir(dir); err == nil {
+			for _, entry := range entries {
+				if score, ok := similarName(name, strings.ToLower(entry.Name())); ok {
+					add(filepath.Join(dir, entry.Name()), score)
+				}
+			}
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	visited := 0
+	if !t.local() {
+		// Walking a remote tree file by file would be too slow
+		return rankSuggestions(candidates)
+	}
+	WalkDir(t.workingDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		visited++
+		if visited > maxSuggestionWalk {
+			return filepath.SkipAll
+		}
+		if d.IsDir() && p != t.workingDir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+			return filepath.SkipDir
+		}
+		if strings.ToLower(d.Name()) == name {
+			// Same name in another directory ranks after close siblings
+			add(p, 10)
+		}
+		return nil
+	})
+
+	return rankSuggestions(candidates)
+}
+
+// suggestion is a candidate path with its distance from the missing path.
+type suggestion struct {
+	path  string
+	score int
+}
+
+// rankSuggestions returns the closest candidates, best first.
+func rankSuggestions(candidates []suggestion) []string {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score < candidates[j].score
+	})
+	var suggestions []string
+	for _, c := range candidates {
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+		suggestions = append(suggestions, c.path)
+	}
+	return suggestions
+}
+
+// similarName reports whether candidate is a plausible correction of name,
+// scored by edit distance (lower is closer).
+func similarName(name, candidate string) (int, bool) {
+	if name == candidate {
+		return 0, true
+	}
+	distance := editDistance(name, candidate)
+	limit := len(name) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	if distance <= limit {
+		return distance, true
+	}
+	// Same stem with another extension, e.g. config.yml for config.yaml
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	if stem != "" && strings.TrimSuffix(candidate, filepath.Ext(candidate)) == stem {
+		return limit, true
+	}
+	return 0, false
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
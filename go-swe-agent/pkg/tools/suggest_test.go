@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"config", "config", 0},
+		{"confg", "config", 1},
+		{"main.go", "mian.go", 2},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarName(t *testing.T) {
+	tests := []struct {
+		name, candidate string
+		want            bool
+	}{
+		{"config.yaml", "config.yaml", true},
+		{"confg.yaml", "config.yaml", true},
+		{"config.yml", "config.yaml", true},
+		{"config.json", "config.yaml", true},
+		{"a.go", "b.go", true},
+		{"handler.go", "server.go", false},
+		{"readme.md", "license", false},
+	}
+	for _, tt := range tests {
+		if _, got := similarName(tt.name, tt.candidate); got != tt.want {
+			t.Errorf("similarName(%q, %q) = %v, want %v", tt.name, tt.candidate, got, tt.want)
+		}
+	}
+}
+
+func TestMissingPathSuggestions(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"pkg/config.yaml", "pkg/server.go", "cmd/server.go", ".cache/server.go", "README.md"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	executor := NewToolExecutor(dir)
+
+	tests := []struct {
+		tool, path string
+		want       []string // suggested paths, in order
+	}{
+		{"read_file", "pkg/confg.yaml", []string{"pkg/config.yaml"}},
+		{"read_file", "pkg/config.yml", []string{"pkg/config.yaml"}},
+		{"read_file", "pkg/missing/config.yaml", []string{"pkg/config.yaml"}},
+		{"read_file", "server.go", []string{"cmd/server.go", "pkg/server.go"}},
+		{"read_file", "pkg/nothing_like_it.txt", nil},
+		{"list_files", "cmds", []string{"cmd"}},
+	}
+	for _, tt := range tests {
+		_, err := executor.Execute(tt.tool, map[string]interface{}{"path": tt.path})
+		if err == nil {
+			t.Errorf("%s %s: no error", tt.tool, tt.path)
+			continue
+		}
+		_, list, found := strings.Cut(err.Error(), "Did you mean one of these?\n")
+		if tt.want == nil {
+			if found {
+				t.Errorf("%s %s: suggested %q, want nothing", tt.tool, tt.path, list)
+			}
+			continue
+		}
+		var got []string
+		for _, line := range strings.Split(list, "\n") {
+			got = append(got, strings.TrimSpace(line))
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s %s: suggested %q, want %q", tt.tool, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrSymlinkEscape is returned for paths inside the working directory that
+// resolve to a location outside it through a symlink.
+var ErrSymlinkEscape = errors.New("symlink points outside the working directory")
+
+// within reports whether path is root or lies below it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveExisting resolves symlinks in the longest existing prefix of path,
+// so paths of files that are about to be created can be checked too.
+func resolveExisting(path string) (string, error) {
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return resolved, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append(missing, filepath.Base(path))
+		path = parent
+	}
+}
+
+// checkSymlinks refuses a path inside the working directory whose symlinks
+// lead outside of it (or the scratch directory), unless AllowOutsideWorkDir
+// is set.
+func (t *ToolExecutor) checkSymlinks(path string) error {
+	if !t.local() || t.AllowOutsideWorkDir || !within(t.workingDir, path) {
+		return nil
+	}
+	resolved, err := resolveExisting(path)
+	if err != nil {
+		// Let the tool itself report the underlying problem
+		return nil
+	}
+	if root, err := filepath.EvalSymlinks(t.workingDir); err == nil && within(root, resolved) {
+		return nil
+	}
+	if t.ScratchDir != "" {
+		if scratch, err := filepath.EvalSymlinks(t.ScratchDir); err == nil && within(scratch, resolved) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %w (resolves to %s)", path, ErrSymlinkEscape, resolved)
+}
+
+// describeSymlink renders a list_files entry for the symlink at path.
+func (t *ToolExecutor) describeSymlink(path, name string) string {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return fmt.Sprintf("[LINK] %s (unreadable)\n", name)
+	}
+	if t.checkSymlinks(path) != nil {
+		return fmt.Sprintf("[LINK] %s -> %s (outside working directory, not followed)\n", name, target)
+	}
+	info, err := os.Stat(path)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("[LINK] %s -> %s (broken)\n", name, target)
+	case info.IsDir():
+		return fmt.Sprintf("[LINK] %s -> %s/\n", name, target)
+	default:
+		return fmt.Sprintf("[LINK] %s -> %s (%d bytes)\n", name, target, info.Size())
+	}
+}
+
+// WalkDir is filepath.WalkDir that also descends into symlinked directories
+// as long as their target stays within root. Directories are tracked by
+// their resolved path and visited once, so symlink loops terminate.
+// Symlinks that escape root, or point to files, are passed to fn as-is.
+func WalkDir(root string, fn fs.WalkDirFunc) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	return walkDir(root, realRoot, make(map[string]bool), fn)
+}
+
+func walkDir(dir, realRoot string, visited map[string]bool, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		path = filepath.Clean(path)
+		if err != nil {
+			return fn(path, d, err)
+		}
+
+		if d.IsDir() {
+			if real, err := filepath.EvalSymlinks(path); err == nil {
+				if visited[real] {
+					return filepath.SkipDir
+				}
+				visited[real] = true
+			}
+			return fn(path, d, nil)
+		}
+
+		if d.Type()&fs.ModeSymlink == 0 {
+			return fn(path, d, nil)
+		}
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil || !within(realRoot, target) {
+			return fn(path, d, nil)
+		}
+		if info, err := os.Stat(target); err != nil || !info.IsDir() {
+			return fn(path, d, nil)
+		}
+		if visited[target] {
+			return nil
+		}
+		// A trailing separator makes WalkDir follow the link itself
+		return walkDir(path+string(filepath.Separator), realRoot, visited, fn)
+	})
+}
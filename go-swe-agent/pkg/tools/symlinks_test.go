@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// symlinkTree creates a working directory holding a file, a directory
+// whose "loop" symlink points back at the working directory, and an
+// "escape" symlink to a secret outside of it.
+func symlinkTree(t *testing.T) (root, outside string) {
+	base := t.TempDir()
+	root = filepath.Join(base, "repo")
+	outside = filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(root, "dir"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(root, "dir", "a.txt"):  "inside",
+		filepath.Join(outside, "secret.txt"): "secret",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		filepath.Join(root, "dir", "loop"): "..",
+		filepath.Join(root, "escape"):      outside,
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	return root, outside
+}
+
+func TestWalkDirSymlinkLoopTerminates(t *testing.T) {
+	root, _ := symlinkTree(t)
+
+	var visited []string
+	err := WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		visited = append(visited, rel)
+		if len(visited) > 100 {
+			t.Fatal("WalkDir did not terminate on a symlink loop")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir: %v", err)
+	}
+	sort.Strings(visited)
+	// The loop leads back to the root, which was already visited
+	want := []string{".", "dir", "dir/a.txt", "escape"}
+	if strings.Join(visited, " ") != strings.Join(want, " ") {
+		t.Errorf("visited %v, want %v", visited, want)
+	}
+}
+
+func TestEscapingSymlinkIsRefused(t *testing.T) {
+	root, _ := symlinkTree(t)
+	executor := NewToolExecutor(root)
+
+	for _, path := range []string{"escape/secret.txt", "escape", "escape/new.txt", "dir/loop/escape/secret.txt"} {
+		if _, err := executor.resolvePath(path); !errors.Is(err, ErrSymlinkEscape) {
+			t.Errorf("resolvePath(%q) error = %v, want ErrSymlinkEscape", path, err)
+		}
+	}
+	if _, err := executor.resolvePath("dir/loop/dir/a.txt"); err != nil {
+		t.Errorf("resolvePath through the in-tree loop: %v", err)
+	}
+
+	output, err := executor.Execute("read_file", map[string]interface{}{"path": "escape/secret.txt"})
+	if err == nil || strings.Contains(output, "secret") {
+		t.Errorf("read_file through an escaping symlink = %q, %v; want it refused", output, err)
+	}
+
+	executor.AllowOutsideWorkDir = true
+	if _, err := executor.resolvePath("escape/secret.txt"); err != nil {
+		t.Errorf("resolvePath with AllowOutsideWorkDir: %v", err)
+	}
+}
+
+func TestListFilesDescribesSymlinks(t *testing.T) {
+	root, outside := symlinkTree(t)
+	executor := NewToolExecutor(root)
+
+	output, err := executor.Execute("list_files", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("list_files: %v", err)
+	}
+	if want := "[LINK] escape -> " + outside + " (outside working directory, not followed)"; !strings.Contains(output, want) {
+		t.Errorf("list_files output %q does not contain %q", output, want)
+	}
+
+	output, err = executor.Execute("list_files", map[string]interface{}{"path": "dir"})
+	if err != nil {
+		t.Fatalf("list_files dir: %v", err)
+	}
+	if !strings.Contains(output, "[LINK] loop -> ../") {
+		t.Errorf("list_files output %q does not show the loop as a directory link", output)
+	}
+}
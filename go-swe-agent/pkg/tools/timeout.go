@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultBashTimeout is how long a bash command may run before it is
+// killed.
+const DefaultBashTimeout = 120 * time.Second
+
+// maxBashTimeout caps the timeout a single bash call may ask for.
+const maxBashTimeout = time.Hour
+
+// ErrTimedOut is wrapped by the error of a command stopped by its timeout.
+var ErrTimedOut = errors.New("timed out")
+
+// TimeoutRunner is implemented by backends that can stop a command, with
+// everything it started, after a timeout. Bash commands on backends that
+// do not implement it run without a timeout.
+type TimeoutRunner interface {
+	// RunTimeout is Run with a timeout; zero means none. Output captured
+	// before the timeout is returned with an error wrapping ErrTimedOut.
+	RunTimeout(dir, command string, env []string, timeout time.Duration) (stdout, stderr string, err error)
+}
+
+// bashTimeout returns the timeout of a bash call: its timeout_seconds, or
+// BashTimeout.
+func (t *ToolExecutor) bashTimeout(args map[string]interface{}) (time.Duration, error) {
+	seconds, ok := args["timeout_seconds"].(float64)
+	if !ok {
+		return t.BashTimeout, nil
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout <= 0 || timeout > maxBashTimeout {
+		return 0, fmt.Errorf("timeout_seconds must be between 1 and %d", int(maxBashTimeout.Seconds()))
+	}
+	return timeout, nil
+}
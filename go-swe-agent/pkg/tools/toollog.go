@@ -0,0 +1,202 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+const (
+	DefaultToolLogMaxBytes = 10 * 1024 * 1024
+	toolLogBackups         = 3
+)
+
+// ToolLog appends every tool call and result to a JSONL file as it happens,
+// rotating the file once it grows past maxBytes. Secrets are redacted
+// before anything is written.
+type ToolLog struct {
+	// Out receives warnings, such as a failed rotation; nil writes to
+	// standard output
+	Out *ui.Printer
+
+	mu       sync.Mutex
+	path     string
+	runID    string
+	maxBytes int64
+	file     *os.File
+	size     int64
+}
+
+type toolLogEntry struct {
+	Time       time.Time              `json:"time"`
+	RunID      string                 `json:"run_id"`
+	Event      string                 `json:"event"`
+	Tool       string                 `json:"tool"`
+	Input      map[string]interface{} `json:"input,omitempty"`
+	Output     string                 `json:"output,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMs int64                  `json:"duration_ms,omitempty"`
+}
+
+func NewToolLog(path, runID string, maxBytes int64) (*ToolLog, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultToolLogMaxBytes
+	}
+
+	l := &ToolLog{
+		path:     path,
+		runID:    runID,
+		maxBytes: maxBytes,
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *ToolLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open tool log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat tool log: %w", err)
+	}
+
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// rotate shifts path -> path.1 -> path.2 ... keeping toolLogBackups old files.
+func (l *ToolLog) rotate() error {
+	l.file.Close()
+
+	for i := toolLogBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	renameErr := os.Rename(l.path, l.path+".1")
+
+	if err := l.open(); err != nil {
+		l.file = nil
+		return err
+	}
+	if renameErr != nil {
+		return fmt.Errorf("failed to rotate tool log: %w", renameErr)
+	}
+	return nil
+}
+
+func (l *ToolLog) write(entry toolLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return
+	}
+
+	entry.Time = time.Now()
+	entry.RunID = l.runID
+	entry.Output = RedactSecrets(entry.Output)
+	entry.Error = RedactSecrets(entry.Error)
+	entry.Input = redactInput(entry.Input)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	if l.size > 0 && l.size+int64(len(data)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			l.Out.Yellow("  ⚠️  %v\n", err)
+			if l.file == nil {
+				return
+			}
+		}
+	}
+
+	n, _ := l.file.Write(data)
+	l.size += int64(n)
+}
+
+func redactInput(input map[string]interface{}) map[string]interface{} {
+	if input == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		if str, ok := v.(string); ok {
+			v = RedactSecrets(str)
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// LogCall records a tool invocation before it runs, so the log shows what
+// the agent was doing even if the tool never returns.
+func (l *ToolLog) LogCall(name string, input map[string]interface{}) {
+	l.write(toolLogEntry{Event: "call", Tool: name, Input: input})
+}
+
+// LogResult records the outcome of a tool invocation.
+func (l *ToolLog) LogResult(name, output string, err error, duration time.Duration) {
+	entry := toolLogEntry{
+		Event:      "result",
+		Tool:       name,
+		Output:     output,
+		DurationMs: duration.Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	l.write(entry)
+}
+
+// ToolCallEvent is logged to the run's event log for every tool call.
+type ToolCallEvent struct {
+	TaskID     string                 `json:"task_id,omitempty"`
+	Tool       string                 `json:"tool"`
+	Input      map[string]interface{} `json:"input,omitempty"`
+	Output     string                 `json:"output,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+}
+
+// logEvent adds a finished tool call to the event log, secrets redacted.
+func (t *ToolExecutor) logEvent(name string, input map[string]interface{}, output string, err error, elapsed time.Duration) {
+	if t.Events == nil {
+		return
+	}
+	event := ToolCallEvent{
+		TaskID:     t.TaskID,
+		Tool:       name,
+		Input:      redactInput(input),
+		Output:     RedactSecrets(output),
+		DurationMs: elapsed.Milliseconds(),
+	}
+	if err != nil {
+		event.Error = RedactSecrets(err.Error())
+	}
+	t.Events.Log("tool_call", event)
+}
+
+func (l *ToolLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
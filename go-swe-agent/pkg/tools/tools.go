@@ -1,36 +1,188 @@
 package tools
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+	"github.com/openswe/go-swe-agent/pkg/ui"
 )
 
 type ToolExecutor struct {
 	workingDir string
+
+	// Backend runs the file operations and commands of the tools. It
+	// defaults to LocalBackend; a RemoteBackend runs them in a sandbox.
+	Backend ToolBackend
+
+	// Log, when set, receives every tool call and result as it happens
+	Log *ToolLog
+
+	// Events, when set, receives every tool call with its result
+	Events *ui.EventLog
+
+	// ScratchDir is a per-run directory outside the project for temporary
+	// files. It is exposed as $SCRATCH_DIR to bash and via the scratch_dir tool.
+	ScratchDir string
+
+	// Journal, when set, records every file written by the file tools.
+	// TaskID is stored with the first write to each file.
+	Journal *state.FileJournal
+	TaskID  string
+
+	// Usage, when set, receives the count, failures, time and output size
+	// of every tool call
+	Usage state.ToolUsage
+
+	// MaxFilesChanged caps the distinct files the file tools may change in
+	// a run. Files already changed can still be edited. Zero means no limit.
+	MaxFilesChanged int
+
+	// GeneratedPolicy decides what happens when a file tool writes a file
+	// that looks generated or vendored: GeneratedWarn (the default),
+	// GeneratedBlock or GeneratedOff.
+	GeneratedPolicy string
+
+	// MaxLineLength is the longest line read_file and search return in
+	// full; longer lines are shortened in place. Zero or less disables it.
+	MaxLineLength int
+
+	// MaxReadLines is the most lines read_file returns when no line range
+	// is given. Zero or less returns whole files.
+	MaxReadLines int
+
+	// ReadConcurrency bounds the files read_files reads at once, and
+	// ReadBudget the bytes of content it returns per call. Zero uses
+	// DefaultReadConcurrency and DefaultReadBudget.
+	ReadConcurrency int
+	ReadBudget      int
+
+	// DetectEncoding makes read_file and write_file detect and keep a
+	// file's text encoding when the call names none. Otherwise files are
+	// read and written as raw bytes unless an encoding is given.
+	DetectEncoding bool
+
+	// Limits, when set, caps the memory and CPU of bash commands
+	Limits *ResourceLimits
+
+	// AllowOutsideWorkDir lets the file tools use paths outside the
+	// working directory and the scratch directory, including through
+	// symlinks. By default such paths are refused.
+	AllowOutsideWorkDir bool
+
+	// DryRun makes write_file and edit_file report success without
+	// writing, and bash without running the command. Later reads see the
+	// files as if they had been written. Preview, when set, receives each
+	// skipped action: a diff of the file, or the command.
+	DryRun      bool
+	Preview     func(tool, preview string)
+	dryRunFiles map[string][]byte // path -> content a dry run would have written
+
+	// Safety decides which bash commands may run. It defaults to
+	// DefaultSafetyPolicy; nil runs every command.
+	Safety *SafetyPolicy
+
+	// BashTimeout is how long a bash command may run before it and the
+	// processes it started are killed, unless the call sets
+	// timeout_seconds. Zero or less means no timeout.
+	BashTimeout time.Duration
+
+	// Ignore hides paths from list_files, search and read_file, as listed
+	// in the project's AgentIgnoreFile. Nil hides nothing.
+	Ignore *IgnoreRules
+
+	// Submodules are the git submodules of the working directory, marked
+	// in list_files. SubmodulePolicy decides whether list_files and search
+	// go into them: SubmodulesRecurse (the default) or SubmodulesSkip.
+	Submodules      []Submodule
+	SubmodulePolicy string
+
+	// IgnoredDirs are directory names list_files, search and glob leave
+	// out, like the files .gitignore ignores, unless include_ignored is set
+	IgnoredDirs []string
+
+	// ProtectedPaths are files the tools must not change, such as the
+	// agent's own AgentIgnoreFile. write_file refuses them, and changes
+	// bash makes to those found by SnapshotProtected are undone.
+	ProtectedPaths []string
+	protected      map[string][]byte // path -> content at the snapshot, nil if absent
+
+	// createConflicts are files the current task planned to create that
+	// already existed, until the first write to each, see PlanCreates
+	createConflicts map[string]bool
+
+	depsCache map[string][]string // ecosystem -> dependencies, see deps
+	edits     taskEdits
 }
 
+// noMatches is the search result when nothing matched.
+const noMatches = "No matches found"
+
 func NewToolExecutor(workingDir string) *ToolExecutor {
 	return &ToolExecutor{
-		workingDir: workingDir,
+		workingDir:     workingDir,
+		Backend:        LocalBackend{},
+		MaxLineLength:  DefaultMaxLineLength,
+		MaxReadLines:   DefaultMaxReadLines,
+		IgnoredDirs:    DefaultIgnoredDirs,
+		ProtectedPaths: DefaultProtectedPaths,
+		BashTimeout:    DefaultBashTimeout,
+		Safety:         DefaultSafetyPolicy(),
 	}
 }
 
 func (t *ToolExecutor) Execute(name string, args map[string]interface{}) (string, error) {
+	if t.Log != nil {
+		t.Log.LogCall(name, args)
+	}
+	start := time.Now()
+	// Malformed calls get a precise error instead of reaching the tool
+	var output string
+	err := validateInput(name, args)
+	if err == nil {
+		output, err = t.dispatch(name, args)
+	}
+	elapsed := time.Since(start)
+	if t.Log != nil {
+		t.Log.LogResult(name, output, err, elapsed)
+	}
+	t.logEvent(name, args, output, err, elapsed)
+	if t.Usage != nil {
+		// A search without matches counts as failed: it usually means the
+		// model guessed a wrong name
+		failed := err != nil || (name == "search" && output == noMatches) || (name == "glob" && output == noGlobMatches)
+		t.Usage.Record(name, failed, elapsed, len(output))
+	}
+	return output, err
+}
+
+func (t *ToolExecutor) dispatch(name string, args map[string]interface{}) (string, error) {
 	switch name {
 	case "bash":
 		return t.executeBash(args)
 	case "read_file":
 		return t.readFile(args)
+	case "read_files":
+		return t.readFiles(args)
 	case "write_file":
 		return t.writeFile(args)
+	case "edit_file":
+		return t.editFile(args)
 	case "list_files":
 		return t.listFiles(args)
 	case "search":
 		return t.search(args)
+	case "glob":
+		return t.glob(args)
+	case "scratch_dir":
+		return t.scratchDir(args)
+	case "deps":
+		return t.deps(args)
 	default:
 		return "", fmt.Errorf("unknown tool: %s", name)
 	}
@@ -41,19 +193,43 @@ func (t *ToolExecutor) executeBash(args map[string]interface{}) (string, error)
 	if !ok {
 		return "", fmt.Errorf("bash requires 'command' parameter")
 	}
+	timeout, err := t.bashTimeout(args)
+	if err != nil {
+		return "", err
+	}
+	if err := t.Safety.Check(command); err != nil {
+		return "", err
+	}
 
-	cmd := exec.Command("bash", "-c", command)
-	cmd.Dir = t.workingDir
-	
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	var env []string
+	if t.ScratchDir != "" {
+		env = append(env, "SCRATCH_DIR="+t.ScratchDir)
+	}
+	if t.DryRun {
+		t.dryRunBash(command)
+		return "", nil
+	}
+	if t.Limits != nil {
+		command = t.Limits.wrap(command)
+	}
 	
-	err := cmd.Run()
+	var stdout, stderr string
+	if runner, ok := t.Backend.(TimeoutRunner); ok {
+		stdout, stderr, err = runner.RunTimeout(t.workingDir, command, env, timeout)
+	} else {
+		stdout, stderr, err = t.Backend.Run(t.workingDir, command, env)
+	}
 	
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		output += "\nSTDERR:\n" + stderr.String()
+	output := stdout
+	if stderr != "" {
+		output += "\nSTDERR:\n" + stderr
+	}
+	if note := t.restoreProtected(); note != "" {
+		output += note
+	}
+	if errors.Is(err, ErrTimedOut) {
+		// Keep the partial output; it often shows where the command hung
+		return output + fmt.Sprintf("\nError: command timed out after %s and was killed. Run long commands in the background, or pass a larger timeout_seconds.", timeout), nil
 	}
 	
 	if err != nil && output == "" {
@@ -68,17 +244,49 @@ func (t *ToolExecutor) readFile(args map[string]interface{}) (string, error) {
 	if !ok {
 		return "", fmt.Errorf("read_file requires 'path' parameter")
 	}
+	enc, err := t.encodingArg(args)
+	if err != nil {
+		return "", err
+	}
 
-	if !filepath.IsAbs(path) {
-		path = filepath.Join(t.workingDir, path)
+	path, err = t.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	if err := t.checkHidden(path); err != nil {
+		return "", err
 	}
 
-	content, err := os.ReadFile(path)
+	content, err := t.readContent(path)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+		return "", fmt.Errorf("failed to read file: %w", t.notFound(path, err))
+	}
+	if enc == "" {
+		text, err := t.selectLines(string(content), args)
+		if err != nil {
+			return "", err
+		}
+		return shortenLines(text, t.MaxLineLength, nil) + t.editNote(path, string(content)), nil
 	}
 
-	return string(content), nil
+	if enc == encodingAuto {
+		enc, _ = detectEncoding(content)
+	}
+	text, err := decodeText(content, enc)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	note := t.editNote(path, text)
+	if text, err = t.selectLines(text, args); err != nil {
+		return "", err
+	}
+	text = shortenLines(text, t.MaxLineLength, nil)
+	if enc != EncodingUTF8 && t.DetectEncoding {
+		text += fmt.Sprintf("\n\n[Decoded from %s. write_file keeps this encoding.]", enc)
+	} else if enc != EncodingUTF8 {
+		text += fmt.Sprintf("\n\n[Decoded from %s. Pass encoding %q to write_file to keep it.]", enc, enc)
+	}
+	return text + note, nil
 }
 
 func (t *ToolExecutor) writeFile(args map[string]interface{}) (string, error) {
@@ -91,84 +299,320 @@ func (t *ToolExecutor) writeFile(args map[string]interface{}) (string, error) {
 	if !ok {
 		return "", fmt.Errorf("write_file requires 'content' parameter")
 	}
+	enc, err := t.encodingArg(args)
+	if err != nil {
+		return "", err
+	}
 
-	if !filepath.IsAbs(path) {
-		path = filepath.Join(t.workingDir, path)
+	path, err = t.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	if err := t.checkProtected(path); err != nil {
+		return "", err
+	}
+	if err := t.checkPlannedCreate(path); err != nil {
+		return "", err
+	}
+	if err := t.claimFile(path); err != nil {
+		return "", err
+	}
+	defer t.releaseFile(path)
+	warning, err := t.checkGenerated(path)
+	if err != nil {
+		return "", err
 	}
+	data := []byte(content)
+	if enc != "" {
+		if data, enc, err = t.encodeFor(path, content, enc); err != nil {
+			return "", fmt.Errorf("failed to encode %s: %w", path, err)
+		}
+		if enc != EncodingUTF8 {
+			warning = fmt.Sprintf(" (encoded as %s)", enc) + warning
+		}
+	}
+	if t.DryRun {
+		t.dryRunWrite("write_file", path, data)
+		return fmt.Sprintf("File written successfully to %s", path) + warning + t.countEdit(path, content), nil
+	}
+	_, statErr := t.Backend.Stat(path)
+	created := errors.Is(statErr, fs.ErrNotExist)
 
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
+	if err := t.Backend.WriteFile(path, data); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
 	}
+	t.recordChange(path, created)
+
+	return fmt.Sprintf("File written successfully to %s", path) + warning + t.countEdit(path, content), nil
+}
 
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+// editFile replaces old_string with new_string in an existing file: the
+// single occurrence, or every one with replace_all. The file goes through
+// the same checks as write_file.
+func (t *ToolExecutor) editFile(args map[string]interface{}) (string, error) {
+	path, ok := args["path"].(string)
+	if !ok {
+		return "", fmt.Errorf("edit_file requires 'path' parameter")
+	}
+	oldString, ok := args["old_string"].(string)
+	if !ok || oldString == "" {
+		return "", fmt.Errorf("edit_file requires a non-empty 'old_string' parameter; use write_file to create a file")
+	}
+	newString, ok := args["new_string"].(string)
+	if !ok {
+		return "", fmt.Errorf("edit_file requires 'new_string' parameter")
+	}
+	if oldString == newString {
+		return "", fmt.Errorf("old_string and new_string are identical; nothing to change")
+	}
+	replaceAll, _ := args["replace_all"].(bool)
+	enc, err := t.encodingArg(args)
+	if err != nil {
+		return "", err
+	}
+
+	path, err = t.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	if err := t.checkProtected(path); err != nil {
+		return "", err
+	}
+	if err := t.claimFile(path); err != nil {
+		return "", err
+	}
+	defer t.releaseFile(path)
+	warning, err := t.checkGenerated(path)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := t.readContent(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", t.notFound(path, err))
+	}
+	content := string(raw)
+	if enc != "" {
+		decodeAs := enc
+		if decodeAs == encodingAuto {
+			decodeAs, _ = detectEncoding(raw)
+		}
+		if content, err = decodeText(raw, decodeAs); err != nil {
+			return "", fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+	}
+
+	count := strings.Count(content, oldString)
+	switch {
+	case count == 0:
+		return "", fmt.Errorf("old_string was not found in %s. Read the file again and copy the text exactly, including whitespace and indentation", t.journalPath(path))
+	case count > 1 && !replaceAll:
+		return "", fmt.Errorf("old_string appears %d times in %s. Include more surrounding lines to make it unique, or set replace_all to change every occurrence", count, t.journalPath(path))
+	}
+	if !replaceAll {
+		count = 1
+	}
+	content = strings.Replace(content, oldString, newString, count)
+
+	data := []byte(content)
+	if enc != "" {
+		if data, enc, err = t.encodeFor(path, content, enc); err != nil {
+			return "", fmt.Errorf("failed to encode %s: %w", path, err)
+		}
+		if enc != EncodingUTF8 {
+			warning = fmt.Sprintf(" (encoded as %s)", enc) + warning
+		}
+	}
+	if t.DryRun {
+		t.dryRunWrite("edit_file", path, data)
+		return fmt.Sprintf("Replaced %d occurrence(s) in %s", count, path) + warning + t.countEdit(path, content), nil
+	}
+	if err := t.Backend.WriteFile(path, data); err != nil {
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
+	t.recordChange(path, false)
+
+	return fmt.Sprintf("Replaced %d occurrence(s) in %s", count, path) + warning + t.countEdit(path, content), nil
+}
+
+// journalPath is the key a file is recorded under in the journal.
+func (t *ToolExecutor) journalPath(path string) string {
+	if rel, err := filepath.Rel(t.workingDir, path); err == nil && within(t.workingDir, path) {
+		return rel
+	}
+	return path
+}
+
+// claimFile refuses a write to a file not yet changed in this run once
+// MaxFilesChanged distinct files have been changed, and otherwise claims
+// the file in the journal, so writes by tasks running in parallel count
+// against the limit before they happen. releaseFile must be called once
+// the write is recorded or abandoned.
+func (t *ToolExecutor) claimFile(path string) error {
+	if t.Journal == nil || t.MaxFilesChanged <= 0 {
+		return nil
+	}
+	if changed, ok := t.Journal.Claim(t.journalPath(path), t.MaxFilesChanged); !ok {
+		return fmt.Errorf("file change limit reached: %d files have been changed in this run; only those files can still be edited. Finish the current task with the files already changed and call complete_task", changed)
+	}
+	return nil
+}
+
+// releaseFile gives back the claim on path when it was not written.
+func (t *ToolExecutor) releaseFile(path string) {
+	if t.Journal != nil && t.MaxFilesChanged > 0 {
+		t.Journal.Release(t.journalPath(path))
+	}
+}
 
-	return fmt.Sprintf("File written successfully to %s", path), nil
+func (t *ToolExecutor) recordChange(path string, created bool) {
+	t.forgetDeps(path)
+	if t.Journal != nil {
+		t.Journal.Record(t.journalPath(path), created, t.TaskID)
+	}
 }
 
 func (t *ToolExecutor) listFiles(args map[string]interface{}) (string, error) {
 	path := t.workingDir
 	if p, ok := args["path"].(string); ok {
-		if filepath.IsAbs(p) {
-			path = p
-		} else {
-			path = filepath.Join(t.workingDir, p)
-		}
+		path = p
+	}
+	path, err := t.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	if err := t.checkHidden(path); err != nil {
+		return "", err
 	}
+	if err := t.checkSubmodule(path); err != nil {
+		return "", err
+	}
+
+	sortBy, _ := args["sort"].(string)
+	showMtime, _ := args["show_mtime"].(bool)
 
-	entries, err := os.ReadDir(path)
+	entries, err := t.Backend.ReadDir(path)
 	if err != nil {
-		return "", fmt.Errorf("failed to list directory: %w", err)
+		return "", fmt.Errorf("failed to list directory: %w", t.notFound(path, err))
 	}
 
-	var result strings.Builder
+	filter := t.newIgnoreFilter(path, args)
+	var listed []listedEntry
+	var ignored []string
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if hidden, _ := t.hidden(filepath.Join(path, entry.Name()), entry.IsDir()); hidden {
+			continue
+		}
+		if filter.ignored(filepath.Join(path, entry.Name()), entry.IsDir()) {
+			ignored = append(ignored, entry.Name())
+			continue
+		}
+		info, _ := entry.Info()
+		listed = append(listed, listedEntry{entry: entry, info: info})
+	}
+	if err := sortEntries(listed, sortBy); err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	for _, l := range listed {
+		entry := l.entry
+		var mtime string
+		if showMtime {
+			mtime = "modified " + formatModTime(l.modTime())
+		}
+		if submodule, ok := t.submoduleAt(filepath.Join(path, entry.Name())); ok {
+			result.WriteString(describeSubmodule(submodule, entry.Name()))
+		} else if entry.Type()&os.ModeSymlink != 0 && !t.local() {
+			result.WriteString(fmt.Sprintf("[LINK] %s\n", entry.Name()))
+		} else if entry.Type()&os.ModeSymlink != 0 {
+			result.WriteString(t.describeSymlink(filepath.Join(path, entry.Name()), entry.Name()))
+		} else if entry.IsDir() && mtime != "" {
+			result.WriteString(fmt.Sprintf("[DIR]  %s (%s)\n", entry.Name(), mtime))
+		} else if entry.IsDir() {
 			result.WriteString(fmt.Sprintf("[DIR]  %s\n", entry.Name()))
+		} else if mtime != "" {
+			result.WriteString(fmt.Sprintf("[FILE] %s (%d bytes, %s)\n", entry.Name(), l.size(), mtime))
 		} else {
-			info, _ := entry.Info()
-			size := int64(0)
-			if info != nil {
-				size = info.Size()
-			}
-			result.WriteString(fmt.Sprintf("[FILE] %s (%d bytes)\n", entry.Name(), size))
+			result.WriteString(fmt.Sprintf("[FILE] %s (%d bytes)\n", entry.Name(), l.size()))
 		}
 	}
+	if len(ignored) > 0 {
+		result.WriteString(ignoredNote(ignored))
+	}
 
 	return result.String(), nil
 }
 
 func (t *ToolExecutor) search(args map[string]interface{}) (string, error) {
-	pattern, ok := args["pattern"].(string)
-	if !ok {
-		return "", fmt.Errorf("search requires 'pattern' parameter")
+	patterns, err := searchPatterns(args)
+	if err != nil {
+		return "", err
 	}
 
 	path := t.workingDir
 	if p, ok := args["path"].(string); ok {
-		if filepath.IsAbs(p) {
-			path = p
-		} else {
-			path = filepath.Join(t.workingDir, p)
-		}
+		path = p
+	}
+	path, err = t.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	if err := t.checkHidden(path); err != nil {
+		return "", err
+	}
+	if err := t.checkSubmodule(path); err != nil {
+		return "", err
+	}
+
+	// Several patterns match any of them, as repeated -e flags
+	var expressions string
+	for _, pattern := range patterns {
+		expressions += " -e " + shellQuote(pattern)
 	}
 
-	// Use ripgrep if available, otherwise fall back to grep
-	cmd := exec.Command("rg", "--no-heading", "--line-number", pattern, path)
-	output, err := cmd.CombinedOutput()
+	// Use ripgrep if available, otherwise fall back to grep. Hidden files
+	// are filtered from either's output; rg also skips them while searching.
+	var ignoreFile string
+	if t.Ignore != nil {
+		ignoreFile = " --ignore-file " + shellQuote(filepath.Join(t.workingDir, AgentIgnoreFile))
+	}
+	// Ignored files are filtered from either's output too, as rg honors
+	// .gitignore only in git repositories and grep not at all
+	filter := t.newIgnoreFilter(path, args)
+	if filter.off {
+		ignoreFile += " --no-ignore-vcs"
+	}
+	stdout, stderr, err := t.Backend.Run(t.workingDir, "rg --no-heading --with-filename --line-number"+ignoreFile+filter.excludeFlags(true)+expressions+" "+shellQuote(path), nil)
+	output := stdout + stderr
 	
 	if err != nil {
 		// Try grep as fallback
-		cmd = exec.Command("grep", "-r", "-n", pattern, path)
-		output, err = cmd.CombinedOutput()
+		stdout, stderr, err = t.Backend.Run(t.workingDir, "grep -r -n -H"+filter.excludeFlags(false)+expressions+" "+shellQuote(path), nil)
+		output = stdout + stderr
 		if err != nil && len(output) == 0 {
-			return "No matches found", nil
+			return noMatches, nil
 		}
 	}
 
-	return string(output), nil
+	output = filter.filterIgnored(t.filterSubmodules(t.filterHidden(output)))
+	if strings.TrimSpace(output) == "" {
+		return noMatches, nil
+	}
+
+	if len(patterns) > 1 {
+		output = groupByPattern(output, patterns)
+	}
+
+	// Show long matching lines, e.g. in minified files, around the match
+	return shortenLines(output, t.MaxLineLength, combinedRegexp(patterns)), nil
+}
+
+func (t *ToolExecutor) scratchDir(args map[string]interface{}) (string, error) {
+	if t.ScratchDir == "" {
+		return "", fmt.Errorf("no scratch directory is configured for this run")
+	}
+	return t.ScratchDir, nil
 }
 
 func GetAvailableTools() []map[string]interface{} {
@@ -183,13 +627,17 @@ func GetAvailableTools() []map[string]interface{} {
 						"type":        "string",
 						"description": "The bash command to execute",
 					},
+					"timeout_seconds": map[string]interface{}{
+						"type":        "integer",
+						"description": "Seconds the command may run before it is killed (optional; defaults to 120)",
+					},
 				},
 				"required": []string{"command"},
 			},
 		},
 		{
 			"name":        "read_file",
-			"description": "Read the contents of a file",
+			"description": "Read the contents of a file. Large files are cut to their first lines with a note giving the total; pass start_line and end_line to read a part, returned with line numbers",
 			"input_schema": map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -197,10 +645,41 @@ func GetAvailableTools() []map[string]interface{} {
 						"type":        "string",
 						"description": "The path to the file to read",
 					},
+					"start_line": map[string]interface{}{
+						"type":        "integer",
+						"description": "First line to read, starting at 1 (optional)",
+					},
+					"end_line": map[string]interface{}{
+						"type":        "integer",
+						"description": "Last line to read, inclusive; defaults to the end of the file (optional)",
+					},
+					"encoding": map[string]interface{}{
+						"type":        "string",
+						"description": "Text encoding of the file: utf-8, latin-1, windows-1252, utf-16le, utf-16be, or auto to detect it (optional)",
+					},
 				},
 				"required": []string{"path"},
 			},
 		},
+		{
+			"name":        "read_files",
+			"description": "Read several files at once, returned in the order given. Prefer it over consecutive read_file calls when a task needs many files. Output is capped in size; files left out are named at the end",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"paths": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "The paths of the files to read",
+					},
+					"encoding": map[string]interface{}{
+						"type":        "string",
+						"description": "Text encoding of the files: utf-8, latin-1, windows-1252, utf-16le, utf-16be, or auto to detect it (optional)",
+					},
+				},
+				"required": []string{"paths"},
+			},
+		},
 		{
 			"name":        "write_file",
 			"description": "Write content to a file",
@@ -215,13 +694,47 @@ func GetAvailableTools() []map[string]interface{} {
 						"type":        "string",
 						"description": "The content to write to the file",
 					},
+					"encoding": map[string]interface{}{
+						"type":        "string",
+						"description": "Text encoding to write: utf-8, latin-1, windows-1252, utf-16le, utf-16be, or auto to keep the existing file's encoding (optional)",
+					},
 				},
 				"required": []string{"path", "content"},
 			},
 		},
+		{
+			"name":        "edit_file",
+			"description": "Replace an exact piece of text in an existing file, without rewriting the whole file. old_string must match the file exactly, including whitespace and indentation, and must be unique unless replace_all is set",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "The path to the file to edit",
+					},
+					"old_string": map[string]interface{}{
+						"type":        "string",
+						"description": "The exact text to replace; include enough surrounding lines to make it unique",
+					},
+					"new_string": map[string]interface{}{
+						"type":        "string",
+						"description": "The text to put in its place",
+					},
+					"replace_all": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Replace every occurrence of old_string instead of requiring exactly one (optional)",
+					},
+					"encoding": map[string]interface{}{
+						"type":        "string",
+						"description": "Text encoding of the file: utf-8, latin-1, windows-1252, utf-16le, utf-16be, or auto to detect and keep it (optional)",
+					},
+				},
+				"required": []string{"path", "old_string", "new_string"},
+			},
+		},
 		{
 			"name":        "list_files",
-			"description": "List files and directories in a given path",
+			"description": "List files and directories in a given path. Entries ignored by .gitignore and dependency or build directories such as node_modules are left out and counted at the end. Symlinks are shown as [LINK] with their target; links leading outside the working directory are not followed. Git submodules are shown as [SUBMODULE].",
 			"input_schema": map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -229,12 +742,22 @@ func GetAvailableTools() []map[string]interface{} {
 						"type":        "string",
 						"description": "The directory path to list (optional, defaults to working directory)",
 					},
+					"sort": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{SortByName, SortBySize, SortByMtime},
+						"description": "Order of the entries: name (default), size (largest files first) or mtime (most recently modified first)",
+					},
+					"show_mtime": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include each entry's modification time (optional)",
+					},
+					"include_ignored": includeIgnoredProperty,
 				},
 			},
 		},
 		{
 			"name":        "search",
-			"description": "Search for a pattern in files using grep/ripgrep",
+			"description": "Search for a pattern in files using grep/ripgrep. Pass several patterns at once to find any of them in one call; matches are then grouped by pattern. Files ignored by .gitignore and dependency or build directories such as node_modules are not searched.",
 			"input_schema": map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -242,13 +765,67 @@ func GetAvailableTools() []map[string]interface{} {
 						"type":        "string",
 						"description": "The pattern to search for",
 					},
+					"patterns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Several patterns to search for at once, instead of or in addition to pattern (optional)",
+					},
 					"path": map[string]interface{}{
 						"type":        "string",
 						"description": "The path to search in (optional, defaults to working directory)",
 					},
+					"include_ignored": includeIgnoredProperty,
+				},
+			},
+		},
+		{
+			"name":        "glob",
+			"description": "Find files by name with a glob pattern, e.g. **/*.go or cmd/**/main.go. Prefer it over bash find. * and ? match within one directory and ** across directories, so *.go only matches files directly in the search path. Files ignored by .gitignore and dependency or build directories such as node_modules are left out. Returns matching paths relative to the working directory, capped at 200.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pattern": map[string]interface{}{
+						"type":        "string",
+						"description": "The glob pattern, matched against paths relative to path",
+					},
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "The directory to search in (optional, defaults to working directory)",
+					},
+					"include_ignored": includeIgnoredProperty,
 				},
 				"required": []string{"pattern"},
 			},
 		},
+		{
+			"name":        "scratch_dir",
+			"description": "Get the path of a temporary scratch directory for this run, outside the project. Use it for extracted archives, build output or other throwaway files instead of writing them into the project. It is also available to bash as $SCRATCH_DIR and is deleted when the run ends.",
+			"input_schema": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			"name":        "deps",
+			"description": "List the project's installed dependencies and their versions (go list -m all, npm ls, pip freeze or cargo tree, depending on the manifests present). Check it before using a library API whose shape depends on the version. Results are cached for the run; pass refresh after installing or upgrading packages with bash.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"filter": map[string]interface{}{
+						"type":        "string",
+						"description": "Only list dependencies whose name or version contains this text, case-insensitively (optional)",
+					},
+					"ecosystem": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"go", "node", "python", "rust"},
+						"description": "List only this ecosystem (optional, defaults to every ecosystem with a manifest in the working directory)",
+					},
+					"refresh": map[string]interface{}{
+						"type":        "boolean",
+						"description": "List the dependencies again instead of using the cached result (optional)",
+					},
+				},
+			},
+		},
 	}
 }
\ No newline at end of file
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/openswe/go-swe-agent/pkg/state"
+)
+
+// TestParallelWritesRespectFileLimit writes distinct files from executors
+// forked for parallel tasks, which share the run's journal.
+func TestParallelWritesRespectFileLimit(t *testing.T) {
+	const limit = 3
+	dir := t.TempDir()
+	base := NewToolExecutor(dir)
+	base.Journal = &state.FileJournal{}
+	base.MaxFilesChanged = limit
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fork := base.Fork(state.ToolUsage{})
+			fork.Execute("write_file", map[string]interface{}{
+				"path":    fmt.Sprintf("file-%d.txt", i),
+				"content": "x",
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	written, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != limit {
+		t.Errorf("%d files written, want %d", len(written), limit)
+	}
+	if modified, created := base.Journal.Counts(); modified+created != limit {
+		t.Errorf("journal records %d files, want %d", modified+created, limit)
+	}
+}
+
+func TestFailedWriteDoesNotCountAgainstFileLimit(t *testing.T) {
+	dir := t.TempDir()
+	executor := NewToolExecutor(dir)
+	executor.Journal = &state.FileJournal{}
+	executor.MaxFilesChanged = 1
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := executor.Execute("edit_file", map[string]interface{}{"path": "a.txt", "old_string": "missing", "new_string": "x"}); err == nil {
+		t.Fatal("edit of missing text succeeded")
+	}
+	if _, err := executor.Execute("write_file", map[string]interface{}{"path": "b.txt", "content": "x"}); err != nil {
+		t.Errorf("write after a failed edit: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// EventLog writes a run as a stream of structured events, one JSON object
+// per line, for programs such as CI jobs that read the run rather than
+// watch it. The console output is unchanged. A nil EventLog records
+// nothing.
+type EventLog struct {
+	// Redact, when set, is applied to every string in the data of an
+	// event before it is written, to mask secrets
+	Redact func(string) string
+
+	mu    sync.Mutex
+	runID string
+	file  *os.File
+}
+
+// Event is one line of the event log.
+type Event struct {
+	Time  time.Time   `json:"time"`
+	RunID string      `json:"run_id"`
+	Event string      `json:"event"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+func NewEventLog(path, runID string) (*EventLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	return &EventLog{runID: runID, file: file}, nil
+}
+
+// Log adds an event with its data to the log.
+func (l *EventLog) Log(event string, data interface{}) {
+	if l == nil {
+		return
+	}
+	if l.Redact != nil {
+		data = redactData(data, l.Redact)
+	}
+	line, err := json.Marshal(Event{Time: time.Now(), RunID: l.runID, Event: event, Data: data})
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	l.file.Write(append(line, '\n'))
+}
+
+// redactData returns data as plain JSON values, with redact applied to
+// every string in it.
+func redactData(data interface{}, redact func(string) string) interface{} {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil
+	}
+	return redactValue(value, redact)
+}
+
+func redactValue(value interface{}, redact func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redact(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i], redact)
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = redactValue(v[key], redact)
+		}
+	}
+	return value
+}
+
+func (l *EventLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
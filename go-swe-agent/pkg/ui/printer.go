@@ -0,0 +1,93 @@
+// Package ui writes the agent's progress output, so programs embedding the
+// agent can send it to a buffer, a file or their own interface instead of
+// standard output.
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Printer writes progress output to a writer. Colored output is used only
+// on standard output, and only when it is a terminal. A nil *Printer
+// writes to standard output.
+type Printer struct {
+	w     io.Writer
+	plain bool
+}
+
+// NewPrinter returns a Printer writing to w, or to standard output when w
+// is nil. Output to any other writer is not colored.
+func NewPrinter(w io.Writer) *Printer {
+	if w == nil {
+		return nil
+	}
+	return &Printer{w: w, plain: true}
+}
+
+func (p *Printer) writer() io.Writer {
+	if p == nil {
+		return color.Output
+	}
+	return p.w
+}
+
+func (p *Printer) Printf(format string, a ...interface{}) {
+	fmt.Fprintf(p.writer(), format, a...)
+}
+
+func (p *Printer) Println(a ...interface{}) {
+	fmt.Fprintln(p.writer(), a...)
+}
+
+func (p *Printer) Print(a ...interface{}) {
+	fmt.Fprint(p.writer(), a...)
+}
+
+// The colored helpers behave like their counterparts in fatih/color: a
+// newline is appended to format if it has none.
+
+func (p *Printer) Red(format string, a ...interface{}) { p.colored(color.FgRed, format, a...) }
+
+func (p *Printer) Green(format string, a ...interface{}) { p.colored(color.FgGreen, format, a...) }
+
+func (p *Printer) Yellow(format string, a ...interface{}) { p.colored(color.FgYellow, format, a...) }
+
+func (p *Printer) Blue(format string, a ...interface{}) { p.colored(color.FgBlue, format, a...) }
+
+func (p *Printer) Magenta(format string, a ...interface{}) { p.colored(color.FgMagenta, format, a...) }
+
+func (p *Printer) Cyan(format string, a ...interface{}) { p.colored(color.FgCyan, format, a...) }
+
+// GreenString and RedString return colored text to embed in a line.
+
+func (p *Printer) GreenString(format string, a ...interface{}) string {
+	return p.color(color.FgGreen).Sprintf(format, a...)
+}
+
+func (p *Printer) RedString(format string, a ...interface{}) string {
+	return p.color(color.FgRed).Sprintf(format, a...)
+}
+
+func (p *Printer) color(attr color.Attribute) *color.Color {
+	c := color.New(attr)
+	if p != nil && p.plain {
+		c.DisableColor()
+	}
+	return c
+}
+
+func (p *Printer) colored(attr color.Attribute, format string, a ...interface{}) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	c := p.color(attr)
+	if len(a) == 0 {
+		c.Fprint(p.writer(), format)
+		return
+	}
+	c.Fprintf(p.writer(), format, a...)
+}
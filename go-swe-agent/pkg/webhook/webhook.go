@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/openswe/go-swe-agent/pkg/hooks"
+	"github.com/openswe/go-swe-agent/pkg/ui"
+)
+
+const (
+	SignatureHeader = "X-Go-Swe-Agent-Signature"
+	EventHeader     = "X-Go-Swe-Agent-Event"
+
+	maxAttempts  = 4
+	initialDelay = time.Second
+	queueSize    = 100
+)
+
+// Notifier POSTs lifecycle events as JSON to a URL. Deliveries happen in
+// the background, in order, and are retried with exponential backoff;
+// failures are reported but never block or fail the run.
+type Notifier struct {
+	url        string
+	secret     []byte
+	out        *ui.Printer
+	httpClient *http.Client
+	queue      chan hooks.Event
+	done       chan struct{}
+}
+
+// New starts a notifier for url. When secret is non-empty every request
+// carries an HMAC-SHA256 signature of its body in SignatureHeader.
+// Failed deliveries are reported to out.
+func New(url, secret string, out *ui.Printer) *Notifier {
+	n := &Notifier{
+		url:        url,
+		secret:     []byte(secret),
+		out:        out,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		queue:      make(chan hooks.Event, queueSize),
+		done:       make(chan struct{}),
+	}
+	go n.loop()
+	return n
+}
+
+func (n *Notifier) OnEvent(event hooks.Event) {
+	select {
+	case n.queue <- event:
+	default:
+		n.out.Yellow("  ⚠️  Webhook queue full, dropping %s event\n", event.Type)
+	}
+}
+
+// Close stops accepting events and waits up to timeout for queued
+// deliveries to finish.
+func (n *Notifier) Close(timeout time.Duration) {
+	close(n.queue)
+	select {
+	case <-n.done:
+	case <-time.After(timeout):
+		n.out.Yellow("  ⚠️  Webhook deliveries still pending after %s\n", timeout)
+	}
+}
+
+func (n *Notifier) loop() {
+	defer close(n.done)
+	for event := range n.queue {
+		if err := n.deliver(event); err != nil {
+			n.out.Yellow("  ⚠️  Webhook delivery of %s failed: %v\n", event.Type, err)
+		}
+	}
+}
+
+func (n *Notifier) deliver(event hooks.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	delay := initialDelay
+	for attempt := 1; ; attempt++ {
+		err = n.post(event.Type, body)
+		if err == nil || attempt == maxAttempts {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (n *Notifier) post(eventType hooks.EventType, body []byte) error {
+	req, err := http.NewRequest("POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(eventType))
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.secret, body))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body, as sent in SignatureHeader.
+// Receivers recompute it with the shared secret to verify a delivery.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}